@@ -0,0 +1,166 @@
+package json2msgp
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ArchiveFormat is the kind of archive ConvertArchive writes.
+type ArchiveFormat int
+
+const (
+	// ArchiveTar is an uncompressed tar archive.
+	ArchiveTar ArchiveFormat = iota
+	// ArchiveZip is a zip archive of deflated files.
+	ArchiveZip
+)
+
+// ArchiveManifestName is the name of the manifest in an archive written by
+// ConvertArchive.
+const ArchiveManifestName = "manifest.json"
+
+// ArchiveEntry describes one converted file of an archive.
+type ArchiveEntry struct {
+	// Input is the path of the JSON file, as given to ConvertArchive.
+	Input string `json:"input"`
+	// Output is the name of the MSGP file in the archive.
+	Output string `json:"output"`
+	// SHA256 is the hex SHA-256 of the MSGP.
+	SHA256 string `json:"sha256"`
+}
+
+// ConvertArchive converts JSON files into a single archive written to w, as
+// system variable bundles are shipped between environments.  Each path is a
+// JSON file, or a directory whose *.json files are all converted.  The MSGP
+// of NAME.json is stored as NAME.msgp, and the archive ends with a manifest,
+// ArchiveManifestName, which lists the entries returned, as a JSON array.
+//
+// opts apply to every file.  The first file which fails stops the
+// conversion, leaving the archive unfinished.  So that the same inputs
+// always make the same archive, every file in it has the same modification
+// time, archiveTime.
+func ConvertArchive(w io.Writer, format ArchiveFormat, paths []string, opts ...Option) ([]ArchiveEntry, error) {
+	var files []string
+	for _, path := range paths {
+		if info, err := os.Stat(path); err == nil && info.IsDir() {
+			matches, err := filepath.Glob(filepath.Join(path, "*.json"))
+			if err != nil {
+				return nil, errors.Wrap(err, "ConvertArchive")
+			}
+			files = append(files, matches...)
+		} else {
+			files = append(files, path)
+		}
+	}
+
+	aw, err := newArchiveWriter(w, format)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]ArchiveEntry, 0, len(files))
+	names := make(map[string]string, len(files))
+	for _, file := range files {
+		name := strings.TrimSuffix(filepath.Base(file), ".json") + ".msgp"
+		if other, ok := names[name]; ok {
+			return entries, fmt.Errorf("ConvertArchive: %s and %s would both be stored as %s", other, file, name)
+		}
+		names[name] = file
+
+		in, err := os.Open(file)
+		if err != nil {
+			return entries, errors.Wrap(err, "ConvertArchive")
+		}
+		var out bytes.Buffer
+		err = ConvertStreamWithOptions(in, &out, opts...)
+		in.Close()
+		if err != nil {
+			return entries, errors.Wrapf(err, "ConvertArchive %s", file)
+		}
+		if err = aw.add(name, out.Bytes()); err != nil {
+			return entries, errors.Wrapf(err, "ConvertArchive writing %s", name)
+		}
+		sum := sha256.Sum256(out.Bytes())
+		entries = append(entries, ArchiveEntry{Input: file, Output: name, SHA256: hex.EncodeToString(sum[:])})
+	}
+
+	manifest, err := json.MarshalIndent(entries, "", "  ")
+	if err == nil {
+		err = aw.add(ArchiveManifestName, append(manifest, '\n'))
+	}
+	if err == nil {
+		err = aw.close()
+	}
+	return entries, errors.Wrap(err, "ConvertArchive writing manifest")
+}
+
+// archiveTime is the modification time of the files ConvertArchive writes:
+// the earliest a zip archive can record.
+var archiveTime = time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// archiveWriter adds files to an archive of either format.
+type archiveWriter struct {
+	tar *tar.Writer
+	zip *zip.Writer
+}
+
+func newArchiveWriter(w io.Writer, format ArchiveFormat) (*archiveWriter, error) {
+	switch format {
+	case ArchiveTar:
+		return &archiveWriter{tar: tar.NewWriter(w)}, nil
+	case ArchiveZip:
+		return &archiveWriter{zip: zip.NewWriter(w)}, nil
+	}
+	return nil, fmt.Errorf("ConvertArchive: %w: unknown archive format %d", ErrBadOption, format)
+}
+
+// add adds the file name holding data.
+func (a *archiveWriter) add(name string, data []byte) error {
+	if a.tar != nil {
+		err := a.tar.WriteHeader(&tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     name,
+			Mode:     0644,
+			Size:     int64(len(data)),
+			ModTime:  archiveTime,
+			Format:   tar.FormatPAX,
+		})
+		if err == nil {
+			_, err = a.tar.Write(data)
+		}
+		return err
+	}
+	f, err := a.zip.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: archiveTime})
+	if err == nil {
+		_, err = f.Write(data)
+	}
+	return err
+}
+
+// close finishes the archive, without closing the writer under it.
+func (a *archiveWriter) close() error {
+	if a.tar != nil {
+		return a.tar.Close()
+	}
+	return a.zip.Close()
+}
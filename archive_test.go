@@ -0,0 +1,110 @@
+package json2msgp_test
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ndau/json2msgp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvertArchive(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, data string) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(data), 0644))
+		return path
+	}
+	write("bundle/a.json", `[1]`)
+	write("bundle/b.json", `{"Fee": 2}`)
+	write("bundle/notes.txt", `not JSON`)
+	c := write("c.json", `"c"`)
+	paths := []string{filepath.Join(dir, "bundle"), c}
+
+	want := map[string][]byte{
+		"a.msgp": {0x91, 1},
+		"b.msgp": []byte("\x81\xa3Fee\x02"),
+		"c.msgp": {0xa1, 'c'},
+	}
+	sum := sha256.Sum256(want["b.msgp"])
+
+	for _, format := range []json2msgp.ArchiveFormat{json2msgp.ArchiveTar, json2msgp.ArchiveZip} {
+		var archive bytes.Buffer
+		entries, err := json2msgp.ConvertArchive(&archive, format, paths)
+		require.NoError(t, err)
+		require.Len(t, entries, 3)
+		require.Equal(t, json2msgp.ArchiveEntry{
+			Input:  filepath.Join(dir, "bundle", "b.json"),
+			Output: "b.msgp",
+			SHA256: hex.EncodeToString(sum[:]),
+		}, entries[1])
+
+		files := readArchive(t, format, archive.Bytes())
+		var manifest []json2msgp.ArchiveEntry
+		require.NoError(t, json.Unmarshal(files[json2msgp.ArchiveManifestName], &manifest))
+		require.Equal(t, entries, manifest)
+		delete(files, json2msgp.ArchiveManifestName)
+		require.Equal(t, want, files)
+
+		// the same inputs make the same archive
+		var again bytes.Buffer
+		_, err = json2msgp.ConvertArchive(&again, format, paths)
+		require.NoError(t, err)
+		require.Equal(t, archive.Bytes(), again.Bytes())
+	}
+
+	write("other/a.json", `[2]`)
+	_, err := json2msgp.ConvertArchive(io.Discard, json2msgp.ArchiveTar, []string{filepath.Join(dir, "bundle"), filepath.Join(dir, "other")})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "would both be stored as a.msgp")
+
+	bad := write("bad.json", `{`)
+	_, err = json2msgp.ConvertArchive(io.Discard, json2msgp.ArchiveZip, []string{bad})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "bad.json")
+}
+
+// readArchive returns the files of an archive by name.
+func readArchive(t *testing.T, format json2msgp.ArchiveFormat, data []byte) map[string][]byte {
+	files := make(map[string][]byte)
+	if format == json2msgp.ArchiveTar {
+		r := tar.NewReader(bytes.NewReader(data))
+		for {
+			hdr, err := r.Next()
+			if err == io.EOF {
+				return files
+			}
+			require.NoError(t, err)
+			files[hdr.Name], err = io.ReadAll(r)
+			require.NoError(t, err)
+		}
+	}
+	r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+	for _, f := range r.File {
+		rc, err := f.Open()
+		require.NoError(t, err)
+		files[f.Name], err = io.ReadAll(rc)
+		require.NoError(t, err)
+		rc.Close()
+	}
+	return files
+}
@@ -0,0 +1,143 @@
+package json2msgp
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// AuditRecord is one line of an AuditLog.
+type AuditRecord struct {
+	// Seq numbers the records of a log from 1.
+	Seq uint64 `json:"seq"`
+	// Time is when the decision was made.
+	Time time.Time `json:"time"`
+	// SignedOffBy identifies who is accountable for the conversion.
+	SignedOffBy string `json:"signed_off_by"`
+	// Path, Hint and Outcome are as for Node.
+	Path    string `json:"path"`
+	Hint    string `json:"hint"`
+	Outcome string `json:"outcome"`
+	// Prev is the hex SHA-256 of the previous line, without its newline, or
+	// empty for the first record.  It chains the records together, so that
+	// VerifyAuditLog can detect any which were removed or altered.
+	Prev string `json:"prev"`
+}
+
+// AuditLog writes an append-only record of every heuristic and hint decision
+// made by the converters using it, as JSON lines, for the review of chain
+// configuration changes.  It is safe for concurrent use.
+type AuditLog struct {
+	lock        sync.Mutex
+	w           io.Writer
+	signedOffBy string
+	seq         uint64
+	prev        string
+	err         error
+}
+
+// NewAuditLog returns an AuditLog which writes to w, attributing every
+// record to signedOffBy.
+//
+// To append to an existing log, open it for appending and use ResumeAuditLog
+// instead, so that the chain continues unbroken.
+func NewAuditLog(w io.Writer, signedOffBy string) *AuditLog {
+	return &AuditLog{w: w, signedOffBy: signedOffBy}
+}
+
+// ResumeAuditLog verifies the existing log read from r, and returns an
+// AuditLog which continues it on w.
+func ResumeAuditLog(r io.Reader, w io.Writer, signedOffBy string) (*AuditLog, error) {
+	seq, prev, err := verifyAuditLog(r)
+	if err != nil {
+		return nil, err
+	}
+	return &AuditLog{w: w, signedOffBy: signedOffBy, seq: seq, prev: prev}, nil
+}
+
+// WithAuditLog records every decision of the conversion to log.
+func WithAuditLog(log *AuditLog) Option {
+	return func(c *Converter) {
+		c.audit = log
+	}
+}
+
+// Err returns the first error writing the log.  Once writing fails, no
+// further records are written, because the chain would be broken.
+func (a *AuditLog) Err() error {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	return a.err
+}
+
+// record appends a record of one decision.
+func (a *AuditLog) record(path, hint, outcome string) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if a.err != nil {
+		return
+	}
+	line, err := json.Marshal(AuditRecord{
+		Seq:         a.seq + 1,
+		Time:        time.Now().UTC(),
+		SignedOffBy: a.signedOffBy,
+		Path:        path,
+		Hint:        hint,
+		Outcome:     outcome,
+		Prev:        a.prev,
+	})
+	if err == nil {
+		_, err = a.w.Write(append(line, '\n'))
+	}
+	if err != nil {
+		a.err = err
+		return
+	}
+	a.seq++
+	a.prev = auditHash(line)
+}
+
+// VerifyAuditLog checks that the records read from r are numbered and
+// chained correctly, and returns how many there are.
+func VerifyAuditLog(r io.Reader) (int, error) {
+	seq, _, err := verifyAuditLog(r)
+	return int(seq), err
+}
+
+func verifyAuditLog(r io.Reader) (uint64, string, error) {
+	var seq uint64
+	var prev string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		var rec AuditRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			return seq, prev, fmt.Errorf("%w: record %d: %s", ErrAuditLog, seq+1, err)
+		}
+		if rec.Seq != seq+1 || rec.Prev != prev {
+			return seq, prev, fmt.Errorf("%w: record %d is out of sequence", ErrAuditLog, seq+1)
+		}
+		seq = rec.Seq
+		prev = auditHash(scanner.Bytes())
+	}
+	return seq, prev, scanner.Err()
+}
+
+func auditHash(line []byte) string {
+	sum := sha256.Sum256(line)
+	return hex.EncodeToString(sum[:])
+}
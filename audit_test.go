@@ -0,0 +1,72 @@
+package json2msgp_test
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ndau/json2msgp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditLog(t *testing.T) {
+	var buf bytes.Buffer
+	log := json2msgp.NewAuditLog(&buf, "alice")
+
+	_, err := json2msgp.ConvertWithOptions(
+		map[string]interface{}{"Script": "oACI", "Fee": 1.0},
+		json2msgp.WithAuditLog(log),
+		json2msgp.WithTypeHints(map[string][]string{"Fee": []string{"uint64"}}),
+	)
+	require.NoError(t, err)
+	require.NoError(t, log.Err())
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	require.Len(t, lines, 2)
+	var rec json2msgp.AuditRecord
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &rec))
+	require.Equal(t, uint64(1), rec.Seq)
+	require.Equal(t, "alice", rec.SignedOffBy)
+	require.Equal(t, "$.Fee", rec.Path)
+	require.Equal(t, "uint64", rec.Hint)
+	require.Empty(t, rec.Prev)
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &rec))
+	require.Equal(t, "$.Script", rec.Path)
+	require.Equal(t, "base64", rec.Outcome)
+	require.NotEmpty(t, rec.Prev)
+
+	n, err := json2msgp.VerifyAuditLog(strings.NewReader(buf.String()))
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+
+	// resuming continues the chain
+	var more bytes.Buffer
+	log, err = json2msgp.ResumeAuditLog(strings.NewReader(buf.String()), &more, "bob")
+	require.NoError(t, err)
+	_, err = json2msgp.ConvertWithOptions("plain", json2msgp.WithAuditLog(log))
+	require.NoError(t, err)
+	n, err = json2msgp.VerifyAuditLog(strings.NewReader(buf.String() + more.String()))
+	require.NoError(t, err)
+	require.Equal(t, 3, n)
+
+	// removing or altering a record breaks the chain
+	for _, broken := range []string{
+		lines[1] + "\n",
+		lines[0] + "\n" + strings.Replace(lines[1], "base64", "string", 1) + "\n" + more.String(),
+		"not json\n",
+	} {
+		_, err = json2msgp.VerifyAuditLog(strings.NewReader(broken))
+		require.True(t, errors.Is(err, json2msgp.ErrAuditLog), "got %v", err)
+	}
+}
@@ -0,0 +1,185 @@
+package json2msgp
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// BenchResult measures the conversion of a corpus.
+type BenchResult struct {
+	// Documents is the number of documents in the corpus, and Iterations how
+	// many times each was converted.
+	Documents  int
+	Iterations int
+	// BytesIn and BytesOut are the sizes of the corpus as JSON and as MSGP.
+	BytesIn  int64
+	BytesOut int64
+	// Elapsed is the total time spent converting.
+	Elapsed time.Duration
+	// Allocs and AllocBytes count the heap allocations made converting.
+	Allocs     uint64
+	AllocBytes uint64
+	// Hot lists the heuristic and hint decisions made converting the corpus
+	// once, most frequent first.
+	Hot []HotPath
+}
+
+// HotPath counts the decisions made for the values at a path.  Array
+// indices in the path are replaced by "[*]", so that the elements of an
+// array are counted together.
+type HotPath struct {
+	Path    string
+	Hint    string
+	Outcome string
+	Count   int
+}
+
+// Bench converts each JSON document of corpus iterations times with opts,
+// and measures the conversions.
+func Bench(corpus [][]byte, iterations int, opts ...Option) (*BenchResult, error) {
+	if iterations < 1 {
+		iterations = 1
+	}
+	result := &BenchResult{Documents: len(corpus), Iterations: iterations}
+
+	// profile once, separately, so that profiling doesn't distort the timing
+	counts := make(map[HotPath]int)
+	profile := func(c *Converter) {
+		c.profile = func(path []string, hint, outcome string) {
+			counts[HotPath{Path: generalPath(path), Hint: hint, Outcome: outcome}]++
+		}
+	}
+	profiled := append(opts[:len(opts):len(opts)], profile)
+	for i, doc := range corpus {
+		n, err := benchConvert(doc, io.Discard, profiled)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Bench document %d", i)
+		}
+		result.BytesIn += int64(len(doc)) * int64(iterations)
+		result.BytesOut += n * int64(iterations)
+	}
+	for hot, count := range counts {
+		hot.Count = count
+		result.Hot = append(result.Hot, hot)
+	}
+	sort.Slice(result.Hot, func(i, j int) bool {
+		a, b := result.Hot[i], result.Hot[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		return a.Path < b.Path
+	})
+
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	start := time.Now()
+	for i := 0; i < iterations; i++ {
+		for _, doc := range corpus {
+			if _, err := benchConvert(doc, io.Discard, opts); err != nil {
+				return nil, err
+			}
+		}
+	}
+	result.Elapsed = time.Since(start)
+	runtime.ReadMemStats(&after)
+	result.Allocs = after.Mallocs - before.Mallocs
+	result.AllocBytes = after.TotalAlloc - before.TotalAlloc
+	return result, nil
+}
+
+// benchConvert converts one document and returns the size of its output.
+func benchConvert(doc []byte, out io.Writer, opts []Option) (int64, error) {
+	counter := &countingWriter{w: out}
+	err := ConvertStreamWithOptions(bytes.NewReader(doc), counter, opts...)
+	return counter.n, err
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.n += int64(n)
+	return n, err
+}
+
+// generalPath joins path, replacing array indices by "[*]".
+func generalPath(path []string) string {
+	var sb strings.Builder
+	sb.WriteString("$")
+	for _, segment := range path {
+		if strings.HasPrefix(segment, "[") && !strings.HasPrefix(segment, `["`) {
+			segment = "[*]"
+		}
+		sb.WriteString(segment)
+	}
+	return sb.String()
+}
+
+// conversions is the total number of documents converted.
+func (r *BenchResult) conversions() float64 {
+	return float64(r.Documents * r.Iterations)
+}
+
+// DocumentsPerSecond is the conversion throughput in documents.
+func (r *BenchResult) DocumentsPerSecond() float64 {
+	return r.conversions() / r.Elapsed.Seconds()
+}
+
+// BytesPerSecond is the conversion throughput in bytes of JSON.
+func (r *BenchResult) BytesPerSecond() float64 {
+	return float64(r.BytesIn) / r.Elapsed.Seconds()
+}
+
+// AllocsPerDocument is the mean number of allocations per conversion.
+func (r *BenchResult) AllocsPerDocument() float64 {
+	return float64(r.Allocs) / r.conversions()
+}
+
+// Report writes a summary of the result, with the top hot paths, to w.
+func (r *BenchResult) Report(w io.Writer, top int) error {
+	_, err := fmt.Fprintf(w,
+		"%d documents x %d iterations in %s\n%.0f documents/s, %.2f MB/s in, %.2f MB/s out\n%.1f allocs/document, %.0f bytes/document\n",
+		r.Documents, r.Iterations, r.Elapsed,
+		r.DocumentsPerSecond(), r.BytesPerSecond()/1e6, float64(r.BytesOut)/r.Elapsed.Seconds()/1e6,
+		r.AllocsPerDocument(), float64(r.AllocBytes)/r.conversions(),
+	)
+	if err != nil || len(r.Hot) == 0 {
+		return err
+	}
+	if _, err = fmt.Fprintln(w, "hottest decisions per pass:"); err != nil {
+		return err
+	}
+	for i, hot := range r.Hot {
+		if i == top {
+			break
+		}
+		hint := hot.Hint
+		if hint == "" {
+			hint = "-"
+		}
+		if _, err = fmt.Fprintf(w, "%8d  %-10s %-12s %s\n", hot.Count, hint, hot.Outcome, hot.Path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
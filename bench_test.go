@@ -0,0 +1,49 @@
+package json2msgp_test
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ndau/json2msgp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBench(t *testing.T) {
+	corpus := [][]byte{
+		[]byte(`{"Fee": 200, "Scripts": ["oACI", "oACJ", "plain!"]}`),
+		[]byte(`{"Fee": 300, "Scripts": []}`),
+	}
+	hints := map[string][]string{"Fee": []string{"uint64"}}
+	result, err := json2msgp.Bench(corpus, 3, json2msgp.WithTypeHints(hints))
+	require.NoError(t, err)
+
+	require.Equal(t, 2, result.Documents)
+	require.Equal(t, 3, result.Iterations)
+	require.Equal(t, int64(3*(len(corpus[0])+len(corpus[1]))), result.BytesIn)
+	require.NotZero(t, result.BytesOut)
+	require.NotZero(t, result.Elapsed)
+	require.Equal(t, []json2msgp.HotPath{
+		{Path: "$.Fee", Hint: "uint64", Outcome: "hint", Count: 2},
+		{Path: "$.Scripts[*]", Outcome: "base64", Count: 2},
+		{Path: "$.Scripts[*]", Outcome: "string", Count: 1},
+	}, result.Hot)
+
+	var report strings.Builder
+	require.NoError(t, result.Report(&report, 2))
+	require.Contains(t, report.String(), "2 documents x 3 iterations")
+	require.Contains(t, report.String(), "allocs/document")
+	require.Contains(t, report.String(), "       2  uint64     hint         $.Fee\n")
+	require.NotContains(t, report.String(), "string")
+
+	_, err = json2msgp.Bench([][]byte{[]byte(`{"Fee": 1.5}`)}, 1)
+	require.Error(t, err)
+}
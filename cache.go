@@ -0,0 +1,146 @@
+package json2msgp
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"io"
+	"sync"
+)
+
+// Cache remembers the MSGP encodings of recently converted JSON documents,
+// so that services which convert the same payloads over and over, such as
+// identical sysvar queries, skip encoding them again.  It holds at most a
+// fixed number of documents, evicting the least recently used.  It is safe
+// for concurrent use.
+//
+// Documents are identified by the SHA-256 of their JSON text and of the type
+// hints they are converted with.  Other options are not part of the key, so
+// a cache must only be shared by conversions which differ at most in their
+// type hints.
+type Cache struct {
+	lock    sync.Mutex
+	size    int
+	order   *list.List
+	entries map[cacheKey]*list.Element
+	hits    int
+	misses  int
+}
+
+type cacheKey struct {
+	input, hints [sha256.Size]byte
+}
+
+type cacheEntry struct {
+	key cacheKey
+	out []byte
+}
+
+// NewCache returns a Cache which holds the encodings of up to size documents.
+func NewCache(size int) *Cache {
+	return &Cache{
+		size:    size,
+		order:   list.New(),
+		entries: make(map[cacheKey]*list.Element),
+	}
+}
+
+// WithCache makes the stream functions look up each document in cache before
+// converting it, and add it afterwards.
+//
+// The cache is bypassed while anything needs to see every value converted:
+// Explain, or an option such as WithTrace, WithWarnings, WithLogger,
+// WithMetrics or WithAuditLog.
+func WithCache(cache *Cache) Option {
+	return func(c *Converter) {
+		c.cache = cache
+	}
+}
+
+// Len returns the number of documents in the cache.
+func (cache *Cache) Len() int {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+	return cache.order.Len()
+}
+
+// Stats returns the number of lookups which found a document, and which
+// did not.
+func (cache *Cache) Stats() (hits, misses int) {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+	return cache.hits, cache.misses
+}
+
+// get returns the encoding of the document with key, if there is one.  Its
+// capacity is its length, so appending to it never changes the cache.
+func (cache *Cache) get(key cacheKey) ([]byte, bool) {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+	elem, ok := cache.entries[key]
+	if !ok {
+		cache.misses++
+		return nil, false
+	}
+	cache.hits++
+	cache.order.MoveToFront(elem)
+	out := elem.Value.(cacheEntry).out
+	return out[:len(out):len(out)], true
+}
+
+// put adds a copy of out as the encoding of the document with key.
+func (cache *Cache) put(key cacheKey, out []byte) {
+	if cache.size <= 0 {
+		return
+	}
+	out = append([]byte(nil), out...)
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+	if elem, ok := cache.entries[key]; ok {
+		elem.Value = cacheEntry{key: key, out: out}
+		cache.order.MoveToFront(elem)
+		return
+	}
+	cache.entries[key] = cache.order.PushFront(cacheEntry{key: key, out: out})
+	for cache.order.Len() > cache.size {
+		oldest := cache.order.Back()
+		cache.order.Remove(oldest)
+		delete(cache.entries, oldest.Value.(cacheEntry).key)
+	}
+}
+
+// cacheKey returns the key of the document whose JSON text is text.
+func (c *Converter) cacheKey(text []byte) cacheKey {
+	if c.hintsSum == nil {
+		h := sha256.New()
+		for _, key := range sortedKeys(c.typeHints) {
+			// Lengths keep the boundaries between names unambiguous.
+			writeCacheString(h, key)
+			writeCacheLen(h, len(c.typeHints[key]))
+			for _, hint := range c.typeHints[key] {
+				writeCacheString(h, hint)
+			}
+		}
+		var sum [sha256.Size]byte
+		h.Sum(sum[:0])
+		c.hintsSum = &sum
+	}
+	return cacheKey{input: sha256.Sum256(text), hints: *c.hintsSum}
+}
+
+func writeCacheLen(w io.Writer, n int) {
+	w.Write([]byte{byte(n >> 24), byte(n >> 16), byte(n >> 8), byte(n)})
+}
+
+func writeCacheString(w io.Writer, s string) {
+	writeCacheLen(w, len(s))
+	io.WriteString(w, s)
+}
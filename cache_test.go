@@ -0,0 +1,75 @@
+package json2msgp_test
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/ndau/json2msgp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCache(t *testing.T) {
+	cache := json2msgp.NewCache(2)
+	convert := func(in string, hints map[string][]string, opts ...json2msgp.Option) []byte {
+		var out bytes.Buffer
+		opts = append(opts, json2msgp.WithTypeHints(hints), json2msgp.WithCache(cache))
+		err := json2msgp.ConvertStreamWithOptions(strings.NewReader(in), &out, opts...)
+		require.NoError(t, err)
+		return out.Bytes()
+	}
+	stats := func(wantHits, wantMisses int) {
+		t.Helper()
+		hits, misses := cache.Stats()
+		require.Equal(t, []int{wantHits, wantMisses}, []int{hits, misses})
+	}
+	signed := map[string][]string{"Fee": {"int64"}}
+	float := map[string][]string{"Fee": {"float64"}}
+
+	require.Equal(t, []byte{0x81, 0xa3, 'F', 'e', 'e', 5}, convert(`{"Fee": 5}`, signed))
+	stats(0, 1)
+	require.Equal(t, []byte{0x81, 0xa3, 'F', 'e', 'e', 5}, convert(" {\"Fee\": 5}\n", signed))
+	stats(1, 1)
+
+	// other hints are another document
+	require.Equal(t, []byte{0x81, 0xa3, 'F', 'e', 'e', 0xcb, 0x40, 0x14, 0, 0, 0, 0, 0, 0}, convert(`{"Fee": 5}`, float))
+	stats(1, 2)
+	require.Equal(t, 2, cache.Len())
+
+	// appending a delimiter must not change the cached encoding
+	require.Equal(t, []byte{0x81, 0xa3, 'F', 'e', 'e', 0xcb, 0x40, 0x14, 0, 0, 0, 0, 0, 0, '\n'},
+		convert(`{"Fee": 5}`, float, json2msgp.WithDelimiter([]byte("\n"))))
+	require.Equal(t, []byte{0x81, 0xa3, 'F', 'e', 'e', 0xcb, 0x40, 0x14, 0, 0, 0, 0, 0, 0}, convert(`{"Fee": 5}`, float))
+	stats(3, 2)
+
+	// the least recently used document is evicted
+	convert(`[1]`, nil)
+	require.Equal(t, 2, cache.Len())
+	convert(`{"Fee": 5}`, signed)
+	stats(3, 4)
+	convert(`{"Fee": 5}`, float)
+	stats(3, 5)
+
+	// failures are not cached
+	var out bytes.Buffer
+	err := json2msgp.ConvertStreamWithOptions(strings.NewReader(`{"Fee": -1}`), &out,
+		json2msgp.WithTypeHints(map[string][]string{"Fee": {"uint64"}}), json2msgp.WithCache(cache))
+	require.Error(t, err)
+	err = json2msgp.ConvertStreamWithOptions(strings.NewReader(`{"Fee": -1}`), &out,
+		json2msgp.WithTypeHints(map[string][]string{"Fee": {"uint64"}}), json2msgp.WithCache(cache))
+	require.Error(t, err)
+	stats(3, 7)
+
+	// each of multiple documents is looked up
+	require.Equal(t, []byte{0x91, 1, 0x91, 1}, convert("[1] [1]", nil, json2msgp.WithMultipleDocuments()))
+	stats(4, 8)
+}
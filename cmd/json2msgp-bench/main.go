@@ -0,0 +1,78 @@
+// Command json2msgp-bench measures the conversion of a corpus of JSON
+// documents, to quantify the effect of hint choices on real payloads.
+//
+// Usage:
+//
+//	json2msgp-bench [-hints hints.json] [-n iterations] [-top n] path...
+//
+// Each path is a JSON document, or a directory whose *.json files are all
+// used.
+package main
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"flag"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/ndau/json2msgp"
+)
+
+func main() {
+	hintsPath := flag.String("hints", "", "path of a JSON file of type hints")
+	iterations := flag.Int("n", 100, "number of times to convert each document")
+	top := flag.Int("top", 10, "number of hot paths to report")
+	flag.Parse()
+
+	var hints map[string][]string
+	if *hintsPath != "" {
+		f, err := os.Open(*hintsPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		hints, err = json2msgp.ReadHints(f)
+		f.Close()
+		if err != nil {
+			log.Fatalf("reading %s: %s", *hintsPath, err)
+		}
+	}
+
+	var corpus [][]byte
+	for _, path := range flag.Args() {
+		files := []string{path}
+		if info, err := os.Stat(path); err == nil && info.IsDir() {
+			files, err = filepath.Glob(filepath.Join(path, "*.json"))
+			if err != nil {
+				log.Fatal(err)
+			}
+		}
+		for _, file := range files {
+			data, err := ioutil.ReadFile(file)
+			if err != nil {
+				log.Fatal(err)
+			}
+			corpus = append(corpus, data)
+		}
+	}
+	if len(corpus) == 0 {
+		log.Fatal("no documents given")
+	}
+
+	result, err := json2msgp.Bench(corpus, *iterations, json2msgp.WithTypeHints(hints))
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err = result.Report(os.Stdout, *top); err != nil {
+		log.Fatal(err)
+	}
+}
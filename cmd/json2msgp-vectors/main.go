@@ -0,0 +1,69 @@
+// Command json2msgp-vectors writes a file of test vectors which other
+// implementations of json2msgp can check themselves against, to stay
+// byte-compatible with this package.
+//
+// Usage:
+//
+//	json2msgp-vectors [-hints hints.json] [-o vectors.json] path...
+//
+// Each path is a JSON file, or a directory whose *.json files are all used.
+// The type hints for NAME.json are read from NAME.hints.json if it exists,
+// and otherwise from the -hints file.
+package main
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"log"
+	"os"
+
+	"github.com/ndau/json2msgp"
+)
+
+func main() {
+	hintsPath := flag.String("hints", "", "path of a JSON file of default type hints")
+	outPath := flag.String("o", "", "path of the vector file to write (default stdout)")
+	flag.Parse()
+
+	var hints map[string][]string
+	if *hintsPath != "" {
+		f, err := os.Open(*hintsPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		hints, err = json2msgp.ReadHints(f)
+		f.Close()
+		if err != nil {
+			log.Fatalf("reading %s: %s", *hintsPath, err)
+		}
+	}
+
+	vectors, err := generate(flag.Args(), hints)
+	if err != nil {
+		log.Fatal(err)
+	}
+	data, err := json.MarshalIndent(vectors, "", "  ")
+	if err != nil {
+		log.Fatal(err)
+	}
+	data = append(data, '\n')
+
+	if *outPath == "" {
+		_, err = os.Stdout.Write(data)
+	} else {
+		err = ioutil.WriteFile(*outPath, data, 0644)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ndau/json2msgp"
+	"github.com/pkg/errors"
+)
+
+// vectorVersion is the version of the vector file format.
+const vectorVersion = 1
+
+// VectorFile is the format of the output.
+type VectorFile struct {
+	Version int      `json:"version"`
+	Vectors []Vector `json:"vectors"`
+}
+
+// Vector is one conversion: Input converted with Hints produces the MSGP
+// bytes in hex.
+type Vector struct {
+	Name  string              `json:"name"`
+	Input json.RawMessage     `json:"input"`
+	Hints map[string][]string `json:"hints,omitempty"`
+	Msgp  string              `json:"msgp"`
+}
+
+// generate converts the JSON files named by paths into vectors.
+func generate(paths []string, hints map[string][]string) (*VectorFile, error) {
+	files, err := inputFiles(paths)
+	if err != nil {
+		return nil, err
+	}
+	vf := &VectorFile{Version: vectorVersion, Vectors: make([]Vector, 0, len(files))}
+	for _, file := range files {
+		v, err := vector(file, hints)
+		if err != nil {
+			return nil, errors.Wrap(err, file)
+		}
+		vf.Vectors = append(vf.Vectors, v)
+	}
+	return vf, nil
+}
+
+// inputFiles lists the JSON inputs named by paths, expanding directories.
+func inputFiles(paths []string) ([]string, error) {
+	var files []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+		matches, err := filepath.Glob(filepath.Join(path, "*.json"))
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(matches)
+		for _, match := range matches {
+			if !strings.HasSuffix(match, ".hints.json") {
+				files = append(files, match)
+			}
+		}
+	}
+	return files, nil
+}
+
+// vector converts one file, with the hints in its NAME.hints.json if there
+// is one.
+func vector(file string, hints map[string][]string) (Vector, error) {
+	base := strings.TrimSuffix(file, ".json")
+	v := Vector{Name: filepath.Base(base), Hints: hints}
+
+	data, err := ioutil.ReadFile(base + ".hints.json")
+	if err == nil {
+		v.Hints = nil
+		if err = json.Unmarshal(data, &v.Hints); err != nil {
+			return v, errors.Wrap(err, "reading hints")
+		}
+	} else if !os.IsNotExist(err) {
+		return v, err
+	}
+
+	data, err = ioutil.ReadFile(file)
+	if err != nil {
+		return v, err
+	}
+	var input bytes.Buffer
+	if err = json.Compact(&input, data); err != nil {
+		return v, err
+	}
+	v.Input = input.Bytes()
+
+	var out bytes.Buffer
+	err = json2msgp.ConvertStream(bytes.NewReader(data), &out, v.Hints)
+	if err != nil {
+		return v, err
+	}
+	v.Msgp = hex.EncodeToString(out.Bytes())
+	return v, nil
+}
@@ -0,0 +1,44 @@
+package main
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerate(t *testing.T) {
+	vf, err := generate([]string{"testdata"}, map[string][]string{"Rate": []string{"int64"}})
+	require.NoError(t, err)
+	require.Equal(t, 1, vf.Version)
+	require.Equal(t, []Vector{
+		{
+			Name:  "fee",
+			Input: json.RawMessage(`{"Fee":200}`),
+			Hints: map[string][]string{"Fee": []string{"uint64"}},
+			Msgp:  "81a3466565ccc8",
+		},
+		{
+			Name:  "rate",
+			Input: json.RawMessage(`{"Rate":-1,"Name":"ndau!"}`),
+			Hints: map[string][]string{"Rate": []string{"int64"}},
+			Msgp:  "82a44e616d65a56e64617521a452617465ff",
+		},
+	}, vf.Vectors)
+
+	vf, err = generate([]string{"testdata/fee.json"}, nil)
+	require.NoError(t, err)
+	require.Len(t, vf.Vectors, 1)
+
+	_, err = generate([]string{"testdata/missing.json"}, nil)
+	require.Error(t, err)
+}
@@ -0,0 +1,89 @@
+//go:build js && wasm
+
+// Command json2msgp-wasm exposes json2msgp to JavaScript, so that a browser
+// can preview the exact MSGP encoding of a document:
+//
+//	GOOS=js GOARCH=wasm go build -o json2msgp.wasm ./cmd/json2msgp-wasm
+//
+// Once loaded with Go's wasm_exec.js, it defines a global json2msgp object:
+//
+//	json2msgp.convert(json, hints)    // => {msgp: Uint8Array} or {error: string}
+//	json2msgp.inferHints(msgp)        // => {hints: object} or {error: string}
+//
+// json is JSON text.  hints is an object of type hints as described in
+// json2msgp.ConvertStream, e.g. {Fee: ["uint64"]}, and may be omitted or null.
+package main
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"bytes"
+	"strings"
+	"syscall/js"
+
+	"github.com/ndau/json2msgp"
+)
+
+func main() {
+	js.Global().Set("json2msgp", map[string]interface{}{
+		"convert":    js.FuncOf(convert),
+		"inferHints": js.FuncOf(inferHints),
+	})
+	// keep the functions available for the life of the page
+	select {}
+}
+
+func convert(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 || args[0].Type() != js.TypeString {
+		return failure("convert: json must be a string")
+	}
+	var hints map[string][]string
+	if len(args) > 1 && !args[1].IsUndefined() && !args[1].IsNull() {
+		// going through JSON text validates the hints as the other front ends do
+		hintsJSON := js.Global().Get("JSON").Call("stringify", args[1]).String()
+		var err error
+		if hints, err = json2msgp.ReadHints(strings.NewReader(hintsJSON)); err != nil {
+			return failure("convert: hints: " + err.Error())
+		}
+	}
+
+	var out bytes.Buffer
+	if err := json2msgp.ConvertStream(strings.NewReader(args[0].String()), &out, hints); err != nil {
+		return failure(err.Error())
+	}
+	msgp := js.Global().Get("Uint8Array").New(out.Len())
+	js.CopyBytesToJS(msgp, out.Bytes())
+	return map[string]interface{}{"msgp": msgp}
+}
+
+func inferHints(this js.Value, args []js.Value) interface{} {
+	if len(args) < 1 || !args[0].InstanceOf(js.Global().Get("Uint8Array")) {
+		return failure("inferHints: msgp must be a Uint8Array")
+	}
+	msgp := make([]byte, args[0].Length())
+	js.CopyBytesToGo(msgp, args[0])
+	hints, err := json2msgp.InferHints(msgp)
+	if err != nil {
+		return failure(err.Error())
+	}
+	result := make(map[string]interface{}, len(hints))
+	for key, types := range hints {
+		list := make([]interface{}, len(types))
+		for i, typ := range types {
+			list[i] = typ
+		}
+		result[key] = list
+	}
+	return map[string]interface{}{"hints": result}
+}
+
+func failure(msg string) interface{} {
+	return map[string]interface{}{"error": msg}
+}
@@ -0,0 +1,262 @@
+// Command json2msgp converts JSON to MSGP.
+//
+// Usage:
+//
+//	json2msgp [-hints hints.json] [-out out.msgp] [file]
+//	json2msgp -follow [-poll interval] [-hints hints.json] [file]
+//	json2msgp manifest [-hints hints.json] manifest.json
+//	json2msgp inspect file|hex
+//	json2msgp tojson [-hints hints.json] [-humanize] [-root name] [file]
+//
+// With no subcommand, it converts the JSON document in file, or standard
+// input, and writes the MSGP to standard output, or to the -out file.  The
+// -out file is replaced atomically once the conversion has succeeded, so a
+// failed conversion never leaves a truncated file behind.  The encoding is
+// that of the heuristics and hints: this command registers no system
+// variable types, so json2msgp.WithSysvar would change nothing here.
+//
+// With -follow, the input is NDJSON, and each record is converted and
+// written as soon as its line is complete.  At the end of the input, it
+// waits for more, like tail -f, until interrupted.
+//
+// The manifest subcommand converts every system variable of a manifest, as
+// json2msgp.Manifest describes, and writes a JSON object of their MSGP
+// encodings in base64, by name, ready for a SetSysvar batch.  A manifest
+// whose name ends in .yaml or .yml is read as YAML.
+//
+// The inspect subcommand prints an offset-annotated breakdown of a MSGP blob,
+// as json2msgp.Inspect makes it, a line per value.  The blob is read from
+// the file named, or if there is none, the argument is taken as hex, with
+// any spaces ignored.
+//
+// The tojson subcommand converts the other way: it writes each MSGP value of
+// file, or standard input, as a line of JSON, as
+// json2msgp.ConvertStreamToJSON does.  With -humanize, the integers of the
+// known system variable fields are also rendered readably, as
+// json2msgp.SysvarUnits lists them; -root names the system variable which
+// the input is, so that a bare value gets its unit too.
+package main
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/ndau/json2msgp"
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := run(ctx, os.Args[1:], os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "json2msgp:", err)
+		os.Exit(1)
+	}
+}
+
+// run runs the command line args, reading from stdin and writing to stdout
+// where it does not name files.  Following stops when ctx is done.
+func run(ctx context.Context, args []string, stdin io.Reader, stdout io.Writer) error {
+	if len(args) > 0 {
+		switch args[0] {
+		case "manifest":
+			return runManifest(args[1:], stdout)
+		case "inspect":
+			return runInspect(args[1:], stdout)
+		case "tojson":
+			return runToJSON(args[1:], stdin, stdout)
+		}
+	}
+	return runConvert(ctx, args, stdin, stdout)
+}
+
+func runConvert(ctx context.Context, args []string, stdin io.Reader, stdout io.Writer) error {
+	flags := flag.NewFlagSet("json2msgp", flag.ContinueOnError)
+	hintsPath := flags.String("hints", "", "path of a JSON file of type hints (default $"+json2msgp.HintsEnv+")")
+	outPath := flags.String("out", "", "path of the file to write (default stdout)")
+	follow := flags.Bool("follow", false, "convert NDJSON as the input grows, until interrupted")
+	poll := flags.Duration("poll", time.Second, "how often to check for more input with -follow")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() > 1 {
+		return fmt.Errorf("at most one input file may be given")
+	}
+	if *follow && *outPath != "" {
+		return fmt.Errorf("-follow cannot be used with -out")
+	}
+	if *follow && *poll <= 0 {
+		return fmt.Errorf("-poll must be positive")
+	}
+
+	hints, err := readHints(*hintsPath)
+	if err != nil {
+		return err
+	}
+	opts := []json2msgp.Option{json2msgp.WithTypeHints(hints)}
+
+	in := stdin
+	if flags.NArg() == 1 {
+		f, err := os.Open(flags.Arg(0))
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+	if *follow {
+		_, err = json2msgp.ConvertFollow(ctx, in, stdout, *poll, opts...)
+		if ctx.Err() != nil {
+			return nil
+		}
+		return err
+	}
+	if *outPath != "" {
+		_, err = json2msgp.ConvertFile(*outPath, in, opts...)
+		return err
+	}
+	return json2msgp.ConvertStreamWithOptions(in, stdout, opts...)
+}
+
+func runManifest(args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("json2msgp manifest", flag.ContinueOnError)
+	hintsPath := flags.String("hints", "", "path of a JSON file of type hints for every system variable, under those of the manifest")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return fmt.Errorf("manifest: exactly one manifest file must be given")
+	}
+
+	f, err := os.Open(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	var m *json2msgp.Manifest
+	switch filepath.Ext(f.Name()) {
+	case ".yaml", ".yml":
+		m, err = json2msgp.ReadManifestYAML(f)
+	default:
+		m, err = json2msgp.ReadManifest(f)
+	}
+	if err != nil {
+		return err
+	}
+
+	if *hintsPath != "" {
+		hints, err := readHints(*hintsPath)
+		if err != nil {
+			return err
+		}
+		if m.Hints == nil {
+			m.Hints = make(map[string][]string, len(hints))
+		}
+		for key, hint := range hints {
+			if _, ok := m.Hints[key]; !ok {
+				m.Hints[key] = hint
+			}
+		}
+	}
+	out, err := m.Convert()
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+func runInspect(args []string, stdout io.Writer) error {
+	if len(args) != 1 {
+		return fmt.Errorf("inspect: exactly one file or hex string must be given")
+	}
+	b, err := ioutil.ReadFile(args[0])
+	if os.IsNotExist(err) {
+		b, err = hex.DecodeString(strings.Join(strings.Fields(args[0]), ""))
+		if err != nil {
+			return fmt.Errorf("inspect: %s is not a file, nor hex: %s", args[0], err)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	entries, err := json2msgp.Inspect(b)
+	for _, entry := range entries {
+		if _, werr := fmt.Fprintln(stdout, entry); werr != nil {
+			return werr
+		}
+	}
+	return err
+}
+
+func runToJSON(args []string, stdin io.Reader, stdout io.Writer) error {
+	flags := flag.NewFlagSet("json2msgp tojson", flag.ContinueOnError)
+	hintsPath := flags.String("hints", "", "path of a JSON file of type hints (default $"+json2msgp.HintsEnv+")")
+	humanize := flags.Bool("humanize", false, "render napu and durations of known system variable fields readably")
+	root := flags.String("root", "", "name of the system variable being converted, for the units of its root")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() > 1 {
+		return fmt.Errorf("tojson: at most one input file may be given")
+	}
+
+	hints, err := readHints(*hintsPath)
+	if err != nil {
+		return err
+	}
+	var opts []json2msgp.JSONOption
+	if *humanize {
+		opts = append(opts, json2msgp.WithUnits(json2msgp.SysvarUnits), json2msgp.WithRootKey(*root))
+	}
+
+	in := stdin
+	if flags.NArg() == 1 {
+		f, err := os.Open(flags.Arg(0))
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+	return json2msgp.ConvertStreamToJSON(in, stdout, hints, opts...)
+}
+
+// readHints reads the type hints file at path, or if path is empty, those
+// of the environment.
+func readHints(path string) (map[string][]string, error) {
+	if path == "" {
+		return json2msgp.HintsFromEnv("")
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	hints, err := json2msgp.ReadHints(f)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %s", path, err)
+	}
+	return hints, nil
+}
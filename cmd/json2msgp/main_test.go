@@ -0,0 +1,195 @@
+package main
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// writeFiles writes files, by name, to a temporary directory, and returns it.
+func writeFiles(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, content := range files {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+	return dir
+}
+
+func TestRun(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"hints.json": `{"Fee": ["uint64"]}`,
+		"fee.json":   `{"Fee": 200}`,
+		"manifest.json": `{"sysvars": {
+			"Fees": {"value": {"Fee": 200}},
+			"Rates": {"value": {"Fee": 200}, "hints": {"Fee": ["int64"]}}
+		}}`,
+		"manifest.yaml": "sysvars:\n  Fees:\n    value: {Fee: 200}\n  Rates:\n    value: {Fee: 200}\n    hints: {Fee: [int64]}\n",
+	})
+	manifestOut := "{\n  \"Fees\": \"gaNGZWXMyA==\",\n  \"Rates\": \"gaNGZWXRAMg=\"\n}\n"
+
+	tests := []struct {
+		name    string
+		args    []string
+		stdin   string
+		want    string
+		wantErr string
+	}{
+		{"stdin", []string{"-hints", filepath.Join(dir, "hints.json")}, `{"Fee": 200}`, "\x81\xa3Fee\xcc\xc8", ""},
+		{"file", []string{"-hints", filepath.Join(dir, "hints.json"), filepath.Join(dir, "fee.json")}, "", "\x81\xa3Fee\xcc\xc8", ""},
+		{"unhinted", []string{filepath.Join(dir, "fee.json")}, "", "\x81\xa3Fee\xd1\x00\xc8", ""},
+		{"two files", []string{"a.json", "b.json"}, "", "", "at most one input file"},
+		{"manifest", []string{"manifest", "-hints", filepath.Join(dir, "hints.json"), filepath.Join(dir, "manifest.json")}, "", manifestOut, ""},
+		{"yaml manifest", []string{"manifest", "-hints", filepath.Join(dir, "hints.json"), filepath.Join(dir, "manifest.yaml")}, "", manifestOut, ""},
+		{"no manifest", []string{"manifest"}, "", "", "exactly one manifest file"},
+		{"follow to file", []string{"-follow", "-out", "out.msgp"}, "", "", "cannot be used with -out"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			err := run(context.Background(), tt.args, strings.NewReader(tt.stdin), &out)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, out.String())
+		})
+	}
+}
+
+func TestRunOut(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"out.msgp": "old"})
+	out := filepath.Join(dir, "out.msgp")
+
+	// a failed conversion leaves the file as it was
+	err := run(context.Background(), []string{"-out", out}, strings.NewReader(`{"Rate": 1.5}`), ioutil.Discard)
+	require.Error(t, err)
+	got, err := ioutil.ReadFile(out)
+	require.NoError(t, err)
+	require.Equal(t, "old", string(got))
+
+	require.NoError(t, run(context.Background(), []string{"-out", out}, strings.NewReader(`[1]`), ioutil.Discard))
+	got, err = ioutil.ReadFile(out)
+	require.NoError(t, err)
+	require.Equal(t, "\x91\x01", string(got))
+
+	// no temporary files are left behind
+	files, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+}
+
+func TestRunFollow(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"log.ndjson": "{\"Fee\": 200}\n"})
+	path := filepath.Join(dir, "log.ndjson")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	out := &syncBuffer{}
+	done := make(chan error)
+	go func() {
+		done <- run(ctx, []string{"-follow", "-poll", "5ms", path}, nil, out)
+	}()
+	require.Eventually(t, func() bool { return out.String() == "\x81\xa3Fee\xd1\x00\xc8" }, time.Second, 5*time.Millisecond)
+
+	// records appended later are converted as they arrive
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	require.NoError(t, err)
+	_, err = f.WriteString("[1]\n")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	require.Eventually(t, func() bool { return strings.HasSuffix(out.String(), "\x91\x01") }, time.Second, 5*time.Millisecond)
+
+	// interrupting is not an error
+	cancel()
+	require.NoError(t, <-done)
+}
+
+// syncBuffer is a bytes.Buffer which is safe to write and read concurrently.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestRunToJSON(t *testing.T) {
+	fee := "\x81\xa3Fee\xce\x08\xf0\xd1\x80"
+	tests := []struct {
+		name  string
+		args  []string
+		stdin string
+		want  string
+	}{
+		{"plain", nil, fee, "{\"Fee\":150000000}\n"},
+		{"humanize", []string{"-humanize"}, fee, "{\"Fee\":{\"Raw\":150000000,\"Text\":\"1.5 ndau\"}}\n"},
+		{"root", []string{"-humanize", "-root", "Fee"}, "\xce\x08\xf0\xd1\x80", "{\"Raw\":150000000,\"Text\":\"1.5 ndau\"}\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			require.NoError(t, run(context.Background(), append([]string{"tojson"}, tt.args...), strings.NewReader(tt.stdin), &out))
+			require.Equal(t, tt.want, out.String())
+		})
+	}
+}
+
+func TestRunInspect(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"fee.msgp": "\x81\xa3Fee\xcc\xc8"})
+	want := `000000  81  map           1  $
+000001  a3  str           3  $.Fee (key)  "Fee"
+000005  cc  uint          0  $.Fee  200
+`
+	tests := []struct {
+		name    string
+		arg     string
+		want    string
+		wantErr string
+	}{
+		{"file", filepath.Join(dir, "fee.msgp"), want, ""},
+		{"hex", "81 a3 46 65 65 cc c8", want, ""},
+		{"truncated", "81a3466565", strings.Join(strings.SplitAfter(want, "\n")[:2], ""), "offset 5"},
+		{"neither", "81 zz", "", "not a file, nor hex"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			err := run(context.Background(), []string{"inspect", tt.arg}, nil, &out)
+			require.Equal(t, tt.want, out.String())
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
@@ -0,0 +1,90 @@
+// Command json2msgpd is a conversion daemon listening on a unix socket.  See
+// json2msgp.Serve for the protocol.  With -grpc, it serves the Json2Msgp
+// gRPC service of proto/json2msgp.proto on a TCP address instead.
+//
+//	json2msgpd -socket /run/json2msgp.sock -hints hints.json
+//	json2msgpd -grpc localhost:9090 -hints hints.json
+package main
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"errors"
+	"flag"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/ndau/json2msgp"
+	json2msgppb "github.com/ndau/json2msgp/proto"
+	"google.golang.org/grpc"
+)
+
+func main() {
+	socket := flag.String("socket", "json2msgp.sock", "path of the unix socket to listen on")
+	grpcAddr := flag.String("grpc", "", "TCP address to serve the gRPC service on, instead of the unix socket")
+	hintsPath := flag.String("hints", "", "path of a JSON file of type hints (default $"+json2msgp.HintsEnv+")")
+	flag.Parse()
+
+	hints, err := json2msgp.HintsFromEnv("")
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *hintsPath != "" {
+		f, err := os.Open(*hintsPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		hints, err = json2msgp.ReadHints(f)
+		f.Close()
+		if err != nil {
+			log.Fatalf("reading %s: %s", *hintsPath, err)
+		}
+	}
+
+	if *grpcAddr != "" {
+		l, err := net.Listen("tcp", *grpcAddr)
+		if err != nil {
+			log.Fatal(err)
+		}
+		srv := grpc.NewServer()
+		json2msgppb.RegisterJson2MsgpServer(srv, json2msgppb.NewServer(json2msgp.WithTypeHints(hints)))
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+		go func() {
+			<-signals
+			srv.GracefulStop()
+		}()
+		if err = srv.Serve(l); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	l, err := net.Listen("unix", *socket)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// closing the listener removes the socket file
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		l.Close()
+	}()
+
+	err = json2msgp.Serve(l, json2msgp.WithTypeHints(hints))
+	if errors.Is(err, net.ErrClosed) {
+		return
+	}
+	log.Fatal(err)
+}
@@ -0,0 +1,51 @@
+package main
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"bytes"
+
+	"github.com/ndau/json2msgp"
+	"github.com/pkg/errors"
+	"github.com/tinylib/msgp/msgp"
+)
+
+// convert converts JSON text with the type hints in hintsJSON, if any.
+func convert(in, hintsJSON []byte) ([]byte, error) {
+	var hints map[string][]string
+	if len(hintsJSON) > 0 {
+		var err error
+		if hints, err = json2msgp.ReadHints(bytes.NewReader(hintsJSON)); err != nil {
+			return nil, errors.Wrap(err, "reading type hints")
+		}
+	}
+	var out bytes.Buffer
+	if err := json2msgp.ConvertStream(bytes.NewReader(in), &out, hints); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// toJSON converts a single MSGP value to JSON text.  Byte arrays become
+// base64 strings.
+func toJSON(in []byte) ([]byte, error) {
+	rest, err := msgp.Skip(in)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) > 0 {
+		return nil, errors.Errorf("%d bytes of trailing data", len(rest))
+	}
+	var out bytes.Buffer
+	if _, err = msgp.UnmarshalAsJSON(&out, in); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
@@ -0,0 +1,32 @@
+package main
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvert(t *testing.T) {
+	out, err := convert([]byte(`{"Fee":200}`), []byte(`{"Fee":["uint64"]}`))
+	require.NoError(t, err)
+	require.Equal(t, "\x81\xa3Fee\xcc\xc8", string(out))
+
+	back, err := toJSON(out)
+	require.NoError(t, err)
+	require.Equal(t, `{"Fee":200}`, string(back))
+
+	_, err = convert([]byte(`{"Fee":200}`), []byte(`{"Fee":"uint64"}`))
+	require.Error(t, err)
+
+	_, err = toJSON(append(out, 0xc0))
+	require.Error(t, err)
+}
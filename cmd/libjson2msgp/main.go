@@ -0,0 +1,70 @@
+// Command libjson2msgp builds json2msgp as a C shared library, so that tooling
+// in other languages can use the same converter as the Go code:
+//
+//	go build -buildmode=c-shared -o libjson2msgp.so ./cmd/libjson2msgp
+//
+// This also writes libjson2msgp.h, declaring:
+//
+//	int json2msgp_convert(char *json, size_t json_len, char *hints, char **out, size_t *out_len, char **err);
+//	int msgp2json_convert(char *msgp, size_t msgp_len, char **out, size_t *out_len, char **err);
+//	void json2msgp_free(void *p);
+//
+// The conversion functions return 0 on success, with the result in *out and
+// *out_len.  On failure they return -1, with a NUL-terminated message in *err.
+// Either way, the caller releases what it was given with json2msgp_free.
+//
+// hints is a NUL-terminated JSON object of type hints as described in
+// json2msgp.ConvertStream, e.g. {"Fee": ["uint64"]}, or NULL for none.
+package main
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+/*
+#include <stdlib.h>
+*/
+import "C"
+
+import (
+	"unsafe"
+)
+
+//export json2msgp_convert
+func json2msgp_convert(json *C.char, jsonLen C.size_t, hints *C.char, out **C.char, outLen *C.size_t, errOut **C.char) C.int {
+	var hintsJSON []byte
+	if hints != nil {
+		hintsJSON = []byte(C.GoString(hints))
+	}
+	result, err := convert(C.GoBytes(unsafe.Pointer(json), C.int(jsonLen)), hintsJSON)
+	return export(result, err, out, outLen, errOut)
+}
+
+//export msgp2json_convert
+func msgp2json_convert(msgp *C.char, msgpLen C.size_t, out **C.char, outLen *C.size_t, errOut **C.char) C.int {
+	result, err := toJSON(C.GoBytes(unsafe.Pointer(msgp), C.int(msgpLen)))
+	return export(result, err, out, outLen, errOut)
+}
+
+//export json2msgp_free
+func json2msgp_free(p unsafe.Pointer) {
+	C.free(p)
+}
+
+// export hands result or err to the caller in C memory.
+func export(result []byte, err error, out **C.char, outLen *C.size_t, errOut **C.char) C.int {
+	if err != nil {
+		*errOut = C.CString(err.Error())
+		return -1
+	}
+	*out = (*C.char)(C.CBytes(result))
+	*outLen = C.size_t(len(result))
+	return 0
+}
+
+func main() {}
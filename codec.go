@@ -0,0 +1,136 @@
+package json2msgp
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import "github.com/tinylib/msgp/msgp"
+
+// Codec writes the binary format a Converter produces.  The heuristics and
+// type hints decide what to write; the Codec decides how.  MsgpCodec is the
+// default; another Codec lets another binary format, such as CBOR, reuse the
+// rest of the machinery.
+//
+// Each method appends its value to b and returns the extended buffer.
+type Codec interface {
+	AppendNil(b []byte) []byte
+	AppendBool(b []byte, v bool) []byte
+	AppendInt(b []byte, v int64) []byte
+	AppendUint(b []byte, v uint64) []byte
+	AppendFloat32(b []byte, v float32) []byte
+	AppendFloat64(b []byte, v float64) []byte
+	AppendString(b []byte, s string) []byte
+	AppendBytes(b []byte, v []byte) []byte
+	// AppendMapHeader starts a map of sz key/value pairs, with a header of the
+	// given width where the format has a choice.
+	AppendMapHeader(b []byte, sz uint32, width HeaderWidth) []byte
+	// AppendArrayHeader starts an array of sz elements, with a header of the
+	// given width where the format has a choice.
+	AppendArrayHeader(b []byte, sz uint32, width HeaderWidth) []byte
+	// AppendExtension appends an application-defined type, such as an
+	// AddressExtension.
+	AppendExtension(b []byte, e msgp.Extension) ([]byte, error)
+	// AppendIntf appends any other Go value.
+	AppendIntf(b []byte, v interface{}) ([]byte, error)
+}
+
+// MsgpCodec is the Codec which writes MSGP.  Integers are written in their
+// most compact encoding, as msgp's generated code does.
+type MsgpCodec struct{}
+
+var _ Codec = MsgpCodec{}
+
+// AppendNil implements Codec.
+func (MsgpCodec) AppendNil(b []byte) []byte { return msgp.AppendNil(b) }
+
+// AppendBool implements Codec.
+func (MsgpCodec) AppendBool(b []byte, v bool) []byte { return msgp.AppendBool(b, v) }
+
+// AppendInt implements Codec.
+func (MsgpCodec) AppendInt(b []byte, v int64) []byte { return msgp.AppendInt64(b, v) }
+
+// AppendUint implements Codec.
+func (MsgpCodec) AppendUint(b []byte, v uint64) []byte { return msgp.AppendUint64(b, v) }
+
+// AppendFloat32 implements Codec.
+func (MsgpCodec) AppendFloat32(b []byte, v float32) []byte { return msgp.AppendFloat32(b, v) }
+
+// AppendFloat64 implements Codec.
+func (MsgpCodec) AppendFloat64(b []byte, v float64) []byte { return msgp.AppendFloat64(b, v) }
+
+// AppendString implements Codec.
+func (MsgpCodec) AppendString(b []byte, s string) []byte { return msgp.AppendString(b, s) }
+
+// AppendStringWidth is like AppendString, but writes a header of the given
+// width.  A width too narrow for s is widened.
+func (MsgpCodec) AppendStringWidth(b []byte, s string, width StringHeaderWidth) []byte {
+	sz := len(s)
+	switch {
+	case width == StringCompact:
+		return msgp.AppendString(b, s)
+	case width == String8 && sz <= 0xff:
+		b = append(b, 0xd9, byte(sz))
+	case width != String32 && sz <= 0xffff:
+		b = append(b, 0xda, byte(sz>>8), byte(sz))
+	default:
+		b = append(b, 0xdb, byte(sz>>24), byte(sz>>16), byte(sz>>8), byte(sz))
+	}
+	return append(b, s...)
+}
+
+// AppendBytes implements Codec.
+func (MsgpCodec) AppendBytes(b []byte, v []byte) []byte { return msgp.AppendBytes(b, v) }
+
+// AppendMapHeader implements Codec.
+func (MsgpCodec) AppendMapHeader(b []byte, sz uint32, width HeaderWidth) []byte {
+	switch width {
+	case Header16:
+		return append(b, 0xde, byte(sz>>8), byte(sz))
+	case Header32:
+		return append(b, 0xdf, byte(sz>>24), byte(sz>>16), byte(sz>>8), byte(sz))
+	default:
+		return msgp.AppendMapHeader(b, sz)
+	}
+}
+
+// AppendArrayHeader implements Codec.
+func (MsgpCodec) AppendArrayHeader(b []byte, sz uint32, width HeaderWidth) []byte {
+	switch width {
+	case Header16:
+		return append(b, 0xdc, byte(sz>>8), byte(sz))
+	case Header32:
+		return append(b, 0xdd, byte(sz>>24), byte(sz>>16), byte(sz>>8), byte(sz))
+	default:
+		return msgp.AppendArrayHeader(b, sz)
+	}
+}
+
+// AppendExtension implements Codec.
+func (MsgpCodec) AppendExtension(b []byte, e msgp.Extension) ([]byte, error) {
+	return msgp.AppendExtension(b, e)
+}
+
+// AppendIntf implements Codec.
+func (MsgpCodec) AppendIntf(b []byte, v interface{}) ([]byte, error) {
+	return msgp.AppendIntf(b, v)
+}
+
+// WithCodec makes the Converter write the binary format of codec rather than
+// MSGP.  System variable types registered with RegisterSysvar marshal
+// themselves as MSGP, so they are not used with another codec.
+func WithCodec(codec Codec) Option {
+	return func(c *Converter) {
+		c.codec = codec
+	}
+}
+
+// isMsgp reports whether c writes MSGP.
+func (c *Converter) isMsgp() bool {
+	_, ok := c.codec.(MsgpCodec)
+	return ok
+}
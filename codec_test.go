@@ -0,0 +1,98 @@
+package json2msgp_test
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/ndau/json2msgp"
+	"github.com/stretchr/testify/require"
+	"github.com/tinylib/msgp/msgp"
+)
+
+// textCodec writes each value as a readable token, to show what the
+// Converter asks a Codec for.
+type textCodec struct{}
+
+func (textCodec) AppendNil(b []byte) []byte { return append(b, "nil "...) }
+func (textCodec) AppendBool(b []byte, v bool) []byte {
+	return append(b, fmt.Sprintf("bool(%v) ", v)...)
+}
+func (textCodec) AppendInt(b []byte, v int64) []byte { return append(b, fmt.Sprintf("int(%d) ", v)...) }
+func (textCodec) AppendUint(b []byte, v uint64) []byte {
+	return append(b, fmt.Sprintf("uint(%d) ", v)...)
+}
+func (textCodec) AppendFloat32(b []byte, v float32) []byte {
+	return append(b, fmt.Sprintf("f32(%v) ", v)...)
+}
+func (textCodec) AppendFloat64(b []byte, v float64) []byte {
+	return append(b, fmt.Sprintf("f64(%v) ", v)...)
+}
+func (textCodec) AppendString(b []byte, s string) []byte {
+	return append(b, fmt.Sprintf("str(%s) ", s)...)
+}
+func (textCodec) AppendBytes(b []byte, v []byte) []byte {
+	return append(b, fmt.Sprintf("bin(%x) ", v)...)
+}
+func (textCodec) AppendMapHeader(b []byte, sz uint32, width json2msgp.HeaderWidth) []byte {
+	return append(b, fmt.Sprintf("map(%d) ", sz)...)
+}
+func (textCodec) AppendArrayHeader(b []byte, sz uint32, width json2msgp.HeaderWidth) []byte {
+	return append(b, fmt.Sprintf("array(%d) ", sz)...)
+}
+func (textCodec) AppendExtension(b []byte, e msgp.Extension) ([]byte, error) {
+	return append(b, fmt.Sprintf("ext(%d) ", e.ExtensionType())...), nil
+}
+func (textCodec) AppendIntf(b []byte, v interface{}) ([]byte, error) {
+	return append(b, fmt.Sprintf("intf(%v) ", v)...), nil
+}
+
+func TestCodec(t *testing.T) {
+	in := map[string]interface{}{
+		"Fee":   []interface{}{200.0, -1.0},
+		"Rate":  0.5,
+		"Blob":  "DwA=",
+		"Name":  "foo",
+		"OK":    true,
+		"Count": 3,
+	}
+	got, err := json2msgp.ConvertWithOptions(in,
+		json2msgp.WithCodec(textCodec{}),
+		json2msgp.WithTypeHints(map[string][]string{"Fee": []string{"uint64", "int64"}, "Rate": []string{"float32"}}),
+	)
+	require.NoError(t, err)
+	require.Equal(t, "map(6) str(Blob) bin(0f00) str(Count) intf(3) str(Fee) array(2) uint(200) int(-1) str(Name) str(foo) str(OK) bool(true) str(Rate) f32(0.5) ", string(got))
+}
+
+func TestAppendStringWidth(t *testing.T) {
+	long := strings.Repeat("x", 300)
+	tests := []struct {
+		s     string
+		width json2msgp.StringHeaderWidth
+		want  []byte
+	}{
+		{"ab", json2msgp.StringCompact, []byte{0xa2}},
+		{"ab", json2msgp.String8, []byte{0xd9, 2}},
+		{"ab", json2msgp.String16, []byte{0xda, 0, 2}},
+		{"ab", json2msgp.String32, []byte{0xdb, 0, 0, 0, 2}},
+		{long, json2msgp.String8, []byte{0xda, 1, 44}},
+	}
+	for _, tt := range tests {
+		got := json2msgp.MsgpCodec{}.AppendStringWidth(nil, tt.s, tt.width)
+		require.Equal(t, append(tt.want, tt.s...), got)
+	}
+
+	_, err := json2msgp.ConvertWithOptions("foo",
+		json2msgp.WithCodec(textCodec{}), json2msgp.WithStringHeaderWidth(json2msgp.String16))
+	require.True(t, errors.Is(err, json2msgp.ErrBadOption), "got %v", err)
+}
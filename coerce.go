@@ -0,0 +1,199 @@
+package json2msgp
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"encoding/json"
+	"io"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+// Coercion is a rule which changes a JSON value of one kind into another
+// before it is converted, so that a team can encode its own conventions,
+// such as numbers sent as strings, without callbacks.  The kinds are
+// "string", "number", "bool" and "null", and for To only, "array" and "map".
+// The coercions are:
+//
+//   - string to number: the string is parsed as a JSON number would be.
+//   - string to bool: "true" and "false", as strconv.ParseBool accepts them.
+//   - number to bool: 0 is false and 1 is true.
+//   - number to string: the shortest decimal form, such as "1.5".
+//   - bool to number: false is 0 and true is 1.
+//   - null to string, array or map: the empty one.
+//
+// A value which cannot be coerced, such as the string "x" to a number, is
+// an ErrBadValue error.
+type Coercion struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	// Keys limits the rule to the values of these keys, which are matched
+	// as type hint keys are: a key name, or a path such as
+	// "/EAIFeeTable/*/Fee".  If empty, the rule applies to values of any key.
+	Keys []string `json:"keys,omitempty"`
+	// Hinted limits the rule to values which have a type hint.
+	Hinted bool `json:"hinted,omitempty"`
+
+	// the paths among Keys, compiled by newConverter
+	paths []pathHint
+}
+
+// WithCoercions applies the rules to every value converted.  For each
+// value, the first rule which matches it applies, and the result is then
+// converted as usual, with the hints and heuristics.  For example,
+//
+//	WithCoercions(Coercion{From: "string", To: "number", Hinted: true})
+//
+// encodes {"Fee": "100"} as {"Fee": 100} given a hint for Fee.  The option
+// may be given more than once; later rules come after earlier ones.
+func WithCoercions(rules ...Coercion) Option {
+	return func(c *Converter) {
+		c.coercions = append(c.coercions, rules...)
+	}
+}
+
+// ReadCoercions reads a table of coercion rules, for WithCoercions, from a
+// JSON array of objects such as {"from": "number", "to": "bool"}.  Unknown
+// fields are an error.
+func ReadCoercions(r io.Reader) ([]Coercion, error) {
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	var rules []Coercion
+	if err := dec.Decode(&rules); err != nil {
+		return nil, errors.Wrap(err, "ReadCoercions")
+	}
+	return rules, nil
+}
+
+// jsonKind returns the kind of in for matching Coercion.From.
+func jsonKind(in interface{}) string {
+	switch in.(type) {
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "bool"
+	case nil:
+		return "null"
+	}
+	return ""
+}
+
+// applies reports whether the rule applies to in, the current value.
+func (r *Coercion) applies(c *Converter, kind string) bool {
+	if r.From != kind {
+		return false
+	}
+	if r.Hinted {
+		if _, ok := c.hint(); !ok {
+			return false
+		}
+	}
+	if len(r.Keys) == 0 {
+		return true
+	}
+	for _, key := range r.Keys {
+		if key == c.currentKey && !isPathHint(key) {
+			return true
+		}
+	}
+	_, _, ok := findPathHint(r.paths, c.path)
+	return ok
+}
+
+// compileCoercions compiles the paths among the keys of the rules.
+func compileCoercions(rules []Coercion) {
+	for i := range rules {
+		keys := make(map[string][]string, len(rules[i].Keys))
+		for _, key := range rules[i].Keys {
+			keys[key] = nil
+		}
+		rules[i].paths = compilePathHints(keys)
+	}
+}
+
+// coerce returns in, the current value, as the first coercion which
+// applies to it makes it.
+func (c *Converter) coerce(in interface{}) (interface{}, error) {
+	kind := jsonKind(in)
+	if kind == "" {
+		return in, nil
+	}
+	for i := range c.coercions {
+		rule := &c.coercions[i]
+		if !rule.applies(c, kind) {
+			continue
+		}
+		out, err := coerceValue(in, rule.To)
+		if err != nil {
+			return in, c.errorf("%w: cannot coerce %s %v to %s: %s", ErrBadValue, kind, in, rule.To, err)
+		}
+		if out == nil {
+			return in, c.errorf("%w: cannot coerce %s to %s", ErrBadOption, kind, rule.To)
+		}
+		return out, nil
+	}
+	return in, nil
+}
+
+// coerceValue returns in as the kind to, or nil if there is no such
+// coercion.
+func coerceValue(in interface{}, to string) (interface{}, error) {
+	switch x := in.(type) {
+	case string:
+		switch to {
+		case "string":
+			return x, nil
+		case "number":
+			var f float64
+			err := json.Unmarshal([]byte(x), &f)
+			return f, err
+		case "bool":
+			return strconv.ParseBool(x)
+		}
+	case float64:
+		switch to {
+		case "number":
+			return x, nil
+		case "string":
+			return strconv.FormatFloat(x, 'f', -1, 64), nil
+		case "bool":
+			switch x {
+			case 0:
+				return false, nil
+			case 1:
+				return true, nil
+			}
+			return nil, errors.New("only 0 and 1 are booleans")
+		}
+	case bool:
+		switch to {
+		case "bool":
+			return x, nil
+		case "number":
+			if x {
+				return 1.0, nil
+			}
+			return 0.0, nil
+		}
+	case nil:
+		switch to {
+		case "string":
+			return "", nil
+		case "array":
+			return []interface{}{}, nil
+		case "map":
+			return map[string]interface{}{}, nil
+		}
+	}
+	return nil, nil
+}
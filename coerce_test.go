@@ -0,0 +1,129 @@
+package json2msgp_test
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ndau/json2msgp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithCoercions(t *testing.T) {
+	hints := map[string][]string{"Fee": {"float64"}}
+	tests := []struct {
+		name    string
+		in      map[string]interface{}
+		rules   []json2msgp.Coercion
+		want    map[string]interface{}
+		wantErr error
+	}{
+		{
+			"string to number when hinted",
+			map[string]interface{}{"Fee": "1.5", "Name": "2"},
+			[]json2msgp.Coercion{{From: "string", To: "number", Hinted: true}},
+			map[string]interface{}{"Fee": 1.5, "Name": "2"},
+			nil,
+		},
+		{
+			"number to bool for 0 and 1",
+			map[string]interface{}{"On": 1.0, "Off": 0.0},
+			[]json2msgp.Coercion{{From: "number", To: "bool"}},
+			map[string]interface{}{"On": true, "Off": false},
+			nil,
+		},
+		{
+			"null to empty array for a key",
+			map[string]interface{}{"To": nil, "From": nil},
+			[]json2msgp.Coercion{{From: "null", To: "array", Keys: []string{"To"}}},
+			map[string]interface{}{"To": []interface{}{}, "From": nil},
+			nil,
+		},
+		{
+			"first matching rule wins",
+			map[string]interface{}{"a": true},
+			[]json2msgp.Coercion{{From: "bool", To: "number"}, {From: "bool", To: "string"}},
+			map[string]interface{}{"a": 1.0},
+			nil,
+		},
+		{
+			"in arrays",
+			map[string]interface{}{"a": []interface{}{"1", "2"}},
+			[]json2msgp.Coercion{{From: "string", To: "number", Keys: []string{"a"}}},
+			map[string]interface{}{"a": []interface{}{1.0, 2.0}},
+			nil,
+		},
+		{
+			"path key",
+			map[string]interface{}{"a": map[string]interface{}{"To": nil}, "To": nil},
+			[]json2msgp.Coercion{{From: "null", To: "map", Keys: []string{"/a/To"}}},
+			map[string]interface{}{"a": map[string]interface{}{"To": map[string]interface{}{}}, "To": nil},
+			nil,
+		},
+		{
+			"number to string",
+			map[string]interface{}{"a": 12.0},
+			[]json2msgp.Coercion{{From: "number", To: "string"}},
+			map[string]interface{}{"a": "12"},
+			nil,
+		},
+		{
+			"not a number",
+			map[string]interface{}{"Fee": "x"},
+			[]json2msgp.Coercion{{From: "string", To: "number"}},
+			nil,
+			json2msgp.ErrBadValue,
+		},
+		{
+			"not a boolean number",
+			map[string]interface{}{"a": 2.0},
+			[]json2msgp.Coercion{{From: "number", To: "bool"}},
+			nil,
+			json2msgp.ErrBadValue,
+		},
+		{
+			"unsupported coercion",
+			map[string]interface{}{"a": true},
+			[]json2msgp.Coercion{{From: "bool", To: "map"}},
+			nil,
+			json2msgp.ErrBadOption,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := json2msgp.ConvertWithOptions(tt.in, json2msgp.WithTypeHints(hints), json2msgp.WithCoercions(tt.rules...))
+			if tt.wantErr != nil {
+				require.True(t, errors.Is(err, tt.wantErr), "got %v", err)
+				return
+			}
+			require.NoError(t, err)
+			want, err := json2msgp.ConvertWithOptions(tt.want, json2msgp.WithTypeHints(hints))
+			require.NoError(t, err)
+			require.Equal(t, want, got)
+		})
+	}
+}
+
+func TestReadCoercions(t *testing.T) {
+	rules, err := json2msgp.ReadCoercions(strings.NewReader(`[
+		{"from": "string", "to": "number", "hinted": true},
+		{"from": "null", "to": "array", "keys": ["To"]}
+	]`))
+	require.NoError(t, err)
+	require.Equal(t, []json2msgp.Coercion{
+		{From: "string", To: "number", Hinted: true},
+		{From: "null", To: "array", Keys: []string{"To"}},
+	}, rules)
+
+	_, err = json2msgp.ReadCoercions(strings.NewReader(`[{"form": "string"}]`))
+	require.Error(t, err)
+}
@@ -0,0 +1,85 @@
+package json2msgp
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import "bytes"
+
+// WithComments makes the stream functions accept // line comments and
+// /* block comments */ in the JSON input, as annotated fixture files have
+// them, without a separate minification step.  Nothing else of JSON5 is
+// accepted.
+//
+// Comments are replaced by spaces, keeping newlines, so that the offsets in
+// errors still point into the input as written.  An unterminated block
+// comment is left as it is, and so fails to parse.  ConvertFollow reads a
+// line at a time, so with it, a block comment must end on its first line.
+func WithComments() Option {
+	return func(c *Converter) {
+		c.comments = true
+	}
+}
+
+// stripComments returns data with its comments blanked out.  data is not
+// modified.
+func stripComments(data []byte) []byte {
+	var out []byte
+	inString, escaped := false, false
+scan:
+	for i := 0; i < len(data); i++ {
+		ch := data[i]
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case ch == '\\':
+				escaped = true
+			case ch == '"':
+				inString = false
+			}
+			continue
+		}
+		if ch == '"' {
+			inString = true
+			continue
+		}
+		if ch != '/' || i+1 == len(data) {
+			continue
+		}
+		var end int
+		switch data[i+1] {
+		case '/':
+			end = i + 2
+			for end < len(data) && data[end] != '\n' {
+				end++
+			}
+		case '*':
+			close := bytes.Index(data[i+2:], []byte("*/"))
+			if close < 0 {
+				break scan
+			}
+			end = i + 2 + close + 2
+		default:
+			continue
+		}
+		if out == nil {
+			out = append([]byte(nil), data...)
+		}
+		for j := i; j < end; j++ {
+			if out[j] != '\n' {
+				out[j] = ' '
+			}
+		}
+		i = end - 1
+	}
+	if out == nil {
+		return data
+	}
+	return out
+}
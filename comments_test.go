@@ -0,0 +1,57 @@
+package json2msgp_test
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/ndau/json2msgp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithComments(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		want    string
+		wantErr string
+	}{
+		{"line comment", "{\"a\": 1 // one\n}", `{"a": 1}`, ""},
+		{"block comment", `[1, /* two, */ 3]`, `[1, 3]`, ""},
+		{"multi-line block comment", "/* a\n * fixture\n */ [1]", `[1]`, ""},
+		{"comment at end", "[1] // done", `[1]`, ""},
+		{"comment markers in strings", `["http://x", "/* y */", "a\"//"]`, `["http://x", "/* y */", "a\"//"]`, ""},
+		{"lone slash", `[1 / 2]`, "", "invalid character '/'"},
+		{"unterminated block comment", `[1] /* oops`, "", "offset 4"},
+		// offsets are those of the input as written
+		{"offsets kept", "/* c */ [1] // x\n2", "", "offset 17"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got bytes.Buffer
+			err := json2msgp.ConvertStreamWithOptions(strings.NewReader(tt.in), &got, json2msgp.WithComments())
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			var want bytes.Buffer
+			require.NoError(t, json2msgp.ConvertStream(strings.NewReader(tt.want), &want, nil))
+			require.Equal(t, want.Bytes(), got.Bytes())
+		})
+	}
+
+	// without the option, comments are an error
+	err := json2msgp.ConvertStream(strings.NewReader(`[1] // one`), &bytes.Buffer{}, nil)
+	require.Error(t, err)
+}
@@ -0,0 +1,270 @@
+package json2msgp
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ConvertStreamConstant is like ConvertStreamWithOptions, but its peak memory
+// use does not depend on the size of the document: it holds one JSON token
+// and one encoded value at a time, plus a little state per level of nesting.
+// It is meant for hosts too constrained to hold a whole document.
+//
+// The price is a different encoding of the same data:
+//
+//   - maps and arrays always have 32-bit headers, which are written as
+//     placeholders and patched once their size is known; hence out must be
+//     seekable, such as an *os.File.
+//   - map keys are written in input order, not sorted, and duplicate keys are
+//     written as they appear.
+//
+// Type hints, the null policy, the key prefix options and the string
+// heuristics work as usual, as do the limits, warnings, logging and metrics.
+// Options which need the whole document fail with ErrBadOption:
+// WithEmptyAsNil, WithHeaderWidth, WithKeyHeaderWidth, WithKeyOrder,
+// WithIntegerKeys, WithFlatten, WithUnflatten, WithPath, WithQuery, WithDefaults,
+// WithRequiredKeys, WithFraming, WithResume, WithCanonical, WithTrace,
+// WithSysvar, WithNormalizedJSON, WithComments and WithInferredHints.
+func ConvertStreamConstant(in io.Reader, out io.WriteSeeker, opts ...Option) error {
+	c := newConverter(opts...)
+	switch {
+	case c.emptyAsNil, c.defaultHeaderWidth != HeaderCompact, c.keyHeaderWidths != nil,
+		c.keyOrder != KeyOrderLexical, c.intKeys, c.flatten, c.unflattenSep != "",
+		c.selection != "", c.query != "", c.defaults != nil,
+		c.required != nil, c.framed, c.resume > 0, c.canonical, c.trace != nil, c.sysvar != "",
+		c.normalized != nil, c.comments, c.inferHints:
+		return fmt.Errorf("ConvertStreamConstant: %w: option needs the whole document", ErrBadOption)
+	}
+	if c.maxInputSize > 0 {
+		in = &inputLimiter{r: in, n: c.maxInputSize}
+	}
+
+	s := &streamer{Converter: c, dec: json.NewDecoder(in), out: out}
+	for n := 0; ; n++ {
+		end := s.dec.InputOffset()
+		tok, err := s.dec.Token()
+		if err == io.EOF && n > 0 {
+			return nil
+		}
+		if err == nil && n > 0 && !c.multipleDocuments {
+			return fmt.Errorf("ConvertStreamConstant: %w after offset %d", ErrTrailingData, end)
+		}
+		if err != nil {
+			return errors.Wrap(err, "ConvertStreamConstant unmarshalling JSON")
+		}
+
+		if err = s.document(tok); err != nil {
+			if c.multipleDocuments {
+				return errors.Wrapf(err, "ConvertStreamConstant document %d", n)
+			}
+			return err
+		}
+	}
+}
+
+// inputLimiter fails with ErrInputTooLarge once more than n bytes are read.
+type inputLimiter struct {
+	r io.Reader
+	n int64
+}
+
+func (l *inputLimiter) Read(p []byte) (int, error) {
+	n, err := l.r.Read(p)
+	l.n -= int64(n)
+	if l.n < 0 {
+		return n, ErrInputTooLarge
+	}
+	return n, err
+}
+
+// streamer converts JSON tokens as they are read, for ConvertStreamConstant.
+type streamer struct {
+	*Converter
+	dec    *json.Decoder
+	out    io.WriteSeeker
+	offset int64
+	stack  []frame
+	buf    []byte
+}
+
+// frame is a map or array being written.
+type frame struct {
+	// offset of the header in the output
+	header int64
+	count  uint32
+	isMap  bool
+	// whether the next token of a map is a key
+	expectKey bool
+}
+
+// document converts the document starting with tok, mirroring convert so
+// that hints apply identically.
+func (s *streamer) document(tok json.Token) error {
+	start, docStart := time.Now(), s.offset
+	inStart := s.dec.InputOffset()
+	err := s.value(tok)
+	if err == nil && len(s.errs) > 0 {
+		err = s.errs
+	}
+	s.errs = nil
+	if err == nil && len(s.delimiter) > 0 {
+		err = s.write(s.delimiter)
+	}
+	s.stack, s.path = s.stack[:0], s.path[:0]
+	s.logDocument(int(s.offset-docStart), err)
+	if s.metrics != nil {
+		s.metrics.Conversion(time.Since(start), int(s.dec.InputOffset()-inStart), int(s.offset-docStart), err)
+	}
+	return err
+}
+
+func (s *streamer) value(tok json.Token) error {
+	s.beginDocument()
+	defer s.endDocument()
+
+	for {
+		if s.expired() {
+			return s.errorf("%w after %s", ErrTimeout, s.timeout)
+		}
+
+		top := s.top()
+		if top != nil && top.isMap && top.expectKey {
+			if tok == json.Delim('}') {
+				if err := s.close(); err != nil {
+					return err
+				}
+			} else {
+				key := tok.(string)
+				if len(s.stack) == 1 {
+					key = s.addKeyPrefix + strings.TrimPrefix(key, s.stripKeyPrefix)
+				}
+				s.currentKey = key
+				s.pushKey(key)
+				text, err := s.sanitize(key)
+				if err = s.collect(err); err != nil {
+					return err
+				}
+				if err := s.write(s.codec.AppendString(s.buf[:0], text)); err != nil {
+					return err
+				}
+				top.expectKey = false
+			}
+		} else if top != nil && !top.isMap && tok == json.Delim(']') {
+			if err := s.close(); err != nil {
+				return err
+			}
+		} else {
+			if top != nil && !top.isMap {
+				s.pushIndex(int(top.count))
+			}
+			switch tok {
+			case json.Delim('{'):
+				if err := s.open(true); err != nil {
+					return err
+				}
+			case json.Delim('['):
+				if err := s.open(false); err != nil {
+					return err
+				}
+			default:
+				var err error
+				s.buf, err = s.check(s.convert(tok, s.buf[:0]))
+				if err == nil {
+					err = s.write(s.buf)
+				}
+				if err != nil {
+					return err
+				}
+				s.finish()
+			}
+		}
+
+		if len(s.stack) == 0 {
+			return nil
+		}
+		var err error
+		if tok, err = s.dec.Token(); err != nil {
+			return s.wrap(err)
+		}
+	}
+}
+
+func (s *streamer) top() *frame {
+	if len(s.stack) == 0 {
+		return nil
+	}
+	return &s.stack[len(s.stack)-1]
+}
+
+// open starts a map or array with a placeholder header.
+func (s *streamer) open(isMap bool) error {
+	if s.maxDepth > 0 && len(s.path) > s.maxDepth {
+		return s.errorf("%w of %d", ErrMaxDepth, s.maxDepth)
+	}
+	s.stack = append(s.stack, frame{header: s.offset, isMap: isMap, expectKey: isMap})
+	return s.write(s.header(isMap, 0))
+}
+
+// close patches the header of the innermost map or array with its size.
+func (s *streamer) close() error {
+	f := s.stack[len(s.stack)-1]
+	s.stack = s.stack[:len(s.stack)-1]
+
+	if _, err := s.out.Seek(f.header, io.SeekStart); err != nil {
+		return s.wrap(err)
+	}
+	if _, err := s.out.Write(s.header(f.isMap, f.count)); err != nil {
+		return s.wrap(err)
+	}
+	if _, err := s.out.Seek(s.offset, io.SeekStart); err != nil {
+		return s.wrap(err)
+	}
+	s.finish()
+	return nil
+}
+
+func (s *streamer) header(isMap bool, count uint32) []byte {
+	if isMap {
+		return s.codec.AppendMapHeader(s.buf[:0], count, Header32)
+	}
+	return s.codec.AppendArrayHeader(s.buf[:0], count, Header32)
+}
+
+// finish completes a value within the innermost map or array, if any.
+func (s *streamer) finish() {
+	top := s.top()
+	if top == nil {
+		return
+	}
+	top.count++
+	s.pop()
+	if top.isMap {
+		top.expectKey = true
+	}
+}
+
+func (s *streamer) write(b []byte) error {
+	if s.maxOutputSize > 0 && s.offset+int64(len(b)) > int64(s.maxOutputSize) {
+		return fmt.Errorf("%w: more than %d bytes", ErrOutputTooLarge, s.maxOutputSize)
+	}
+	n, err := s.out.Write(b)
+	s.offset += int64(n)
+	if err != nil {
+		return errors.Wrap(err, "ConvertStreamConstant writing to out stream")
+	}
+	return nil
+}
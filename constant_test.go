@@ -0,0 +1,196 @@
+package json2msgp_test
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/ndau/json2msgp"
+	"github.com/stretchr/testify/require"
+)
+
+// convertConstant converts in with ConvertStreamConstant through a file.
+func convertConstant(t *testing.T, in string, opts ...json2msgp.Option) ([]byte, error) {
+	f, err := os.Create(filepath.Join(t.TempDir(), "out.msgp"))
+	require.NoError(t, err)
+	defer f.Close()
+	err = json2msgp.ConvertStreamConstant(strings.NewReader(in), f, opts...)
+	if err != nil {
+		return nil, err
+	}
+	_, err = f.Seek(0, io.SeekStart)
+	require.NoError(t, err)
+	return ioutil.ReadAll(f)
+}
+
+func TestConvertStreamConstant(t *testing.T) {
+	hints := json2msgp.WithTypeHints(map[string][]string{
+		"Fee":      []string{"uint64"},
+		"ChangeOn": []string{"uint64"},
+		"":         []string{"int64", "uint64"},
+	})
+	tests := []string{
+		`{"EAIFeeTable":[{"Fee":4000000,"To":["ndnf9ffbzhyf8mk7z5vvqc4quzz5i2exp5zgsmhyhc9cuwr4"]},{"Fee":200,"To":null}]}`,
+		`[[7776000000000,10000000000],[15552000000000,20000000000]]`,
+		`{"Script":"oACI","Name":"foo","Nested":{"a":[],"b":{},"c":[true,false,null]}}`,
+		`[[1,2],3,{"ChangeOn":5}]`,
+		`"DwA="`,
+	}
+	for _, in := range tests {
+		want := &bytes.Buffer{}
+		require.NoError(t, json2msgp.ConvertStreamWithOptions(strings.NewReader(in), want, hints))
+		got, err := convertConstant(t, in, hints)
+		require.NoError(t, err, in)
+
+		// the encodings differ in header widths and key order only
+		changes, err := json2msgp.Diff(want.Bytes(), got)
+		require.NoError(t, err)
+		require.Empty(t, changes, in)
+	}
+
+	got, err := convertConstant(t, `[1]`)
+	require.NoError(t, err)
+	require.Equal(t, []byte{0xdd, 0, 0, 0, 1, 1}, got)
+
+	got, err = convertConstant(t, `{"old.a":{"old.b":1}}`,
+		json2msgp.WithStripKeyPrefix("old."), json2msgp.WithAddKeyPrefix("n"))
+	require.NoError(t, err)
+	require.Equal(t, []byte{0xdf, 0, 0, 0, 1, 0xa2, 'n', 'a', 0xdf, 0, 0, 0, 1, 0xa5, 'o', 'l', 'd', '.', 'b', 1}, got)
+}
+
+func TestConvertStreamConstantErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		opts    []json2msgp.Option
+		wantErr error
+	}{
+		{"unhinted float", `{"a":[1.5]}`, nil, json2msgp.ErrUnhintedNumber},
+		{"trailing data", `[1] [2]`, nil, json2msgp.ErrTrailingData},
+		{"max depth", `[[[1]]]`, []json2msgp.Option{json2msgp.WithMaxDepth(1)}, json2msgp.ErrMaxDepth},
+		{"input size", `[1,2,3]`, []json2msgp.Option{json2msgp.WithMaxInputSize(4)}, json2msgp.ErrInputTooLarge},
+		{"output size", `[1,2,3]`, []json2msgp.Option{json2msgp.WithMaxOutputSize(7)}, json2msgp.ErrOutputTooLarge},
+		{"whole document option", `[1]`, []json2msgp.Option{json2msgp.WithCanonical()}, json2msgp.ErrBadOption},
+		{"key order", `{}`, []json2msgp.Option{json2msgp.WithKeyOrder(json2msgp.KeyOrderNumeric)}, json2msgp.ErrBadOption},
+		{"integer keys", `{}`, []json2msgp.Option{json2msgp.WithIntegerKeys()}, json2msgp.ErrBadOption},
+		{"flatten", `{}`, []json2msgp.Option{json2msgp.WithFlatten(".")}, json2msgp.ErrBadOption},
+		{"unflatten", `{}`, []json2msgp.Option{json2msgp.WithUnflatten(".")}, json2msgp.ErrBadOption},
+		{"path", `{}`, []json2msgp.Option{json2msgp.WithPath("a")}, json2msgp.ErrBadOption},
+		{"query", `{}`, []json2msgp.Option{json2msgp.WithQuery("$..a")}, json2msgp.ErrBadOption},
+		{"defaults", `{}`, []json2msgp.Option{json2msgp.WithDefaults(map[string]interface{}{})}, json2msgp.ErrBadOption},
+		{"required keys", `{}`, []json2msgp.Option{json2msgp.WithRequiredKeys("$", "a")}, json2msgp.ErrBadOption},
+		{"framing", `{}`, []json2msgp.Option{json2msgp.WithFraming()}, json2msgp.ErrBadOption},
+		{"resume", `{}`, []json2msgp.Option{json2msgp.WithResume(1)}, json2msgp.ErrBadOption},
+		{"comments", `{}`, []json2msgp.Option{json2msgp.WithComments()}, json2msgp.ErrBadOption},
+		{"inferred hints", `{}`, []json2msgp.Option{json2msgp.WithInferredHints()}, json2msgp.ErrBadOption},
+		{"normalized JSON", `{}`, []json2msgp.Option{json2msgp.WithNormalizedJSON(io.Discard)}, json2msgp.ErrBadOption},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := convertConstant(t, tt.in, tt.opts...)
+			require.True(t, errors.Is(err, tt.wantErr), "got %v", err)
+		})
+	}
+
+	_, err := convertConstant(t, `[1.5, 2, 2.5]`, json2msgp.WithAllErrors())
+	var list json2msgp.ErrorList
+	require.True(t, errors.As(err, &list))
+	require.Len(t, list, 2)
+
+	got, err := convertConstant(t, `[1] [2]`, json2msgp.WithMultipleDocuments())
+	require.NoError(t, err)
+	require.Equal(t, []byte{0xdd, 0, 0, 0, 1, 1, 0xdd, 0, 0, 0, 1, 2}, got)
+}
+
+// generator produces a JSON array of n long strings without holding it.  The
+// strings are not base64, so they are encoded at full length.
+type generator struct {
+	n, i int
+	buf  []byte
+}
+
+func (g *generator) Read(p []byte) (int, error) {
+	if len(g.buf) == 0 {
+		switch {
+		case g.i == 0:
+			g.buf = []byte(`[`)
+		case g.i > g.n:
+			return 0, io.EOF
+		case g.i == g.n:
+			g.buf = []byte(`"end"]`)
+		default:
+			g.buf = []byte(`"` + strings.Repeat("x", 999) + `.",`)
+		}
+		g.i++
+	}
+	n := copy(p, g.buf)
+	g.buf = g.buf[n:]
+	return n, nil
+}
+
+// discard is a WriteSeeker which keeps nothing, but records the peak heap.
+type discard struct {
+	pos, size int64
+	writes    int
+	peak      uint64
+}
+
+func (d *discard) Write(p []byte) (int, error) {
+	d.pos += int64(len(p))
+	if d.pos > d.size {
+		d.size = d.pos
+	}
+	d.writes++
+	if d.writes%1000 == 0 {
+		var m runtime.MemStats
+		runtime.ReadMemStats(&m)
+		if m.HeapAlloc > d.peak {
+			d.peak = m.HeapAlloc
+		}
+	}
+	return len(p), nil
+}
+
+func (d *discard) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+		d.pos = offset
+	case io.SeekEnd:
+		d.pos = d.size + offset
+	default:
+		d.pos += offset
+	}
+	return d.pos, nil
+}
+
+func TestConvertStreamConstantMemory(t *testing.T) {
+	if testing.Short() {
+		t.Skip("converts 64MB of JSON")
+	}
+	runtime.GC()
+	var before runtime.MemStats
+	runtime.ReadMemStats(&before)
+
+	out := &discard{}
+	err := json2msgp.ConvertStreamConstant(&generator{n: 70000}, out)
+	require.NoError(t, err)
+	require.True(t, out.size > 64<<20)
+
+	// a conversion holding the document would need several times its size
+	require.Less(t, out.peak, before.HeapAlloc+16<<20, "peak heap %d", out.peak)
+}
@@ -0,0 +1,79 @@
+package json2msgp
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// ControlCharPolicy determines what happens to control characters, including
+// embedded NULs, in strings which are encoded as MSGP strings.  Values
+// encoded as bytes are never affected.
+type ControlCharPolicy int
+
+const (
+	// ControlPassThrough encodes control characters unchanged.  This is the
+	// default.
+	ControlPassThrough ControlCharPolicy = iota
+	// ControlStrip removes control characters.
+	ControlStrip
+	// ControlEscape replaces each control character with its JSON escape,
+	// such as `\u0000`.
+	ControlEscape
+	// ControlError fails the conversion.
+	ControlError
+)
+
+// WithControlCharPolicy sets how control characters in map keys and string
+// values are handled.
+func WithControlCharPolicy(policy ControlCharPolicy) Option {
+	return func(c *Converter) {
+		c.controlChars = policy
+	}
+}
+
+// sanitize applies the control character policy to s.
+func (c *Converter) sanitize(s string) (string, error) {
+	if c.controlChars == ControlPassThrough || strings.IndexFunc(s, unicode.IsControl) < 0 {
+		return s, nil
+	}
+	switch c.controlChars {
+	case ControlStrip:
+		return strings.Map(func(r rune) rune {
+			if unicode.IsControl(r) {
+				return -1
+			}
+			return r
+		}, s), nil
+	case ControlEscape:
+		var sb strings.Builder
+		for _, r := range s {
+			if unicode.IsControl(r) {
+				fmt.Fprintf(&sb, `\u%04x`, r)
+			} else {
+				sb.WriteRune(r)
+			}
+		}
+		return sb.String(), nil
+	}
+	return s, c.errorf("%w in %q", ErrControlChar, s)
+}
+
+// appendText appends s as an MSGP string, after applying the control
+// character policy.
+func (c *Converter) appendText(buffer []byte, s string) ([]byte, error) {
+	s, err := c.sanitize(s)
+	if err != nil {
+		return buffer, err
+	}
+	return c.appendString(buffer, s)
+}
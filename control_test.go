@@ -0,0 +1,53 @@
+package json2msgp_test
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ndau/json2msgp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestControlCharPolicy(t *testing.T) {
+	in := map[string]interface{}{"k\x01!": "a\x00b\u0085!"}
+	tests := []struct {
+		name   string
+		policy json2msgp.ControlCharPolicy
+		key    string
+		value  string
+	}{
+		{"pass through", json2msgp.ControlPassThrough, "k\x01!", "a\x00b\u0085!"},
+		{"strip", json2msgp.ControlStrip, "k!", "ab!"},
+		{"escape", json2msgp.ControlEscape, `k\u0001!`, `a\u0000b\u0085!`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want, err := json2msgp.ConvertWithOptions(map[string]interface{}{tt.key: tt.value})
+			require.NoError(t, err)
+			got, err := json2msgp.ConvertWithOptions(in, json2msgp.WithControlCharPolicy(tt.policy))
+			require.NoError(t, err)
+			require.Equal(t, want, got)
+		})
+	}
+
+	opt := json2msgp.WithControlCharPolicy(json2msgp.ControlError)
+	for _, in := range []interface{}{in, map[string]interface{}{"k": "a\x7f!"}} {
+		_, err := json2msgp.ConvertWithOptions(in, opt)
+		require.True(t, errors.Is(err, json2msgp.ErrControlChar), "got %v", err)
+	}
+
+	// bytes are never affected
+	got, err := json2msgp.ConvertWithOptions(map[string]interface{}{"b": "a\x00!"}, opt,
+		json2msgp.WithTypeHints(map[string][]string{"b": []string{"bin"}}))
+	require.NoError(t, err)
+	require.Equal(t, []byte("\x81\xa1b\xc4\x03a\x00!"), got)
+}
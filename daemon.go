@@ -0,0 +1,136 @@
+package json2msgp
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+)
+
+// MaxFrameSize is the largest payload of the daemon protocol.
+const MaxFrameSize = 64 << 20
+
+// Status bytes of the daemon protocol.
+const (
+	StatusOK    byte = 0
+	StatusError byte = 1
+)
+
+// Serve runs the conversion daemon protocol on connections accepted from l,
+// typically a unix socket, until l is closed.  It saves processes which
+// convert repeatedly the cost of starting a converter each time.
+//
+// Each request is a frame of JSON text: a 4 byte big-endian length, then the
+// payload.  Each response is a status byte, StatusOK or StatusError, followed
+// by a frame holding the MSGP or the error message.  A connection may carry
+// any number of requests, answered in order.  opts configure every conversion.
+func Serve(l net.Listener, opts ...Option) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go serveConn(conn, opts)
+	}
+}
+
+func serveConn(conn net.Conn, opts []Option) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	w := bufio.NewWriter(conn)
+	for {
+		in, err := readFrame(r)
+		if err != nil {
+			if err != io.EOF {
+				writeResponse(w, nil, err)
+			}
+			return
+		}
+		var out bytes.Buffer
+		err = ConvertStreamWithOptions(bytes.NewReader(in), &out, opts...)
+		if err = writeResponse(w, out.Bytes(), err); err != nil {
+			return
+		}
+	}
+}
+
+// Request sends JSON text to a conversion daemon over conn, and returns the
+// MSGP it answers with.
+func Request(conn io.ReadWriter, in []byte) ([]byte, error) {
+	if err := writeFrame(conn, in); err != nil {
+		return nil, err
+	}
+	var status [1]byte
+	if _, err := io.ReadFull(conn, status[:]); err != nil {
+		return nil, err
+	}
+	out, err := readFrame(conn)
+	if err != nil {
+		return nil, err
+	}
+	if status[0] != StatusOK {
+		return nil, errors.New(string(out))
+	}
+	return out, nil
+}
+
+// writeResponse writes the response to a request.  Output too large for a
+// frame is answered with an error, leaving the connection usable.
+func writeResponse(w *bufio.Writer, out []byte, convErr error) error {
+	status := StatusOK
+	if convErr == nil && len(out) > MaxFrameSize {
+		convErr = fmt.Errorf("output of %d bytes exceeds MaxFrameSize", len(out))
+	}
+	if convErr != nil {
+		status = StatusError
+		out = []byte(convErr.Error())
+	}
+	if err := w.WriteByte(status); err != nil {
+		return err
+	}
+	if err := writeFrame(w, out); err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+func writeFrame(w io.Writer, payload []byte) error {
+	if len(payload) > MaxFrameSize {
+		return fmt.Errorf("frame of %d bytes exceeds MaxFrameSize", len(payload))
+	}
+	var size [4]byte
+	binary.BigEndian.PutUint32(size[:], uint32(len(payload)))
+	if _, err := w.Write(size[:]); err != nil {
+		return err
+	}
+	_, err := w.Write(payload)
+	return err
+}
+
+func readFrame(r io.Reader) ([]byte, error) {
+	var size [4]byte
+	if _, err := io.ReadFull(r, size[:]); err != nil {
+		return nil, err
+	}
+	n := binary.BigEndian.Uint32(size[:])
+	if n > MaxFrameSize {
+		return nil, fmt.Errorf("frame of %d bytes exceeds MaxFrameSize", n)
+	}
+	payload := make([]byte, n)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
@@ -0,0 +1,70 @@
+package json2msgp_test
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"bytes"
+	"net"
+	"path/filepath"
+	"testing"
+
+	"github.com/ndau/json2msgp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDaemon(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "json2msgp.sock")
+	l, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+	defer l.Close()
+	go json2msgp.Serve(l, json2msgp.WithTypeHints(map[string][]string{"Fee": []string{"uint64"}}))
+
+	conn, err := net.Dial("unix", socket)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// several requests share the connection, and errors don't end it
+	out, err := json2msgp.Request(conn, []byte(`{"Fee":200}`))
+	require.NoError(t, err)
+	require.Equal(t, "\x81\xa3Fee\xcc\xc8", string(out))
+
+	_, err = json2msgp.Request(conn, []byte(`{"Rate":1.5}`))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "Unsupported numeric value")
+
+	out, err = json2msgp.Request(conn, []byte(`[1]`))
+	require.NoError(t, err)
+	require.Equal(t, "\x91\x01", string(out))
+}
+
+func TestDaemonOutputTooLarge(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "json2msgp.sock")
+	l, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+	defer l.Close()
+	go json2msgp.Serve(l, json2msgp.WithTypeHints(map[string][]string{"": []string{"float64"}}))
+
+	conn, err := net.Dial("unix", socket)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// each 2 byte element becomes a 9 byte float64
+	n := json2msgp.MaxFrameSize / 8
+	in := append(bytes.Repeat([]byte("1,"), n), '1')
+	in = append(append([]byte{'['}, in...), ']')
+	_, err = json2msgp.Request(conn, in)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "exceeds MaxFrameSize")
+
+	// the connection survives
+	out, err := json2msgp.Request(conn, []byte(`[1]`))
+	require.NoError(t, err)
+	require.Equal(t, "\x91\xcb\x3f\xf0\x00\x00\x00\x00\x00\x00", string(out))
+}
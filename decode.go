@@ -0,0 +1,182 @@
+package json2msgp
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// DuplicateKeyPolicy determines what happens when a JSON object contains the
+// same key more than once.
+type DuplicateKeyPolicy int
+
+const (
+	// DuplicateKeyError fails the conversion.  This is the default: silently
+	// losing a key from chain configuration is never what anyone wants.
+	DuplicateKeyError DuplicateKeyPolicy = iota
+	// DuplicateKeyFirstWins keeps the first occurrence of the key.
+	DuplicateKeyFirstWins
+	// DuplicateKeyLastWins keeps the last occurrence of the key, which is what
+	// encoding/json does.
+	DuplicateKeyLastWins
+)
+
+// WithMultipleDocuments makes the stream functions convert every JSON document
+// in the input, rather than just one.  Documents may be concatenated or
+// separated by whitespace, as in NDJSON.  Each is written as its own MSGP
+// value, followed by the delimiter if one is set.
+//
+// Without this option, anything but whitespace after the first document is
+// an ErrTrailingData error.
+func WithMultipleDocuments() Option {
+	return func(c *Converter) {
+		c.multipleDocuments = true
+	}
+}
+
+// WithDuplicateKeyPolicy sets how the stream functions handle duplicate keys
+// in the JSON input.
+func WithDuplicateKeyPolicy(policy DuplicateKeyPolicy) Option {
+	return func(c *Converter) {
+		c.duplicateKeys = policy
+	}
+}
+
+// decoder parses JSON into the same values as json.Unmarshal into an
+// interface{}, but applies the converter's duplicate key policy.
+type decoder struct {
+	*json.Decoder
+	data     []byte
+	policy   DuplicateKeyPolicy
+	maxDepth int
+	expired  func() bool
+	path     []string
+}
+
+// newDecoder returns a decoder for the JSON documents in data.
+func (c *Converter) newDecoder(data []byte) *decoder {
+	if c.comments {
+		data = stripComments(data)
+	}
+	return &decoder{
+		Decoder:  json.NewDecoder(bytes.NewReader(data)),
+		data:     data,
+		policy:   c.duplicateKeys,
+		maxDepth: c.maxDepth,
+		expired:  c.expired,
+	}
+}
+
+// document parses the next JSON document.  It returns io.EOF if only
+// whitespace remains.
+func (d *decoder) document() (interface{}, error) {
+	if d.trailing() < 0 {
+		return nil, io.EOF
+	}
+	return d.value()
+}
+
+// single parses the data as exactly one JSON document.
+func (d *decoder) single() (interface{}, error) {
+	v, err := d.value()
+	if err != nil {
+		return nil, err
+	}
+	if offset := d.trailing(); offset >= 0 {
+		return nil, fmt.Errorf("%w at offset %d", ErrTrailingData, offset)
+	}
+	return v, nil
+}
+
+// trailing returns the offset of the first non-whitespace byte after the
+// documents parsed so far, or -1 if there is none.
+func (d *decoder) trailing() int {
+	offset := int(d.InputOffset())
+	rest := bytes.TrimLeft(d.data[offset:], " \t\r\n")
+	if len(rest) == 0 {
+		return -1
+	}
+	return len(d.data) - len(rest)
+}
+
+func (d *decoder) value() (interface{}, error) {
+	if d.maxDepth > 0 && len(d.path) > d.maxDepth {
+		return nil, fmt.Errorf("$%s: %w of %d", strings.Join(d.path, ""), ErrMaxDepth, d.maxDepth)
+	}
+	if d.expired() {
+		return nil, fmt.Errorf("$%s: %w", strings.Join(d.path, ""), ErrTimeout)
+	}
+	tok, err := d.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch tok {
+	case json.Delim('{'):
+		return d.object()
+	case json.Delim('['):
+		return d.array()
+	}
+	return tok, nil
+}
+
+func (d *decoder) object() (interface{}, error) {
+	m := make(map[string]interface{})
+	for d.More() {
+		tok, err := d.Token()
+		if err != nil {
+			return nil, err
+		}
+		key := tok.(string)
+
+		d.path = append(d.path, keySegment(key))
+		v, err := d.value()
+		if err != nil {
+			return nil, err
+		}
+		if _, dup := m[key]; dup {
+			switch d.policy {
+			case DuplicateKeyFirstWins:
+				d.path = d.path[:len(d.path)-1]
+				continue
+			case DuplicateKeyLastWins:
+			default:
+				return nil, fmt.Errorf("$%s: %w", strings.Join(d.path, ""), ErrDuplicateKey)
+			}
+		}
+		d.path = d.path[:len(d.path)-1]
+		m[key] = v
+	}
+	// consume the closing delimiter
+	if _, err := d.Token(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (d *decoder) array() (interface{}, error) {
+	a := make([]interface{}, 0)
+	for d.More() {
+		d.path = append(d.path, indexSegment(len(a)))
+		v, err := d.value()
+		if err != nil {
+			return nil, err
+		}
+		d.path = d.path[:len(d.path)-1]
+		a = append(a, v)
+	}
+	if _, err := d.Token(); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
@@ -0,0 +1,114 @@
+package json2msgp_test
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ndau/json2msgp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDuplicateKeyPolicy(t *testing.T) {
+	in := `{"EAIFeeTable":[{"Fee":1,"To":null,"Fee":2}]}`
+
+	tests := []struct {
+		name    string
+		opts    []json2msgp.Option
+		wantOut string
+		wantErr error
+	}{
+		{"default", nil, "", json2msgp.ErrDuplicateKey},
+		{"error", []json2msgp.Option{json2msgp.WithDuplicateKeyPolicy(json2msgp.DuplicateKeyError)}, "", json2msgp.ErrDuplicateKey},
+		{"first wins", []json2msgp.Option{json2msgp.WithDuplicateKeyPolicy(json2msgp.DuplicateKeyFirstWins)}, "81 ab 45 41 49 46 65 65 54 61 62 6c 65 91 82 a3 46 65 65 01 a2 54 6f c0", nil},
+		{"last wins", []json2msgp.Option{json2msgp.WithDuplicateKeyPolicy(json2msgp.DuplicateKeyLastWins)}, "81 ab 45 41 49 46 65 65 54 61 62 6c 65 91 82 a3 46 65 65 02 a2 54 6f c0", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			err := json2msgp.ConvertStreamWithOptions(bytes.NewBufferString(in), out, tt.opts...)
+			if tt.wantErr != nil {
+				require.True(t, errors.Is(err, tt.wantErr), "got %v", err)
+				require.Contains(t, err.Error(), `$.EAIFeeTable[0].Fee`)
+				return
+			}
+			require.NoError(t, err)
+			want, err := hex.DecodeString(strings.Replace(tt.wantOut, " ", "", -1))
+			require.NoError(t, err)
+			require.Equal(t, want, out.Bytes())
+		})
+	}
+}
+
+func TestTrailingData(t *testing.T) {
+	for _, in := range []string{`{"a":1} x`, `{"a":1}{"b":2}`, "1\n2\n"} {
+		out := &bytes.Buffer{}
+		err := json2msgp.ConvertStream(bytes.NewBufferString(in), out, nil)
+		require.True(t, errors.Is(err, json2msgp.ErrTrailingData), "%s: got %v", in, err)
+	}
+
+	err := json2msgp.ConvertStream(bytes.NewBufferString("{\"a\":1}  \n\t"), &bytes.Buffer{}, nil)
+	require.NoError(t, err)
+}
+
+func TestMultipleDocuments(t *testing.T) {
+	out := &bytes.Buffer{}
+	err := json2msgp.ConvertStreamWithOptions(
+		bytes.NewBufferString("{\"a\":1}\n{\"b\":2}{\"c\":3}\n"),
+		out,
+		json2msgp.WithMultipleDocuments(),
+		json2msgp.WithDelimiter([]byte{'\n'}),
+	)
+	require.NoError(t, err)
+	require.Equal(t, []byte("\x81\xa1a\x01\n\x81\xa1b\x02\n\x81\xa1c\x03\n"), out.Bytes())
+
+	err = json2msgp.ConvertStreamWithOptions(
+		bytes.NewBufferString("1\n1.5\n"),
+		&bytes.Buffer{},
+		json2msgp.WithMultipleDocuments(),
+	)
+	require.EqualError(t, err, "ConvertStream document 1: $: Unsupported numeric value 1.5")
+
+	err = json2msgp.ConvertStreamWithOptions(bytes.NewBufferString(" "), &bytes.Buffer{}, json2msgp.WithMultipleDocuments())
+	require.Error(t, err)
+}
+
+func TestMaxDepth(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		opts    []json2msgp.Option
+		wantErr bool
+	}{
+		{"within limit", `[[1]]`, []json2msgp.Option{json2msgp.WithMaxDepth(2)}, false},
+		{"too deep", `[[[1]]]`, []json2msgp.Option{json2msgp.WithMaxDepth(2)}, true},
+		{"too deep object", `{"a":{"b":{"c":1}}}`, []json2msgp.Option{json2msgp.WithMaxDepth(2)}, true},
+		{"default limit", strings.Repeat("[", 1002) + strings.Repeat("]", 1002), nil, true},
+		{"unlimited", strings.Repeat("[", 1002) + strings.Repeat("]", 1002), []json2msgp.Option{json2msgp.WithMaxDepth(0)}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := json2msgp.ConvertStreamWithOptions(bytes.NewBufferString(tt.in), &bytes.Buffer{}, tt.opts...)
+			if !tt.wantErr {
+				require.NoError(t, err)
+				return
+			}
+			require.True(t, errors.Is(err, json2msgp.ErrMaxDepth), "got %v", err)
+		})
+	}
+
+	// values built in Go are limited too
+	_, err := json2msgp.ConvertWithOptions([]interface{}{[]interface{}{[]interface{}{}}}, json2msgp.WithMaxDepth(1))
+	require.True(t, errors.Is(err, json2msgp.ErrMaxDepth))
+}
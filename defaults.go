@@ -0,0 +1,62 @@
+package json2msgp
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import "github.com/pkg/errors"
+
+// WithDefaults fills in the keys missing from the document from defaults, a
+// document as decoded by encoding/json, so that a partial edit of a system
+// variable need not repeat every unchanged field.
+//
+// Where both the document and defaults have a map at the same place, the
+// keys missing from the document's map are added from the defaults' map,
+// and so on down.  Anything else in the document, including null and arrays,
+// is kept as it is.  The document is never modified.
+//
+// Defaults apply to the value which is converted: after WithPath, and after
+// WithStripKeyPrefix and WithUnflatten, so they are written with the keys
+// the chain expects.
+func WithDefaults(defaults interface{}) Option {
+	return func(c *Converter) {
+		c.defaults = defaults
+	}
+}
+
+// ReadDefaults reads a defaults document, for WithDefaults, from JSON.
+func ReadDefaults(data []byte) (interface{}, error) {
+	defaults, err := newConverter().newDecoder(data).single()
+	return defaults, errors.Wrap(err, "ReadDefaults")
+}
+
+// withDefaults returns in with the keys missing from its maps filled in
+// from defaults.
+func withDefaults(in, defaults interface{}) interface{} {
+	m, ok := in.(map[string]interface{})
+	if !ok {
+		return in
+	}
+	d, ok := defaults.(map[string]interface{})
+	if !ok {
+		return in
+	}
+	out := make(map[string]interface{}, len(m)+len(d))
+	for key, value := range m {
+		if dv, ok := d[key]; ok {
+			value = withDefaults(value, dv)
+		}
+		out[key] = value
+	}
+	for key, value := range d {
+		if _, ok := m[key]; !ok {
+			out[key] = deepCopy(value)
+		}
+	}
+	return out
+}
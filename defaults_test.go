@@ -0,0 +1,65 @@
+package json2msgp_test
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/ndau/json2msgp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithDefaults(t *testing.T) {
+	defaults, err := json2msgp.ReadDefaults([]byte(`{
+		"ChangeOn": 0,
+		"Current": ["oAA=", "oAE="],
+		"Nested": {"a": 1, "b": {"c": 2}},
+		"List": [1, 2]
+	}`))
+	require.NoError(t, err)
+	tests := []struct {
+		name string
+		in   string
+		opts []json2msgp.Option
+		want string
+	}{
+		{"all missing", `{}`, nil, `{"ChangeOn":0,"Current":["oAA=","oAE="],"Nested":{"a":1,"b":{"c":2}},"List":[1,2]}`},
+		{"input wins", `{"ChangeOn":5,"Current":null,"List":[3]}`, nil,
+			`{"ChangeOn":5,"Current":null,"Nested":{"a":1,"b":{"c":2}},"List":[3]}`},
+		{"nested maps merge", `{"Nested":{"b":{"d":3}}}`, nil,
+			`{"ChangeOn":0,"Current":["oAA=","oAE="],"Nested":{"a":1,"b":{"c":2,"d":3}},"List":[1,2]}`},
+		{"not a map", `[1]`, nil, `[1]`},
+		{"after path", `{"svi":{"ChangeOn":5}}`, []json2msgp.Option{json2msgp.WithPath("svi")},
+			`{"ChangeOn":5,"Current":["oAA=","oAE="],"Nested":{"a":1,"b":{"c":2}},"List":[1,2]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got, want bytes.Buffer
+			opts := append(tt.opts, json2msgp.WithDefaults(defaults))
+			require.NoError(t, json2msgp.ConvertStreamWithOptions(strings.NewReader(tt.in), &got, opts...))
+			require.NoError(t, json2msgp.ConvertStream(strings.NewReader(tt.want), &want, nil))
+			require.Equal(t, want.Bytes(), got.Bytes())
+		})
+	}
+
+	// neither the input nor the defaults are modified
+	in := map[string]interface{}{"Nested": map[string]interface{}{}}
+	_, err = json2msgp.ConvertWithOptions(in, json2msgp.WithDefaults(defaults))
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{"Nested": map[string]interface{}{}}, in)
+	_, err = json2msgp.ConvertWithOptions(in, json2msgp.WithDefaults(defaults), json2msgp.WithFlatten("."))
+	require.NoError(t, err)
+	require.Len(t, defaults.(map[string]interface{})["Nested"], 2)
+
+	_, err = json2msgp.ReadDefaults([]byte(`{} {}`))
+	require.Error(t, err)
+}
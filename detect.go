@@ -0,0 +1,130 @@
+package json2msgp
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+
+	"github.com/pkg/errors"
+	"github.com/tinylib/msgp/msgp"
+)
+
+// Format is an encoding DetectFormat recognizes.
+type Format int
+
+const (
+	// FormatUnknown is input which is none of the others, or empty.
+	FormatUnknown Format = iota
+	// FormatJSON is a JSON document, or several separated by spaces.
+	FormatJSON
+	// FormatNDJSON is newline-delimited JSON: a document per line.
+	FormatNDJSON
+	// FormatMsgp is one or more MSGP values.
+	FormatMsgp
+)
+
+var formatNames = map[Format]string{
+	FormatUnknown: "unknown",
+	FormatJSON:    "JSON",
+	FormatNDJSON:  "NDJSON",
+	FormatMsgp:    "MSGP",
+}
+
+func (f Format) String() string {
+	return formatNames[f]
+}
+
+// sniffSize is how much of its input DetectFormat looks at.
+const sniffSize = 64 << 10
+
+// DetectFormat reads the start of r to tell what it holds, so that callers
+// can pick the direction of a conversion.  It returns a reader which yields
+// all of r, including what was read.
+//
+// Input which parses as JSON is JSON, even if it is also valid MSGP, like 1.
+// It is NDJSON if a newline separates its first two documents.  Otherwise,
+// it is MSGP if it is a sequence of well-formed MSGP values which starts
+// with anything but an ASCII character: all of ASCII is valid MSGP, as small
+// integers, so text would otherwise pass for MSGP.  Only the first
+// 64 KiB are looked at, and a document or value cut off there counts as
+// well-formed.
+func DetectFormat(r io.Reader) (Format, io.Reader, error) {
+	br := bufio.NewReaderSize(r, sniffSize)
+	sample, err := br.Peek(sniffSize)
+	complete := err == io.EOF
+	if err != nil && !complete && err != bufio.ErrBufferFull {
+		return FormatUnknown, br, errors.Wrap(err, "DetectFormat")
+	}
+	if len(bytes.TrimSpace(sample)) == 0 {
+		return FormatUnknown, br, nil
+	}
+	if documents, newline := sniffJSON(sample, complete); documents > 0 {
+		if documents > 1 && newline {
+			return FormatNDJSON, br, nil
+		}
+		return FormatJSON, br, nil
+	}
+	if sniffMsgp(sample, complete) {
+		return FormatMsgp, br, nil
+	}
+	return FormatUnknown, br, nil
+}
+
+// sniffJSON returns the number of JSON documents in sample, or 0 if it is not
+// JSON, and whether the first two are separated by a newline.
+func sniffJSON(sample []byte, complete bool) (int, bool) {
+	d := json.NewDecoder(bytes.NewReader(sample))
+	var (
+		documents int
+		newline   bool
+		end       int64
+	)
+	for {
+		var v json.RawMessage
+		err := d.Decode(&v)
+		if err == io.EOF || (err == io.ErrUnexpectedEOF && !complete && documents > 0) {
+			return documents, newline
+		}
+		if err == io.ErrUnexpectedEOF && !complete {
+			// the first document is larger than the sample
+			return 1, false
+		}
+		if err != nil {
+			return 0, false
+		}
+		if documents == 1 {
+			start := d.InputOffset() - int64(len(v))
+			newline = bytes.IndexByte(sample[end:start], '\n') >= 0
+		}
+		documents++
+		end = d.InputOffset()
+	}
+}
+
+// sniffMsgp reports whether sample is a sequence of MSGP values.
+func sniffMsgp(sample []byte, complete bool) bool {
+	if !msgpLeader(sample) {
+		return false
+	}
+	for len(sample) > 0 {
+		rest, err := msgp.Skip(sample)
+		if errors.Is(err, msgp.ErrShortBytes) && !complete {
+			return true
+		}
+		if err != nil {
+			return false
+		}
+		sample = rest
+	}
+	return true
+}
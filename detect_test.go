@@ -0,0 +1,61 @@
+package json2msgp_test
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/ndau/json2msgp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectFormat(t *testing.T) {
+	big := `{"a":"` + strings.Repeat("x", 100<<10) + `"}`
+	tests := []struct {
+		name string
+		in   string
+		want json2msgp.Format
+	}{
+		{"empty", "", json2msgp.FormatUnknown},
+		{"whitespace", " \n", json2msgp.FormatUnknown},
+		{"object", ` {"a": [1, 2]}` + "\n", json2msgp.FormatJSON},
+		{"number", "1", json2msgp.FormatJSON},
+		{"several on a line", `{"a":1} {"a":2}`, json2msgp.FormatJSON},
+		{"ndjson", "{\"a\":1}\n{\"a\":2}\n", json2msgp.FormatNDJSON},
+		{"ndjson with blank lines", "[1]\r\n\r\n[2]", json2msgp.FormatNDJSON},
+		{"large document", big, json2msgp.FormatJSON},
+		{"large ndjson", big + "\n" + big, json2msgp.FormatJSON},
+		{"msgp", "\x81\xa3Fee\xcd\x01\x2c", json2msgp.FormatMsgp},
+		{"msgp values", "\x91\x01\x91\x02", json2msgp.FormatMsgp},
+		{"msgp like a number", "\xc3\x31\x7b", json2msgp.FormatMsgp},
+		{"ASCII", "1\xc3", json2msgp.FormatUnknown},
+		{"large msgp", "\xdb\x00\x01\x90\x00" + strings.Repeat("x", 100<<10), json2msgp.FormatMsgp},
+		{"truncated msgp", "\x81\xa3Fe", json2msgp.FormatUnknown},
+		{"bad JSON", `{"a":}`, json2msgp.FormatUnknown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, r, err := json2msgp.DetectFormat(strings.NewReader(tt.in))
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got, "got %s", got)
+			all, err := io.ReadAll(r)
+			require.NoError(t, err)
+			require.Equal(t, tt.in, string(all))
+		})
+	}
+
+	got, r, err := json2msgp.DetectFormat(bytes.NewReader(nil))
+	require.NoError(t, err)
+	require.Equal(t, "unknown", got.String())
+	require.NotNil(t, r)
+}
@@ -0,0 +1,219 @@
+package json2msgp
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/tinylib/msgp/msgp"
+)
+
+// Change is a difference between two MSGP values.
+type Change struct {
+	// JSONPath of the value which differs, e.g. "$[2][1]".
+	Path string
+	// The old and new values, rendered for reading.  Old is empty for an added
+	// value, and New for a removed one.
+	Old, New string
+}
+
+// String returns the change as a single line.
+func (c Change) String() string {
+	switch {
+	case c.Old == "":
+		return fmt.Sprintf("%s: added %s", c.Path, c.New)
+	case c.New == "":
+		return fmt.Sprintf("%s: removed %s", c.Path, c.Old)
+	}
+	return fmt.Sprintf("%s: %s -> %s", c.Path, c.Old, c.New)
+}
+
+// Diff compares two MSGP values, and returns their differences ordered by path.
+//
+// The comparison is semantic rather than byte for byte: map order and the
+// width of integer encodings are ignored, and integers are compared by value.
+// Floats are rendered with their type, as float32(...) or float64(...), so
+// that an integer which became a float, or a float which changed width, is a
+// change.  Strings are rendered quoted, and byte arrays as base64(...).
+func Diff(old, new []byte) ([]Change, error) {
+	oldValue, err := readValue(old)
+	if err != nil {
+		return nil, errors.Wrap(err, "Diff reading old value")
+	}
+	newValue, err := readValue(new)
+	if err != nil {
+		return nil, errors.Wrap(err, "Diff reading new value")
+	}
+	var changes []Change
+	diffValues("$", oldValue, newValue, &changes)
+	return changes, nil
+}
+
+// Equivalent reports whether out is the MSGP jsonValue converts to with
+// typeHints, compared value by value as for Diff, and if not, the paths of
+// the values which differ.
+//
+// If jsonValue cannot be converted, or out cannot be read, the only entry
+// is a description of the error.
+func Equivalent(jsonValue interface{}, out []byte, typeHints map[string][]string) (bool, []string) {
+	want, err := Convert(jsonValue, typeHints)
+	if err != nil {
+		return false, []string{errors.Wrap(err, "Equivalent converting JSON value").Error()}
+	}
+	changes, err := Diff(want, out)
+	if err != nil {
+		return false, []string{err.Error()}
+	}
+	if len(changes) == 0 {
+		return true, nil
+	}
+	paths := make([]string, len(changes))
+	for i, change := range changes {
+		paths[i] = change.Path
+	}
+	return false, paths
+}
+
+// DiffSysvar compares the current value of the named system variable on the
+// ndau node at nodeURL with proposed, its new value as JSON, so that a change
+// review shows exactly which values differ.
+//
+// If typeHints is nil, hints are inferred from the current value.
+func DiffSysvar(ctx context.Context, client *http.Client, nodeURL, name string, proposed []byte, typeHints map[string][]string) ([]Change, error) {
+	sysvars, err := FetchSysvars(ctx, client, nodeURL, name)
+	if err != nil {
+		return nil, err
+	}
+	current, ok := sysvars[name]
+	if !ok {
+		return nil, fmt.Errorf("DiffSysvar: %w: %s", ErrUnknownSysvar, name)
+	}
+	if typeHints == nil {
+		typeHints, err = InferHints(current)
+		if err != nil {
+			return nil, errors.Wrap(err, "DiffSysvar")
+		}
+	}
+	var out bytes.Buffer
+	err = ConvertStream(bytes.NewReader(proposed), &out, typeHints)
+	if err != nil {
+		return nil, errors.Wrap(err, "DiffSysvar converting proposed value")
+	}
+	return Diff(current, out.Bytes())
+}
+
+// readValue reads a single MSGP value.
+func readValue(b []byte) (interface{}, error) {
+	v, rest, err := msgp.ReadIntfBytes(b)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) > 0 {
+		return nil, fmt.Errorf("%d bytes of trailing data", len(rest))
+	}
+	return v, nil
+}
+
+func diffValues(path string, old, new interface{}, changes *[]Change) {
+	switch o := old.(type) {
+	case map[string]interface{}:
+		if n, ok := new.(map[string]interface{}); ok {
+			keys := sortedKeys(o)
+			for _, key := range sortedKeys(n) {
+				if _, ok := o[key]; !ok {
+					keys = append(keys, key)
+				}
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				ov, ok := o[key]
+				if !ok {
+					ov = absent{}
+				}
+				nv, ok := n[key]
+				if !ok {
+					nv = absent{}
+				}
+				diffValues(path+keySegment(key), ov, nv, changes)
+			}
+			return
+		}
+	case []interface{}:
+		if n, ok := new.([]interface{}); ok {
+			for i := 0; i < len(o) || i < len(n); i++ {
+				var ov, nv interface{}
+				if i < len(o) {
+					ov = o[i]
+				} else {
+					ov = absent{}
+				}
+				if i < len(n) {
+					nv = n[i]
+				} else {
+					nv = absent{}
+				}
+				diffValues(path+indexSegment(i), ov, nv, changes)
+			}
+			return
+		}
+	}
+
+	oldText, newText := render(old), render(new)
+	if oldText != newText {
+		*changes = append(*changes, Change{Path: path, Old: oldText, New: newText})
+	}
+}
+
+// absent stands for a map value or array element which only one side of a
+// diff has.
+type absent struct{}
+
+// render returns v as text for a Change.  Absent values render as "".
+func render(v interface{}) string {
+	switch x := v.(type) {
+	case absent:
+		return ""
+	case nil:
+		return "nil"
+	case string:
+		return strconv.Quote(x)
+	case []byte:
+		return "base64(" + base64.StdEncoding.EncodeToString(x) + ")"
+	case int64:
+		return strconv.FormatInt(x, 10)
+	case uint64:
+		return strconv.FormatUint(x, 10)
+	case float32:
+		return "float32(" + strconv.FormatFloat(float64(x), 'g', -1, 32) + ")"
+	case float64:
+		return "float64(" + strconv.FormatFloat(x, 'g', -1, 64) + ")"
+	case map[string]interface{}:
+		parts := make([]string, 0, len(x))
+		for _, key := range sortedKeys(x) {
+			parts = append(parts, strconv.Quote(key)+": "+render(x[key]))
+		}
+		return "{" + strings.Join(parts, ", ") + "}"
+	case []interface{}:
+		parts := make([]string, 0, len(x))
+		for _, elem := range x {
+			parts = append(parts, render(elem))
+		}
+		return "[" + strings.Join(parts, ", ") + "]"
+	}
+	return fmt.Sprintf("%v", v)
+}
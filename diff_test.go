@@ -0,0 +1,99 @@
+package json2msgp_test
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"context"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/ndau/json2msgp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name string
+		old  string
+		new  string
+		want []string
+	}{
+		{"identical", "81 a1 61 01", "81 a1 61 01", nil},
+		{"integer width ignored", "81 a1 61 01", "81 a1 61 d3 00 00 00 00 00 00 00 01", nil},
+		{"map order ignored", "82 a1 61 01 a1 62 02", "82 a1 62 02 a1 61 01", nil},
+		{"changed value", "81 a1 61 01", "81 a1 61 02", []string{"$.a: 1 -> 2"}},
+		{"added key", "81 a1 61 01", "82 a1 61 01 a1 62 c0", []string{"$.b: added nil"}},
+		{"removed element", "92 01 a1 78", "91 01", []string{`$[1]: removed "x"`}},
+		{"integer and float differ", "81 a1 61 05", "81 a1 61 cb 40 14 00 00 00 00 00 00", []string{"$.a: 5 -> float64(5)"}},
+		{"float width differs", "ca 3f 00 00 00", "cb 3f e0 00 00 00 00 00 00", []string{"$: float32(0.5) -> float64(0.5)"}},
+		{"string and bytes differ", "a2 44 77", "c4 02 44 77", []string{`$: "Dw" -> base64(RHc=)`}},
+		{"type of container changed", "81 a1 61 91 01", "81 a1 61 81 a1 62 01", []string{`$.a: [1] -> {"b": 1}`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			old, err := hex.DecodeString(strings.Replace(tt.old, " ", "", -1))
+			require.NoError(t, err)
+			new, err := hex.DecodeString(strings.Replace(tt.new, " ", "", -1))
+			require.NoError(t, err)
+
+			changes, err := json2msgp.Diff(old, new)
+			require.NoError(t, err)
+			var got []string
+			for _, change := range changes {
+				got = append(got, change.String())
+			}
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestDiffSysvar(t *testing.T) {
+	table, err := json2msgp.Convert(
+		[]interface{}{[]interface{}{7776000000000.0, 10000000000.0}},
+		map[string][]string{"": []string{"int64", "uint64"}},
+	)
+	require.NoError(t, err)
+
+	node := fakeNode(t, map[string][]byte{"LockedRateTable": table})
+	defer node.Close()
+
+	changes, err := json2msgp.DiffSysvar(context.Background(), nil, node.URL, "LockedRateTable",
+		[]byte(`[[7776000000000,20000000000]]`), nil)
+	require.NoError(t, err)
+	require.Equal(t, []json2msgp.Change{{Path: "$[0][1]", Old: "10000000000", New: "20000000000"}}, changes)
+}
+
+func TestEquivalent(t *testing.T) {
+	in := map[string]interface{}{"Fee": 200.0, "Script": "oACI", "Names": []interface{}{"a!", "b!"}}
+	hints := map[string][]string{"Fee": []string{"uint64"}}
+
+	// integer widths don't matter
+	ok, paths := json2msgp.Equivalent(in, []byte("\x83\xa3Fee\xcd\x00\xc8\xa5Names\x92\xa2a!\xa2b!\xa6Script\xc4\x03\xa0\x00\x88"), hints)
+	require.True(t, ok)
+	require.Empty(t, paths)
+
+	ok, paths = json2msgp.Equivalent(in, []byte("\x83\xa3Fee\xcc\xc9\xa5Names\x91\xa2a!\xa6Script\xa4oACI"), hints)
+	require.False(t, ok)
+	require.Equal(t, []string{"$.Fee", "$.Names[1]", "$.Script"}, paths)
+
+	// but a float in place of an integer does
+	ok, paths = json2msgp.Equivalent(in, []byte("\x83\xa3Fee\xcb\x40\x69\x00\x00\x00\x00\x00\x00\xa5Names\x92\xa2a!\xa2b!\xa6Script\xc4\x03\xa0\x00\x88"), hints)
+	require.False(t, ok)
+	require.Equal(t, []string{"$.Fee"}, paths)
+
+	ok, paths = json2msgp.Equivalent(map[string]interface{}{"Fee": 1.5}, []byte("\xc0"), nil)
+	require.False(t, ok)
+	require.Len(t, paths, 1)
+	require.Contains(t, paths[0], "$.Fee")
+
+	ok, _ = json2msgp.Equivalent(nil, []byte("\xc0\xc0"), nil)
+	require.False(t, ok)
+}
@@ -0,0 +1,156 @@
+package json2msgp
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// These errors are wrapped by the errors returned from conversion, so callers
+// can test for them with errors.Is.
+var (
+	// ErrUnhintedNumber is returned for a non-integral number without a type hint.
+	ErrUnhintedNumber = errors.New("Unsupported numeric value")
+
+	// ErrBadHint is returned for a type hint which does not name a supported type.
+	ErrBadHint = errors.New("Unsupported type hint")
+
+	// ErrMissingHint is returned when a value requires a type hint but has none.
+	ErrMissingHint = errors.New("Missing type hint")
+
+	// ErrOverflow is returned for a number which does not fit in its hinted type.
+	ErrOverflow = errors.New("Value out of range")
+
+	// ErrNonUTF8Key is returned for a map key which is not valid utf-8.
+	ErrNonUTF8Key = errors.New("Map key is not valid utf-8")
+
+	// ErrInvalidBase64 is returned for a string hinted as base64 which is not.
+	ErrInvalidBase64 = errors.New("Invalid base64 value")
+
+	// ErrBadValue is returned for a string which cannot be parsed as its hinted type.
+	ErrBadValue = errors.New("Invalid value for type hint")
+
+	// ErrAddressKind is returned for an ndau address of a kind not allowed for its key.
+	ErrAddressKind = errors.New("Address kind not allowed")
+
+	// ErrMaxDepth is returned for a value nested too deeply; see WithMaxDepth.
+	ErrMaxDepth = errors.New("Maximum nesting depth exceeded")
+
+	// ErrInputTooLarge is returned for input larger than WithMaxInputSize allows.
+	ErrInputTooLarge = errors.New("Input size limit exceeded")
+
+	// ErrOutputTooLarge is returned when the output would be larger than
+	// WithMaxOutputSize allows.
+	ErrOutputTooLarge = errors.New("Output size limit exceeded")
+
+	// ErrStringTooLong is returned for a string longer than WithMaxStringLength allows.
+	ErrStringTooLong = errors.New("String length limit exceeded")
+
+	// ErrTimeout is returned when a document takes longer than WithTimeout allows.
+	ErrTimeout = errors.New("Conversion timed out")
+
+	// ErrUnsupportedType is returned for input of a type WithoutReflection rejects.
+	ErrUnsupportedType = errors.New("Unsupported input type")
+
+	// ErrControlChar is returned for a string containing a control character
+	// under ControlError.
+	ErrControlChar = errors.New("Control character in string")
+
+	// ErrAuditLog is returned by VerifyAuditLog for a malformed or broken log.
+	ErrAuditLog = errors.New("Invalid audit log")
+
+	// ErrInvalidMsgp is returned by ValidateMsgp for malformed MSGP.
+	ErrInvalidMsgp = errors.New("Invalid MSGP")
+
+	// ErrTrailingMsgp is returned by ValidateMsgp for data after the value.
+	ErrTrailingMsgp = errors.New("Trailing data after MSGP value")
+
+	// ErrPatch is returned for a JSON patch which is malformed or does not apply.
+	ErrPatch = errors.New("JSON patch failed")
+
+	// ErrDuplicateKey is returned for a JSON object which repeats a key.
+	ErrDuplicateKey = errors.New("Duplicate key")
+
+	// ErrTrailingData is returned for non-whitespace input after a JSON document.
+	ErrTrailingData = errors.New("Trailing data after JSON document")
+
+	// ErrSchema is returned for a value which does not conform to its schema.
+	ErrSchema = errors.New("Schema violation")
+
+	// ErrUnknownSysvar is returned for a system variable this package doesn't know.
+	ErrUnknownSysvar = errors.New("Unknown system variable")
+
+	// ErrBadOption is returned when an option has an unsupported value.
+	ErrBadOption = errors.New("Unsupported option value")
+
+	// ErrPathNotFound is returned when there is no value at the path given
+	// to WithPath.
+	ErrPathNotFound = errors.New("Path not found")
+
+	// ErrMissingKey is returned for a map without a key WithRequiredKeys
+	// requires.
+	ErrMissingKey = errors.New("Missing required key")
+
+	// ErrMsgpInput is returned by the stream functions for input which is
+	// already MSGP; see WithMsgpInput.
+	ErrMsgpInput = errors.New("Input appears to already be MSGP")
+
+	// ErrAllWritersFailed is returned by a Tee once none of its writers can
+	// be written to.
+	ErrAllWritersFailed = errors.New("All writers failed")
+)
+
+// ErrorList is returned when conversion with WithAllErrors finds problems.
+// It lists them in document order, one per line.
+type ErrorList []error
+
+func (e ErrorList) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// Unwrap supports errors.Is and errors.As for the listed errors.
+func (e ErrorList) Unwrap() []error {
+	return e
+}
+
+// WriteError is returned by the stream functions when writing to their
+// output fails part way, such as on a network hiccup or a full disk.  It
+// tells how far they got, so that the conversion can be resumed with
+// WithResume.
+type WriteError struct {
+	// Written counts the values written whole before the failure, and every
+	// byte written, including those of the failed value.
+	Written StreamResult
+	// Partial is the number of bytes of the failed value which were
+	// written, and should be discarded from the end of the output.
+	Partial int
+	// Err is the error of the writer.
+	Err error
+}
+
+func (e *WriteError) Error() string {
+	values := "values"
+	if e.Written.Values == 1 {
+		values = "value"
+	}
+	return fmt.Sprintf("ConvertStream writing to out stream after %d %s (%d bytes written): %s",
+		e.Written.Values, values, e.Written.Bytes, e.Err)
+}
+
+// Unwrap supports errors.Is and errors.As for the error of the writer.
+func (e *WriteError) Unwrap() error {
+	return e.Err
+}
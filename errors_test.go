@@ -0,0 +1,85 @@
+package json2msgp_test
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ndau/json2msgp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrorValues(t *testing.T) {
+	hints := func(h map[string][]string) json2msgp.Option { return json2msgp.WithTypeHints(h) }
+
+	tests := []struct {
+		name string
+		in   interface{}
+		opts []json2msgp.Option
+		want error
+	}{
+		{"unhinted number", map[string]interface{}{"Fee": 1.5}, nil, json2msgp.ErrUnhintedNumber},
+		{"bad hint", map[string]interface{}{"Fee": 1.0}, []json2msgp.Option{hints(map[string][]string{"Fee": []string{"int128"}})}, json2msgp.ErrBadHint},
+		{"missing hint", map[string]interface{}{"Fee": nil}, []json2msgp.Option{json2msgp.WithNullPolicy(json2msgp.NullAsZero)}, json2msgp.ErrMissingHint},
+		{"overflow uint8", map[string]interface{}{"Fee": 256.0}, []json2msgp.Option{hints(map[string][]string{"Fee": []string{"uint8"}})}, json2msgp.ErrOverflow},
+		{"overflow negative unsigned", map[string]interface{}{"Fee": -1.0}, []json2msgp.Option{hints(map[string][]string{"Fee": []string{"uint64"}})}, json2msgp.ErrOverflow},
+		{"overflow int64", map[string]interface{}{"Fee": 9223372036854775808.0}, []json2msgp.Option{hints(map[string][]string{"Fee": []string{"int64"}})}, json2msgp.ErrOverflow},
+		{"non-utf8 key", map[string]interface{}{string([]byte{0xff}): 1.0}, nil, json2msgp.ErrNonUTF8Key},
+		{"invalid base64", map[string]interface{}{"s": "foo"}, []json2msgp.Option{hints(map[string][]string{"s": []string{"base64"}})}, json2msgp.ErrInvalidBase64},
+		{"bad null policy", map[string]interface{}{"s": nil}, []json2msgp.Option{json2msgp.WithNullPolicy(json2msgp.NullPolicy(99))}, json2msgp.ErrBadOption},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := json2msgp.ConvertWithOptions(tt.in, tt.opts...)
+			require.Error(t, err)
+			require.True(t, errors.Is(err, tt.want), "got %v, want %v", err, tt.want)
+		})
+	}
+}
+
+func TestHintBoundsAllowExtremes(t *testing.T) {
+	for hint, v := range map[string]float64{
+		"uint8": 255,
+		"int8":  -128,
+		"int64": -9223372036854775808,
+	} {
+		_, err := json2msgp.Convert(map[string]interface{}{"x": v}, map[string][]string{"x": []string{hint}})
+		require.NoError(t, err, hint)
+	}
+}
+
+func TestAllErrors(t *testing.T) {
+	in := map[string]interface{}{
+		"EAIFeeTable": []interface{}{
+			map[string]interface{}{"Fee": 1.5},
+			map[string]interface{}{"Fee": 1.0},
+			map[string]interface{}{"Fee": 2.5},
+		},
+		"Rate": 300.0,
+	}
+	hints := map[string][]string{"Rate": []string{"uint8"}}
+
+	_, err := json2msgp.Convert(in, hints)
+	require.EqualError(t, err, "$.EAIFeeTable[0].Fee: Unsupported numeric value 1.5")
+
+	_, err = json2msgp.ConvertWithOptions(in, json2msgp.WithTypeHints(hints), json2msgp.WithAllErrors())
+	require.EqualError(t, err, strings.Join([]string{
+		"$.EAIFeeTable[0].Fee: Unsupported numeric value 1.5",
+		"$.EAIFeeTable[2].Fee: Unsupported numeric value 2.5",
+		"$.Rate: Value out of range for uint8: 300",
+	}, "\n"))
+	require.True(t, errors.Is(err, json2msgp.ErrOverflow))
+
+	var list json2msgp.ErrorList
+	require.True(t, errors.As(err, &list))
+	require.Len(t, list, 3)
+}
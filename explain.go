@@ -0,0 +1,101 @@
+package json2msgp
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"fmt"
+
+	"github.com/tinylib/msgp/msgp"
+)
+
+// Report describes how a document was converted.
+type Report struct {
+	// The root of the tree of converted values.
+	Root *Node
+	// The MSGP output, into which the nodes' byte ranges point.
+	Output []byte
+}
+
+// Node describes the conversion of a single value.
+type Node struct {
+	// JSONPath of the value, e.g. "$.EAIFeeTable[4].Fee".
+	Path string
+	// Go type of the input value, e.g. "float64" or "map[string]interface {}".
+	InputType string
+	// Type hint which determined the encoding, if any.
+	Hint string
+	// Why the value was encoded the way it was: "hint", "null policy", or for
+	// unhinted values the heuristic outcome: "binary", "address", "base64",
+	// "string" or "integer".  Empty for values with only one possible encoding.
+	Outcome string
+	// MSGP type of the output, e.g. "str", "bin", "int" or "map".  Empty
+	// when converting with another Codec.
+	OutputType string
+	// The value occupies Output[Start:End].
+	Start, End int
+	// Elements of an array or values of a map, in output order.
+	Children []*Node
+}
+
+// Explain converts in like Convert, and reports the decisions made for every
+// value of the document.
+//
+// If conversion fails, the partial report is returned along with the error.
+func Explain(in interface{}, typeHints map[string][]string, opts ...Option) (*Report, error) {
+	c := newConverter(append([]Option{WithTypeHints(typeHints)}, opts...)...)
+	c.report = &Report{}
+	out, err := c.convertRoot(in)
+	c.report.Output = out
+	return c.report, err
+}
+
+// newNode returns a Node for the value in, which will be appended to buffer,
+// as a child of the current node.
+func (c *Converter) newNode(in interface{}, buffer []byte) *Node {
+	node := &Node{
+		Path:      c.pathString(),
+		InputType: fmt.Sprintf("%T", in),
+		Start:     len(buffer),
+	}
+	if c.node == nil {
+		c.report.Root = node
+	} else {
+		c.node.Children = append(c.node.Children, node)
+	}
+	return node
+}
+
+// finishNode completes the current node once its value has been appended to buffer.
+func (c *Converter) finishNode(buffer []byte) {
+	c.node.End = len(buffer)
+	if c.node.End > c.node.Start && c.isMsgp() {
+		c.node.OutputType = msgp.NextType(buffer[c.node.Start:]).String()
+	}
+}
+
+// decide records, for Explain, the logger, metrics, audit log and Bench, the hint
+// and outcome which determined the encoding of the current value.
+func (c *Converter) decide(hint, outcome string) {
+	c.logDecision(hint, outcome)
+	if c.metrics != nil {
+		c.metrics.Decision(outcome)
+	}
+	if c.audit != nil {
+		c.audit.record(c.pathString(), hint, outcome)
+	}
+	if c.profile != nil {
+		c.profile(c.path, hint, outcome)
+	}
+	if c.node == nil {
+		return
+	}
+	c.node.Hint = hint
+	c.node.Outcome = outcome
+}
@@ -0,0 +1,68 @@
+package json2msgp_test
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/ndau/json2msgp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExplain(t *testing.T) {
+	in := map[string]interface{}{
+		"ChangeOn": 0.0,
+		"Current":  []interface{}{"oACI", "ndnf9ffbzhyf8mk7z5vvqc4quzz5i2exp5zgsmhyhc9cuwr4", "foo"},
+	}
+	hints := map[string][]string{"ChangeOn": []string{"uint64"}}
+
+	report, err := json2msgp.Explain(in, hints)
+	require.NoError(t, err)
+
+	want, err := json2msgp.Convert(in, hints)
+	require.NoError(t, err)
+	require.Equal(t, want, report.Output)
+
+	root := report.Root
+	require.Equal(t, "$", root.Path)
+	require.Equal(t, "map", root.OutputType)
+	require.Equal(t, 0, root.Start)
+	require.Equal(t, len(want), root.End)
+	require.Len(t, root.Children, 2)
+
+	changeOn := root.Children[0]
+	require.Equal(t, "$.ChangeOn", changeOn.Path)
+	require.Equal(t, "float64", changeOn.InputType)
+	require.Equal(t, "uint64", changeOn.Hint)
+	require.Equal(t, "hint", changeOn.Outcome)
+	require.Equal(t, "int", changeOn.OutputType) // positive fixint
+
+	current := root.Children[1]
+	require.Equal(t, "array", current.OutputType)
+	require.Len(t, current.Children, 3)
+	outcomes := []string{}
+	for i, n := range current.Children {
+		outcomes = append(outcomes, n.Outcome)
+		require.Equal(t, fmt.Sprintf("$.Current[%d]", i), n.Path)
+	}
+	require.Equal(t, []string{"base64", "address", "string"}, outcomes)
+
+	script := current.Children[0]
+	require.Equal(t, []byte{0xc4, 0x03, 0xa0, 0x00, 0x88}, report.Output[script.Start:script.End])
+}
+
+func TestExplainError(t *testing.T) {
+	report, err := json2msgp.Explain([]interface{}{1.0, 1.5}, nil)
+	require.Error(t, err)
+	require.NotNil(t, report.Root)
+	require.Len(t, report.Root.Children, 2)
+	require.Equal(t, "$[1]", report.Root.Children[1].Path)
+}
@@ -0,0 +1,66 @@
+package json2msgp
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"github.com/ndau/ndaumath/pkg/address"
+	"github.com/tinylib/msgp/msgp"
+)
+
+// AddressExtensionType is the msgpack extension type of AddressExtension.
+const AddressExtensionType int8 = 'n'
+
+// AddressExtension is a msgpack extension carrying an ndau address.
+//
+// Its payload is the address string itself.  Decoders which want to receive
+// addresses as this type from msgp's interface-reading functions should call
+// RegisterAddressExtension once.
+type AddressExtension string
+
+var _ msgp.Extension = (*AddressExtension)(nil)
+
+// ExtensionType implements msgp.Extension.
+func (a *AddressExtension) ExtensionType() int8 {
+	return AddressExtensionType
+}
+
+// Len implements msgp.Extension.
+func (a *AddressExtension) Len() int {
+	return len(*a)
+}
+
+// MarshalBinaryTo implements msgp.Extension.
+func (a *AddressExtension) MarshalBinaryTo(b []byte) error {
+	copy(b, *a)
+	return nil
+}
+
+// UnmarshalBinary implements msgp.Extension.
+//
+// It is an error for the payload not to be a valid ndau address.
+func (a *AddressExtension) UnmarshalBinary(b []byte) error {
+	addr, err := address.Validate(string(b))
+	if err != nil {
+		return err
+	}
+	*a = AddressExtension(addr.String())
+	return nil
+}
+
+// RegisterAddressExtension registers AddressExtension with msgp, so that
+// msgp.ReadIntf and friends decode it rather than returning a RawExtension.
+//
+// msgp panics if an extension type is registered twice, so call this once,
+// typically from an init function.
+func RegisterAddressExtension() {
+	msgp.RegisterExtension(AddressExtensionType, func() msgp.Extension {
+		return new(AddressExtension)
+	})
+}
@@ -0,0 +1,88 @@
+package json2msgp_test
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/ndau/json2msgp"
+	"github.com/stretchr/testify/require"
+	"github.com/tinylib/msgp/msgp"
+)
+
+func TestAddressExtension(t *testing.T) {
+	addr := "ndnf9ffbzhyf8mk7z5vvqc4quzz5i2exp5zgsmhyhc9cuwr4"
+
+	out, err := json2msgp.ConvertWithOptions([]interface{}{addr, "foo"}, json2msgp.WithAddressExtension())
+	require.NoError(t, err)
+
+	sz, out, err := msgp.ReadArrayHeaderBytes(out)
+	require.NoError(t, err)
+	require.Equal(t, uint32(2), sz)
+
+	var got json2msgp.AddressExtension
+	out, err = msgp.ReadExtensionBytes(out, &got)
+	require.NoError(t, err)
+	require.Equal(t, json2msgp.AddressExtension(addr), got)
+
+	s, _, err := msgp.ReadStringBytes(out)
+	require.NoError(t, err)
+	require.Equal(t, "foo", s)
+}
+
+func TestAddressExtensionRejectsNonAddress(t *testing.T) {
+	ext := json2msgp.AddressExtension("foo")
+	b, err := msgp.AppendExtension(nil, &ext)
+	require.NoError(t, err)
+
+	var got json2msgp.AddressExtension
+	_, err = msgp.ReadExtensionBytes(b, &got)
+	require.Error(t, err)
+}
+
+func TestAddressKinds(t *testing.T) {
+	// an address of kind 'n'
+	node := "ndnf9ffbzhyf8mk7z5vvqc4quzz5i2exp5zgsmhyhc9cuwr4"
+
+	tests := []struct {
+		name    string
+		in      string
+		opts    []json2msgp.Option
+		wantErr error
+	}{
+		{"allowed kind", `{"Node":"` + node + `"}`, []json2msgp.Option{json2msgp.WithKeyAddressKinds("Node", 'n')}, nil},
+		{"one of several kinds", `{"Node":"` + node + `"}`, []json2msgp.Option{json2msgp.WithKeyAddressKinds("Node", 'x', 'n')}, nil},
+		{"wrong kind", `{"Node":"` + node + `"}`, []json2msgp.Option{json2msgp.WithKeyAddressKinds("Node", 'x')}, json2msgp.ErrAddressKind},
+		{"not an address", `{"Node":"foo"}`, []json2msgp.Option{json2msgp.WithKeyAddressKinds("Node", 'n')}, json2msgp.ErrBadValue},
+		{"other keys unrestricted", `{"To":"` + node + `"}`, []json2msgp.Option{json2msgp.WithKeyAddressKinds("Node", 'x')}, nil},
+		{"address hint", `{"Node":"DwA="}`, []json2msgp.Option{json2msgp.WithTypeHints(map[string][]string{"Node": []string{"address"}})}, json2msgp.ErrBadValue},
+		{
+			"address hint with kinds",
+			`{"Node":"` + node + `"}`,
+			[]json2msgp.Option{
+				json2msgp.WithTypeHints(map[string][]string{"Node": []string{"address"}}),
+				json2msgp.WithKeyAddressKinds("Node", 'x'),
+			},
+			json2msgp.ErrAddressKind,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := json2msgp.ConvertStreamWithOptions(bytes.NewBufferString(tt.in), &bytes.Buffer{}, tt.opts...)
+			if tt.wantErr == nil {
+				require.NoError(t, err)
+				return
+			}
+			require.True(t, errors.Is(err, tt.wantErr), "got %v", err)
+		})
+	}
+}
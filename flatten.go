@@ -0,0 +1,192 @@
+package json2msgp
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// WithFlatten flattens maps nested in maps before encoding, joining their
+// keys with sep, for consumers which expect flat maps of keys to values.
+// For example, with sep ".",
+//
+//	{"svi": {"ChangeOn": 1, "Current": ["a", "b"]}}
+//
+// is encoded as
+//
+//	{"svi.ChangeOn": 1, "svi.Current": ["a", "b"]}
+//
+// Arrays are kept as values, but maps inside them are flattened in turn.
+// Empty nested maps are kept as values too, so that their keys are not lost.
+//
+// Type hints and other per-key options apply to the flattened keys, such as
+// "svi.ChangeOn".  If two keys flatten to the same one, conversion fails with
+// ErrDuplicateKey.
+func WithFlatten(sep string) Option {
+	return func(c *Converter) {
+		c.flatten = true
+		c.flattenSep = sep
+	}
+}
+
+// flattenValue returns in with its nested maps flattened.
+func (c *Converter) flattenValue(in interface{}) (interface{}, error) {
+	switch x := in.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(x))
+		if err := c.flattenInto(out, "", x); err != nil {
+			return nil, err
+		}
+		return out, nil
+	case []interface{}:
+		out := make([]interface{}, len(x))
+		for i, elem := range x {
+			var err error
+			if out[i], err = c.flattenValue(elem); err != nil {
+				return nil, err
+			}
+		}
+		return out, nil
+	}
+	return in, nil
+}
+
+// flattenInto adds the entries of m to out, with their keys prefixed.
+func (c *Converter) flattenInto(out map[string]interface{}, prefix string, m map[string]interface{}) error {
+	for _, key := range sortedKeys(m) {
+		name := prefix + key
+		val := m[key]
+		if nested, ok := val.(map[string]interface{}); ok && len(nested) > 0 {
+			if err := c.flattenInto(out, name+c.flattenSep, nested); err != nil {
+				return err
+			}
+			continue
+		}
+		val, err := c.flattenValue(val)
+		if err != nil {
+			return err
+		}
+		if _, ok := out[name]; ok {
+			return fmt.Errorf("%w %q after flattening", ErrDuplicateKey, name)
+		}
+		out[name] = val
+	}
+	return nil
+}
+
+// WithUnflatten expands keys containing sep into nested values before
+// encoding, for the flat config formats some tooling emits.  For example,
+// with sep ".",
+//
+//	{"EAIFeeTable.0.Fee": 1, "EAIFeeTable.0.To": null, "EAIFeeTable.1.Fee": 2}
+//
+// is encoded as
+//
+//	{"EAIFeeTable": [{"Fee": 1, "To": null}, {"Fee": 2}]}
+//
+// Keys at every level are expanded, including in maps inside arrays.  The
+// keys sharing a prefix become an array if they are exactly the indices 0
+// to n-1, without leading zeros, and a map otherwise, so maps keyed by
+// block height stay maps.
+//
+// If a key is both a value and a prefix of other keys, such as "a" and
+// "a.b", conversion fails with ErrDuplicateKey.
+func WithUnflatten(sep string) Option {
+	return func(c *Converter) {
+		c.unflattenSep = sep
+	}
+}
+
+// unflatNode collects the values of keys sharing a prefix.
+type unflatNode map[string]interface{}
+
+// unflattenValue returns in with its keys expanded.
+func (c *Converter) unflattenValue(in interface{}) (interface{}, error) {
+	switch x := in.(type) {
+	case map[string]interface{}:
+		root := make(unflatNode, len(x))
+		for _, key := range sortedKeys(x) {
+			val, err := c.unflattenValue(x[key])
+			if err != nil {
+				return nil, err
+			}
+			if err = root.insert(strings.Split(key, c.unflattenSep), val, key); err != nil {
+				return nil, err
+			}
+		}
+		return root.build(true), nil
+	case []interface{}:
+		out := make([]interface{}, len(x))
+		for i, elem := range x {
+			var err error
+			if out[i], err = c.unflattenValue(elem); err != nil {
+				return nil, err
+			}
+		}
+		return out, nil
+	}
+	return in, nil
+}
+
+// insert adds val at the path of segments below n.
+func (n unflatNode) insert(segments []string, val interface{}, key string) error {
+	seg := segments[0]
+	existing, ok := n[seg]
+	if len(segments) == 1 {
+		if ok {
+			return fmt.Errorf("%w %q when unflattening", ErrDuplicateKey, key)
+		}
+		n[seg] = val
+		return nil
+	}
+	child, isNode := existing.(unflatNode)
+	if ok && !isNode {
+		return fmt.Errorf("%w %q when unflattening", ErrDuplicateKey, key)
+	}
+	if !ok {
+		child = make(unflatNode)
+		n[seg] = child
+	}
+	return child.insert(segments[1:], val, key)
+}
+
+// build returns n as a JSON array or map.  The top level of an object is
+// always a map, as it was in the input.
+func (n unflatNode) build(top bool) interface{} {
+	for seg, val := range n {
+		if node, ok := val.(unflatNode); ok {
+			n[seg] = node.build(false)
+		}
+	}
+	if !top && n.isArray() {
+		a := make([]interface{}, len(n))
+		for seg, val := range n {
+			i, _ := strconv.Atoi(seg)
+			a[i] = val
+		}
+		return a
+	}
+	return map[string]interface{}(n)
+}
+
+// isArray is true if the keys of n are exactly the indices 0 to len(n)-1.
+func (n unflatNode) isArray() bool {
+	for seg := range n {
+		if !isDecimal(seg) || (len(seg) > 1 && seg[0] == '0') {
+			return false
+		}
+		if i, err := strconv.Atoi(seg); err != nil || i >= len(n) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,101 @@
+package json2msgp_test
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ndau/json2msgp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFlatten(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		sep     string
+		hints   map[string][]string
+		want    string
+		wantErr error
+	}{
+		{"flat", `{"a":1}`, ".", nil, `{"a":1}`, nil},
+		{"nested", `{"a":{"b":1,"c":{"d":2}},"e":3}`, ".", nil, `{"a.b":1,"a.c.d":2,"e":3}`, nil},
+		{"separator", `{"a":{"b":1}}`, "/", nil, `{"a/b":1}`, nil},
+		{"empty map kept", `{"a":{},"b":{"c":{}}}`, ".", nil, `{"a":{},"b.c":{}}`, nil},
+		{"maps in arrays", `[{"a":{"b":1}},{"c":[{"d":{"e":2}}]}]`, ".", nil, `[{"a.b":1},{"c":[{"d.e":2}]}]`, nil},
+		{"hints see flattened keys", `{"svi":{"ChangeOn":1}}`, ".",
+			map[string][]string{"svi.ChangeOn": {"float64"}}, `{"svi.ChangeOn":1}`, nil},
+		{"collision", `{"a":{"b":1},"a.b":2}`, ".", nil, "", json2msgp.ErrDuplicateKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got bytes.Buffer
+			err := json2msgp.ConvertStreamWithOptions(strings.NewReader(tt.in), &got,
+				json2msgp.WithFlatten(tt.sep), json2msgp.WithTypeHints(tt.hints))
+			if tt.wantErr != nil {
+				require.True(t, errors.Is(err, tt.wantErr), "got %v", err)
+				return
+			}
+			require.NoError(t, err)
+			var want bytes.Buffer
+			err = json2msgp.ConvertStream(strings.NewReader(tt.want), &want, tt.hints)
+			require.NoError(t, err)
+			require.Equal(t, want.Bytes(), got.Bytes())
+		})
+	}
+}
+
+func TestUnflatten(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		sep     string
+		want    string
+		wantErr error
+	}{
+		{"flat", `{"a":1,"0":2}`, ".", `{"a":1,"0":2}`, nil},
+		{"nested", `{"a.b":1,"a.c.d":2,"e":3}`, ".", `{"a":{"b":1,"c":{"d":2}},"e":3}`, nil},
+		{"separator", `{"a/b":1,"a.c":2}`, "/", `{"a":{"b":1},"a.c":2}`, nil},
+		{"arrays", `{"EAIFeeTable.0.Fee":1,"EAIFeeTable.0.To":null,"EAIFeeTable.1.Fee":2}`, ".",
+			`{"EAIFeeTable":[{"Fee":1,"To":null},{"Fee":2}]}`, nil},
+		{"gaps stay maps", `{"svi.100":1,"svi.200":2}`, ".", `{"svi":{"100":1,"200":2}}`, nil},
+		{"leading zeros stay maps", `{"a.0":1,"a.01":2}`, ".", `{"a":{"0":1,"01":2}}`, nil},
+		{"inside values", `[{"a.b":1},{"c":{"d.e":2}}]`, ".", `[{"a":{"b":1}},{"c":{"d":{"e":2}}}]`, nil},
+		{"value and prefix", `{"a":1,"a.b":2}`, ".", "", json2msgp.ErrDuplicateKey},
+		{"map value and prefix", `{"a":{"b":1},"a.c":2}`, ".", "", json2msgp.ErrDuplicateKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got bytes.Buffer
+			err := json2msgp.ConvertStreamWithOptions(strings.NewReader(tt.in), &got, json2msgp.WithUnflatten(tt.sep))
+			if tt.wantErr != nil {
+				require.True(t, errors.Is(err, tt.wantErr), "got %v", err)
+				return
+			}
+			require.NoError(t, err)
+			var want bytes.Buffer
+			err = json2msgp.ConvertStream(strings.NewReader(tt.want), &want, nil)
+			require.NoError(t, err)
+			require.Equal(t, want.Bytes(), got.Bytes())
+		})
+	}
+
+	// flattening undoes unflattening
+	in := `{"a.b":1,"a.c.d":[2,3]}`
+	var flat, want bytes.Buffer
+	err := json2msgp.ConvertStreamWithOptions(strings.NewReader(in), &flat,
+		json2msgp.WithUnflatten("."), json2msgp.WithFlatten("."))
+	require.NoError(t, err)
+	require.NoError(t, json2msgp.ConvertStream(strings.NewReader(in), &want, nil))
+	require.Equal(t, want.Bytes(), flat.Bytes())
+}
@@ -0,0 +1,84 @@
+package json2msgp
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ConvertFollow converts NDJSON from in a line at a time, writing each value
+// to out as soon as its line is complete, as the stream functions do.  At the
+// end of in, it waits for poll and reads again, like tail -f, so that it can
+// convert a log file as it grows.  It returns when ctx is done, or at the
+// first error.  If poll is not positive, it returns at the end of in instead,
+// converting a last line which has no newline.
+//
+// Blank lines are skipped.  Each other line must hold exactly one document.
+// WithMaxInputSize limits each line, and WithMaxOutputSize each line's
+// output, rather than the whole stream.
+func ConvertFollow(ctx context.Context, in io.Reader, out io.Writer, poll time.Duration, opts ...Option) (StreamResult, error) {
+	var result StreamResult
+	c := newConverter(opts...)
+	c.multipleDocuments = false
+	if c.resume > 0 && c.trace != nil {
+		return result, fmt.Errorf("ConvertFollow: %w: WithResume cannot be used with WithTrace", ErrBadOption)
+	}
+	if c.framed {
+		c.traceBase = frameHeaderSize
+	}
+	r := bufio.NewReader(in)
+	var line []byte
+	for n := 1; ; {
+		chunk, err := r.ReadBytes('\n')
+		line = append(line, chunk...)
+		if c.maxInputSize > 0 && int64(len(bytes.TrimSpace(line))) > c.maxInputSize {
+			return result, fmt.Errorf("ConvertFollow line %d: %w: more than %d bytes", n, ErrInputTooLarge, c.maxInputSize)
+		}
+		if err == io.EOF && poll > 0 {
+			select {
+			case <-ctx.Done():
+				return result, ctx.Err()
+			case <-time.After(poll):
+			}
+			continue
+		}
+		if err != nil && err != io.EOF {
+			return result, errors.Wrap(err, "ConvertFollow reading input")
+		}
+		if len(bytes.TrimSpace(line)) > 0 {
+			values, cerr := c.streamDocument(c.newDecoder(line))
+			if cerr != nil {
+				return result, errors.Wrapf(cerr, "ConvertFollow line %d", n)
+			}
+			var counted StreamResult
+			werr := c.writeValues(out, values, &counted)
+			result.Values += counted.Values
+			result.Bytes += counted.Bytes
+			if werr != nil {
+				return result, errors.Wrapf(werr, "ConvertFollow line %d", n)
+			}
+		}
+		if err == io.EOF {
+			return result, nil
+		}
+		line = line[:0]
+		n++
+		if ctx.Err() != nil {
+			return result, ctx.Err()
+		}
+	}
+}
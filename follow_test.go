@@ -0,0 +1,106 @@
+package json2msgp_test
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ndau/json2msgp"
+	"github.com/stretchr/testify/require"
+)
+
+// syncBuffer is a bytes.Buffer which may be written and read concurrently.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) Bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]byte(nil), b.buf.Bytes()...)
+}
+
+func TestConvertFollow(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		want    []byte
+		wantErr string
+	}{
+		{"lines", "[1]\n\n\"a\"\n", []byte{0x91, 1, 0xa1, 'a'}, ""},
+		{"last line without newline", "[1]\n2", []byte{0x91, 1, 2}, ""},
+		{"empty", "", nil, ""},
+		{"two documents on a line", "[1]\n1 2\n", []byte{0x91, 1}, "line 2"},
+		{"bad line", "[1]\n{\n[2]\n", []byte{0x91, 1}, "line 2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			result, err := json2msgp.ConvertFollow(context.Background(), strings.NewReader(tt.in), &out, 0)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tt.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, tt.want, out.Bytes())
+			require.Equal(t, int64(len(tt.want)), result.Bytes)
+		})
+	}
+}
+
+func TestConvertFollowGrowing(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log.ndjson")
+	require.NoError(t, os.WriteFile(path, []byte("[1]\n[2"), 0644))
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var out syncBuffer
+	done := make(chan error)
+	go func() {
+		_, err := json2msgp.ConvertFollow(ctx, f, &out, time.Millisecond, json2msgp.WithFraming())
+		done <- err
+	}()
+
+	require.Eventually(t, func() bool {
+		return bytes.Equal(out.Bytes(), []byte{0, 0, 0, 2, 0x91, 1})
+	}, time.Second, time.Millisecond)
+
+	w, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	require.NoError(t, err)
+	_, err = w.WriteString("]\n")
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	require.Eventually(t, func() bool {
+		return bytes.Equal(out.Bytes(), []byte{0, 0, 0, 2, 0x91, 1, 0, 0, 0, 2, 0x91, 2})
+	}, time.Second, time.Millisecond)
+
+	cancel()
+	require.True(t, errors.Is(<-done, context.Canceled))
+}
@@ -0,0 +1,172 @@
+package json2msgp
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+// frameHeaderSize is the size of the length which starts a frame.
+const frameHeaderSize = 4
+
+// WithFraming makes the stream functions write each value as a frame of the
+// daemon protocol, a 4 byte big-endian length followed by the value, rather
+// than following it with the delimiter.  Unlike a delimiter, framing never
+// confuses values with their contents.
+func WithFraming() Option {
+	return func(c *Converter) {
+		c.framed = true
+	}
+}
+
+// separatorSize is the number of bytes the stream functions write between
+// two values.
+func (c *Converter) separatorSize() int {
+	if c.framed {
+		return frameHeaderSize
+	}
+	return len(c.delimiter)
+}
+
+// appendFrame returns value as a frame.
+func appendFrame(b, value []byte) ([]byte, error) {
+	if len(value) > MaxFrameSize {
+		return b, fmt.Errorf("frame of %d bytes exceeds MaxFrameSize", len(value))
+	}
+	b = binary.BigEndian.AppendUint32(b, uint32(len(value)))
+	return append(b, value...), nil
+}
+
+// ReadFrames calls f with the value of each frame read from r until EOF, as
+// written with WithFraming.  It stops at the first error f returns.
+func ReadFrames(r io.Reader, f func(value []byte) error) error {
+	r = bufio.NewReader(r)
+	for {
+		value, err := readFrame(r)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return errors.Wrap(err, "ReadFrames")
+		}
+		if err = f(value); err != nil {
+			return err
+		}
+	}
+}
+
+// Appender appends frames to a file, so that long-running collectors can
+// build an archive of MSGP values incrementally.  A record is never left
+// half written: if a write fails, the file is cut back to its last whole
+// frame, and if the process dies in the middle of one, OpenAppender cuts
+// off the partial frame before appending again.
+type Appender struct {
+	f    *os.File
+	size int64
+}
+
+// OpenAppender opens the file at path for appending frames, creating it if
+// need be.  If the file ends with a partial frame, it is removed.  It is an
+// error for the file to hold anything but frames.
+func OpenAppender(path string) (*Appender, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, errors.Wrap(err, "OpenAppender")
+	}
+	size, err := wholeFrames(f)
+	if err == nil {
+		err = f.Truncate(size)
+	}
+	if err == nil {
+		_, err = f.Seek(size, io.SeekStart)
+	}
+	if err != nil {
+		f.Close()
+		return nil, errors.Wrapf(err, "OpenAppender %s", path)
+	}
+	return &Appender{f: f, size: size}, nil
+}
+
+// wholeFrames returns the size of the whole frames at the start of r.
+func wholeFrames(r io.Reader) (int64, error) {
+	br := bufio.NewReader(r)
+	var size int64
+	for {
+		var header [frameHeaderSize]byte
+		_, err := io.ReadFull(br, header[:])
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return size, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		n := binary.BigEndian.Uint32(header[:])
+		if n > MaxFrameSize {
+			return 0, fmt.Errorf("frame of %d bytes at offset %d exceeds MaxFrameSize", n, size)
+		}
+		skipped, err := io.CopyN(io.Discard, br, int64(n))
+		if skipped < int64(n) {
+			return size, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		size += frameHeaderSize + int64(n)
+	}
+}
+
+// Write appends p, which should be whole frames, such as the stream
+// functions write with WithFraming.  If it fails, the file is left as it
+// was.
+func (a *Appender) Write(p []byte) (int, error) {
+	n, err := a.f.Write(p)
+	if err != nil {
+		if terr := a.f.Truncate(a.size); terr == nil {
+			a.f.Seek(a.size, io.SeekStart)
+		}
+		return n, err
+	}
+	a.size += int64(n)
+	return n, nil
+}
+
+// Sync commits the frames written so far to stable storage.
+func (a *Appender) Sync() error {
+	return a.f.Sync()
+}
+
+// Close syncs and closes the file.
+func (a *Appender) Close() error {
+	err := a.f.Sync()
+	if cerr := a.f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// ConvertAppend converts in as ConvertStreamCounted does, and appends each
+// value to the file at path as a frame, using an Appender.
+func ConvertAppend(path string, in io.Reader, opts ...Option) (StreamResult, error) {
+	a, err := OpenAppender(path)
+	if err != nil {
+		return StreamResult{}, err
+	}
+	result, err := ConvertStreamCounted(in, a, append(opts[:len(opts):len(opts)], WithFraming())...)
+	if cerr := a.Close(); err == nil {
+		err = errors.Wrap(cerr, "ConvertAppend")
+	}
+	return result, err
+}
@@ -0,0 +1,96 @@
+package json2msgp_test
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ndau/json2msgp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithFraming(t *testing.T) {
+	var out bytes.Buffer
+	var trace json2msgp.Trace
+	result, err := json2msgp.ConvertStreamCounted(strings.NewReader(`[1] "a"`), &out,
+		json2msgp.WithFraming(), json2msgp.WithMultipleDocuments(), json2msgp.WithTrace(&trace),
+		json2msgp.WithDelimiter([]byte("\n")))
+	require.NoError(t, err)
+	require.Equal(t, []byte{0, 0, 0, 2, 0x91, 1, 0, 0, 0, 2, 0xa1, 'a'}, out.Bytes())
+	require.Equal(t, json2msgp.StreamResult{Values: 2, Bytes: 12}, result)
+
+	// trace offsets skip the frame headers
+	path, ok := trace.Lookup(5)
+	require.True(t, ok)
+	require.Equal(t, "$[0]", path)
+	path, ok = trace.Lookup(10)
+	require.True(t, ok)
+	require.Equal(t, "$", path)
+	_, ok = trace.Lookup(7)
+	require.False(t, ok)
+
+	var values [][]byte
+	err = json2msgp.ReadFrames(&out, func(value []byte) error {
+		values = append(values, value)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, [][]byte{{0x91, 1}, {0xa1, 'a'}}, values)
+
+	err = json2msgp.ReadFrames(bytes.NewReader([]byte{0, 0, 0, 2, 1}), func([]byte) error { return nil })
+	require.Error(t, err)
+}
+
+func TestConvertAppend(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "archive.msgpf")
+	read := func() [][]byte {
+		f, err := os.Open(path)
+		require.NoError(t, err)
+		defer f.Close()
+		var values [][]byte
+		require.NoError(t, json2msgp.ReadFrames(f, func(value []byte) error {
+			values = append(values, value)
+			return nil
+		}))
+		return values
+	}
+
+	_, err := json2msgp.ConvertAppend(path, strings.NewReader(`1 2`), json2msgp.WithMultipleDocuments())
+	require.NoError(t, err)
+	result, err := json2msgp.ConvertAppend(path, strings.NewReader(`3`))
+	require.NoError(t, err)
+	require.Equal(t, 1, result.Values)
+	require.Equal(t, [][]byte{{1}, {2}, {3}}, read())
+
+	// a crash in the middle of a frame leaves a partial one, which is removed
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	require.NoError(t, err)
+	_, err = f.Write([]byte{0, 0, 0, 9, 0x91})
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	_, err = json2msgp.ConvertAppend(path, strings.NewReader(`4`))
+	require.NoError(t, err)
+	require.Equal(t, [][]byte{{1}, {2}, {3}, {4}}, read())
+
+	// values converted before a failure are kept
+	_, err = json2msgp.ConvertAppend(path, strings.NewReader(`5 1.5`), json2msgp.WithMultipleDocuments())
+	require.Error(t, err)
+	require.Equal(t, [][]byte{{1}, {2}, {3}, {4}, {5}}, read())
+
+	// anything but frames is not appended to
+	other := filepath.Join(t.TempDir(), "other")
+	require.NoError(t, os.WriteFile(other, []byte(`{"not": "frames"}`), 0644))
+	_, err = json2msgp.OpenAppender(other)
+	require.Error(t, err)
+}
@@ -0,0 +1,168 @@
+package json2msgp
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"time"
+
+	"github.com/tinylib/msgp/msgp"
+)
+
+// TimeEncoding selects the encoding of Go time.Time and time.Duration
+// values given to Convert, such as in maps built in Go.
+type TimeEncoding int
+
+const (
+	// TimeAsMsgpExtension encodes a time.Time as msgp's own time extension,
+	// type 5, which msgp.ReadTimeBytes reads, and a time.Duration as an int64
+	// number of nanoseconds.  This is the default.
+	TimeAsMsgpExtension TimeEncoding = iota
+	// TimeAsTimestampExtension encodes a time.Time as the MessagePack
+	// timestamp extension, type -1, which other MessagePack libraries read;
+	// see TimestampExtension.  A time.Duration is an int64 number of
+	// nanoseconds.
+	TimeAsTimestampExtension
+	// TimeAsMicroseconds encodes as ndau does: a time.Time as an int64 number
+	// of microseconds since NdauEpoch, and a time.Duration as an int64 number
+	// of microseconds.
+	TimeAsMicroseconds
+)
+
+// WithTimeEncoding sets how Go time.Time and time.Duration values are
+// encoded.  Either is encoded as TimeAsMicroseconds does, whatever the
+// encoding, if its key is hinted "ndautimestamp" or "ndauduration"
+// respectively.  Both are accepted even WithoutReflection.
+func WithTimeEncoding(enc TimeEncoding) Option {
+	return func(c *Converter) {
+		c.timeEncoding = enc
+	}
+}
+
+// convertTime encodes a time.Time.
+func (c *Converter) convertTime(t time.Time, buffer []byte) ([]byte, error) {
+	enc := c.timeEncoding
+	if hint, ok := c.hint(); ok && hint == "ndautimestamp" {
+		c.decide(hint, "hint")
+		enc = TimeAsMicroseconds
+	}
+	switch enc {
+	case TimeAsMsgpExtension:
+		b, err := c.codec.AppendIntf(buffer, t)
+		if err != nil {
+			return buffer, c.wrap(err)
+		}
+		return b, nil
+	case TimeAsTimestampExtension:
+		ts := TimestampExtension(t)
+		b, err := c.codec.AppendExtension(buffer, &ts)
+		if err != nil {
+			return buffer, c.wrap(err)
+		}
+		return b, nil
+	case TimeAsMicroseconds:
+		d := t.Sub(NdauEpoch)
+		if d == math.MaxInt64 || d == math.MinInt64 {
+			return buffer, c.errorf("%w for ndautimestamp: %s", ErrOverflow, t)
+		}
+		return c.codec.AppendInt(buffer, d.Microseconds()), nil
+	default:
+		return buffer, c.errorf("%w: time encoding %d", ErrBadOption, enc)
+	}
+}
+
+// convertDuration encodes a time.Duration.
+func (c *Converter) convertDuration(d time.Duration, buffer []byte) ([]byte, error) {
+	enc := c.timeEncoding
+	if hint, ok := c.hint(); ok && hint == "ndauduration" {
+		c.decide(hint, "hint")
+		enc = TimeAsMicroseconds
+	}
+	switch enc {
+	case TimeAsMsgpExtension, TimeAsTimestampExtension:
+		return c.codec.AppendInt(buffer, int64(d)), nil
+	case TimeAsMicroseconds:
+		return c.codec.AppendInt(buffer, d.Microseconds()), nil
+	default:
+		return buffer, c.errorf("%w: time encoding %d", ErrBadOption, enc)
+	}
+}
+
+// TimestampExtensionType is the msgpack extension type of TimestampExtension.
+const TimestampExtensionType int8 = -1
+
+// TimestampExtension is the MessagePack timestamp extension.
+//
+// Its payload is the smallest of the three forms the MessagePack
+// specification defines: 4 bytes of seconds since the Unix epoch, 8 bytes of
+// nanoseconds and seconds packed together, or 12 bytes of nanoseconds and
+// signed seconds.  Times decode in UTC.  MessagePack reserves negative
+// extension types, so msgp refuses to register it; decode it from the
+// RawExtension msgp returns instead.
+type TimestampExtension time.Time
+
+var _ msgp.Extension = (*TimestampExtension)(nil)
+
+// ExtensionType implements msgp.Extension.
+func (t *TimestampExtension) ExtensionType() int8 {
+	return TimestampExtensionType
+}
+
+// Len implements msgp.Extension.
+func (t *TimestampExtension) Len() int {
+	sec, nsec := time.Time(*t).Unix(), time.Time(*t).Nanosecond()
+	switch {
+	case sec>>34 != 0:
+		return 12
+	case nsec != 0 || sec>>32 != 0:
+		return 8
+	}
+	return 4
+}
+
+// MarshalBinaryTo implements msgp.Extension.
+func (t *TimestampExtension) MarshalBinaryTo(b []byte) error {
+	sec, nsec := time.Time(*t).Unix(), time.Time(*t).Nanosecond()
+	switch t.Len() {
+	case 12:
+		binary.BigEndian.PutUint32(b, uint32(nsec))
+		binary.BigEndian.PutUint64(b[4:], uint64(sec))
+	case 8:
+		binary.BigEndian.PutUint64(b, uint64(nsec)<<34|uint64(sec))
+	default:
+		binary.BigEndian.PutUint32(b, uint32(sec))
+	}
+	return nil
+}
+
+// UnmarshalBinary implements msgp.Extension.
+//
+// It is an error for the payload not to be one of the three forms.
+func (t *TimestampExtension) UnmarshalBinary(b []byte) error {
+	var sec, nsec int64
+	switch len(b) {
+	case 4:
+		sec = int64(binary.BigEndian.Uint32(b))
+	case 8:
+		v := binary.BigEndian.Uint64(b)
+		sec, nsec = int64(v&(1<<34-1)), int64(v>>34)
+	case 12:
+		sec, nsec = int64(binary.BigEndian.Uint64(b[4:])), int64(binary.BigEndian.Uint32(b))
+	default:
+		return errors.New("timestamp extension must be 4, 8 or 12 bytes")
+	}
+	if nsec >= 1e9 {
+		return errors.New("timestamp extension nanoseconds out of range")
+	}
+	*t = TimestampExtension(time.Unix(sec, nsec).UTC())
+	return nil
+}
@@ -0,0 +1,93 @@
+package json2msgp_test
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ndau/json2msgp"
+	"github.com/stretchr/testify/require"
+	"github.com/tinylib/msgp/msgp"
+)
+
+func TestTimeEncoding(t *testing.T) {
+	when := json2msgp.NdauEpoch.Add(90 * time.Second)
+	tests := []struct {
+		name string
+		in   interface{}
+		opts []json2msgp.Option
+		want []byte
+	}{
+		{"msgp time", when, nil, msgp.AppendTime(nil, when)},
+		{"duration", 2 * time.Second, nil, msgp.AppendInt64(nil, 2e9)},
+		{"timestamp 32", time.Unix(1, 0),
+			[]json2msgp.Option{json2msgp.WithTimeEncoding(json2msgp.TimeAsTimestampExtension)},
+			[]byte{0xd6, 0xff, 0, 0, 0, 1}},
+		{"timestamp 64", time.Unix(1, 3),
+			[]json2msgp.Option{json2msgp.WithTimeEncoding(json2msgp.TimeAsTimestampExtension)},
+			[]byte{0xd7, 0xff, 0, 0, 0, 0x0c, 0, 0, 0, 1}},
+		{"timestamp 96", time.Unix(-1, 0),
+			[]json2msgp.Option{json2msgp.WithTimeEncoding(json2msgp.TimeAsTimestampExtension)},
+			[]byte{0xc7, 12, 0xff, 0, 0, 0, 0, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}},
+		{"microseconds", when,
+			[]json2msgp.Option{json2msgp.WithTimeEncoding(json2msgp.TimeAsMicroseconds)},
+			msgp.AppendInt64(nil, 90e6)},
+		{"duration microseconds", 2 * time.Second,
+			[]json2msgp.Option{json2msgp.WithTimeEncoding(json2msgp.TimeAsMicroseconds)},
+			msgp.AppendInt64(nil, 2e6)},
+		{"without reflection", 2 * time.Second,
+			[]json2msgp.Option{json2msgp.WithoutReflection()},
+			msgp.AppendInt64(nil, 2e9)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := json2msgp.ConvertWithOptions(tt.in, tt.opts...)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+
+	// the ndau hints pick microseconds whatever the encoding
+	got, err := json2msgp.Convert(map[string]interface{}{"At": when, "For": time.Second},
+		map[string][]string{"At": {"ndautimestamp"}, "For": {"ndauduration"}})
+	require.NoError(t, err)
+	want, err := json2msgp.Convert(map[string]interface{}{"At": 90e6, "For": 1e6}, nil)
+	require.NoError(t, err)
+	require.Equal(t, want, got)
+
+	_, err = json2msgp.ConvertWithOptions(time.Date(3000, 1, 1, 0, 0, 0, 0, time.UTC),
+		json2msgp.WithTimeEncoding(json2msgp.TimeAsMicroseconds))
+	require.True(t, errors.Is(err, json2msgp.ErrOverflow), "got %v", err)
+}
+
+func TestTimestampExtension(t *testing.T) {
+	for _, when := range []time.Time{
+		time.Unix(0, 0),
+		time.Unix(1<<32-1, 0),
+		time.Unix(1<<32, 0),
+		time.Unix(1<<34-1, 999999999),
+		time.Unix(1<<34, 1),
+		time.Unix(-1<<40, 500),
+	} {
+		ts := json2msgp.TimestampExtension(when)
+		b, err := msgp.AppendExtension(nil, &ts)
+		require.NoError(t, err)
+
+		var back json2msgp.TimestampExtension
+		_, err = msgp.ReadExtensionBytes(b, &back)
+		require.NoError(t, err)
+		require.True(t, when.Equal(time.Time(back)), "%s: got %s", when, time.Time(back))
+	}
+
+	var back json2msgp.TimestampExtension
+	require.Error(t, back.UnmarshalBinary([]byte{1, 2, 3}))
+}
@@ -0,0 +1,163 @@
+package json2msgp
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/tinylib/msgp/msgp"
+)
+
+// Hash returns a structural hash of the MSGP value b: a SHA-256 digest of
+// the value rather than of its encoding, so that two encodings of the same
+// value can be compared cheaply, or published in place of the value.
+//
+// As for Diff, map order and the width of integer encodings make no
+// difference: an integer hashes the same whether it is encoded as a fixint
+// or an int64, and whether signed or unsigned.  Types do: 1 and 1.0 hash
+// differently, as do a string and bin holding the same bytes.  Map keys may
+// be of any type.  Floats hash by their value as a float64.  Extensions hash
+// by their encoding.
+func Hash(b []byte) ([32]byte, error) {
+	sum, rest, err := hashValue(b, len(b), 0)
+	if err != nil {
+		return sum, err
+	}
+	if len(rest) > 0 {
+		return sum, fmt.Errorf("%w: %d bytes at offset %d", ErrTrailingMsgp, len(rest), len(b)-len(rest))
+	}
+	return sum, nil
+}
+
+// hashValue hashes the value at the start of b, which is the tail of a
+// document of size bytes, and returns what follows it.
+func hashValue(b []byte, size, depth int) ([32]byte, []byte, error) {
+	var sum [32]byte
+	if depth > DefaultMaxDepth {
+		return sum, b, fmt.Errorf("%w of %d at offset %d", ErrMaxDepth, DefaultMaxDepth, size-len(b))
+	}
+	h := sha256.New()
+	var tag [1]byte
+	var scratch [8]byte
+	rest := b
+	var err error
+	switch msgp.NextType(b) {
+	case msgp.MapType:
+		var n uint32
+		n, rest, err = msgp.ReadMapHeaderBytes(b)
+		if err != nil {
+			break
+		}
+		// each entry takes at least 2 bytes; check before allocating
+		if uint64(n)*2 > uint64(len(rest)) {
+			err = fmt.Errorf("map of %d entries in %d bytes", n, len(rest))
+			break
+		}
+		// entries are hashed on their own and sorted, so that their order
+		// makes no difference
+		entries := make([][]byte, 0, n)
+		for ; n > 0; n-- {
+			var key, value [32]byte
+			if key, rest, err = hashValue(rest, size, depth+1); err != nil {
+				return sum, rest, err
+			}
+			if value, rest, err = hashValue(rest, size, depth+1); err != nil {
+				return sum, rest, err
+			}
+			entry := sha256.Sum256(append(key[:], value[:]...))
+			entries = append(entries, entry[:])
+		}
+		sort.Slice(entries, func(i, j int) bool { return bytes.Compare(entries[i], entries[j]) < 0 })
+		tag[0] = 'm'
+		h.Write(tag[:])
+		h.Write(binary.BigEndian.AppendUint64(scratch[:0], uint64(len(entries))))
+		for _, entry := range entries {
+			h.Write(entry)
+		}
+	case msgp.ArrayType:
+		var n uint32
+		n, rest, err = msgp.ReadArrayHeaderBytes(b)
+		if err != nil {
+			break
+		}
+		tag[0] = 'a'
+		h.Write(tag[:])
+		h.Write(binary.BigEndian.AppendUint64(scratch[:0], uint64(n)))
+		for ; n > 0; n-- {
+			var elem [32]byte
+			if elem, rest, err = hashValue(rest, size, depth+1); err != nil {
+				return sum, rest, err
+			}
+			h.Write(elem[:])
+		}
+	case msgp.IntType:
+		var i int64
+		i, rest, err = msgp.ReadInt64Bytes(b)
+		if i >= 0 {
+			// the same as the unsigned integer
+			tag[0] = 'u'
+		} else {
+			tag[0] = 'i'
+		}
+		h.Write(tag[:])
+		h.Write(binary.BigEndian.AppendUint64(scratch[:0], uint64(i)))
+	case msgp.UintType:
+		var u uint64
+		u, rest, err = msgp.ReadUint64Bytes(b)
+		tag[0] = 'u'
+		h.Write(tag[:])
+		h.Write(binary.BigEndian.AppendUint64(scratch[:0], u))
+	case msgp.Float32Type, msgp.Float64Type:
+		var f float64
+		f, rest, err = msgp.ReadFloat64Bytes(b)
+		tag[0] = 'f'
+		h.Write(tag[:])
+		h.Write(binary.BigEndian.AppendUint64(scratch[:0], math.Float64bits(f)))
+	case msgp.StrType:
+		var s []byte
+		s, rest, err = msgp.ReadStringZC(b)
+		tag[0] = 's'
+		h.Write(tag[:])
+		h.Write(s)
+	case msgp.BinType:
+		var s []byte
+		s, rest, err = msgp.ReadBytesZC(b)
+		tag[0] = 'b'
+		h.Write(tag[:])
+		h.Write(s)
+	case msgp.BoolType:
+		var v bool
+		v, rest, err = msgp.ReadBoolBytes(b)
+		tag[0] = 'F'
+		if v {
+			tag[0] = 'T'
+		}
+		h.Write(tag[:])
+	case msgp.NilType:
+		rest, err = msgp.ReadNilBytes(b)
+		tag[0] = 'n'
+		h.Write(tag[:])
+	default:
+		rest, err = msgp.Skip(b)
+		tag[0] = 'x'
+		h.Write(tag[:])
+		h.Write(b[:len(b)-len(rest)])
+	}
+	if err != nil {
+		return sum, b, fmt.Errorf("%w at offset %d: %s", ErrInvalidMsgp, size-len(b), err)
+	}
+	h.Sum(sum[:0])
+	return sum, rest, nil
+}
@@ -0,0 +1,72 @@
+package json2msgp_test
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ndau/json2msgp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHash(t *testing.T) {
+	tests := []struct {
+		name  string
+		a     string
+		b     string
+		equal bool
+	}{
+		{"identical", "81 a1 61 01", "81 a1 61 01", true},
+		{"integer width ignored", "81 a1 61 01", "81 a1 61 d3 00 00 00 00 00 00 00 01", true},
+		{"signedness ignored", "cf 00 00 00 00 00 00 00 05", "d0 05", true},
+		{"negative integer width ignored", "ff", "d3 ff ff ff ff ff ff ff ff", true},
+		{"float width ignored", "ca 3f c0 00 00", "cb 3f f8 00 00 00 00 00 00", true},
+		{"string header width ignored", "a1 78", "d9 01 78", true},
+		{"map order ignored", "82 a1 61 01 a1 62 02", "82 a1 62 02 a1 61 01", true},
+		{"integer keys", "82 01 a1 61 02 a1 62", "82 02 a1 62 01 a1 61", true},
+		{"array order matters", "92 01 02", "92 02 01", false},
+		{"changed value", "81 a1 61 01", "81 a1 61 02", false},
+		{"keys and values are not swapped", "81 a1 61 a1 62", "81 a1 62 a1 61", false},
+		{"integer and float differ", "01", "cb 3f f0 00 00 00 00 00 00", false},
+		{"string and bytes differ", "a2 44 77", "c4 02 44 77", false},
+		{"nil and false differ", "c0", "c2", false},
+		{"nesting matters", "92 91 01 02", "92 01 91 02", false},
+		{"-1 and max uint64 differ", "ff", "cf ff ff ff ff ff ff ff ff", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := hex.DecodeString(strings.Replace(tt.a, " ", "", -1))
+			require.NoError(t, err)
+			b, err := hex.DecodeString(strings.Replace(tt.b, " ", "", -1))
+			require.NoError(t, err)
+
+			ha, err := json2msgp.Hash(a)
+			require.NoError(t, err)
+			hb, err := json2msgp.Hash(b)
+			require.NoError(t, err)
+			require.Equal(t, tt.equal, ha == hb)
+		})
+	}
+}
+
+func TestHashInvalid(t *testing.T) {
+	_, err := json2msgp.Hash([]byte{0x92, 0x01})
+	require.True(t, errors.Is(err, json2msgp.ErrInvalidMsgp))
+	_, err = json2msgp.Hash([]byte{0x01, 0x02})
+	require.True(t, errors.Is(err, json2msgp.ErrTrailingMsgp))
+	_, err = json2msgp.Hash(nil)
+	require.True(t, errors.Is(err, json2msgp.ErrInvalidMsgp))
+	// a header claiming more than the input holds fails before allocating
+	_, err = json2msgp.Hash([]byte{0xdf, 0xff, 0xff, 0xff, 0xff})
+	require.True(t, errors.Is(err, json2msgp.ErrInvalidMsgp), "got %v", err)
+}
@@ -0,0 +1,86 @@
+package json2msgp
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// ParseHexdump parses hex as pasted from a chain dump or runbook into bytes.
+// It accepts the output of `hexdump -ve '1/1 "%.2x "'`, with or without the
+// spaces, as well as `hexdump -C` output: the offset column, the ASCII
+// column between "|" characters and the final length are ignored.  Bytes may be prefixed by
+// "0x", and anything from "#" to the end of a line is a comment.
+func ParseHexdump(s string) ([]byte, error) {
+	var digits strings.Builder
+	canonical := false
+	for n, line := range strings.Split(s, "\n") {
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		if i := strings.IndexByte(line, '|'); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if isOffsetColumn(fields) {
+			canonical = true
+			fields = fields[1:]
+		} else if canonical && len(fields) == 1 {
+			// the final line of `hexdump -C` is the length
+			continue
+		}
+		for _, field := range fields {
+			field = strings.TrimPrefix(strings.TrimPrefix(field, "0x"), "0X")
+			if len(field)%2 != 0 {
+				return nil, fmt.Errorf("ParseHexdump line %d: odd number of digits in %q", n+1, field)
+			}
+			digits.WriteString(field)
+		}
+	}
+	b, err := hex.DecodeString(digits.String())
+	if err != nil {
+		return nil, fmt.Errorf("ParseHexdump: %w", err)
+	}
+	return b, nil
+}
+
+// isOffsetColumn is true if the first field looks like a `hexdump -C`
+// offset: a long number followed by single bytes.
+func isOffsetColumn(fields []string) bool {
+	if len(fields) < 2 || len(fields[0]) < 7 {
+		return false
+	}
+	for _, field := range fields[1:] {
+		if len(field) != 2 {
+			return false
+		}
+	}
+	return true
+}
+
+// FormatHexdump formats b as ParseHexdump accepts it and as the runbooks show
+// it: space-separated bytes, 16 per line.
+func FormatHexdump(b []byte) string {
+	var sb strings.Builder
+	for i, c := range b {
+		switch {
+		case i == 0:
+		case i%16 == 0:
+			sb.WriteByte('\n')
+		default:
+			sb.WriteByte(' ')
+		}
+		fmt.Fprintf(&sb, "%02x", c)
+	}
+	sb.WriteByte('\n')
+	return sb.String()
+}
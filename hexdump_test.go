@@ -0,0 +1,55 @@
+package json2msgp_test
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"testing"
+
+	"github.com/ndau/json2msgp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseHexdump(t *testing.T) {
+	want := []byte("\x81\xa3Fee\xcc\xc8")
+	tests := []struct {
+		name string
+		in   string
+	}{
+		{"spaced", "81 a3 46 65 65 cc c8"},
+		{"continuous", "81a3466565ccc8\n"},
+		{"0x", "0x81 0xa3 0x46 0x65 0x65 0xCC 0xC8"},
+		{"comments", "81          # map of 1\na3 46 65 65 # \"Fee\"\ncc c8       # 200\n"},
+		{"canonical", "00000000  81 a3 46 65 65 cc c8                              |..Fee..|\n00000007\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := json2msgp.ParseHexdump(tt.in)
+			require.NoError(t, err)
+			require.Equal(t, want, got)
+		})
+	}
+
+	for _, bad := range []string{"8", "81 a", "zz"} {
+		_, err := json2msgp.ParseHexdump(bad)
+		require.Error(t, err, bad)
+	}
+}
+
+func TestFormatHexdump(t *testing.T) {
+	b := make([]byte, 17)
+	for i := range b {
+		b[i] = byte(i)
+	}
+	s := json2msgp.FormatHexdump(b)
+	require.Equal(t, "00 01 02 03 04 05 06 07 08 09 0a 0b 0c 0d 0e 0f\n10\n", s)
+	back, err := json2msgp.ParseHexdump(s)
+	require.NoError(t, err)
+	require.Equal(t, b, back)
+}
@@ -0,0 +1,152 @@
+package json2msgp
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// pathHint is a type hint key which is a path from the root of the
+// document, rather than a key name.
+type pathHint struct {
+	// the key of the hint in the type hints
+	key string
+	// the JSONPath segments each step of the path matches, as in
+	// Converter.path; nil matches any
+	steps     [][]string
+	wildcards int
+}
+
+// isPathHint is true for a type hint key which is a path: a JSON Pointer,
+// such as "/LockedRateTable/*/1", or dotted, such as "EAIFeeTable.*.Fee".
+func isPathHint(key string) bool {
+	return strings.HasPrefix(key, "/") || strings.Contains(key, ".")
+}
+
+// compilePathHints returns the path hints among the keys of typeHints, most
+// specific first: the longest, then those with fewer wildcards, then in
+// lexical order.
+func compilePathHints[V any](typeHints map[string]V) []pathHint {
+	var hints []pathHint
+	for key := range typeHints {
+		if !isPathHint(key) {
+			continue
+		}
+		names, _ := parsePointer(key)
+		if !strings.HasPrefix(key, "/") {
+			names = strings.Split(strings.TrimPrefix(key, "$."), ".")
+		}
+
+		hint := pathHint{key: key, steps: make([][]string, len(names))}
+		for i, name := range names {
+			if name == "*" {
+				hint.wildcards++
+				continue
+			}
+			hint.steps[i] = []string{keySegment(name)}
+			if index, err := strconv.Atoi(name); err == nil && index >= 0 {
+				hint.steps[i] = append(hint.steps[i], indexSegment(index))
+			}
+		}
+		hints = append(hints, hint)
+	}
+	sort.Slice(hints, func(i, j int) bool {
+		if len(hints[i].steps) != len(hints[j].steps) {
+			return len(hints[i].steps) > len(hints[j].steps)
+		}
+		if hints[i].wildcards != hints[j].wildcards {
+			return hints[i].wildcards < hints[j].wildcards
+		}
+		return hints[i].key < hints[j].key
+	})
+	return hints
+}
+
+// matches is true if the path hint selects the value at path, or an array
+// path is nested in, in which case it also returns the index segments of
+// path within that array.
+func (h *pathHint) matches(path []string) ([]string, bool) {
+	if len(path) < len(h.steps) {
+		return nil, false
+	}
+	rest := path[len(h.steps):]
+	for _, segment := range rest {
+		if !isIndexSegment(segment) {
+			return nil, false
+		}
+	}
+	for i, step := range h.steps {
+		if step == nil {
+			continue
+		}
+		if path[i] != step[0] && (len(step) == 1 || path[i] != step[1]) {
+			return nil, false
+		}
+	}
+	return rest, true
+}
+
+// findPathHint returns the key of the most specific of pathHints which
+// selects the value at path, and the position of the value within the array
+// it selects, if any.  A hint which selects the value itself indexes its
+// hint list as a key name does.
+func findPathHint(pathHints []pathHint, path []string) (string, []string, bool) {
+	for i := range pathHints {
+		if rest, ok := pathHints[i].matches(path); ok {
+			if len(rest) == 0 {
+				rest = hintPosition(path)
+			}
+			return pathHints[i].key, rest, true
+		}
+	}
+	return "", nil, false
+}
+
+// nearestKey returns the key of the innermost map the value at path is in,
+// or "" at the root.
+func nearestKey(path []string) string {
+	for i := len(path) - 1; i >= 0; i-- {
+		segment := path[i]
+		switch {
+		case isIndexSegment(segment):
+			continue
+		case strings.HasPrefix(segment, "."):
+			return segment[1:]
+		}
+		key, _ := strconv.Unquote(segment[1 : len(segment)-1])
+		return key
+	}
+	return ""
+}
+
+// lookupHint returns the type hint which applies to the value at path,
+// whose nearest key is key, if any.
+func lookupHint(typeHints map[string][]string, pathHints []pathHint, nestedHints map[string][]nestedHint,
+	key string, path []string) (string, bool) {
+	hintKey, position, ok := findPathHint(pathHints, path)
+	if !ok {
+		hintKey, position = key, hintPosition(path)
+	}
+	if nested, ok := nestedHints[hintKey]; ok {
+		return resolveNestedHint(nested, position), true
+	}
+	typeHint := typeHints[hintKey]
+	if len(typeHint) == 0 {
+		return "", false
+	}
+	// a flat list is indexed by the innermost array
+	i := 0
+	if len(position) > 0 {
+		i = segmentIndex(position[len(position)-1])
+	}
+	return typeHint[i%len(typeHint)], true
+}
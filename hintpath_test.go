@@ -0,0 +1,69 @@
+package json2msgp_test
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"bytes"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/ndau/json2msgp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPathHints(t *testing.T) {
+	tests := []struct {
+		name  string
+		in    string
+		hints map[string][]string
+		want  string
+	}{
+		{
+			"dotted path beats key name",
+			`{"EAIFeeTable":[{"Fee":200}],"Other":{"Fee":-200}}`,
+			map[string][]string{"EAIFeeTable.*.Fee": {"uint64"}, "Fee": {"int64"}},
+			"82 ab 45 41 49 46 65 65 54 61 62 6c 65 91 81 a3 46 65 65 cc c8 a5 4f 74 68 65 72 81 a3 46 65 65 d1 ff 38",
+		},
+		{
+			"JSON Pointer with index",
+			`{"LockedRateTable":[[200,200]]}`,
+			map[string][]string{"/LockedRateTable/*/1": {"uint64"}},
+			"81 af 4c 6f 63 6b 65 64 52 61 74 65 54 61 62 6c 65 91 92 d1 00 c8 cc c8",
+		},
+		{
+			"fewest wildcards wins",
+			`{"a":{"b":200,"c":200}}`,
+			map[string][]string{"a.*": {"int64"}, "$.a.b": {"uint64"}},
+			"81 a1 61 82 a1 62 cc c8 a1 63 d1 00 c8",
+		},
+		{
+			"dotted key name",
+			`{"x.y":200,"x":{"y":200}}`,
+			map[string][]string{"x.y": {"uint64"}},
+			"82 a1 78 81 a1 79 cc c8 a3 78 2e 79 cc c8",
+		},
+		{
+			"escaped pointer",
+			`{"a/b":{"~":200}}`,
+			map[string][]string{"/a~1b/~0": {"uint64"}},
+			"81 a3 61 2f 62 81 a1 7e cc c8",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want, err := hex.DecodeString(strings.ReplaceAll(tt.want, " ", ""))
+			require.NoError(t, err)
+			got := &bytes.Buffer{}
+			require.NoError(t, json2msgp.ConvertStream(strings.NewReader(tt.in), got, tt.hints))
+			require.Equal(t, want, got.Bytes())
+		})
+	}
+}
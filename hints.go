@@ -0,0 +1,221 @@
+package json2msgp
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/tinylib/msgp/msgp"
+)
+
+// ReadHints reads type hints from r, as a JSON object mapping each key to its
+// list of hints, such as {"Fee": ["uint64"]}.  The lists may be nested for
+// nested arrays, such as {"": [["int64","uint64"],["float64"]]}; each inner
+// list is returned as its JSON text, as ConvertStream expects.  r is read to
+// EOF, and must contain nothing else.
+func ReadHints(r io.Reader) (map[string][]string, error) {
+	var raw map[string][]json.RawMessage
+	d := json.NewDecoder(r)
+	if err := d.Decode(&raw); err != nil {
+		return nil, errors.Wrap(err, "ReadHints")
+	}
+	if _, err := d.Token(); err != io.EOF {
+		return nil, fmt.Errorf("ReadHints: %w", ErrTrailingData)
+	}
+	if raw == nil {
+		return nil, nil
+	}
+	hints := make(map[string][]string, len(raw))
+	for key, list := range raw {
+		hints[key] = make([]string, len(list))
+		for i, elem := range list {
+			hint, err := readHint(elem)
+			if err != nil {
+				return nil, fmt.Errorf("ReadHints: %w: %q[%d]: %s", ErrBadHint, key, i, err)
+			}
+			hints[key][i] = hint
+		}
+	}
+	return hints, nil
+}
+
+// readHint returns the hint elem, a string or a nested list of strings.
+func readHint(elem json.RawMessage) (string, error) {
+	var hint string
+	if err := json.Unmarshal(elem, &hint); err == nil {
+		return hint, nil
+	}
+	var list []interface{}
+	if err := json.Unmarshal(elem, &list); err != nil {
+		return "", fmt.Errorf("want a string or a list, got %s", elem)
+	}
+	if _, ok := nestedHintList(list); !ok {
+		return "", fmt.Errorf("want a non-empty list of strings and lists, got %s", elem)
+	}
+	out, err := json.Marshal(list)
+	return string(out), err
+}
+
+// HintsEnv is the environment variable HintsFromEnv reads by default.
+const HintsEnv = "JSON2MSGP_HINTS"
+
+// HintsFromEnv reads type hints, as for ReadHints, from the environment
+// variable name, or HintsEnv if name is empty.  It returns nil hints and no
+// error if the variable is unset or empty.
+func HintsFromEnv(name string) (map[string][]string, error) {
+	if name == "" {
+		name = HintsEnv
+	}
+	value := os.Getenv(name)
+	if value == "" {
+		return nil, nil
+	}
+	hints, err := ReadHints(strings.NewReader(value))
+	if err != nil {
+		return nil, errors.Wrapf(err, "HintsFromEnv %s", name)
+	}
+	return hints, nil
+}
+
+// InferHints derives type hints from an existing MSGP encoding of a value,
+// such as a system variable's current value on chain.  Converting the JSON
+// form of the value with the inferred hints reproduces its numeric types.
+//
+// msgp writes integers in their most compact form, so only the signedness of
+// an integer is visible, and only if it doesn't fit in a positive fixint.
+// Integers are therefore hinted as "int64" or "uint64", and values which
+// don't reveal their type contribute nothing.
+func InferHints(b []byte) (map[string][]string, error) {
+	h := hintInferrer{seen: make(map[string]map[int]string)}
+	rest, err := h.walk(b)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) > 0 {
+		return nil, fmt.Errorf("InferHints: %d bytes of trailing data", len(rest))
+	}
+	return h.hints(), nil
+}
+
+// hintInferrer walks an MSGP value tracking the current key and hint index
+// exactly as the Converter does, recording the numeric types it sees.
+type hintInferrer struct {
+	currentKey  string
+	currentHint int
+	// key -> hint index -> type
+	seen map[string]map[int]string
+}
+
+func (h *hintInferrer) walk(b []byte) ([]byte, error) {
+	var typ string
+	switch msgp.NextType(b) {
+	case msgp.MapType:
+		sz, b, err := msgp.ReadMapHeaderBytes(b)
+		if err != nil {
+			return b, err
+		}
+		for i := uint32(0); i < sz; i++ {
+			h.currentKey, b, err = msgp.ReadStringBytes(b)
+			if err != nil {
+				return b, err
+			}
+			if b, err = h.walk(b); err != nil {
+				return b, err
+			}
+		}
+		return b, nil
+	case msgp.ArrayType:
+		sz, b, err := msgp.ReadArrayHeaderBytes(b)
+		if err != nil {
+			return b, err
+		}
+		outer := h.currentHint
+		for i := uint32(0); i < sz; i++ {
+			h.currentHint = int(i)
+			if b, err = h.walk(b); err != nil {
+				return b, err
+			}
+		}
+		h.currentHint = outer
+		return b, nil
+	case msgp.IntType:
+		// positive fixints are also how msgp writes small unsigned values, so
+		// only the int8-64 and negative fixint encodings reveal a signed type
+		if b[0] >= 0xd0 {
+			typ = "int64"
+		}
+	case msgp.UintType:
+		typ = "uint64"
+	case msgp.Float32Type:
+		typ = "float32"
+	case msgp.Float64Type:
+		typ = "float64"
+	case msgp.InvalidType:
+		return b, fmt.Errorf("InferHints: invalid msgp")
+	}
+
+	if typ != "" {
+		if err := h.record(typ); err != nil {
+			return b, err
+		}
+	}
+	return msgp.Skip(b)
+}
+
+func (h *hintInferrer) record(typ string) error {
+	positions, ok := h.seen[h.currentKey]
+	if !ok {
+		positions = make(map[int]string)
+		h.seen[h.currentKey] = positions
+	}
+	if prev, ok := positions[h.currentHint]; ok && prev != typ {
+		return fmt.Errorf("InferHints: %q[%d] is both %s and %s", h.currentKey, h.currentHint, prev, typ)
+	}
+	positions[h.currentHint] = typ
+	return nil
+}
+
+// hints builds the hint map.  A key whose values all have the same type gets a
+// single hint; otherwise its hints are positional, with unobserved positions
+// falling back to the "int64" the heuristic would choose anyway.
+func (h *hintInferrer) hints() map[string][]string {
+	out := make(map[string][]string)
+	for key, positions := range h.seen {
+		types := make(map[string]bool)
+		last := 0
+		for pos, typ := range positions {
+			types[typ] = true
+			if pos > last {
+				last = pos
+			}
+		}
+		if len(types) == 1 {
+			for typ := range types {
+				out[key] = []string{typ}
+			}
+			continue
+		}
+		hint := make([]string, last+1)
+		for pos := range hint {
+			if typ, ok := positions[pos]; ok {
+				hint[pos] = typ
+			} else {
+				hint[pos] = "int64"
+			}
+		}
+		out[key] = hint
+	}
+	return out
+}
@@ -0,0 +1,109 @@
+package json2msgp_test
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"bytes"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/ndau/json2msgp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInferHints(t *testing.T) {
+	tests := []struct {
+		name string
+		in   interface{}
+		hint map[string][]string
+		want map[string][]string
+	}{
+		{
+			"LockedRateTable",
+			[]interface{}{[]interface{}{7776000000000.0, 10000000000.0}, []interface{}{15552000000000.0, 20000000000.0}},
+			map[string][]string{"": []string{"int64", "uint64"}},
+			map[string][]string{"": []string{"int64", "uint64"}},
+		},
+		{
+			"uniform",
+			map[string]interface{}{"ChangeOn": 1000.0, "Fee": -1.0, "Rate": 0.5},
+			map[string][]string{"ChangeOn": []string{"uint64"}, "Rate": []string{"float64"}},
+			map[string][]string{"ChangeOn": []string{"uint64"}, "Fee": []string{"int64"}, "Rate": []string{"float64"}},
+		},
+		{
+			"small values reveal nothing",
+			map[string]interface{}{"ChangeOn": 0.0},
+			map[string][]string{"ChangeOn": []string{"uint64"}},
+			map[string][]string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := json2msgp.Convert(tt.in, tt.hint)
+			require.NoError(t, err)
+			got, err := json2msgp.InferHints(b)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestInferHintsConflict(t *testing.T) {
+	// {"a": int16(-200), "b": {"a": uint8(200)}}
+	b, err := hex.DecodeString(strings.Replace("82 a1 61 d1 ff 38 a1 62 81 a1 61 cc c8", " ", "", -1))
+	require.NoError(t, err)
+	_, err = json2msgp.InferHints(b)
+	require.Error(t, err)
+}
+
+func TestReadHints(t *testing.T) {
+	hints, err := json2msgp.ReadHints(strings.NewReader(`{"Fee": ["uint64"], "": ["int64", "uint64"]}` + "\n"))
+	require.NoError(t, err)
+	require.Equal(t, map[string][]string{"Fee": []string{"uint64"}, "": []string{"int64", "uint64"}}, hints)
+
+	hints, err = json2msgp.ReadHints(strings.NewReader(`{"": [["int64", ["uint64"]], "float64"]}`))
+	require.NoError(t, err)
+	require.Equal(t, map[string][]string{"": []string{`["int64",["uint64"]]`, "float64"}}, hints)
+
+	for _, bad := range []string{``, `{"Fee": "uint64"}`, `{} {}`, `{"": [[]]}`, `{"": [["int64", 1]]}`} {
+		_, err = json2msgp.ReadHints(strings.NewReader(bad))
+		require.Error(t, err, bad)
+	}
+}
+
+func TestConvertStreamWithHintReader(t *testing.T) {
+	var out bytes.Buffer
+	err := json2msgp.ConvertStreamWithHintReader(strings.NewReader(`{"Fee": 200}`), &out,
+		strings.NewReader(`{"Fee": ["uint16"]}`))
+	require.NoError(t, err)
+	require.Equal(t, []byte("\x81\xa3Fee\xcc\xc8"), out.Bytes())
+
+	err = json2msgp.ConvertStreamWithHintReader(strings.NewReader(`{"Fee": 200}`), &out,
+		strings.NewReader(`[]`))
+	require.Error(t, err)
+}
+
+func TestHintsFromEnv(t *testing.T) {
+	t.Setenv(json2msgp.HintsEnv, `{"Fee": ["uint64"]}`)
+	hints, err := json2msgp.HintsFromEnv("")
+	require.NoError(t, err)
+	require.Equal(t, map[string][]string{"Fee": []string{"uint64"}}, hints)
+
+	t.Setenv("MY_HINTS", "")
+	hints, err = json2msgp.HintsFromEnv("MY_HINTS")
+	require.NoError(t, err)
+	require.Nil(t, hints)
+
+	t.Setenv("MY_HINTS", `{"Fee": "uint64"}`)
+	_, err = json2msgp.HintsFromEnv("MY_HINTS")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "HintsFromEnv MY_HINTS")
+}
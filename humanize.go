@@ -0,0 +1,122 @@
+package json2msgp
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Unit is the unit of a numeric field, for rendering it readably.
+type Unit int
+
+const (
+	// UnitNapu is an amount of napu, rendered in ndau.
+	UnitNapu Unit = iota + 1
+	// UnitDuration is a number of microseconds, rendered as by FormatNdauDuration.
+	UnitDuration
+	// UnitTimestamp is a number of microseconds since NdauEpoch, rendered as by
+	// FormatNdauTimestamp.
+	UnitTimestamp
+)
+
+// NapuPerNdau is the number of napu in one ndau.
+const NapuPerNdau = 100000000
+
+// SysvarUnits are the units of the numeric fields of known system variables.
+var SysvarUnits = map[string]Unit{
+	"DefaultRecourseDuration": UnitDuration,
+	"Fee":                     UnitNapu,
+	"MinDurationBetweenNodeRewardNominations": UnitDuration,
+	"MinNodeRegistrationStakeAmount":          UnitNapu,
+	"NodeRewardNominationTimeout":             UnitDuration,
+}
+
+// FormatNapu formats an amount of napu in ndau, e.g. "1.5 ndau".
+func FormatNapu(napu int64) string {
+	var sb strings.Builder
+	whole, frac := napu/NapuPerNdau, napu%NapuPerNdau
+	if napu < 0 {
+		sb.WriteByte('-')
+		whole, frac = -whole, -frac
+	}
+	sb.WriteString(strconv.FormatInt(whole, 10))
+	if frac != 0 {
+		digits := strconv.FormatInt(frac+NapuPerNdau, 10)[1:]
+		sb.WriteByte('.')
+		sb.WriteString(strings.TrimRight(digits, "0"))
+	}
+	sb.WriteString(" ndau")
+	return sb.String()
+}
+
+// Format renders n in unit u.
+func (u Unit) Format(n int64) string {
+	switch u {
+	case UnitNapu:
+		return FormatNapu(n)
+	case UnitDuration:
+		return FormatNdauDuration(n)
+	case UnitTimestamp:
+		return FormatNdauTimestamp(n)
+	}
+	return strconv.FormatInt(n, 10)
+}
+
+// Humanize returns a copy of v, a value decoded from MSGP as by
+// msgp.ReadIntfBytes, in which the integers of keys listed in units are
+// replaced by an object preserving the raw value alongside a readable one:
+//
+//	"Fee": {"Raw": 150000000, "Text": "1.5 ndau"}
+//
+// As with type hints, elements of an array take the key of the array.
+//
+// This makes decoded system variables reviewable by non-engineers.  The result
+// is for reading only; it no longer converts back to the original encoding.
+// To render MSGP this way directly, and name the root value, use
+// ConvertStreamToJSON with WithUnits and WithRootKey.
+func Humanize(v interface{}, units map[string]Unit) interface{} {
+	return humanize(v, "", units)
+}
+
+func humanize(v interface{}, key string, units map[string]Unit) interface{} {
+	switch x := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(x))
+		for k, value := range x {
+			out[k] = humanize(value, k, units)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(x))
+		for i, value := range x {
+			out[i] = humanize(value, key, units)
+		}
+		return out
+	}
+
+	unit, ok := units[key]
+	if !ok {
+		return v
+	}
+	var n int64
+	switch x := v.(type) {
+	case int64:
+		n = x
+	case uint64:
+		if x > 1<<63-1 {
+			return v
+		}
+		n = int64(x)
+	default:
+		return v
+	}
+	return map[string]interface{}{"Raw": v, "Text": unit.Format(n)}
+}
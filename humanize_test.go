@@ -0,0 +1,63 @@
+package json2msgp_test
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"testing"
+
+	"github.com/ndau/json2msgp"
+	"github.com/stretchr/testify/require"
+	"github.com/tinylib/msgp/msgp"
+)
+
+func TestFormatNapu(t *testing.T) {
+	tests := []struct {
+		in   int64
+		want string
+	}{
+		{0, "0 ndau"},
+		{100000000, "1 ndau"},
+		{150000000, "1.5 ndau"},
+		{9800000, "0.098 ndau"},
+		{1, "0.00000001 ndau"},
+		{-250000000, "-2.5 ndau"},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.want, json2msgp.FormatNapu(tt.in))
+	}
+}
+
+func TestHumanize(t *testing.T) {
+	b, err := json2msgp.Convert(
+		map[string]interface{}{
+			"EAIFeeTable": []interface{}{
+				map[string]interface{}{"Fee": 9800000.0, "To": nil},
+			},
+			"DefaultRecourseDuration": 172800000000.0,
+			"Other":                   5.0,
+		},
+		map[string][]string{"Fee": []string{"uint64"}},
+	)
+	require.NoError(t, err)
+	v, _, err := msgp.ReadIntfBytes(b)
+	require.NoError(t, err)
+
+	got := json2msgp.Humanize(v, json2msgp.SysvarUnits)
+	require.Equal(t, map[string]interface{}{
+		"EAIFeeTable": []interface{}{
+			map[string]interface{}{
+				"Fee": map[string]interface{}{"Raw": uint64(9800000), "Text": "0.098 ndau"},
+				"To":  nil,
+			},
+		},
+		"DefaultRecourseDuration": map[string]interface{}{"Raw": int64(172800000000), "Text": "2d"},
+		"Other":                   int64(5),
+	}, got)
+}
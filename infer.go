@@ -0,0 +1,60 @@
+package json2msgp
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import "fmt"
+
+// WithInferredHints converts each document in two passes.  The first scans
+// the whole document for the numbers of each key without a type hint, and
+// picks one numeric type which fits them all; the second encodes the
+// document as if that type had been given as the key's hint.  Every value
+// of a key is so encoded the same way, which the heuristic alone does not
+// promise: it encodes integers as int64 and fails on fractions.
+//
+// The type picked for a key is "float64" if any of its numbers is a
+// fraction, else "int64" if any is negative, else "uint64".  A key with both
+// negative numbers and numbers too large for an int64 fails with
+// ErrMissingHint.  Keys with strings as well as numbers are left to the
+// heuristic, as are keys which have type hints.  As with hints, array
+// elements count towards the key holding the array.
+func WithInferredHints() Option {
+	return func(c *Converter) {
+		c.inferHints = true
+	}
+}
+
+// inferredHints returns the type hints of the converter, plus a hint for each
+// unhinted key of in holding only numbers.
+func (c *Converter) inferredHints(in interface{}) (map[string][]string, error) {
+	stats := make(map[string]*keyStats)
+	collectStats(stats, "", in)
+
+	hints := make(map[string][]string, len(c.typeHints)+len(stats))
+	for key, hint := range c.typeHints {
+		hints[key] = hint
+	}
+	for _, key := range sortedKeys(stats) {
+		s := stats[key]
+		if _, ok := hints[key]; ok || s.numbers == 0 || s.strings > 0 {
+			continue
+		}
+		switch {
+		case s.fractions > 0:
+			hints[key] = []string{"float64"}
+		case s.negatives > 0 && s.huge > 0:
+			return nil, fmt.Errorf("%w for %q: both negative numbers and numbers too large for an int64", ErrMissingHint, key)
+		case s.negatives > 0:
+			hints[key] = []string{"int64"}
+		default:
+			hints[key] = []string{"uint64"}
+		}
+	}
+	return hints, nil
+}
@@ -0,0 +1,70 @@
+package json2msgp_test
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ndau/json2msgp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithInferredHints(t *testing.T) {
+	tests := []struct {
+		name  string
+		in    string
+		hints map[string][]string
+		want  map[string][]string
+	}{
+		{"negative", `{"EAIFeeTable":[{"Fee":200},{"Fee":-1}]}`, nil, map[string][]string{"Fee": {"int64"}}},
+		{"non-negative", `{"EAIFeeTable":[{"Fee":200},{"Fee":300}]}`, nil, map[string][]string{"Fee": {"uint64"}}},
+		{"fraction", `{"Rate":[1,1.5,-2]}`, nil, map[string][]string{"Rate": {"float64"}}},
+		{"huge", `[1,1e19]`, nil, map[string][]string{"": {"uint64"}}},
+		{"hinted", `{"Fee":200}`, map[string][]string{"Fee": {"uint8"}}, map[string][]string{"Fee": {"uint8"}}},
+		{"strings", `{"a":[200,"x"]}`, nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var in interface{}
+			require.NoError(t, json.Unmarshal([]byte(tt.in), &in))
+			got, err := json2msgp.ConvertWithOptions(in,
+				json2msgp.WithTypeHints(tt.hints), json2msgp.WithInferredHints())
+			require.NoError(t, err)
+			want, err := json2msgp.ConvertWithOptions(in, json2msgp.WithTypeHints(tt.want))
+			require.NoError(t, err)
+			require.Equal(t, want, got)
+		})
+	}
+
+	_, err := json2msgp.ConvertWithOptions([]interface{}{-1.0, 1e19}, json2msgp.WithInferredHints())
+	require.True(t, errors.Is(err, json2msgp.ErrMissingHint), "got %v", err)
+}
+
+func TestWithInferredHintsPerDocument(t *testing.T) {
+	hints := map[string][]string{"b": {"int8"}}
+	got := &bytes.Buffer{}
+	err := json2msgp.ConvertStreamWithOptions(strings.NewReader(`{"a":-200} {"a":200}`), got,
+		json2msgp.WithTypeHints(hints), json2msgp.WithInferredHints(), json2msgp.WithMultipleDocuments())
+	require.NoError(t, err)
+
+	// each document gets its own hints, and the caller's are left alone
+	first, err := json2msgp.ConvertWithOptions(map[string]interface{}{"a": -200.0},
+		json2msgp.WithTypeHints(map[string][]string{"a": {"int64"}}))
+	require.NoError(t, err)
+	second, err := json2msgp.ConvertWithOptions(map[string]interface{}{"a": 200.0},
+		json2msgp.WithTypeHints(map[string][]string{"a": {"uint64"}}))
+	require.NoError(t, err)
+	require.Equal(t, append(first, second...), got.Bytes())
+	require.Equal(t, map[string][]string{"b": {"int8"}}, hints)
+}
@@ -0,0 +1,152 @@
+package json2msgp
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"fmt"
+
+	"github.com/tinylib/msgp/msgp"
+)
+
+// InspectEntry describes one encoded value of a MSGP blob: a map or array
+// header, a map key, or any other value.
+type InspectEntry struct {
+	// Offset of the value in the blob.
+	Offset int
+	// Type is the first byte of the value, and Kind the type it encodes.
+	Type byte
+	Kind string
+	// Length is the number of entries of a map, the number of elements of an
+	// array, or the number of bytes of a string, bin or extension.  It is 0
+	// for other values.
+	Length int
+	// Size is the number of bytes of the value, not counting the entries
+	// or elements of a map or array.
+	Size int
+	// Path is the JSONPath of the value, e.g. "$.EAIFeeTable[4].Fee".  For a
+	// map key, it is the path of the value it names.
+	Path string
+	// Key is true for a map key.
+	Key bool
+	// Value is the value as rendered by NormalizedJSON, or empty for a map
+	// or array.
+	Value string
+}
+
+// String returns the entry as a single line: its offset in hex, type byte,
+// kind, length, path and value.
+func (e InspectEntry) String() string {
+	path := e.Path
+	if e.Key {
+		path += " (key)"
+	}
+	s := fmt.Sprintf("%06x  %02x  %-9s %5d  %s", e.Offset, e.Type, e.Kind, e.Length, path)
+	if e.Value != "" {
+		s += "  " + e.Value
+	}
+	return s
+}
+
+// Inspect breaks the MSGP blob b down into its values, in order, which is
+// what to reach for when a decode of chain data fails.  If b holds several
+// values, as a stream does, each is inspected in turn, with the path "$".
+//
+// If b is malformed, Inspect returns the entries up to the fault, and an
+// ErrInvalidMsgp error giving its offset.
+func Inspect(b []byte) ([]InspectEntry, error) {
+	i := inspector{size: len(b)}
+	var err error
+	for len(b) > 0 && err == nil {
+		b, err = i.value(b, "$", false, 0)
+	}
+	return i.entries, err
+}
+
+// inspector collects the entries of a blob of size bytes.
+type inspector struct {
+	size    int
+	entries []InspectEntry
+}
+
+// value inspects the value at the start of b, and returns what follows it.
+func (i *inspector) value(b []byte, path string, key bool, depth int) ([]byte, error) {
+	offset := i.size - len(b)
+	if depth > DefaultMaxDepth {
+		return b, fmt.Errorf("%w of %d at offset %d", ErrMaxDepth, DefaultMaxDepth, offset)
+	}
+	t := msgp.NextType(b)
+	entry := InspectEntry{Offset: offset, Kind: t.String(), Path: path, Key: key}
+	if len(b) > 0 {
+		entry.Type = b[0]
+	}
+
+	var (
+		n    uint32
+		rest []byte
+		err  error
+	)
+	switch t {
+	case msgp.MapType:
+		n, rest, err = msgp.ReadMapHeaderBytes(b)
+	case msgp.ArrayType:
+		n, rest, err = msgp.ReadArrayHeaderBytes(b)
+	default:
+		rest, err = msgp.Skip(b)
+	}
+	if err != nil {
+		return b, fmt.Errorf("%w at offset %d: %s", ErrInvalidMsgp, offset, err)
+	}
+	raw := b[:len(b)-len(rest)]
+	entry.Size = len(raw)
+
+	switch t {
+	case msgp.MapType, msgp.ArrayType:
+		entry.Length = int(n)
+	case msgp.StrType:
+		s, _, _ := msgp.ReadStringZC(raw)
+		entry.Length = len(s)
+	case msgp.BinType:
+		s, _, _ := msgp.ReadBytesZC(raw)
+		entry.Length = len(s)
+	case msgp.ExtensionType:
+		_, data := splitExtension(raw)
+		entry.Length = len(data)
+	}
+	if t != msgp.MapType && t != msgp.ArrayType {
+		value, _, err := appendNormalized(nil, raw, len(raw), 0)
+		if err != nil {
+			return b, fmt.Errorf("%w at offset %d: %s", ErrInvalidMsgp, offset, err)
+		}
+		entry.Value = string(value)
+	}
+	i.entries = append(i.entries, entry)
+
+	for j := 0; j < int(n); j++ {
+		if t == msgp.ArrayType {
+			rest, err = i.value(rest, path+indexSegment(j), false, depth+1)
+		} else {
+			keyAt := len(i.entries)
+			name, _, serr := msgp.ReadStringBytes(rest)
+			rest, err = i.value(rest, path, true, depth+1)
+			if err == nil {
+				if serr != nil {
+					// not a string: name it as it renders
+					name = i.entries[keyAt].Value
+				}
+				i.entries[keyAt].Path = path + keySegment(name)
+				rest, err = i.value(rest, i.entries[keyAt].Path, false, depth+1)
+			}
+		}
+		if err != nil {
+			return rest, err
+		}
+	}
+	return rest, nil
+}
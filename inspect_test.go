@@ -0,0 +1,57 @@
+package json2msgp_test
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ndau/json2msgp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInspect(t *testing.T) {
+	b, err := json2msgp.ParseHexdump(`
+		82                # map of 2
+		a3 46 65 65       # "Fee"
+		cc c8             # 200
+		a5 4e 6f 64 65 73 # "Nodes"
+		92 c0 c4 01 ff    # [nil, bin]
+		01                # a second value
+	`)
+	require.NoError(t, err)
+	entries, err := json2msgp.Inspect(b)
+	require.NoError(t, err)
+	var got []string
+	for _, e := range entries {
+		got = append(got, e.String())
+	}
+	require.Equal(t, []string{
+		`000000  82  map           2  $`,
+		`000001  a3  str           3  $.Fee (key)  "Fee"`,
+		`000005  cc  uint          0  $.Fee  200`,
+		`000007  a5  str           5  $.Nodes (key)  "Nodes"`,
+		`00000d  92  array         2  $.Nodes`,
+		`00000e  c0  nil           0  $.Nodes[0]  null`,
+		`00000f  c4  bin           1  $.Nodes[1]  {"$bin":"/w=="}`,
+		`000012  01  int           0  $  1`,
+	}, got)
+	require.Equal(t, 2, entries[2].Size)
+}
+
+func TestInspectTruncated(t *testing.T) {
+	b, err := json2msgp.ParseHexdump("92 01 a3 46 65")
+	require.NoError(t, err)
+	entries, err := json2msgp.Inspect(b)
+	require.True(t, errors.Is(err, json2msgp.ErrInvalidMsgp))
+	require.Contains(t, err.Error(), "offset 2")
+	require.Len(t, entries, 2)
+	require.Equal(t, "$[0]", entries[1].Path)
+}
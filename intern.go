@@ -0,0 +1,87 @@
+package json2msgp
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import "unicode/utf8"
+
+// maxInternedKeys is how many distinct map keys a Converter interns.  Keys
+// past the limit are prepared afresh each time, so that a long stream of
+// documents with ever-new keys does not grow without bound.
+const maxInternedKeys = 4096
+
+// internedKey is what a Converter prepares once for a map key it sees over
+// and over, as in the svi document, where "ChangeOn", "Current" and
+// "Future" repeat dozens of times.
+type internedKey struct {
+	// segment is the key's JSONPath segment.
+	segment string
+	// encoded is the key as the codec writes it, or nil if the key fails
+	// the checks of appendKey and so must go through them each time, to
+	// report the failure at its path.
+	encoded []byte
+}
+
+// intern returns the prepared form of key.
+func (c *Converter) intern(key string) *internedKey {
+	if k, ok := c.interned[key]; ok {
+		return k
+	}
+	k := &internedKey{segment: keySegment(key)}
+	if utf8.ValidString(key) {
+		if text, err := c.sanitize(key); err == nil {
+			k.encoded = c.codec.AppendString(nil, text)
+		}
+	}
+	if c.interned == nil {
+		c.interned = make(map[string]*internedKey)
+	}
+	if len(c.interned) < maxInternedKeys {
+		c.interned[key] = k
+	}
+	return k
+}
+
+// appendKey appends key, the key of the current value, after checking it
+// is valid UTF-8 and applying the control character policy.
+func (c *Converter) appendKey(b []byte, key string) ([]byte, error) {
+	if k := c.intern(key); k.encoded != nil {
+		return append(b, k.encoded...), nil
+	}
+	if !utf8.ValidString(key) {
+		if err := c.collect(c.errorf("%w: %q", ErrNonUTF8Key, key)); err != nil {
+			return b, err
+		}
+	}
+	text, err := c.sanitize(key)
+	if err = c.collect(err); err != nil {
+		return b, err
+	}
+	return c.codec.AppendString(b, text), nil
+}
+
+// keyList returns an empty list with room for n keys, reusing one which
+// releaseKeys returned if it can, so that converting many maps does not
+// allocate a list of keys for each.
+func (c *Converter) keyList(n int) []string {
+	if last := len(c.keyLists) - 1; last >= 0 && cap(c.keyLists[last]) >= n {
+		keys := c.keyLists[last]
+		c.keyLists = c.keyLists[:last]
+		return keys[:0]
+	}
+	return make([]string, 0, n)
+}
+
+// releaseKeys returns keys, which keyList returned, for reuse.
+func (c *Converter) releaseKeys(keys []string) {
+	for i := range keys {
+		keys[i] = ""
+	}
+	c.keyLists = append(c.keyLists, keys[:0])
+}
@@ -0,0 +1,49 @@
+package json2msgp_test
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ndau/json2msgp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepeatedKeys(t *testing.T) {
+	entry := func(x float64) map[string]interface{} {
+		return map[string]interface{}{"ChangeOn": x, "Current": []interface{}{x}, "Future": nil}
+	}
+	in := []interface{}{entry(1), entry(2), map[string]interface{}{"Future": entry(3)}}
+	got, err := json2msgp.ConvertWithOptions(in)
+	require.NoError(t, err)
+	want := "\x93" +
+		"\x83\xa8ChangeOn\x01\xa7Current\x91\x01\xa6Future\xc0" +
+		"\x83\xa8ChangeOn\x02\xa7Current\x91\x02\xa6Future\xc0" +
+		"\x81\xa6Future\x83\xa8ChangeOn\x03\xa7Current\x91\x03\xa6Future\xc0"
+	require.Equal(t, []byte(want), got)
+
+	// a bad key is reported every time it appears, at its own path
+	bad := string([]byte{0xff})
+	in = []interface{}{map[string]interface{}{bad: 1.0}, map[string]interface{}{bad: 2.0}}
+	_, err = json2msgp.ConvertWithOptions(in, json2msgp.WithAllErrors())
+	var list json2msgp.ErrorList
+	require.True(t, errors.As(err, &list), "got %v", err)
+	require.Len(t, list, 2)
+	require.Contains(t, list[0].Error(), "$[0]")
+	require.Contains(t, list[1].Error(), "$[1]")
+
+	opt := json2msgp.WithControlCharPolicy(json2msgp.ControlError)
+	in = []interface{}{map[string]interface{}{"k\x01": 1.0}, map[string]interface{}{"k\x01": 2.0}}
+	_, err = json2msgp.ConvertWithOptions(in, opt, json2msgp.WithAllErrors())
+	require.True(t, errors.As(err, &list), "got %v", err)
+	require.Len(t, list, 2)
+	require.True(t, errors.Is(list[1], json2msgp.ErrControlChar))
+}
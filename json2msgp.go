@@ -11,12 +11,18 @@ package json2msgp
 
 import (
 	"bytes"
+	"crypto/sha256"
 	"encoding/base64"
-	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
+	"math"
 	"reflect"
 	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
 	"unicode/utf8"
 
 	"github.com/ndau/ndaumath/pkg/address"
@@ -32,71 +38,395 @@ type Converter struct {
 	// Use this map with the current key to find its expected type.
 	typeHints map[string][]string
 
-	// When there are multiple types per hint name, it is used with arrays of values in json.
-	// This is an index into the []string of typeHints[currentKey].
-	currentHint int
+	// The keys of typeHints which are paths, most specific first.
+	pathHints []pathHint
+
+	// The hint lists of typeHints which have nested lists, for nested arrays.
+	nestedHints map[string][]nestedHint
+
+	// How to encode JSON null when no per-key policy applies.
+	nullPolicy NullPolicy
+
+	// Per-key and per-path overrides of nullPolicy, and the paths among them.
+	keyNullPolicies map[string]NullPolicy
+	nullPolicyPaths []pathHint
+
+	// Whether empty maps and arrays are encoded as nil.
+	emptyAsNil bool
+
+	// Map and array header widths, by default and per key.
+	defaultHeaderWidth HeaderWidth
+	keyHeaderWidths    map[string]HeaderWidth
+
+	// String header widths, by default and per key.
+	defaultStringWidth StringHeaderWidth
+	keyStringWidths    map[string]StringHeaderWidth
+
+	// The binary format written; see WithCodec.
+	codec Codec
+
+	// Whether ndau addresses are encoded as AddressExtension.
+	addressExtension bool
+
+	// The address kinds allowed, per key.
+	keyAddressKinds map[string][]byte
+
+	// Written after each value by the stream functions, unless they write
+	// frames; see WithFraming.
+	delimiter []byte
+	framed    bool
+
+	// The number of values the stream functions are yet to skip; see
+	// WithResume.
+	resume int
+
+	// The order map keys are written in, and whether maps with integer keys
+	// are written with MSGP integer keys.
+	keyOrder KeyOrder
+	intKeys  bool
+
+	// Whether to produce the canonical encoding; see WithCanonical.
+	canonical bool
+
+	// The report being built by Explain, and the node of the current value.
+	report *Report
+	node   *Node
+
+	// Whether the stream functions convert more than one JSON document.
+	multipleDocuments bool
+
+	// What the stream functions do with input which is already MSGP.
+	msgpInput MsgpInputPolicy
+
+	// How the stream functions handle duplicate keys in the JSON input.
+	duplicateKeys DuplicateKeyPolicy
+
+	// Where to log debug events, if anywhere.
+	logger *slog.Logger
+
+	// Where to report metrics, if anywhere, and the size of the JSON text of
+	// the current document, if known.
+	metrics   Metrics
+	inputSize int
+
+	// Where to record the audit trail of decisions, if anywhere.
+	audit *AuditLog
+
+	// What Bench counts decisions with, if anything.
+	profile func(path []string, hint, outcome string)
+
+	// Where to record the trace, if anywhere, and the offset of the current
+	// document in the output stream.
+	trace     *Trace
+	traceBase int
+
+	// The name of the system variable being converted, if known.
+	sysvar string
+
+	// Where to record warnings, if anywhere.
+	warnings *[]Warning
+
+	// How long a document may take, and when the current one must be done;
+	// see WithTimeout.
+	timeout  time.Duration
+	deadline time.Time
+
+	// Shared by converters limited in parallelism; see WithLimits.
+	slots chan struct{}
+
+	// Whether a document is in progress, holding a slot and timed.
+	inDocument bool
+
+	// How deeply values may be nested; see WithMaxDepth.
+	maxDepth int
+
+	// Size limits, or zero for none; see WithMaxInputSize, WithMaxOutputSize,
+	// WithMaxStringLength and WithMaxBase64Length.
+	maxInputSize    int64
+	maxOutputSize   int
+	maxStringLength int
+	maxBase64Length int
+
+	// Whether only the types JSON decodes to are accepted; see WithoutReflection.
+	noReflection bool
+
+	// Which string heuristics are turned off; see WithoutBase64Detection,
+	// WithoutAddressDetection and WithoutBinaryPassthrough.
+	noBase64Detection   bool
+	noAddressDetection  bool
+	noBinaryPassthrough bool
+
+	// How Go times and durations are encoded; see WithTimeEncoding.
+	timeEncoding TimeEncoding
+
+	// Whether Go structs are converted as maps; see WithStructs.
+	structs bool
+
+	// What to do with control characters in strings; see WithControlCharPolicy.
+	controlChars ControlCharPolicy
+
+	// Whether to continue after errors, and the errors found so far.
+	allErrors bool
+	errs      ErrorList
+
+	// Spare capacity for ConvertAll to convert the next document into.
+	arena []byte
+
+	// Whether to memoize subtrees, and the subtrees of the current document;
+	// see WithMemoization.
+	memoize bool
+	memo    *memo
+
+	// Whether to flatten nested maps, and the separator of their keys; see
+	// WithFlatten.
+	flatten    bool
+	flattenSep string
+
+	// The separator of keys to expand into nested values, if any; see
+	// WithUnflatten.
+	unflattenSep string
+
+	// The path of the value to convert instead of the whole document, if
+	// any; see WithPath.
+	selection string
+
+	// The query of the values to convert instead of the whole document, if
+	// any; see WithQuery.
+	query string
+
+	// The document to fill in missing keys from, if any; see WithDefaults.
+	defaults interface{}
+
+	// The keys maps must have; see WithRequiredKeys.
+	required []requirement
+
+	// The prefixes to remove from and add to top-level keys, if any; see
+	// WithStripKeyPrefix and WithAddKeyPrefix.
+	stripKeyPrefix string
+	addKeyPrefix   string
+
+	// Where to look up and remember whole documents, if anywhere, and the
+	// hash of typeHints once computed; see WithCache.
+	cache    *Cache
+	hintsSum *[sha256.Size]byte
+
+	// Where to write the normalized JSON of each value, if anywhere; see
+	// WithNormalizedJSON.
+	normalized io.Writer
+
+	// Whether the JSON input may have comments; see WithComments.
+	comments bool
+
+	// Whether to infer type hints from each document; see WithInferredHints.
+	inferHints bool
+
+	// How to parse strings hinted as numbers, if at all; see
+	// WithNumberParser.
+	numberParser NumberParser
+
+	// The rules to change values by before converting them; see
+	// WithCoercions.
+	coercions []Coercion
+
+	// Map keys prepared for reuse, and lists of keys to sort them in; see
+	// intern and keyList.
+	interned map[string]*internedKey
+	keyLists [][]string
+
+	// The path from the root to the value we're currently processing, as
+	// JSONPath segments: ".key", `["odd key"]` or "[index]".
+	path []string
 }
 
 // - if the string is not valid utf-8, it is passed through as a byte array without modification.
 // - if the string is a valid ndau address, it's represented as a string.
 // - if the string is valid padded base64 in the standard encoding, it is decoded and represented in the MSGP as a byte array.
 // - otherwise, it is assumed to be a string, and represented as a string.
-func (c *Converter) stringHeuristic(s string, buffer []byte) []byte {
-	if !utf8.ValidString(s) {
-		return msgp.AppendBytes(buffer, []byte(s))
+func (c *Converter) stringHeuristic(s string, buffer []byte) ([]byte, error) {
+	if c.maxStringLength > 0 && len(s) > c.maxStringLength {
+		return buffer, c.errorf("%w: %d bytes, limit %d", ErrStringTooLong, len(s), c.maxStringLength)
 	}
-	_, err := address.Validate(s)
-	if err == nil {
-		return msgp.AppendString(buffer, s)
+	if currentHint, ok := c.hint(); ok {
+		if c.numberParser != nil && isNumericHint(currentHint) {
+			return c.convertNumberString(s, currentHint, buffer)
+		}
+		switch currentHint {
+		case "str", "bin", "base64", "address", "ndauduration", "ndautimestamp":
+			c.decide(currentHint, "hint")
+		}
+		switch currentHint {
+		case "address":
+			return c.convertAddress(s, buffer)
+		case "ndauduration", "ndautimestamp":
+			return c.convertNdauTime(s, currentHint, buffer)
+		case "str":
+			return c.appendText(buffer, s)
+		case "bin":
+			return c.codec.AppendBytes(buffer, []byte(s)), nil
+		case "base64":
+			b64bytes, err := base64.StdEncoding.DecodeString(s)
+			if err != nil {
+				return buffer, c.errorf("%w: %s", ErrInvalidBase64, err)
+			}
+			return c.codec.AppendBytes(buffer, b64bytes), nil
+		}
+	}
+	_, restricted := c.keyAddressKinds[c.currentKey]
+	if !utf8.ValidString(s) && !restricted && !c.noBinaryPassthrough {
+		c.warn(WarnBinary, "string is not valid utf-8; encoded as bytes")
+		c.decide("", "binary")
+		return c.codec.AppendBytes(buffer, []byte(s)), nil
+	}
+	if restricted || !c.noAddressDetection {
+		if _, err := address.Validate(s); err == nil || restricted {
+			c.warn(WarnAddress, "string %q is an ndau address", s)
+			c.decide("", "address")
+			return c.convertAddress(s, buffer)
+		}
+	}
+	if c.noBase64Detection || c.maxBase64Length > 0 && len(s) > c.maxBase64Length {
+		c.decide("", "string")
+		return c.appendText(buffer, s)
 	}
 	b64bytes, err := base64.StdEncoding.DecodeString(s)
 	if err == nil {
-		return msgp.AppendBytes(buffer, b64bytes)
+		c.warn(WarnBase64, "string %q decoded as base64 into %d bytes", s, len(b64bytes))
+		c.decide("", "base64")
+		return c.codec.AppendBytes(buffer, b64bytes), nil
+	}
+	c.decide("", "string")
+	return c.appendText(buffer, s)
+}
+
+// convertAddress encodes s, which must be an ndau address of a kind allowed
+// for the current key.
+func (c *Converter) convertAddress(s string, buffer []byte) ([]byte, error) {
+	addr, err := address.Validate(s)
+	if err != nil {
+		return buffer, c.errorf("%w: %q is not an ndau address: %s", ErrBadValue, s, err)
+	}
+	if kinds, ok := c.keyAddressKinds[c.currentKey]; ok && !bytes.ContainsRune(kinds, rune(addr.Kind())) {
+		return buffer, c.errorf("%w: %q has kind %q, want one of %q", ErrAddressKind, s, addr.Kind(), kinds)
+	}
+	if c.addressExtension {
+		buffer, err = c.codec.AppendExtension(buffer, (*AddressExtension)(&s))
+		if err != nil {
+			return buffer, c.wrap(err)
+		}
+		return buffer, nil
 	}
-	return msgp.AppendString(buffer, s)
+	return c.appendString(buffer, s)
 }
 
-func (c *Converter) convertMapStrStr(m map[string]string, b []byte) []byte {
+// appendString appends a string value of the current key, with the header
+// width configured for it.
+func (c *Converter) appendString(b []byte, s string) ([]byte, error) {
+	width := c.defaultStringWidth
+	if w, ok := c.keyStringWidths[c.currentKey]; ok {
+		width = w
+	}
+	if width == StringCompact {
+		return c.codec.AppendString(b, s), nil
+	}
+	m, ok := c.codec.(MsgpCodec)
+	if !ok {
+		return b, c.errorf("%w: string header width needs MsgpCodec", ErrBadOption)
+	}
+	return m.AppendStringWidth(b, s, width), nil
+}
+
+// appendMapHeader appends a map header, or nil for an empty map if so configured.
+func (c *Converter) appendMapHeader(b []byte, sz uint32) []byte {
+	if sz == 0 && c.emptyAsNil {
+		return c.codec.AppendNil(b)
+	}
+	return c.codec.AppendMapHeader(b, sz, c.headerWidth(sz))
+}
+
+// appendArrayHeader appends an array header, or nil for an empty array if so configured.
+func (c *Converter) appendArrayHeader(b []byte, sz uint32) []byte {
+	if sz == 0 && c.emptyAsNil {
+		return c.codec.AppendNil(b)
+	}
+	return c.codec.AppendArrayHeader(b, sz, c.headerWidth(sz))
+}
+
+// headerWidth returns the header width to use for a container of sz elements
+// belonging to the current key.  A forced width too narrow for sz is widened.
+func (c *Converter) headerWidth(sz uint32) HeaderWidth {
+	width := c.defaultHeaderWidth
+	if w, ok := c.keyHeaderWidths[c.currentKey]; ok {
+		width = w
+	}
+	if width == Header16 && sz > 0xffff {
+		width = Header32
+	}
+	return width
+}
+
+func (c *Converter) convertMapStrStr(m map[string]string, b []byte) ([]byte, error) {
 	sz := uint32(len(m))
-	b = msgp.AppendMapHeader(b, sz)
+	b = c.appendMapHeader(b, sz)
 
 	// sort keys for deterministic output
 	// not critical for actual behavior, but we can't really test properly
 	// without this
-	keys := make([]string, 0, len(m))
+	keys := c.keyList(len(m))
+	defer func() { c.releaseKeys(keys) }()
 	for key := range m {
 		keys = append(keys, key)
 	}
-	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	c.sortKeys(keys)
+	ints := c.integerKeys(keys)
 
-	for _, key := range keys {
+	var err error
+	for i, key := range keys {
 		val := m[key]
-		b = msgp.AppendString(b, key)
 		c.currentKey = key
-		b = c.stringHeuristic(val, b)
+		c.pushKey(key)
+		if ints != nil {
+			b = c.codec.AppendInt(b, ints[i])
+		} else if b, err = c.appendKey(b, key); err != nil {
+			c.pop()
+			return b, err
+		}
+		b, err = c.check(c.convert(val, b))
+		c.pop()
+		if err != nil {
+			return b, err
+		}
 	}
-	return b
+	return b, nil
 }
 
 func (c *Converter) convertMapStrIntf(m map[string]interface{}, b []byte) ([]byte, error) {
 	sz := uint32(len(m))
-	b = msgp.AppendMapHeader(b, sz)
+	b = c.appendMapHeader(b, sz)
 
 	// sort keys for deterministic output
 	// not critical for actual behavior, but we can't really test properly
 	// without this
-	keys := make([]string, 0, len(m))
+	keys := c.keyList(len(m))
+	defer func() { c.releaseKeys(keys) }()
 	for key := range m {
 		keys = append(keys, key)
 	}
-	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	c.sortKeys(keys)
+	ints := c.integerKeys(keys)
 
 	var err error
-	for _, key := range keys {
+	for i, key := range keys {
 		val := m[key]
-		b = msgp.AppendString(b, key)
 		c.currentKey = key
-		b, err = c.convert(val, b)
+		c.pushKey(key)
+		if ints != nil {
+			b = c.codec.AppendInt(b, ints[i])
+		} else if b, err = c.appendKey(b, key); err != nil {
+			c.pop()
+			return b, err
+		}
+		b, err = c.check(c.convert(val, b))
+		c.pop()
 		if err != nil {
 			return b, err
 		}
@@ -104,72 +434,418 @@ func (c *Converter) convertMapStrIntf(m map[string]interface{}, b []byte) ([]byt
 	return b, nil
 }
 
+// sortKeys sorts map keys into the order they are written in.
+func (c *Converter) sortKeys(keys []string) {
+	if c.keyOrder != KeyOrderNumeric {
+		sort.Strings(keys)
+		return
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		aNum, bNum := isDecimal(a), isDecimal(b)
+		if aNum != bNum {
+			return aNum
+		}
+		if aNum {
+			// compare by value, ignoring leading zeros; equal values such as
+			// "1" and "01" fall back to their text
+			x, y := strings.TrimLeft(a, "0"), strings.TrimLeft(b, "0")
+			if len(x) != len(y) {
+				return len(x) < len(y)
+			}
+			if x != y {
+				return x < y
+			}
+		}
+		return a < b
+	})
+}
+
+// integerKeys returns the values of keys if they are to be written as
+// integers: with WithIntegerKeys, when every key is an int64 in its shortest
+// decimal form.  It then sorts keys by value.
+func (c *Converter) integerKeys(keys []string) []int64 {
+	if !c.intKeys || len(keys) == 0 {
+		return nil
+	}
+	ints := make([]int64, len(keys))
+	for i, key := range keys {
+		v, err := strconv.ParseInt(key, 10, 64)
+		if err != nil || strconv.FormatInt(v, 10) != key {
+			return nil
+		}
+		ints[i] = v
+	}
+	sort.Sort(intKeys{keys, ints})
+	return ints
+}
+
+// intKeys sorts keys and their values together, by value.
+type intKeys struct {
+	keys []string
+	ints []int64
+}
+
+func (k intKeys) Len() int           { return len(k.keys) }
+func (k intKeys) Less(i, j int) bool { return k.ints[i] < k.ints[j] }
+func (k intKeys) Swap(i, j int) {
+	k.keys[i], k.keys[j] = k.keys[j], k.keys[i]
+	k.ints[i], k.ints[j] = k.ints[j], k.ints[i]
+}
+
+// isDecimal is true if s is a non-negative decimal integer.
+func isDecimal(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// pushKey descends into the value of key.
+func (c *Converter) pushKey(key string) {
+	c.path = append(c.path, c.intern(key).segment)
+}
+
+// pushIndex descends into the element at index i.
+func (c *Converter) pushIndex(i int) {
+	c.path = append(c.path, indexSegment(i))
+}
+
+// keySegment returns the JSONPath segment selecting key.
+func keySegment(key string) string {
+	if isIdentifier(key) {
+		return "." + key
+	}
+	return "[" + strconv.Quote(key) + "]"
+}
+
+// indexSegment returns the JSONPath segment selecting index i.
+func indexSegment(i int) string {
+	return "[" + strconv.Itoa(i) + "]"
+}
+
+// pop returns to the parent of the current value.
+func (c *Converter) pop() {
+	c.path = c.path[:len(c.path)-1]
+}
+
+// pathString returns the JSONPath of the current value, e.g. "$.EAIFeeTable[4].Fee".
+func (c *Converter) pathString() string {
+	return "$" + strings.Join(c.path, "")
+}
+
+// errorf returns an error annotated with the path of the current value.
+func (c *Converter) errorf(format string, args ...interface{}) error {
+	return c.wrap(fmt.Errorf(format, args...))
+}
+
+// wrap annotates err with the path of the current value.
+func (c *Converter) wrap(err error) error {
+	return errors.Wrap(err, c.pathString())
+}
+
+// isIdentifier is true if key can be written as a dotted JSONPath segment.
+func isIdentifier(key string) bool {
+	if key == "" {
+		return false
+	}
+	for i, r := range key {
+		if !(r == '_' || unicode.IsLetter(r) || (i > 0 && unicode.IsDigit(r))) {
+			return false
+		}
+	}
+	return true
+}
+
+// collect records err and returns nil if errors are being aggregated;
+// otherwise it returns err.  A timeout always ends the conversion.
+func (c *Converter) collect(err error) error {
+	if err == nil || !c.allErrors || errors.Is(err, ErrTimeout) {
+		return err
+	}
+	c.errs = append(c.errs, err)
+	return nil
+}
+
+// check handles the result of converting a child value.  If errors are being
+// aggregated, a failed value is recorded and replaced by nil, so that the
+// output stays well-formed and conversion can continue with its siblings.
+func (c *Converter) check(b []byte, err error) ([]byte, error) {
+	if err == nil {
+		return b, nil
+	}
+	if err = c.collect(err); err != nil {
+		return b, err
+	}
+	return c.codec.AppendNil(b), nil
+}
+
+// convertRoot converts a whole document.
+func (c *Converter) convertRoot(in interface{}) ([]byte, error) {
+	start := time.Now()
+	if c.beginDocument() {
+		defer c.endDocument()
+	}
+	c.errs = nil
+	var (
+		b     []byte
+		typed bool
+		err   error
+	)
+	if c.selection != "" {
+		in, err = c.selectValue(in)
+		defer func() { c.path = c.path[:0] }()
+	}
+	if c.stripKeyPrefix != "" && err == nil {
+		in, err = renameKeys(in, c.stripPrefix)
+	}
+	if c.unflattenSep != "" && err == nil {
+		in, err = c.unflattenValue(in)
+	}
+	if c.defaults != nil && err == nil {
+		in = withDefaults(in, c.defaults)
+	}
+	if c.required != nil && err == nil {
+		err = c.checkRequired(in)
+	}
+	if c.flatten && err == nil {
+		in, err = c.flattenValue(in)
+	}
+	if c.addKeyPrefix != "" && err == nil {
+		in, err = renameKeys(in, c.addPrefix)
+	}
+	if c.inferHints && err == nil {
+		hints := c.typeHints
+		c.typeHints, err = c.inferredHints(in)
+		defer func() { c.typeHints = hints }()
+	}
+	if c.memoize && !c.observed() && err == nil {
+		c.memo = newMemo(in)
+		defer func() { c.memo = nil }()
+	}
+	if err == nil {
+		b, typed, err = c.convertSysvar(in)
+	}
+	if err == nil && !typed {
+		buffer := c.arena
+		if buffer == nil {
+			buffer = make([]byte, 0)
+		}
+		b, err = c.check(c.convert(in, buffer))
+		if err == nil && len(c.errs) > 0 {
+			err = c.errs
+		}
+	}
+	if err == nil && c.maxOutputSize > 0 && c.traceBase+len(b) > c.maxOutputSize {
+		err = fmt.Errorf("%w: more than %d bytes", ErrOutputTooLarge, c.maxOutputSize)
+	}
+	if err == nil && c.normalized != nil {
+		err = c.writeNormalized(b)
+	}
+	c.logDocument(len(b), err)
+	if c.metrics != nil {
+		c.metrics.Conversion(time.Since(start), c.inputSize, len(b), err)
+	}
+	return b, err
+}
+
+// hint returns the type hint which applies to the current value, if any.
+func (c *Converter) hint() (string, bool) {
+	if c.typeHints == nil {
+		return "", false
+	}
+	return lookupHint(c.typeHints, c.pathHints, c.nestedHints, c.currentKey, c.path)
+}
+
+// hintBounds holds the range [min, max+1) of each integer hint type.  The upper
+// bound is exclusive because the maximum of a 64-bit type is not exactly
+// representable as a float64.
+var hintBounds = map[string][2]float64{
+	"byte":   {0, 1 << 8},
+	"int":    {-(1 << (strconv.IntSize - 1)), 1 << (strconv.IntSize - 1)},
+	"int8":   {-(1 << 7), 1 << 7},
+	"int16":  {-(1 << 15), 1 << 15},
+	"int32":  {-(1 << 31), 1 << 31},
+	"int64":  {-(1 << 63), 1 << 63},
+	"uint":   {0, 1 << strconv.IntSize},
+	"uint8":  {0, 1 << 8},
+	"uint16": {0, 1 << 16},
+	"uint32": {0, 1 << 32},
+	"uint64": {0, 1 << 64},
+
+	"ndauduration":  {-(1 << 63), 1 << 63},
+	"ndautimestamp": {-(1 << 63), 1 << 63},
+}
+
+// appendHinted encodes the numeric value x as the msgp type named by hint.
+func (c *Converter) appendHinted(buffer []byte, hint string, x float64) ([]byte, error) {
+	// Support type hints for all msgp numeric formats.  We ensure that the value
+	// fits into the range of the hinted type, but fractional parts are truncated
+	// for integer types.  If there is a casting problem, the tool's user will have
+	// to supply a different type hint, or alter the input json.
+	if bounds, ok := hintBounds[hint]; ok && (x < bounds[0] || x >= bounds[1]) {
+		return buffer, c.errorf("%w for %s: %v", ErrOverflow, hint, x)
+	}
+	if hint == "float32" && math.Abs(x) > math.MaxFloat32 {
+		return buffer, c.errorf("%w for %s: %v", ErrOverflow, hint, x)
+	}
+	if _, ok := hintBounds[hint]; ok && x != math.Trunc(x) {
+		c.warn(WarnLossyNumber, "fractional part of %v truncated for %s", x, hint)
+	}
+	if hint == "float32" && !c.canonical && float64(float32(x)) != x {
+		c.warn(WarnLossyNumber, "%v loses precision as %s", x, hint)
+	}
+
+	switch hint {
+	case "byte":
+		return c.codec.AppendUint(buffer, uint64(x)), nil
+	case "float32":
+		if c.canonical {
+			return c.codec.AppendFloat64(buffer, canonicalFloat(x)), nil
+		}
+		return c.codec.AppendFloat32(buffer, float32(x)), nil
+	case "float64":
+		if c.canonical {
+			return c.codec.AppendFloat64(buffer, canonicalFloat(x)), nil
+		}
+		return c.codec.AppendFloat64(buffer, x), nil
+	case "int":
+		return c.codec.AppendInt(buffer, int64(x)), nil
+	case "int8":
+		return c.codec.AppendInt(buffer, int64(x)), nil
+	case "int16":
+		return c.codec.AppendInt(buffer, int64(x)), nil
+	case "int32":
+		return c.codec.AppendInt(buffer, int64(x)), nil
+	case "int64", "ndauduration", "ndautimestamp":
+		return c.codec.AppendInt(buffer, int64(x)), nil
+	case "uint":
+		return c.codec.AppendUint(buffer, uint64(x)), nil
+	case "uint8":
+		return c.codec.AppendUint(buffer, uint64(x)), nil
+	case "uint16":
+		return c.codec.AppendUint(buffer, uint64(x)), nil
+	case "uint32":
+		return c.codec.AppendUint(buffer, uint64(x)), nil
+	case "uint64":
+		return c.codec.AppendUint(buffer, uint64(x)), nil
+	default:
+		return buffer, c.errorf("%w %s=%s", ErrBadHint, c.currentKey, hint)
+	}
+}
+
+// canonicalFloat returns the canonical representative of x: negative zero
+// becomes positive zero, and every other value is unchanged.
+func canonicalFloat(x float64) float64 {
+	if x == 0 {
+		return 0
+	}
+	return x
+}
+
+// convertNull encodes a JSON null according to the null policy for the current key.
+func (c *Converter) convertNull(buffer []byte) ([]byte, error) {
+	policy := c.nullPolicy
+	if key, _, ok := findPathHint(c.nullPolicyPaths, c.path); ok {
+		policy = c.keyNullPolicies[key]
+	} else if p, ok := c.keyNullPolicies[nearestKey(c.path)]; ok {
+		policy = p
+	}
+
+	switch policy {
+	case NullAsNil:
+		return c.codec.AppendNil(buffer), nil
+	case NullAsEmptyArray:
+		return c.codec.AppendArrayHeader(buffer, 0, HeaderCompact), nil
+	case NullAsEmptyMap:
+		return c.codec.AppendMapHeader(buffer, 0, HeaderCompact), nil
+	case NullAsZero:
+		currentHint, ok := c.hint()
+		if !ok {
+			return buffer, c.errorf("%w for null value", ErrMissingHint)
+		}
+		c.decide(currentHint, "null policy")
+		switch currentHint {
+		case "str":
+			return c.appendString(buffer, "")
+		case "address":
+			return buffer, c.errorf("%w: null is not an ndau address", ErrBadValue)
+		case "bin", "base64":
+			return c.codec.AppendBytes(buffer, nil), nil
+		}
+		return c.appendHinted(buffer, currentHint, 0)
+	default:
+		return buffer, c.errorf("%w: null policy %d", ErrBadOption, policy)
+	}
+}
+
 func (c *Converter) convert(in interface{}, buffer []byte) ([]byte, error) {
+	if c.maxDepth > 0 && len(c.path) > c.maxDepth {
+		return buffer, c.errorf("%w of %d", ErrMaxDepth, c.maxDepth)
+	}
+	if c.expired() {
+		return buffer, c.errorf("%w after %s", ErrTimeout, c.timeout)
+	}
+	if c.memo != nil {
+		return c.convertMemoized(in, buffer)
+	}
+	if c.report == nil && c.trace == nil {
+		return c.convertValue(in, buffer)
+	}
+	start := len(buffer)
+	parent := c.node
+	if c.report != nil {
+		c.node = c.newNode(in, buffer)
+	}
+	buffer, err := c.convertValue(in, buffer)
+	if c.report != nil {
+		c.finishNode(buffer)
+		c.node = parent
+	}
+	if c.trace != nil && err == nil {
+		c.traceSpan(start, len(buffer))
+	}
+	return buffer, err
+}
+
+func (c *Converter) convertValue(in interface{}, buffer []byte) ([]byte, error) {
+	if c.coercions != nil {
+		var err error
+		if in, err = c.coerce(in); err != nil {
+			return buffer, err
+		}
+	}
 	switch x := in.(type) {
 	case string:
-		return c.stringHeuristic(x, buffer), nil
+		return c.stringHeuristic(x, buffer)
 	case map[string]interface{}:
 		return c.convertMapStrIntf(x, buffer)
 	case map[string]string:
-		return c.convertMapStrStr(x, buffer), nil
+		return c.convertMapStrStr(x, buffer)
 	case []interface{}:
-		buffer = msgp.AppendArrayHeader(buffer, uint32(len(x)))
+		buffer = c.appendArrayHeader(buffer, uint32(len(x)))
 		var err error
-		// Because we reset this every time, this only works on the innermost of nested arrays.
-		// TODO: Generalize the type hint spec.  The way this is done now, with the % operator
-		// to grab currentHint below, is just a minimal solution to account for arrays with
-		// unnamed values.  We might consider generalized nested arrays, and also allowing a
-		// single-type hint without having to be inside an array within the hint json.
-		c.currentHint = 0
-		for _, v := range x {
-			buffer, err = c.convert(v, buffer)
+		// The hint of each element is chosen by its position, which hint() reads from c.path.
+		for i, v := range x {
+			c.pushIndex(i)
+			buffer, err = c.check(c.convert(v, buffer))
+			c.pop()
 			if err != nil {
 				return buffer, err
 			}
-			c.currentHint++
 		}
 		return buffer, nil
 	case float64:
 		// The json input treats all numeric values as float64.  Without knowing the original
 		// data type, we don't know how to encode numeric values.  First, see if there's a hint.
-		if c.typeHints != nil {
-			if typeHint, ok := c.typeHints[c.currentKey]; ok {
-				currentHint := typeHint[c.currentHint % len(typeHint)]
-				// Support type hints for all msgp numeric formats.  We don't ensure that the
-				// value fits into the hinted type.  If there is a casting problem, the tool's
-				// user will have to supply a different type hint, or alter the input json.
-				switch currentHint {
-				case "byte":
-					return msgp.AppendByte(buffer, byte(x)), nil
-				case "float32":
-					return msgp.AppendFloat32(buffer, float32(x)), nil
-				case "float64":
-					return msgp.AppendFloat64(buffer, x), nil
-				case "int":
-					return msgp.AppendInt(buffer, int(x)), nil
-				case "int8":
-					return msgp.AppendInt8(buffer, int8(x)), nil
-				case "int16":
-					return msgp.AppendInt16(buffer, int16(x)), nil
-				case "int32":
-					return msgp.AppendInt32(buffer, int32(x)), nil
-				case "int64":
-					return msgp.AppendInt64(buffer, int64(x)), nil
-				case "uint":
-					return msgp.AppendUint(buffer, uint(x)), nil
-				case "uint8":
-					return msgp.AppendUint8(buffer, uint8(x)), nil
-				case "uint16":
-					return msgp.AppendUint16(buffer, uint16(x)), nil
-				case "uint32":
-					return msgp.AppendUint32(buffer, uint32(x)), nil
-				case "uint64":
-					return msgp.AppendUint64(buffer, uint64(x)), nil
-				default:
-					return buffer, fmt.Errorf(
-						"Unsupported numeric type hint %s=%s", c.currentKey, currentHint)
-				}
-			}
+		if currentHint, ok := c.hint(); ok {
+			c.decide(currentHint, "hint")
+			return c.appendHinted(buffer, currentHint, x)
 		}
 
 		// Most of what we encode are of type int64, so we make that assumption here as part of
@@ -178,7 +854,8 @@ func (c *Converter) convert(in interface{}, buffer []byte) ([]byte, error) {
 		// Make sure the value is indeed an integer (has no fractional part).  This is meant as
 		// a convenience check for the tool's user.  We'll error below if this check fails.
 		if float64(i) == x {
-			return msgp.AppendInt64(buffer, i), nil
+			c.decide("", "integer")
+			return c.codec.AppendInt(buffer, i), nil
 		}
 
 		// We error here, rather than encoding to general float64.  Otherwise we could wind up
@@ -186,26 +863,62 @@ func (c *Converter) convert(in interface{}, buffer []byte) ([]byte, error) {
 		// array of objects), some of which get encoded one way, the rest another way.  In that
 		// case, when msgp unmarshals it later, it won't be able to handle the two different ways
 		// we encode the numeric values.  So, it's better to make this clear at encode-time.
-		return buffer, fmt.Errorf("Unsupported numeric value %v", x)
+		return buffer, c.errorf("%w %v", ErrUnhintedNumber, x)
+	case float32:
+		if c.canonical {
+			// Widen via the shortest decimal which round-trips the float32, so that
+			// float32(0.1) encodes identically to a JSON 0.1 hinted as a float.
+			f, err := strconv.ParseFloat(strconv.FormatFloat(float64(x), 'g', -1, 32), 64)
+			if err != nil {
+				return buffer, c.wrap(err)
+			}
+			return c.codec.AppendFloat64(buffer, canonicalFloat(f)), nil
+		}
+		return c.codec.AppendFloat32(buffer, x), nil
+	case bool:
+		return c.codec.AppendBool(buffer, x), nil
+	case nil:
+		return c.convertNull(buffer)
+	case time.Time:
+		return c.convertTime(x, buffer)
+	case time.Duration:
+		return c.convertDuration(x, buffer)
+	}
+	if c.noReflection {
+		return buffer, c.errorf("%w %T", ErrUnsupportedType, in)
 	}
 
 	var err error
 	v := reflect.ValueOf(in)
 	switch v.Kind() {
 	case reflect.Ptr:
+		if v.IsNil() {
+			return c.convert(nil, buffer)
+		}
 		return c.convert(v.Elem().Interface(), buffer)
 	case reflect.Array, reflect.Slice:
 		l := v.Len()
-		buffer = msgp.AppendArrayHeader(buffer, uint32(l))
+		buffer = c.appendArrayHeader(buffer, uint32(l))
 		for i := 0; i < l; i++ {
-			buffer, err = c.convert(v.Index(i).Interface(), buffer)
+			c.pushIndex(i)
+			buffer, err = c.check(c.convert(v.Index(i).Interface(), buffer))
+			c.pop()
 			if err != nil {
 				return buffer, err
 			}
 		}
 		return buffer, nil
+	case reflect.Struct:
+		if c.structs {
+			return c.convertMapStrIntf(structMap(v), buffer)
+		}
+		fallthrough
 	default:
-		return msgp.AppendIntf(buffer, in)
+		buffer, err = c.codec.AppendIntf(buffer, in)
+		if err != nil {
+			return buffer, c.wrap(err)
+		}
+		return buffer, nil
 	}
 }
 
@@ -219,12 +932,41 @@ func (c *Converter) convert(in interface{}, buffer []byte) ([]byte, error) {
 // - otherwise, it is assumed to be a string, and represented as a string.
 //
 // This is primarily intended to assist conversion from JSON to MSGP, so certain
-// conversions such as structs are intentionally excluded. If you have a struct,
-// use `msgp.Marshal` directly.
+// conversions such as structs are excluded unless WithStructs is given. If you
+// have a struct with generated msgp code, use `msgp.Marshal` directly.
 func Convert(in interface{}, typeHints map[string][]string) ([]byte, error) {
-	buffer := make([]byte, 0)
-	c := Converter{typeHints: typeHints}
-	return c.convert(in, buffer)
+	return ConvertWithOptions(in, WithTypeHints(typeHints))
+}
+
+// ConvertRaw is like Convert, but returns the result as a msgp.Raw, ready to
+// be assigned to a msgp.Raw field of another msgp-generated structure.
+func ConvertRaw(in interface{}, typeHints map[string][]string) (msgp.Raw, error) {
+	b, err := Convert(in, typeHints)
+	return msgp.Raw(b), err
+}
+
+// arenaChunk is the size of the chunks ConvertAll allocates its results from.
+const arenaChunk = 64 << 10
+
+// ConvertAll is like Convert for each of ins, but shares one converter and
+// its allocations between them, which is much cheaper when converting many
+// small documents.  The results are carved from shared chunks of memory, but
+// each has its own capacity, so appending to one never overwrites another.
+func ConvertAll(ins []interface{}, typeHints map[string][]string) ([][]byte, error) {
+	c := newConverter(WithTypeHints(typeHints))
+	outs := make([][]byte, len(ins))
+	for i, in := range ins {
+		if cap(c.arena)-len(c.arena) < arenaChunk/16 {
+			c.arena = make([]byte, 0, arenaChunk)
+		}
+		b, err := c.convertRoot(in)
+		if err != nil {
+			return nil, errors.Wrapf(err, "ConvertAll input %d", i)
+		}
+		outs[i] = b[:len(b):len(b)]
+		c.arena = b[len(b):]
+	}
+	return outs, nil
 }
 
 // ConvertStream reads JSON from `in` and copies it as MSGP to `out` until EOF.
@@ -243,31 +985,195 @@ func Convert(in interface{}, typeHints map[string][]string) ([]byte, error) {
 // - if there are blobs of json without names, yet there are arrays of differing numeric types,
 //   such as: [[0,1],[-2,3],[4,5]], then use:
 //   typeHints = {"": []string{"int64", "uint64"}}
+//   A list of hints applies to the innermost array, cyclically by index.
+// - if the arrays are nested more deeply, and the inner arrays differ, nest the hint
+//   lists to match, writing the inner lists as JSON: for [[[0,1],[-2]],[[2.5]]], use:
+//   typeHints = {"": []string{`[["uint64","int64"],["int64"]]`, `["float64"]`}}
+//   Each level of the nested list is indexed, cyclically, by the index at the same depth
+//   of the nested arrays, outermost first; a hint in place of a list applies to
+//   everything within.  ReadHints accepts these lists as nested JSON arrays.
+// - if a key means different things in different places, hint it by its path from the
+//   root of the document instead, dotted or as a JSON Pointer, with * for any key or index:
+//   typeHints = {"EAIFeeTable.*.Fee": []string{"uint64"}, "/LockedRateTable/*/1": []string{"uint64"}}
+//   A path hint which selects an array applies to the elements of its nested arrays, as a
+//   hint for its key name would.  A path hint wins over a hint for the key name, and of
+//   several matching path hints, the longest, then the one with the fewest wildcards, wins.
+//   Hint keys containing "." or starting with "/" are paths; they also still apply to keys
+//   of the same name, such as flattened keys.
+//
+// The "ndauduration" and "ndautimestamp" hints encode an int64 number of
+// microseconds.  Besides numbers, they accept human-readable strings: durations
+// such as "2d" or "t1h30m" (see ParseNdauDuration), and RFC 3339 timestamps such
+// as "2020-07-01T00:00:00Z" (see ParseNdauTimestamp).
+//
+// Type hints can also override the string heuristic where it guesses wrong:
+//
+// - "str" always encodes the string as a string.
+// - "bin" always encodes the string's bytes as a byte array, without decoding.
+// - "base64" always decodes the string as padded standard base64 into a byte array,
+//   and fails if it is not valid base64.
+// - "address" encodes the string as an ndau address, and fails if it is not one.
+//
+// See WithKeyAddressKinds to also restrict the kinds of address allowed.
 func ConvertStream(in io.Reader, out io.Writer, typeHints map[string][]string) error {
+	return ConvertStreamWithOptions(in, out, WithTypeHints(typeHints))
+}
+
+// streamDocument parses and converts the next document of d, and returns
+// the values to write for it.  It returns io.EOF if there is none.
+func (c *Converter) streamDocument(d *decoder) ([][]byte, error) {
+	c.beginDocument()
+	defer c.endDocument()
+
+	docStart := d.InputOffset()
+	jsobj, err := d.document()
+	if err == io.EOF {
+		return nil, err
+	}
+	if err != nil {
+		if docStart == 0 && c.splitMsgp(d.data) != nil {
+			return nil, fmt.Errorf("ConvertStream: %w", ErrMsgpInput)
+		}
+		return nil, errors.Wrap(err, "ConvertStream unmarshalling JSON")
+	}
+	if !c.multipleDocuments {
+		if offset := d.trailing(); offset >= 0 {
+			return nil, fmt.Errorf("ConvertStream: %w at offset %d", ErrTrailingData, offset)
+		}
+	}
+	c.inputSize = int(d.InputOffset() - docStart)
+	if c.query != "" {
+		return c.convertQuery(jsobj)
+	}
+	if c.cache == nil || c.observed() {
+		out, err := c.convertRoot(jsobj)
+		return [][]byte{out}, err
+	}
+	key := c.cacheKey(bytes.TrimSpace(d.data[docStart:d.InputOffset()]))
+	if out, ok := c.cache.get(key); ok {
+		if c.normalized != nil {
+			return [][]byte{out}, c.writeNormalized(out)
+		}
+		return [][]byte{out}, nil
+	}
+	out, err := c.convertRoot(jsobj)
+	if err == nil {
+		c.cache.put(key, out)
+	}
+	return [][]byte{out}, err
+}
+
+// ConvertStreamWithOptions is like ConvertStream, but configures the conversion with opts.
+func ConvertStreamWithOptions(in io.Reader, out io.Writer, opts ...Option) error {
+	_, err := ConvertStreamCounted(in, out, opts...)
+	return err
+}
+
+// ConvertStreamWithHintReader is like ConvertStreamWithOptions, but first
+// reads the type hints from hintsReader, as for ReadHints, so that a pipeline
+// can supply them on a second stream rather than in a file.
+func ConvertStreamWithHintReader(in io.Reader, out io.Writer, hintsReader io.Reader, opts ...Option) error {
+	typeHints, err := ReadHints(hintsReader)
+	if err != nil {
+		return errors.Wrap(err, "ConvertStream reading hints")
+	}
+	return ConvertStreamWithOptions(in, out, append(opts[:len(opts):len(opts)], WithTypeHints(typeHints))...)
+}
+
+// StreamResult counts what a stream conversion wrote.
+type StreamResult struct {
+	// Values is the number of top-level MSGP values written: one per JSON
+	// document, or one per match of WithQuery.
+	Values int
+	// Bytes is the number of bytes written, including delimiters.
+	Bytes int64
+}
+
+// ConvertStreamCounted is like ConvertStreamWithOptions, but also returns
+// what it wrote to out, whether or not it succeeded.
+func ConvertStreamCounted(in io.Reader, out io.Writer, opts ...Option) (StreamResult, error) {
+	var result StreamResult
 	// JSON isn't length-prefixed, so we kind of have to parse the whole thing.
 	// It's a nice convenience function, at least, and we all have Effectively
 	// Infinite Memory, right?
+	c := newConverter(opts...)
+	if c.resume > 0 && c.trace != nil {
+		return result, fmt.Errorf("ConvertStream: %w: WithResume cannot be used with WithTrace", ErrBadOption)
+	}
+	if c.framed {
+		// values start after their frame header
+		c.traceBase = frameHeaderSize
+	}
+	if c.maxInputSize > 0 {
+		in = io.LimitReader(in, c.maxInputSize+1)
+	}
 	var buffer bytes.Buffer
 	_, err := buffer.ReadFrom(in)
 	if err != nil {
-		return errors.Wrap(err, "ConvertStream reading input")
+		return result, errors.Wrap(err, "ConvertStream reading input")
 	}
-
-	var jsobj interface{}
-	err = json.Unmarshal(buffer.Bytes(), &jsobj)
-	if err != nil {
-		return errors.Wrap(err, "ConvertStream unmarshalling JSON")
+	if c.maxInputSize > 0 && int64(buffer.Len()) > c.maxInputSize {
+		return result, fmt.Errorf("ConvertStream: %w: more than %d bytes", ErrInputTooLarge, c.maxInputSize)
 	}
 
-	msgp, err := Convert(jsobj, typeHints)
-	if err != nil {
-		return err
-	}
+	d := c.newDecoder(buffer.Bytes())
+	for n := 0; ; n++ {
+		values, err := c.streamDocument(d)
+		passThrough := errors.Is(err, ErrMsgpInput) && c.msgpInput == MsgpInputPassThrough
+		if passThrough {
+			values, err = c.splitMsgp(buffer.Bytes()), nil
+		}
+		if err == io.EOF {
+			if n > 0 {
+				break
+			}
+			return result, errors.Wrap(err, "ConvertStream unmarshalling JSON")
+		}
+		if err != nil {
+			if c.multipleDocuments {
+				return result, errors.Wrapf(err, "ConvertStream document %d", n)
+			}
+			return result, err
+		}
+		if err = c.writeValues(out, values, &result); err != nil {
+			return result, err
+		}
 
-	_, err = out.Write(msgp)
-	if err != nil {
-		return errors.Wrap(err, "ConvertStream writing to out stream")
+		if !c.multipleDocuments || passThrough {
+			break
+		}
 	}
 
+	return result, nil
+}
+
+// writeValues writes values to out, each framed or followed by the
+// delimiter, and counts them in result.
+func (c *Converter) writeValues(out io.Writer, values [][]byte, result *StreamResult) error {
+	for _, msgp := range values {
+		if c.resume > 0 {
+			c.resume--
+			continue
+		}
+		var err error
+		if c.framed {
+			if msgp, err = appendFrame(nil, msgp); err != nil {
+				return errors.Wrap(err, "ConvertStream")
+			}
+		} else {
+			msgp = append(msgp, c.delimiter...)
+		}
+		if c.maxOutputSize > 0 && int(result.Bytes)+len(msgp) > c.maxOutputSize {
+			return fmt.Errorf("ConvertStream: %w: more than %d bytes", ErrOutputTooLarge, c.maxOutputSize)
+		}
+
+		written, err := out.Write(msgp)
+		result.Bytes += int64(written)
+		if err != nil {
+			return &WriteError{Written: *result, Partial: written, Err: err}
+		}
+		result.Values++
+		c.traceBase += len(msgp)
+	}
 	return nil
 }
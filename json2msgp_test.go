@@ -12,6 +12,8 @@ package json2msgp_test
 import (
 	"bytes"
 	"encoding/hex"
+	"errors"
+	"io"
 	"strings"
 	"testing"
 
@@ -58,6 +60,60 @@ func TestConvert(t *testing.T) {
 	}
 }
 
+func TestConvertRaw(t *testing.T) {
+	got, err := json2msgp.ConvertRaw(map[string]interface{}{"foo": 0xff}, nil)
+	require.NoError(t, err)
+
+	// a Raw field marshals its contents verbatim
+	b, err := got.MarshalMsg(nil)
+	require.NoError(t, err)
+	want, err := hex.DecodeString("81a3666f6fd100ff")
+	require.NoError(t, err)
+	require.Equal(t, want, b)
+}
+
+func TestConvertAll(t *testing.T) {
+	hints := map[string][]string{"Fee": []string{"uint64"}}
+	ins := make([]interface{}, 5000)
+	for i := range ins {
+		ins[i] = map[string]interface{}{"Fee": float64(i), "Script": "oACI"}
+	}
+	outs, err := json2msgp.ConvertAll(ins, hints)
+	require.NoError(t, err)
+	require.Len(t, outs, len(ins))
+	for i, in := range ins {
+		want, err := json2msgp.Convert(in, hints)
+		require.NoError(t, err)
+		require.Equal(t, want, outs[i])
+	}
+
+	// results don't share capacity
+	first := append(outs[0], 0xc0)
+	require.Equal(t, byte(0x82), outs[1][0])
+	require.Len(t, first, len(outs[0])+1)
+
+	_, err = json2msgp.ConvertAll([]interface{}{nil, 1.5}, nil)
+	require.EqualError(t, err, "ConvertAll input 1: $: Unsupported numeric value 1.5")
+}
+
+func TestConvertErrorPath(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"root", `1.5`, "$: Unsupported numeric value 1.5"},
+		{"nested", `{"EAIFeeTable":[{"Fee":1},{"Fee":1.5}]}`, "$.EAIFeeTable[1].Fee: Unsupported numeric value 1.5"},
+		{"odd key", `{"a b":[1.5]}`, `$["a b"][0]: Unsupported numeric value 1.5`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := json2msgp.ConvertStream(bytes.NewBufferString(tt.in), &bytes.Buffer{}, nil)
+			require.EqualError(t, err, tt.want)
+		})
+	}
+}
+
 // more complicated tests go here because it's easier to do complicated
 // nesting structures in json than raw go
 func TestConvertStream(t *testing.T) {
@@ -191,3 +247,70 @@ func TestConvertStream(t *testing.T) {
 		})
 	}
 }
+
+// shortWriter accepts at most n bytes, then fails.
+type shortWriter struct {
+	bytes.Buffer
+	n int
+}
+
+func (w *shortWriter) Write(p []byte) (int, error) {
+	if len(p) > w.n {
+		w.Buffer.Write(p[:w.n])
+		n := w.n
+		w.n = 0
+		return n, io.ErrShortWrite
+	}
+	w.n -= len(p)
+	return w.Buffer.Write(p)
+}
+
+func TestConvertStreamCounted(t *testing.T) {
+	in := `{"a": 1} [2, 3] "x!"`
+	opts := []json2msgp.Option{json2msgp.WithMultipleDocuments(), json2msgp.WithDelimiter([]byte{'\n'})}
+
+	var out bytes.Buffer
+	result, err := json2msgp.ConvertStreamCounted(strings.NewReader(in), &out, opts...)
+	require.NoError(t, err)
+	require.Equal(t, json2msgp.StreamResult{Values: 3, Bytes: int64(out.Len())}, result)
+	require.Equal(t, int64(13), result.Bytes)
+
+	// a failed write counts what got through
+	w := &shortWriter{n: 7}
+	result, err = json2msgp.ConvertStreamCounted(strings.NewReader(in), w, opts...)
+	require.True(t, errors.Is(err, io.ErrShortWrite), "got %v", err)
+	require.Equal(t, json2msgp.StreamResult{Values: 1, Bytes: 7}, result)
+
+	result, err = json2msgp.ConvertStreamCounted(strings.NewReader(in+" 1.5"), &out, opts...)
+	require.Error(t, err)
+	require.Equal(t, 3, result.Values)
+}
+
+func TestWriteErrorResume(t *testing.T) {
+	in := `{"a": 1} [2, 3] "x!"`
+	opts := []json2msgp.Option{json2msgp.WithMultipleDocuments(), json2msgp.WithDelimiter([]byte{'\n'})}
+	var want bytes.Buffer
+	require.NoError(t, json2msgp.ConvertStreamWithOptions(strings.NewReader(in), &want, opts...))
+
+	// the second value, 4 bytes and a delimiter, fails after 2
+	w := &shortWriter{n: 7}
+	err := json2msgp.ConvertStreamWithOptions(strings.NewReader(in), w, opts...)
+	var werr *json2msgp.WriteError
+	require.True(t, errors.As(err, &werr), "got %v", err)
+	require.Equal(t, json2msgp.StreamResult{Values: 1, Bytes: 7}, werr.Written)
+	require.Equal(t, 2, werr.Partial)
+	require.True(t, errors.Is(err, io.ErrShortWrite))
+	require.EqualError(t, err, "ConvertStream writing to out stream after 1 value (7 bytes written): short write")
+
+	// cut the partial value, and carry on from the next
+	out := bytes.NewBuffer(w.Bytes()[:int(werr.Written.Bytes)-werr.Partial])
+	resume := append(opts, json2msgp.WithResume(werr.Written.Values))
+	result, err := json2msgp.ConvertStreamCounted(strings.NewReader(in), out, resume...)
+	require.NoError(t, err)
+	require.Equal(t, json2msgp.StreamResult{Values: 2, Bytes: 8}, result)
+	require.Equal(t, want.Bytes(), out.Bytes())
+
+	var trace json2msgp.Trace
+	_, err = json2msgp.ConvertStreamCounted(strings.NewReader(in), out, append(resume, json2msgp.WithTrace(&trace))...)
+	require.True(t, errors.Is(err, json2msgp.ErrBadOption), "got %v", err)
+}
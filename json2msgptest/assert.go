@@ -0,0 +1,203 @@
+package json2msgptest
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/tinylib/msgp/msgp"
+)
+
+// MsgpEqual fails t unless actual is the MSGP in expectedHex, which is
+// decoded as for DecodeHex.  The failure shows a line diff of the two
+// values, annotated with the path, encoding and value of each element.
+func MsgpEqual(t testing.TB, expectedHex string, actual []byte) {
+	t.Helper()
+	want, err := DecodeHex(expectedHex)
+	if err != nil {
+		t.Fatalf("bad expected hex: %s", err)
+		return
+	}
+	if bytes.Equal(want, actual) {
+		return
+	}
+	t.Fatalf("MSGP differs (- want, + got):\n%s\nwant %x\n got %x",
+		diffLines(Annotate(want), Annotate(actual)), want, actual)
+}
+
+// JSONEncodesTo fails t unless the JSON text json converts with hints to the
+// MSGP in expectedHex, as for MsgpEqual.
+func JSONEncodesTo(t testing.TB, json string, hints map[string][]string, expectedHex string) {
+	t.Helper()
+	got, err := Case{JSON: []byte(json), Hints: hints}.Convert()
+	if err != nil {
+		t.Fatalf("converting: %s", err)
+		return
+	}
+	MsgpEqual(t, expectedHex, got)
+}
+
+// Annotate describes each element of the MSGP values in b on its own line,
+// as "path: encoding value".  Undecodable data is described by an error
+// line.
+func Annotate(b []byte) []string {
+	var lines []string
+	for doc := 0; len(b) > 0; doc++ {
+		path := "$"
+		if doc > 0 {
+			path = fmt.Sprintf("(%d)$", doc)
+		}
+		var err error
+		b, err = annotate(path, b, &lines)
+		if err != nil {
+			lines = append(lines, fmt.Sprintf("%s: error: %s", path, err))
+			break
+		}
+	}
+	return lines
+}
+
+func annotate(path string, b []byte, lines *[]string) ([]byte, error) {
+	if len(b) == 0 {
+		return b, msgp.ErrShortBytes
+	}
+	encoding := encodingName(b[0])
+	switch msgp.NextType(b) {
+	case msgp.MapType:
+		sz, rest, err := msgp.ReadMapHeaderBytes(b)
+		if err != nil {
+			return b, err
+		}
+		*lines = append(*lines, fmt.Sprintf("%s: %s of %d", path, encoding, sz))
+		for i := uint32(0); i < sz; i++ {
+			var key interface{}
+			key, rest, err = msgp.ReadIntfBytes(rest)
+			if err != nil {
+				return rest, err
+			}
+			segment := fmt.Sprintf("[%#v]", key)
+			if s, ok := key.(string); ok {
+				segment = fmt.Sprintf("[%q]", s)
+			}
+			rest, err = annotate(path+segment, rest, lines)
+			if err != nil {
+				return rest, err
+			}
+		}
+		return rest, nil
+	case msgp.ArrayType:
+		sz, rest, err := msgp.ReadArrayHeaderBytes(b)
+		if err != nil {
+			return b, err
+		}
+		*lines = append(*lines, fmt.Sprintf("%s: %s of %d", path, encoding, sz))
+		for i := uint32(0); i < sz; i++ {
+			rest, err = annotate(fmt.Sprintf("%s[%d]", path, i), rest, lines)
+			if err != nil {
+				return rest, err
+			}
+		}
+		return rest, nil
+	}
+	v, rest, err := msgp.ReadIntfBytes(b)
+	if err != nil {
+		return b, err
+	}
+	var value string
+	switch x := v.(type) {
+	case string:
+		value = fmt.Sprintf("%q", x)
+	case []byte:
+		value = fmt.Sprintf("%x", x)
+	case *msgp.RawExtension:
+		value = fmt.Sprintf("type %d %x", x.Type, x.Data)
+	default:
+		value = fmt.Sprint(x)
+	}
+	switch v.(type) {
+	case nil, bool:
+		// the encoding says it all
+		*lines = append(*lines, fmt.Sprintf("%s: %s", path, encoding))
+	default:
+		*lines = append(*lines, fmt.Sprintf("%s: %s %s", path, encoding, value))
+	}
+	return rest, nil
+}
+
+// encodingName names the MSGP format with the given first byte.
+func encodingName(prefix byte) string {
+	switch {
+	case prefix <= 0x7f:
+		return "positive fixint"
+	case prefix <= 0x8f:
+		return "fixmap"
+	case prefix <= 0x9f:
+		return "fixarray"
+	case prefix <= 0xbf:
+		return "fixstr"
+	case prefix >= 0xe0:
+		return "negative fixint"
+	}
+	names := map[byte]string{
+		0xc0: "nil", 0xc1: "never used", 0xc2: "false", 0xc3: "true",
+		0xc4: "bin 8", 0xc5: "bin 16", 0xc6: "bin 32",
+		0xc7: "ext 8", 0xc8: "ext 16", 0xc9: "ext 32",
+		0xca: "float 32", 0xcb: "float 64",
+		0xcc: "uint 8", 0xcd: "uint 16", 0xce: "uint 32", 0xcf: "uint 64",
+		0xd0: "int 8", 0xd1: "int 16", 0xd2: "int 32", 0xd3: "int 64",
+		0xd4: "fixext 1", 0xd5: "fixext 2", 0xd6: "fixext 4", 0xd7: "fixext 8", 0xd8: "fixext 16",
+		0xd9: "str 8", 0xda: "str 16", 0xdb: "str 32",
+		0xdc: "array 16", 0xdd: "array 32", 0xde: "map 16", 0xdf: "map 32",
+	}
+	return names[prefix]
+}
+
+// diffLines returns a line diff of want and got, marking lines only in want
+// with "-" and lines only in got with "+".
+func diffLines(want, got []string) string {
+	// lcs[i][j] is the length of the longest common subsequence of want[i:]
+	// and got[j:]
+	lcs := make([][]int, len(want)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(got)+1)
+	}
+	for i := len(want) - 1; i >= 0; i-- {
+		for j := len(got) - 1; j >= 0; j-- {
+			if want[i] == got[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var sb strings.Builder
+	i, j := 0, 0
+	for i < len(want) || j < len(got) {
+		switch {
+		case i < len(want) && j < len(got) && want[i] == got[j]:
+			fmt.Fprintf(&sb, "  %s\n", want[i])
+			i++
+			j++
+		case j == len(got) || (i < len(want) && lcs[i+1][j] >= lcs[i][j+1]):
+			fmt.Fprintf(&sb, "- %s\n", want[i])
+			i++
+		default:
+			fmt.Fprintf(&sb, "+ %s\n", got[j])
+			j++
+		}
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
@@ -0,0 +1,58 @@
+package json2msgptest_test
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"testing"
+
+	"github.com/ndau/json2msgp/json2msgptest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMsgpEqual(t *testing.T) {
+	json2msgptest.MsgpEqual(t, "81 a3 46 65 65 cc c8", []byte("\x81\xa3Fee\xcc\xc8"))
+
+	rec := &recorder{TB: t}
+	json2msgptest.MsgpEqual(rec, "82 a3 46 65 65 cc c8 a1 61 c0", []byte("\x82\xa3Fee\xcd\x00\xc8\xa1a\xc0"))
+	require.Contains(t, rec.failure, `MSGP differs (- want, + got):
+  $: fixmap of 2
+- $["Fee"]: uint 8 200
++ $["Fee"]: uint 16 200
+  $["a"]: nil
+`)
+
+	rec = &recorder{TB: t}
+	json2msgptest.MsgpEqual(rec, "81 a1 61 c0", []byte("\x81\xa1a"))
+	require.Contains(t, rec.failure, `+ $: error:`)
+}
+
+func TestJSONEncodesTo(t *testing.T) {
+	hints := map[string][]string{"Fee": []string{"uint64"}}
+	json2msgptest.JSONEncodesTo(t, `{"Fee": 200}`, hints, "81 a3 46 65 65 cc c8")
+
+	rec := &recorder{TB: t}
+	json2msgptest.JSONEncodesTo(rec, `{"Fee": "oACI"}`, nil, "81 a3 46 65 65 a4 6f 41 43 49")
+	require.Contains(t, rec.failure, `- $["Fee"]: fixstr "oACI"
++ $["Fee"]: bin 8 a00088`)
+
+	rec = &recorder{TB: t}
+	json2msgptest.JSONEncodesTo(rec, `{"Fee": 1.5}`, nil, "c0")
+	require.Contains(t, rec.failure, "converting:")
+}
+
+func TestAnnotate(t *testing.T) {
+	require.Equal(t, []string{
+		"$: fixarray of 3",
+		"$[0]: negative fixint -1",
+		`$[1]: fixstr "x"`,
+		"$[2]: true",
+		"(1)$: float 64 1.5",
+	}, json2msgptest.Annotate([]byte("\x93\xff\xa1x\xc3\xcb\x3f\xf8\x00\x00\x00\x00\x00\x00")))
+}
@@ -0,0 +1,95 @@
+package json2msgptest
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ndau/json2msgp"
+)
+
+// seeds start every fuzz target, whatever the caller adds.
+var seeds = []string{
+	`null`,
+	`{"Fee": 200, "Script": "oACI", "Names": ["a", "b!"], "On": true}`,
+	`[[7776000000000, 10000000000], [], {}]`,
+	`{"a": {"b": {"c": [1, -1, 1e3, "\u0000"]}}}`,
+	`"ndaaiz7rmgxqm5m4i6vn6v4wdeabw2pa6ufpacxw3h4v3zd6"`,
+}
+
+// AddSeedDir adds the contents of each *.json file in dir to the seed corpus
+// of f.
+func AddSeedDir(f *testing.F, dir string) {
+	f.Helper()
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		f.Fatal(err)
+	}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(data)
+	}
+}
+
+// FuzzConvert fuzzes ConvertWithOptions with opts, on the values
+// encoding/json decodes from the fuzzed text.  It fails if the conversion
+// panics, or succeeds with output which ValidateMsgp rejects.
+//
+// Call it from a fuzz target in a _test.go file, after adding seeds:
+//
+//	func FuzzSysvars(f *testing.F) {
+//		json2msgptest.AddSeedDir(f, "testdata/sysvars")
+//		json2msgptest.FuzzConvert(f, json2msgp.WithTypeHints(hints))
+//	}
+func FuzzConvert(f *testing.F, opts ...json2msgp.Option) {
+	f.Helper()
+	for _, seed := range seeds {
+		f.Add([]byte(seed))
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var v interface{}
+		if json.Unmarshal(data, &v) != nil {
+			return
+		}
+		out, err := json2msgp.ConvertWithOptions(v, opts...)
+		if err != nil {
+			return
+		}
+		if err = json2msgp.ValidateMsgp(out); err != nil {
+			t.Fatalf("invalid output %x: %s", out, err)
+		}
+	})
+}
+
+// FuzzConvertStream is like FuzzConvert, but fuzzes ConvertStreamWithOptions
+// with the fuzzed text as its input.  The options must produce a single
+// value, so WithMultipleDocuments and WithDelimiter are not supported.
+func FuzzConvertStream(f *testing.F, opts ...json2msgp.Option) {
+	f.Helper()
+	for _, seed := range seeds {
+		f.Add([]byte(seed))
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var out bytes.Buffer
+		if json2msgp.ConvertStreamWithOptions(bytes.NewReader(data), &out, opts...) != nil {
+			return
+		}
+		if err := json2msgp.ValidateMsgp(out.Bytes()); err != nil {
+			t.Fatalf("invalid output %x: %s", out.Bytes(), err)
+		}
+	})
+}
@@ -0,0 +1,27 @@
+package json2msgptest_test
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"testing"
+
+	"github.com/ndau/json2msgp"
+	"github.com/ndau/json2msgp/json2msgptest"
+)
+
+func FuzzConvert(f *testing.F) {
+	json2msgptest.AddSeedDir(f, "testdata")
+	json2msgptest.FuzzConvert(f)
+}
+
+func FuzzConvertStream(f *testing.F) {
+	json2msgptest.AddSeedDir(f, "testdata")
+	json2msgptest.FuzzConvertStream(f, json2msgp.WithAllErrors(), json2msgp.WithAddressExtension())
+}
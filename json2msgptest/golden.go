@@ -0,0 +1,156 @@
+// Package json2msgptest provides helpers for testing conversions with
+// json2msgp, so that downstream repositories can maintain their own golden
+// corpora of sysvars with little boilerplate.
+package json2msgptest
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"bytes"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ndau/json2msgp"
+	"github.com/pkg/errors"
+)
+
+// UpdateEnv is the environment variable which, if set to anything but ""
+// or "0", makes RunDir regenerate the expected output of its cases instead
+// of checking it.
+const UpdateEnv = "JSON2MSGP_UPDATE"
+
+// Case is a golden conversion: JSON converted with Hints must produce the
+// MSGP bytes in Hex.
+type Case struct {
+	Name  string
+	JSON  []byte
+	Hints map[string][]string
+	// Hex is in any form json2msgp.ParseHexdump accepts.
+	Hex string
+	// HexPath is the file Hex was read from, if any.
+	HexPath string
+}
+
+// Golden is a set of golden cases.
+type Golden struct {
+	Cases []Case
+	// Update makes Run write the actual output of each case read from a
+	// directory to its hex file, instead of checking it.  Cases without a
+	// hex file may then be added, and get one.
+	Update bool
+}
+
+// Add registers a case.
+func (g *Golden) Add(name, json string, hints map[string][]string, hex string) {
+	g.Cases = append(g.Cases, Case{Name: name, JSON: []byte(json), Hints: hints, Hex: hex})
+}
+
+// AddDir registers a case for each file NAME.json in dir and its
+// subdirectories.  Its expected output is read from NAME.hex, and its type
+// hints, if any, from NAME.hints.json.  Cases are named by their path
+// relative to dir, without the extension.
+func (g *Golden) AddDir(dir string) error {
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.HasSuffix(path, ".json") || strings.HasSuffix(path, ".hints.json") {
+			return nil
+		}
+		base := strings.TrimSuffix(path, ".json")
+		name, err := filepath.Rel(dir, base)
+		if err != nil {
+			return err
+		}
+		c := Case{Name: filepath.ToSlash(name), HexPath: base + ".hex"}
+		if c.JSON, err = os.ReadFile(path); err != nil {
+			return err
+		}
+		h, err := os.ReadFile(c.HexPath)
+		if err != nil && !(g.Update && os.IsNotExist(err)) {
+			return err
+		}
+		c.Hex = string(h)
+		h, err = os.ReadFile(base + ".hints.json")
+		if err == nil {
+			if c.Hints, err = json2msgp.ReadHints(bytes.NewReader(h)); err != nil {
+				return errors.Wrapf(err, "%s.hints.json", base)
+			}
+		} else if !os.IsNotExist(err) {
+			return err
+		}
+		g.Cases = append(g.Cases, c)
+		return nil
+	})
+	return errors.Wrap(err, "AddDir")
+}
+
+// Run checks each case in a subtest, converting with opts as well as the
+// case's hints.
+func (g *Golden) Run(t *testing.T, opts ...json2msgp.Option) {
+	t.Helper()
+	for _, c := range g.Cases {
+		c := c
+		t.Run(c.Name, func(t *testing.T) {
+			got, err := c.Convert(opts...)
+			if err != nil {
+				t.Fatalf("converting: %s", err)
+			}
+			if g.Update && c.HexPath != "" {
+				if err = os.WriteFile(c.HexPath, []byte(EncodeHex(got)), 0644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			MsgpEqual(t, c.Hex, got)
+		})
+	}
+}
+
+// RunDir registers the cases in dir, as for AddDir, and runs them.  If the
+// UpdateEnv environment variable is set, their expected output is
+// regenerated instead.
+func RunDir(t *testing.T, dir string, opts ...json2msgp.Option) {
+	t.Helper()
+	update := os.Getenv(UpdateEnv)
+	g := Golden{Update: update != "" && update != "0"}
+	if err := g.AddDir(dir); err != nil {
+		t.Fatal(err)
+	}
+	if len(g.Cases) == 0 {
+		t.Fatalf("no golden cases in %s", dir)
+	}
+	g.Run(t, opts...)
+}
+
+// Convert converts the case's JSON with opts as well as its hints, which
+// replace any hints in opts.  A case without hints keeps those of opts.
+func (c Case) Convert(opts ...json2msgp.Option) ([]byte, error) {
+	var out bytes.Buffer
+	if c.Hints != nil {
+		opts = append(opts[:len(opts):len(opts)], json2msgp.WithTypeHints(c.Hints))
+	}
+	err := json2msgp.ConvertStreamWithOptions(bytes.NewReader(c.JSON), &out, opts...)
+	return out.Bytes(), err
+}
+
+// DecodeHex decodes hex as json2msgp.ParseHexdump does.
+func DecodeHex(s string) ([]byte, error) {
+	return json2msgp.ParseHexdump(s)
+}
+
+// EncodeHex formats b as json2msgp.FormatHexdump does: 16 space-separated
+// bytes per line.
+func EncodeHex(b []byte) string {
+	return json2msgp.FormatHexdump(b)
+}
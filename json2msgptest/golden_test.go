@@ -0,0 +1,87 @@
+package json2msgptest_test
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ndau/json2msgp"
+	"github.com/ndau/json2msgp/json2msgptest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunDir(t *testing.T) {
+	json2msgptest.RunDir(t, "testdata")
+}
+
+func TestGolden(t *testing.T) {
+	var g json2msgptest.Golden
+	g.Add("null", `null`, nil, "c0")
+	g.Add("empty", `{"a": {}}`, nil, "81 a1 61 c0")
+	g.Run(t, json2msgp.WithEmptyAsNil())
+
+	// a case without hints keeps those of the options
+	hints := json2msgp.WithTypeHints(map[string][]string{"Fee": {"uint64"}})
+	got, err := json2msgptest.Case{JSON: []byte(`{"Fee":200}`)}.Convert(hints)
+	require.NoError(t, err)
+	require.Equal(t, []byte("\x81\xa3Fee\xcc\xc8"), got)
+
+	// every case needs its expected output
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "x.json"), []byte("1"), 0600))
+	require.Error(t, g.AddDir(dir))
+}
+
+func TestDecodeHex(t *testing.T) {
+	b, err := json2msgptest.DecodeHex("81 # map\n a1 61\n\tc0")
+	require.NoError(t, err)
+	require.Equal(t, []byte("\x81\xa1a\xc0"), b)
+
+	_, err = json2msgptest.DecodeHex("8")
+	require.Error(t, err)
+}
+
+func TestUpdate(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "sub"), 0700))
+	write := func(name, content string) {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0600))
+	}
+	write("a.json", `{"Fee": 200}`)
+	write("a.hints.json", `{"Fee": ["uint16"]}`)
+	write("a.hex", "c0")
+	write("sub/b.json", `"oACI"`)
+
+	t.Setenv(json2msgptest.UpdateEnv, "1")
+	json2msgptest.RunDir(t, dir)
+
+	hex, err := os.ReadFile(filepath.Join(dir, "a.hex"))
+	require.NoError(t, err)
+	require.Equal(t, "81 a3 46 65 65 cc c8\n", string(hex))
+	hex, err = os.ReadFile(filepath.Join(dir, "sub", "b.hex"))
+	require.NoError(t, err)
+	require.Equal(t, "c4 03 a0 00 88\n", string(hex))
+
+	var g json2msgptest.Golden
+	require.NoError(t, g.AddDir(dir))
+	require.Equal(t, "a", g.Cases[0].Name)
+	require.Equal(t, "sub/b", g.Cases[1].Name)
+	g.Run(t)
+}
+
+func TestEncodeHex(t *testing.T) {
+	b := make([]byte, 17)
+	for i := range b {
+		b[i] = byte(i)
+	}
+	require.Equal(t, "00 01 02 03 04 05 06 07 08 09 0a 0b 0c 0d 0e 0f\n10\n", json2msgptest.EncodeHex(b))
+}
@@ -0,0 +1,239 @@
+package json2msgptest
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/ndau/json2msgp"
+	"github.com/tinylib/msgp/msgp"
+)
+
+// Harness checks that converting random JSON-shaped values to MSGP preserves
+// their structure.
+//
+// Each value is converted, decoded again with msgp, and compared with the
+// original.  The comparison allows for what the conversion legitimately
+// changes: numbers may come back as any numeric type of the same value,
+// strings as their bytes or base64-decoded bytes, and empty maps and arrays
+// as nil.  A map key whose value is null must be kept, with a nil value.
+type Harness struct {
+	// Seed seeds the random values.  If it is zero, the current time is used.
+	// Either way, it is reported on failure so that the run can be repeated.
+	Seed int64
+	// Values is how many values to check; the default is 100.
+	Values int
+	// MaxDepth is how deeply the default generator nests maps and arrays;
+	// the default is 4.
+	MaxDepth int
+	// Generate returns a random value to convert.  The default is
+	// RandomValue, whose values convert without type hints; hint
+	// configurations need a generator whose values suit the hints.
+	Generate func(r *rand.Rand) interface{}
+	// Options configure the conversion.
+	Options []json2msgp.Option
+}
+
+// Run checks the values, failing t at the first which does not survive
+// conversion.
+func (h Harness) Run(t testing.TB) {
+	t.Helper()
+	seed := h.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	values := h.Values
+	if values == 0 {
+		values = 100
+	}
+	generate := h.Generate
+	if generate == nil {
+		depth := h.MaxDepth
+		if depth == 0 {
+			depth = 4
+		}
+		generate = func(r *rand.Rand) interface{} { return RandomValue(r, depth) }
+	}
+
+	r := rand.New(rand.NewSource(seed))
+	for i := 0; i < values; i++ {
+		v := generate(r)
+		if err := h.check(v); err != nil {
+			js, _ := json.Marshal(v)
+			t.Fatalf("seed %d, value %d: %s\ninput: %s", seed, i, err, js)
+			return
+		}
+	}
+}
+
+// check converts v and compares the result with it.
+func (h Harness) check(v interface{}) error {
+	b, err := json2msgp.ConvertWithOptions(v, h.Options...)
+	if err != nil {
+		return fmt.Errorf("converting: %w", err)
+	}
+	got, rest, err := msgp.ReadIntfBytes(b)
+	if err != nil {
+		return fmt.Errorf("decoding: %w", err)
+	}
+	if len(rest) > 0 {
+		return fmt.Errorf("%d bytes of trailing data", len(rest))
+	}
+	return compare("$", v, got)
+}
+
+// compare returns an error describing the first difference between the JSON
+// value want and the decoded MSGP value got.
+func compare(path string, want, got interface{}) error {
+	mismatch := func() error {
+		return fmt.Errorf("%s: %#v became %#v", path, want, got)
+	}
+	switch w := want.(type) {
+	case nil:
+		if got != nil {
+			return mismatch()
+		}
+	case bool:
+		if g, ok := got.(bool); !ok || g != w {
+			return mismatch()
+		}
+	case float64:
+		if !sameNumber(w, got) {
+			return mismatch()
+		}
+	case string:
+		switch g := got.(type) {
+		case string:
+			if g != w {
+				return mismatch()
+			}
+		case []byte:
+			decoded, err := base64.StdEncoding.DecodeString(w)
+			if string(g) != w && (err != nil || !bytes.Equal(g, decoded)) {
+				return mismatch()
+			}
+		default:
+			return mismatch()
+		}
+	case []interface{}:
+		if got == nil && len(w) == 0 {
+			return nil
+		}
+		g, ok := got.([]interface{})
+		if !ok || len(g) != len(w) {
+			return mismatch()
+		}
+		for i := range w {
+			if err := compare(fmt.Sprintf("%s[%d]", path, i), w[i], g[i]); err != nil {
+				return err
+			}
+		}
+	case map[string]interface{}:
+		if got == nil && len(w) == 0 {
+			return nil
+		}
+		g, ok := got.(map[string]interface{})
+		if !ok {
+			return mismatch()
+		}
+		keys := make([]string, 0, len(w))
+		for key := range w {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			gv, present := g[key]
+			if !present {
+				return fmt.Errorf("%s: key %q is missing", path, key)
+			}
+			if err := compare(fmt.Sprintf("%s[%q]", path, key), w[key], gv); err != nil {
+				return err
+			}
+		}
+		for key := range g {
+			if _, ok := w[key]; !ok {
+				return fmt.Errorf("%s: unexpected key %q", path, key)
+			}
+		}
+	default:
+		return fmt.Errorf("%s: %T is not a JSON type", path, want)
+	}
+	return nil
+}
+
+// sameNumber is true if got is a number equal to want.
+func sameNumber(want float64, got interface{}) bool {
+	switch g := got.(type) {
+	case int64:
+		return float64(g) == want
+	case uint64:
+		return float64(g) == want
+	case float64:
+		return g == want
+	case float32:
+		return g == float32(want)
+	}
+	return false
+}
+
+// runes are what RandomValue builds strings and keys from: some which
+// suggest base64, some punctuation, some multibyte and some control
+// characters.
+var runes = []rune("abcXYZ019+/= !.-_\"\\é€😀\x00\t\n")
+
+// RandomValue returns a random value of the kind json.Unmarshal produces,
+// nesting maps and arrays at most depth deep.  Numbers are integral, so that
+// the value converts without type hints.
+func RandomValue(r *rand.Rand, depth int) interface{} {
+	kinds := 7
+	if depth <= 0 {
+		kinds = 5
+	}
+	switch r.Intn(kinds) {
+	case 0:
+		return nil
+	case 1:
+		return r.Intn(2) == 0
+	case 2:
+		// integral, and exactly representable
+		return float64(r.Int63n(1<<53) - 1<<52)
+	case 3:
+		return float64(r.Intn(256) - 128)
+	case 4:
+		return randomString(r)
+	case 5:
+		a := make([]interface{}, r.Intn(5))
+		for i := range a {
+			a[i] = RandomValue(r, depth-1)
+		}
+		return a
+	default:
+		m := make(map[string]interface{})
+		for n := r.Intn(5); n > 0; n-- {
+			m[randomString(r)] = RandomValue(r, depth-1)
+		}
+		return m
+	}
+}
+
+func randomString(r *rand.Rand) string {
+	s := make([]rune, r.Intn(12))
+	for i := range s {
+		s[i] = runes[r.Intn(len(runes))]
+	}
+	return string(s)
+}
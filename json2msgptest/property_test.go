@@ -0,0 +1,67 @@
+package json2msgptest_test
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/ndau/json2msgp"
+	"github.com/ndau/json2msgp/json2msgptest"
+	"github.com/stretchr/testify/require"
+)
+
+// recorder is a testing.TB which records failures instead of failing.
+type recorder struct {
+	testing.TB
+	failure string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Fatalf(format string, args ...interface{}) {
+	r.failure = fmt.Sprintf(format, args...)
+}
+
+func TestHarness(t *testing.T) {
+	json2msgptest.Harness{Seed: 1, Values: 500}.Run(t)
+	json2msgptest.Harness{
+		Seed:    2,
+		Options: []json2msgp.Option{json2msgp.WithEmptyAsNil(), json2msgp.WithCanonical()},
+	}.Run(t)
+
+	// hinted values need a suitable generator
+	hints := map[string][]string{"Rate": []string{"float64"}, "Fee": []string{"uint64"}}
+	json2msgptest.Harness{
+		Generate: func(r *rand.Rand) interface{} {
+			return map[string]interface{}{"Rate": r.Float64(), "Fee": float64(r.Intn(1000))}
+		},
+		Options: []json2msgp.Option{json2msgp.WithTypeHints(hints)},
+	}.Run(t)
+}
+
+func TestHarnessFails(t *testing.T) {
+	rec := &recorder{TB: t}
+	json2msgptest.Harness{
+		Seed:     7,
+		Generate: func(r *rand.Rand) interface{} { return map[string]interface{}{"Rate": 1.5} },
+	}.Run(rec)
+	require.Contains(t, rec.failure, "seed 7, value 0: converting:")
+	require.Contains(t, rec.failure, `input: {"Rate":1.5}`)
+
+	// a conversion which changes the value is caught
+	rec = &recorder{TB: t}
+	json2msgptest.Harness{
+		Generate: func(r *rand.Rand) interface{} { return map[string]interface{}{"s": "a\x00!"} },
+		Options:  []json2msgp.Option{json2msgp.WithControlCharPolicy(json2msgp.ControlStrip)},
+	}.Run(rec)
+	require.Contains(t, rec.failure, `$["s"]: "a\x00!" became "a!"`)
+}
@@ -0,0 +1,112 @@
+package json2msgp
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// jsonSchemaDialect is the JSON Schema draft InferSchema writes.
+const jsonSchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// InferSchema converts the sample document data with typeHints, and returns
+// a JSON Schema describing its structure and the MSGP types it resolved to,
+// as a contract derived from real data.
+//
+// Every key of a sample object is required, and no others are allowed.  The
+// elements of an array share a schema, or if they differ, the schema of each
+// distinct one is allowed through "anyOf".  Besides the standard keywords,
+// each value has "x-msgp", the MSGP type it was written as, such as "uint"
+// or "bin", and "x-msgp-hint", the type hint which applied, if any.
+func InferSchema(data []byte, typeHints map[string][]string) ([]byte, error) {
+	in, err := newConverter().newDecoder(data).single()
+	if err != nil {
+		return nil, errors.Wrap(err, "InferSchema")
+	}
+	report, err := Explain(in, typeHints)
+	if err != nil {
+		return nil, errors.Wrap(err, "InferSchema")
+	}
+	schema := inferSchema(in, report.Root)
+	schema["$schema"] = jsonSchemaDialect
+	out, err := json.MarshalIndent(schema, "", "  ")
+	return out, errors.Wrap(err, "InferSchema")
+}
+
+// inferSchema returns the JSON Schema of in, whose conversion node describes.
+func inferSchema(in interface{}, node *Node) map[string]interface{} {
+	schema := map[string]interface{}{}
+	if node.OutputType != "" {
+		schema["x-msgp"] = node.OutputType
+	}
+	if node.Hint != "" {
+		schema["x-msgp-hint"] = node.Hint
+	}
+	switch x := in.(type) {
+	case map[string]interface{}:
+		keys := sortedKeys(x)
+		properties := make(map[string]interface{}, len(keys))
+		for i, key := range keys {
+			properties[key] = inferSchema(x[key], node.Children[i])
+		}
+		schema["type"] = "object"
+		schema["properties"] = properties
+		schema["required"] = keys
+		schema["additionalProperties"] = false
+	case []interface{}:
+		schema["type"] = "array"
+		var items []interface{}
+		seen := make(map[string]bool)
+		for i, elem := range x {
+			item := inferSchema(elem, node.Children[i])
+			// json sorts keys, so equal schemas marshal equally
+			text, _ := json.Marshal(item)
+			if !seen[string(text)] {
+				seen[string(text)] = true
+				items = append(items, item)
+			}
+		}
+		switch len(items) {
+		case 0:
+		case 1:
+			schema["items"] = items[0]
+		default:
+			schema["items"] = map[string]interface{}{"anyOf": items}
+		}
+	case string:
+		schema["type"] = "string"
+		switch {
+		case node.Outcome == "address":
+			schema["format"] = "ndau-address"
+		case node.OutputType == "bin":
+			// JSON strings are valid utf-8, so bin was decoded from base64
+			schema["contentEncoding"] = "base64"
+		}
+	case float64:
+		schema["type"] = "number"
+		switch node.OutputType {
+		case "int", "uint":
+			schema["type"] = "integer"
+			// small integers are written compactly, as fixints, whatever
+			// their hint; the hint says what they may hold
+			if strings.HasPrefix(node.Hint, "uint") {
+				schema["x-msgp"] = "uint"
+			}
+		}
+	case bool:
+		schema["type"] = "boolean"
+	case nil:
+		schema["type"] = "null"
+	}
+	return schema
+}
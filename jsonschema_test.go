@@ -0,0 +1,61 @@
+package json2msgp_test
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"testing"
+
+	"github.com/ndau/json2msgp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInferSchema(t *testing.T) {
+	in := `{
+		"Fee": 100,
+		"Rate": [[1.5, 2], [2.5, 3]],
+		"Script": "oAAgiA==",
+		"Name": "x",
+		"On": true,
+		"Gone": null,
+		"Mixed": [1, "a", 2]
+	}`
+	hints := map[string][]string{"Fee": {"uint64"}, "Rate": {"float64", "int64"}}
+	got, err := json2msgp.InferSchema([]byte(in), hints)
+	require.NoError(t, err)
+	require.JSONEq(t, `{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"type": "object",
+		"x-msgp": "map",
+		"additionalProperties": false,
+		"required": ["Fee", "Gone", "Mixed", "Name", "On", "Rate", "Script"],
+		"properties": {
+			"Fee": {"type": "integer", "x-msgp": "uint", "x-msgp-hint": "uint64"},
+			"Gone": {"type": "null", "x-msgp": "nil"},
+			"Mixed": {"type": "array", "x-msgp": "array", "items": {"anyOf": [
+				{"type": "integer", "x-msgp": "int"},
+				{"type": "string", "x-msgp": "str"}
+			]}},
+			"Name": {"type": "string", "x-msgp": "str"},
+			"On": {"type": "boolean", "x-msgp": "bool"},
+			"Rate": {"type": "array", "x-msgp": "array", "items": {
+				"type": "array", "x-msgp": "array", "items": {"anyOf": [
+					{"type": "number", "x-msgp": "float64", "x-msgp-hint": "float64"},
+					{"type": "integer", "x-msgp": "int", "x-msgp-hint": "int64"}
+				]}
+			}},
+			"Script": {"type": "string", "x-msgp": "bin", "contentEncoding": "base64"}
+		}
+	}`, string(got))
+
+	_, err = json2msgp.InferSchema([]byte(`{`), nil)
+	require.Error(t, err)
+	_, err = json2msgp.InferSchema([]byte(`1.5`), nil)
+	require.Error(t, err)
+}
@@ -0,0 +1,113 @@
+package json2msgp
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"runtime"
+	"time"
+)
+
+// Limits bundles the resource limits of a conversion.  Zero fields impose no
+// limit, except MaxDepth, which keeps DefaultMaxDepth.
+type Limits struct {
+	// See WithMaxDepth.
+	MaxDepth int
+	// See WithMaxInputSize.
+	MaxInputSize int64
+	// See WithMaxOutputSize.
+	MaxOutputSize int
+	// See WithMaxStringLength.
+	MaxStringLength int
+	// See WithMaxBase64Length.  StrictDefaults leaves this unset, since it
+	// changes how long base64 strings are encoded.
+	MaxBase64Length int
+	// See WithTimeout.
+	Timeout time.Duration
+	// The number of documents converted at once by all conversions sharing
+	// the Option returned by WithLimits.  Others wait their turn.
+	Parallelism int
+}
+
+// StrictDefaults returns limits suitable for services exposed to untrusted
+// input.  They comfortably fit every ndau system variable.
+func StrictDefaults() Limits {
+	return Limits{
+		MaxDepth:        64,
+		MaxInputSize:    4 << 20,
+		MaxOutputSize:   4 << 20,
+		MaxStringLength: 1 << 20,
+		Timeout:         5 * time.Second,
+		Parallelism:     runtime.NumCPU(),
+	}
+}
+
+// WithLimits applies all of limits at once, e.g.:
+//
+//	handler := json2msgp.Middleware(backend, json2msgp.WithLimits(json2msgp.StrictDefaults()))
+//
+// Parallelism is enforced across every conversion configured with the
+// returned Option, so create it once and share it.
+func WithLimits(limits Limits) Option {
+	var slots chan struct{}
+	if limits.Parallelism > 0 {
+		slots = make(chan struct{}, limits.Parallelism)
+	}
+	return func(c *Converter) {
+		if limits.MaxDepth != 0 {
+			c.maxDepth = limits.MaxDepth
+		}
+		c.maxInputSize = limits.MaxInputSize
+		c.maxOutputSize = limits.MaxOutputSize
+		c.maxStringLength = limits.MaxStringLength
+		c.maxBase64Length = limits.MaxBase64Length
+		c.timeout = limits.Timeout
+		c.slots = slots
+	}
+}
+
+// beginDocument waits for a slot to convert a document in, and starts timing
+// it.  It returns false if a document is already in progress.
+func (c *Converter) beginDocument() bool {
+	if c.inDocument {
+		return false
+	}
+	c.inDocument = true
+	if c.slots != nil {
+		c.slots <- struct{}{}
+	}
+	c.startClock()
+	return true
+}
+
+// endDocument ends the document in progress, freeing its slot.
+func (c *Converter) endDocument() {
+	c.stopClock()
+	if c.slots != nil {
+		<-c.slots
+	}
+	c.inDocument = false
+}
+
+// startClock starts timing a document, if there is a timeout.
+func (c *Converter) startClock() {
+	if c.timeout > 0 {
+		c.deadline = time.Now().Add(c.timeout)
+	}
+}
+
+// stopClock ends timing a document.
+func (c *Converter) stopClock() {
+	c.deadline = time.Time{}
+}
+
+// expired reports whether the current document has run out of time.
+func (c *Converter) expired() bool {
+	return !c.deadline.IsZero() && time.Now().After(c.deadline)
+}
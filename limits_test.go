@@ -0,0 +1,80 @@
+package json2msgp_test
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ndau/json2msgp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStrictDefaults(t *testing.T) {
+	strict := json2msgp.WithLimits(json2msgp.StrictDefaults())
+
+	err := json2msgp.ConvertStreamWithOptions(strings.NewReader(`{"a":[1,"foo"]}`), &bytes.Buffer{}, strict)
+	require.NoError(t, err)
+
+	deep := strings.Repeat("[", 100) + strings.Repeat("]", 100)
+	err = json2msgp.ConvertStreamWithOptions(strings.NewReader(deep), &bytes.Buffer{}, strict)
+	require.True(t, errors.Is(err, json2msgp.ErrMaxDepth), "got %v", err)
+
+	long := `"` + strings.Repeat("a", 1<<20+1) + `"`
+	err = json2msgp.ConvertStreamWithOptions(strings.NewReader(long), &bytes.Buffer{}, strict)
+	require.True(t, errors.Is(err, json2msgp.ErrStringTooLong), "got %v", err)
+}
+
+func TestLimitsParallelism(t *testing.T) {
+	var active, peak int32
+	limits := json2msgp.WithLimits(json2msgp.Limits{Parallelism: 2})
+	// the metrics hook runs while the document holds its slot
+	metrics := json2msgp.WithMetrics(decisionFunc(func() {
+		n := atomic.AddInt32(&active, 1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		atomic.AddInt32(&active, -1)
+	}))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			in := strings.NewReader(`[1] [2] [3]`)
+			err := json2msgp.ConvertStreamWithOptions(in, &bytes.Buffer{}, limits, metrics, json2msgp.WithMultipleDocuments())
+			require.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+	require.True(t, peak <= 2, "peak %d", peak)
+
+	// a single slot doesn't deadlock the stream functions
+	one := json2msgp.WithLimits(json2msgp.Limits{Parallelism: 1})
+	err := json2msgp.ConvertStreamWithOptions(strings.NewReader(`[1] [2]`), &bytes.Buffer{}, one, json2msgp.WithMultipleDocuments())
+	require.NoError(t, err)
+	_, err = json2msgp.ConvertWithOptions([]interface{}{1.0}, one)
+	require.NoError(t, err)
+}
+
+// decisionFunc is a Metrics which calls itself for each decision.
+type decisionFunc func()
+
+func (f decisionFunc) Conversion(elapsed time.Duration, bytesIn, bytesOut int, err error) {}
+func (f decisionFunc) Decision(outcome string)                                            { f() }
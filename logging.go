@@ -0,0 +1,54 @@
+package json2msgp
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"context"
+	"log/slog"
+)
+
+// WithLogger logs the conversion to logger at debug level: one event per
+// heuristic or hint decision, scoped by the JSONPath of the value, and one
+// event per completed document.
+func WithLogger(logger *slog.Logger) Option {
+	return func(c *Converter) {
+		c.logger = logger
+	}
+}
+
+// debugging is true if debug events would be logged.
+func (c *Converter) debugging() bool {
+	return c.logger != nil && c.logger.Enabled(context.Background(), slog.LevelDebug)
+}
+
+// logDecision logs the hint and outcome which determined the encoding of the
+// current value.
+func (c *Converter) logDecision(hint, outcome string) {
+	if !c.debugging() {
+		return
+	}
+	c.logger.Debug("json2msgp decision",
+		slog.String("path", c.pathString()),
+		slog.String("hint", hint),
+		slog.String("outcome", outcome),
+	)
+}
+
+// logDocument logs the result of converting a whole document.
+func (c *Converter) logDocument(size int, err error) {
+	if !c.debugging() {
+		return
+	}
+	if err != nil {
+		c.logger.Debug("json2msgp conversion failed", slog.String("error", err.Error()))
+		return
+	}
+	c.logger.Debug("json2msgp conversion", slog.Int("bytes", size))
+}
@@ -0,0 +1,42 @@
+package json2msgp_test
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+
+	"github.com/ndau/json2msgp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	_, err := json2msgp.ConvertWithOptions(
+		map[string]interface{}{"Script": "oACI", "Fee": 1.0},
+		json2msgp.WithLogger(logger),
+	)
+	require.NoError(t, err)
+	require.Contains(t, buf.String(), `msg="json2msgp decision" path=$.Script hint="" outcome=base64`)
+	require.Contains(t, buf.String(), `msg="json2msgp decision" path=$.Fee hint="" outcome=integer`)
+	require.Contains(t, buf.String(), `msg="json2msgp conversion" bytes=`)
+}
+
+func TestWithLoggerQuietAboveDebug(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, nil))
+
+	_, err := json2msgp.ConvertWithOptions("oACI", json2msgp.WithLogger(logger))
+	require.NoError(t, err)
+	require.Empty(t, buf.String())
+}
@@ -0,0 +1,131 @@
+package json2msgp
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// Manifest declares many system variables at once, for a SetSysvar batch:
+//
+//	{
+//	  "hints": {"ChangeOn": ["uint64"]},
+//	  "sysvars": {
+//	    "EAIFeeTable": {
+//	      "value": [{"Fee": 4000000, "To": ["ndnf9ffbz..."]}],
+//	      "hints": {"Fee": ["uint64"]}
+//	    },
+//	    "DefaultRecourseDuration": {"value": 172800000000}
+//	  }
+//	}
+type Manifest struct {
+	// Hints apply to every system variable, except for keys which its own
+	// hints name.
+	Hints map[string][]string `json:"hints,omitempty"`
+	// The system variables, by name.
+	Sysvars map[string]ManifestEntry `json:"sysvars"`
+}
+
+// ManifestEntry is the declaration of one system variable in a Manifest.
+type ManifestEntry struct {
+	// The JSON value of the system variable.
+	Value json.RawMessage `json:"value"`
+	// Type hints for this system variable.
+	Hints map[string][]string `json:"hints,omitempty"`
+}
+
+// ReadManifest reads a Manifest from JSON.  Unknown fields are an error, so
+// that a misspelled "hints" is not silently ignored.
+func ReadManifest(r io.Reader) (*Manifest, error) {
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	m := &Manifest{}
+	if err := dec.Decode(m); err != nil {
+		return nil, errors.Wrap(err, "ReadManifest")
+	}
+	return m, nil
+}
+
+// ReadManifestYAML reads a Manifest from YAML, with the same fields as
+// ReadManifest reads from JSON.  Each value is converted to JSON text, with
+// the keys of its maps as strings.
+func ReadManifestYAML(r io.Reader) (*Manifest, error) {
+	var doc struct {
+		Hints   map[string][]string `yaml:"hints"`
+		Sysvars map[string]struct {
+			Value yaml.Node           `yaml:"value"`
+			Hints map[string][]string `yaml:"hints"`
+		} `yaml:"sysvars"`
+	}
+	dec := yaml.NewDecoder(r)
+	dec.KnownFields(true)
+	if err := dec.Decode(&doc); err != nil {
+		return nil, errors.Wrap(err, "ReadManifestYAML")
+	}
+	m := &Manifest{Hints: doc.Hints, Sysvars: make(map[string]ManifestEntry, len(doc.Sysvars))}
+	for name, entry := range doc.Sysvars {
+		m.Sysvars[name] = ManifestEntry{Hints: entry.Hints}
+		if entry.Value.Kind == 0 {
+			continue
+		}
+		var value interface{}
+		if err := entry.Value.Decode(&value); err != nil {
+			return nil, errors.Wrapf(err, "ReadManifestYAML: sysvar %s", name)
+		}
+		js, err := json.Marshal(value)
+		if err != nil {
+			return nil, errors.Wrapf(err, "ReadManifestYAML: sysvar %s", name)
+		}
+		m.Sysvars[name] = ManifestEntry{Value: js, Hints: entry.Hints}
+	}
+	return m, nil
+}
+
+// Convert converts every system variable of the manifest, returning their
+// MSGP encodings by name.  Each is converted as by WithSysvar, so registered
+// types apply; opts apply to all of them.
+//
+// Every system variable is converted even if some fail; the error is then an
+// ErrorList with an entry per failure.
+func (m *Manifest) Convert(opts ...Option) (map[string][]byte, error) {
+	out := make(map[string][]byte, len(m.Sysvars))
+	var errs ErrorList
+	for _, name := range sortedKeys(m.Sysvars) {
+		entry := m.Sysvars[name]
+		if entry.Value == nil {
+			errs = append(errs, errors.Errorf("sysvar %s: no value", name))
+			continue
+		}
+		hints := make(map[string][]string, len(m.Hints)+len(entry.Hints))
+		for key, hint := range m.Hints {
+			hints[key] = hint
+		}
+		for key, hint := range entry.Hints {
+			hints[key] = hint
+		}
+
+		var buf bytes.Buffer
+		entryOpts := append([]Option{WithTypeHints(hints), WithSysvar(name)}, opts...)
+		if err := ConvertStreamWithOptions(bytes.NewReader(entry.Value), &buf, entryOpts...); err != nil {
+			errs = append(errs, errors.Wrapf(err, "sysvar %s", name))
+			continue
+		}
+		out[name] = buf.Bytes()
+	}
+	if len(errs) > 0 {
+		return out, errs
+	}
+	return out, nil
+}
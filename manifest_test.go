@@ -0,0 +1,104 @@
+package json2msgp_test
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ndau/json2msgp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestManifest(t *testing.T) {
+	m, err := json2msgp.ReadManifest(strings.NewReader(`{
+		"hints": {"Fee": ["int64"]},
+		"sysvars": {
+			"EAIFeeTable": {"value": [{"Fee": 200, "To": null}], "hints": {"Fee": ["uint64"]}},
+			"Fees": {"value": {"Fee": 200}},
+			"NodeGoodnessFunction": {"value": "DwA="}
+		}
+	}`))
+	require.NoError(t, err)
+
+	got, err := m.Convert()
+	require.NoError(t, err)
+	want := map[string]string{
+		"EAIFeeTable":          "91 82 a3 46 65 65 cc c8 a2 54 6f c0",
+		"Fees":                 "81 a3 46 65 65 d1 00 c8",
+		"NodeGoodnessFunction": "c4 02 0f 00",
+	}
+	require.Len(t, got, len(want))
+	for name, wantHex := range want {
+		require.Equal(t, strings.Replace(wantHex, " ", "", -1), hex.EncodeToString(got[name]), name)
+	}
+}
+
+func TestReadManifestYAML(t *testing.T) {
+	m, err := json2msgp.ReadManifestYAML(strings.NewReader(`
+hints:
+  Fee: [int64]
+sysvars:
+  EAIFeeTable:
+    value:
+      - Fee: 200
+        To: null
+    hints:
+      Fee: [uint64]
+  Fees:
+    value: {Fee: 200}
+  NodeGoodnessFunction:
+    value: DwA=
+  Missing: {}
+`))
+	require.NoError(t, err)
+	require.Nil(t, m.Sysvars["Missing"].Value)
+
+	delete(m.Sysvars, "Missing")
+	got, err := m.Convert()
+	require.NoError(t, err)
+	want := map[string]string{
+		"EAIFeeTable":          "91 82 a3 46 65 65 cc c8 a2 54 6f c0",
+		"Fees":                 "81 a3 46 65 65 d1 00 c8",
+		"NodeGoodnessFunction": "c4 02 0f 00",
+	}
+	require.Len(t, got, len(want))
+	for name, wantHex := range want {
+		require.Equal(t, strings.Replace(wantHex, " ", "", -1), hex.EncodeToString(got[name]), name)
+	}
+
+	_, err = json2msgp.ReadManifestYAML(strings.NewReader("hint: {}\nsysvars: {}\n"))
+	require.Error(t, err)
+	_, err = json2msgp.ReadManifestYAML(strings.NewReader("sysvars:\n  A:\n    value: {[1]: x}\n"))
+	require.Error(t, err)
+}
+
+func TestManifestErrors(t *testing.T) {
+	_, err := json2msgp.ReadManifest(strings.NewReader(`{"hint": {}, "sysvars": {}}`))
+	require.Error(t, err)
+
+	m, err := json2msgp.ReadManifest(strings.NewReader(`{
+		"sysvars": {
+			"A": {"value": 1.5},
+			"B": {"value": 1},
+			"C": {}
+		}
+	}`))
+	require.NoError(t, err)
+	got, err := m.Convert()
+	var list json2msgp.ErrorList
+	require.True(t, errors.As(err, &list))
+	require.Len(t, list, 2)
+	require.True(t, errors.Is(list[0], json2msgp.ErrUnhintedNumber))
+	require.Contains(t, list[1].Error(), "sysvar C")
+	require.Equal(t, []byte{0x01}, got["B"])
+}
@@ -0,0 +1,241 @@
+package json2msgp
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"hash/maphash"
+	"math"
+	"math/bits"
+	"reflect"
+	"strings"
+)
+
+// WithMemoization caches the encoding of each map and array within a
+// document, so that structurally identical subtrees, such as the public key
+// arrays repeated throughout the svi, are converted only once.
+//
+// Subtrees are identified by a 128-bit hash of their contents, together with
+// everything else which can affect their encoding: the key and hint index
+// they are converted under, and their depth.  Hashing the document costs an
+// extra pass over it, so the option only pays for documents with repetition.
+//
+// Memoization is skipped while anything observes the individual values of a
+// conversion: Explain, WithTrace, WithWarnings, WithLogger, WithMetrics,
+// WithAuditLog and Bench.
+func WithMemoization() Option {
+	return func(c *Converter) {
+		c.memoize = true
+	}
+}
+
+// memoKey identifies the conversion of a subtree.
+type memoKey struct {
+	hash treeHash
+	key  string
+	// the position of the subtree within nested arrays, as hintPosition
+	hint  string
+	depth int
+	// the path of the subtree, only when path hints may tell it apart
+	path string
+}
+
+// memoEntry is the result of converting a subtree: its encoding, and the
+// current key it left behind.
+type memoEntry struct {
+	out []byte
+	key string
+}
+
+// treeHash is a 128-bit hash of a subtree, made of two independently seeded
+// and mixed 64-bit halves.
+type treeHash [2]uint64
+
+// treeID identifies a map or array in memory.  A slice is identified by its
+// length as well as its first element, as slices of one array can share it.
+type treeID struct {
+	ptr uintptr
+	len int
+}
+
+// memo holds the hashes of the subtrees of a document, and the encodings of
+// those converted so far.
+type memo struct {
+	seeds   [2]maphash.Seed
+	hashes  map[treeID]treeHash
+	entries map[memoKey]memoEntry
+}
+
+// newMemo hashes every subtree of in, bottom up, so that each value is
+// hashed only once.
+func newMemo(in interface{}) *memo {
+	m := &memo{
+		seeds:   [2]maphash.Seed{maphash.MakeSeed(), maphash.MakeSeed()},
+		hashes:  make(map[treeID]treeHash),
+		entries: make(map[memoKey]memoEntry),
+	}
+	m.hash(in)
+	return m
+}
+
+// observed is true if something needs to see every value converted.
+func (c *Converter) observed() bool {
+	return c.report != nil || c.trace != nil || c.warnings != nil || c.logger != nil ||
+		c.metrics != nil || c.audit != nil || c.profile != nil
+}
+
+// convertMemoized converts in, reusing the encoding of an identical subtree
+// if there is one.
+func (c *Converter) convertMemoized(in interface{}, buffer []byte) ([]byte, error) {
+	id, ok := identify(in)
+	if !ok {
+		return c.convertValue(in, buffer)
+	}
+	hash, ok := c.memo.hashes[id]
+	if !ok {
+		return c.convertValue(in, buffer)
+	}
+	key := memoKey{hash: hash, key: c.currentKey, hint: strings.Join(hintPosition(c.path), ""), depth: len(c.path)}
+	if len(c.pathHints) > 0 {
+		key.path = strings.Join(c.path, "")
+	}
+	if _, isMap := in.(map[string]interface{}); isMap {
+		// a map's values are converted under their own keys, so the current
+		// key only matters to the width of its header
+		if _, ok := c.keyHeaderWidths[c.currentKey]; !ok {
+			key.key = ""
+		}
+	}
+	if entry, ok := c.memo.entries[key]; ok {
+		c.currentKey = entry.key
+		return append(buffer, entry.out...), nil
+	}
+
+	start, errs := len(buffer), len(c.errs)
+	buffer, err := c.convertValue(in, buffer)
+	if err == nil && len(c.errs) == errs {
+		c.memo.entries[key] = memoEntry{
+			out: append([]byte(nil), buffer[start:]...),
+			key: c.currentKey,
+		}
+	}
+	return buffer, err
+}
+
+// identify returns the identity of a non-empty map or array.
+func identify(v interface{}) (treeID, bool) {
+	switch x := v.(type) {
+	case map[string]interface{}:
+		if len(x) > 0 {
+			return treeID{ptr: reflect.ValueOf(x).Pointer()}, true
+		}
+	case []interface{}:
+		if len(x) > 0 {
+			return treeID{ptr: reflect.ValueOf(x).Pointer(), len: len(x)}, true
+		}
+	}
+	return treeID{}, false
+}
+
+// Constants from xxHash, which mix the words of a treeHash.
+const (
+	prime1 = 11400714785074694791
+	prime2 = 14029467366897019727
+	prime3 = 1609587929392839161
+	prime4 = 9650029242287828579
+)
+
+// add mixes a word into each half of h.
+func (h *treeHash) add(x0, x1 uint64) {
+	h[0] = bits.RotateLeft64(h[0]^(x0*prime2), 31) * prime1
+	h[1] = bits.RotateLeft64(h[1]^(x1*prime4), 27) * prime3
+}
+
+// addString mixes the independently seeded hashes of s into h.
+func (m *memo) addString(h *treeHash, s string) {
+	h.add(maphash.String(m.seeds[0], s), maphash.String(m.seeds[1], s))
+}
+
+// hash records the hash of v and its subtrees, and returns it.  It returns
+// false for a value of a type it can't hash.
+func (m *memo) hash(v interface{}) (treeHash, bool) {
+	id, composite := identify(v)
+	if composite {
+		if hash, ok := m.hashes[id]; ok {
+			return hash, true
+		}
+	}
+
+	var h treeHash
+	switch x := v.(type) {
+	case nil:
+		h.add('n', 'n')
+	case bool:
+		if x {
+			h.add('t', 't')
+		} else {
+			h.add('f', 'f')
+		}
+	case float64:
+		h.add('d', 'd')
+		h.add(math.Float64bits(x), math.Float64bits(x))
+	case float32:
+		h.add('e', 'e')
+		h.add(uint64(math.Float32bits(x)), uint64(math.Float32bits(x)))
+	case string:
+		h.add('s', 's')
+		m.addString(&h, x)
+	case []interface{}:
+		h.add('a', 'a')
+		h.add(uint64(len(x)), uint64(len(x)))
+		for _, elem := range x {
+			child, ok := m.hash(elem)
+			if !ok {
+				return h, false
+			}
+			h.add(child[0], child[1])
+		}
+	case map[string]interface{}:
+		// entries are summed, so that their order doesn't matter
+		var sum treeHash
+		for key, elem := range x {
+			child, ok := m.hash(elem)
+			if !ok {
+				return h, false
+			}
+			var entry treeHash
+			m.addString(&entry, key)
+			entry.add(child[0], child[1])
+			sum[0] += entry[0]
+			sum[1] += entry[1]
+		}
+		h.add('m', 'm')
+		h.add(uint64(len(x)), uint64(len(x)))
+		h.add(sum[0], sum[1])
+	case map[string]string:
+		var sum treeHash
+		for key, elem := range x {
+			var entry treeHash
+			m.addString(&entry, key)
+			m.addString(&entry, elem)
+			sum[0] += entry[0]
+			sum[1] += entry[1]
+		}
+		h.add('M', 'M')
+		h.add(uint64(len(x)), uint64(len(x)))
+		h.add(sum[0], sum[1])
+	default:
+		return h, false
+	}
+
+	if composite {
+		m.hashes[id] = h
+	}
+	return h, true
+}
@@ -0,0 +1,73 @@
+package json2msgp_test
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/ndau/json2msgp"
+	"github.com/ndau/json2msgp/json2msgptest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoization(t *testing.T) {
+	keys := []interface{}{"oACI", "oACJ", 1.0}
+	pair := []interface{}{7776000000000.0, 10000000000.0}
+	tests := []struct {
+		name  string
+		in    interface{}
+		hints map[string][]string
+	}{
+		{"repeated arrays", map[string]interface{}{
+			"A": map[string]interface{}{"Keys": keys, "Table": []interface{}{pair, pair}},
+			"B": map[string]interface{}{"Keys": keys, "Table": []interface{}{pair}},
+		}, map[string][]string{"Table": []string{"int64", "uint64"}}},
+		{"same subtree, different keys", map[string]interface{}{
+			"Signed":   []interface{}{1.0, 2.0},
+			"Unsigned": []interface{}{1.0, 2.0},
+		}, map[string][]string{"Signed": []string{"int8"}, "Unsigned": []string{"uint8"}}},
+		// the key a subtree leaves behind carries on to its
+		// siblings, and must be replayed
+		{"state left behind", []interface{}{
+			map[string]interface{}{"x": 1.0}, 300.0,
+			map[string]interface{}{"x": 1.0}, 300.0,
+		}, map[string][]string{"x": []string{"uint16"}, "": []string{"int64"}}},
+		{"same subtree, different path hints", map[string]interface{}{
+			"A": map[string]interface{}{"x": 1.0, "y": 2.0},
+			"B": map[string]interface{}{"x": 1.0, "y": 2.0},
+		}, map[string][]string{"A.x": []string{"float64"}, "B.x": []string{"uint64"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want, err := json2msgp.Convert(tt.in, tt.hints)
+			require.NoError(t, err)
+			got, err := json2msgp.ConvertWithOptions(tt.in, json2msgp.WithTypeHints(tt.hints), json2msgp.WithMemoization())
+			require.NoError(t, err)
+			require.Equal(t, want, got)
+		})
+	}
+
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 200; i++ {
+		v := json2msgptest.RandomValue(r, 4)
+		in := []interface{}{v, map[string]interface{}{"k": v}, v}
+		want, err := json2msgp.Convert(in, nil)
+		require.NoError(t, err)
+		got, err := json2msgp.ConvertWithOptions(in, json2msgp.WithMemoization())
+		require.NoError(t, err)
+		require.Equal(t, want, got)
+	}
+
+	// errors in a memoized subtree are still reported at each path
+	bad := map[string]interface{}{"Fee": 1.5}
+	_, err := json2msgp.ConvertWithOptions([]interface{}{bad, bad}, json2msgp.WithMemoization(), json2msgp.WithAllErrors())
+	require.EqualError(t, err, "$[0].Fee: Unsupported numeric value 1.5\n$[1].Fee: Unsupported numeric value 1.5")
+}
@@ -0,0 +1,85 @@
+package json2msgp
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"expvar"
+	"fmt"
+	"time"
+)
+
+// Metrics receives measurements of conversions.  Implementations must be
+// safe for concurrent use if the converter is used concurrently.
+//
+// It is deliberately small, so that it can be backed by Prometheus
+// collectors, expvar (see ExpvarMetrics), or anything else.
+type Metrics interface {
+	// Conversion is called once per converted document with the time spent
+	// converting it, the size of its JSON input (0 if the input was not JSON
+	// text), the size of its MSGP output, and the conversion error, if any.
+	Conversion(elapsed time.Duration, bytesIn, bytesOut int, err error)
+	// Decision is called for each heuristic or hint decision, with the
+	// outcome as described for Node.Outcome.
+	Decision(outcome string)
+}
+
+// WithMetrics reports measurements of the conversion to m.
+func WithMetrics(m Metrics) Option {
+	return func(c *Converter) {
+		c.metrics = m
+	}
+}
+
+// latencyBuckets are the upper bounds of the ExpvarMetrics latency histogram.
+var latencyBuckets = []time.Duration{
+	100 * time.Microsecond,
+	time.Millisecond,
+	10 * time.Millisecond,
+	100 * time.Millisecond,
+	time.Second,
+}
+
+// ExpvarMetrics implements Metrics with an expvar.Map, which is published
+// on the default expvar handler at /debug/vars.
+//
+// The map holds the counters "conversions", "errors", "bytes_in" and
+// "bytes_out"; one "decision_<outcome>" counter per heuristic outcome; and a
+// cumulative latency histogram of "latency_le_<bound>" counters plus
+// "latency_le_inf".
+type ExpvarMetrics struct {
+	*expvar.Map
+}
+
+// NewExpvarMetrics publishes a new ExpvarMetrics under name.  Like
+// expvar.NewMap, it panics if name is already in use.
+func NewExpvarMetrics(name string) ExpvarMetrics {
+	return ExpvarMetrics{expvar.NewMap(name)}
+}
+
+// Conversion implements Metrics.
+func (m ExpvarMetrics) Conversion(elapsed time.Duration, bytesIn, bytesOut int, err error) {
+	m.Add("conversions", 1)
+	if err != nil {
+		m.Add("errors", 1)
+	}
+	m.Add("bytes_in", int64(bytesIn))
+	m.Add("bytes_out", int64(bytesOut))
+	for _, bound := range latencyBuckets {
+		if elapsed <= bound {
+			m.Add(fmt.Sprintf("latency_le_%s", bound), 1)
+		}
+	}
+	m.Add("latency_le_inf", 1)
+}
+
+// Decision implements Metrics.
+func (m ExpvarMetrics) Decision(outcome string) {
+	m.Add("decision_"+outcome, 1)
+}
@@ -0,0 +1,40 @@
+package json2msgp_test
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"bytes"
+	"strconv"
+	"testing"
+
+	"github.com/ndau/json2msgp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpvarMetrics(t *testing.T) {
+	m := json2msgp.NewExpvarMetrics("json2msgp_test")
+
+	in := "{\"Script\":\"oACI\"}\n{\"Fee\":1}\n1.5"
+	err := json2msgp.ConvertStreamWithOptions(
+		bytes.NewBufferString(in),
+		&bytes.Buffer{},
+		json2msgp.WithMetrics(m),
+		json2msgp.WithMultipleDocuments(),
+	)
+	require.Error(t, err)
+
+	require.Equal(t, "3", m.Get("conversions").String())
+	require.Equal(t, "1", m.Get("errors").String())
+	require.Equal(t, "3", m.Get("latency_le_inf").String())
+	require.Equal(t, "1", m.Get("decision_base64").String())
+	require.Equal(t, "1", m.Get("decision_integer").String())
+	require.Equal(t, "19", m.Get("bytes_out").String())
+	require.Equal(t, strconv.Itoa(len(in)), m.Get("bytes_in").String())
+}
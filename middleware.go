@@ -0,0 +1,178 @@
+package json2msgp
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/pkg/errors"
+	"github.com/tinylib/msgp/msgp"
+)
+
+// MsgpackContentType is the media type of MSGP request bodies.
+const MsgpackContentType = "application/msgpack"
+
+// Middleware wraps next so that it receives application/json request bodies
+// converted to application/msgpack, configured by opts.  Other requests pass
+// through unchanged, so a backend which speaks MSGP can accept JSON clients
+// without changes.  Responses pass through unchanged; see
+// MiddlewareWithResponses to convert them too.
+//
+// A body which fails to convert is answered with 400 Bad Request, without
+// calling next.
+func Middleware(next http.Handler, opts ...Option) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r, err := requestToMsgp(r, opts)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// MiddlewareWithResponses is like Middleware, and also converts responses
+// for clients which accept JSON but not MSGP: next is asked for MSGP, and
+// its application/msgpack responses are converted to JSON, as NewProxy
+// converts them.  Byte arrays appear in the JSON as base64.
+//
+// The responses to these clients are buffered whole, so that their headers
+// can be changed.  A response which fails to convert is answered with 500
+// Internal Server Error.
+func MiddlewareWithResponses(next http.Handler, opts ...Option) http.Handler {
+	return Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !acceptsJSONOnly(r.Header.Get("Accept")) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		r = r.Clone(r.Context())
+		r.Header.Set("Accept", MsgpackContentType)
+		buf := &responseBuffer{header: make(http.Header)}
+		next.ServeHTTP(buf, r)
+
+		body := buf.body.Bytes()
+		mediaType, _, err := mime.ParseMediaType(buf.header.Get("Content-Type"))
+		if err == nil && mediaType == MsgpackContentType {
+			body, err = decodeContent(buf.header.Get("Content-Encoding"), body)
+			if err == nil {
+				body, err = msgpBodyToJSON(body)
+			}
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			buf.header.Del("Content-Encoding")
+			buf.header.Set("Content-Type", "application/json")
+			buf.header.Set("Content-Length", strconv.Itoa(len(body)))
+		}
+		for key, values := range buf.header {
+			w.Header()[key] = values
+		}
+		if buf.status != 0 {
+			w.WriteHeader(buf.status)
+		}
+		w.Write(body)
+	}), opts...)
+}
+
+// responseBuffer is an http.ResponseWriter which holds the response.
+type responseBuffer struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (b *responseBuffer) Header() http.Header {
+	return b.header
+}
+
+func (b *responseBuffer) WriteHeader(status int) {
+	if b.status == 0 {
+		b.status = status
+	}
+}
+
+func (b *responseBuffer) Write(p []byte) (int, error) {
+	b.WriteHeader(http.StatusOK)
+	return b.body.Write(p)
+}
+
+// decodeContent returns body decoded from its Content-Encoding, which must be
+// gzip or identity.
+func decodeContent(encoding string, body []byte) ([]byte, error) {
+	switch encoding {
+	case "", "identity":
+		return body, nil
+	case "gzip", "x-gzip":
+		r, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return nil, errors.Wrap(err, "decompressing response")
+		}
+		defer r.Close()
+		out, err := ioutil.ReadAll(r)
+		return out, errors.Wrap(err, "decompressing response")
+	}
+	return nil, fmt.Errorf("cannot convert a response with Content-Encoding %q", encoding)
+}
+
+// msgpBodyToJSON converts an MSGP body to JSON.  A body holding several MSGP
+// values becomes one JSON value per line.
+func msgpBodyToJSON(body []byte) ([]byte, error) {
+	var out bytes.Buffer
+	for len(body) > 0 {
+		if out.Len() > 0 {
+			out.WriteByte('\n')
+		}
+		rest, err := msgp.Skip(body)
+		if err == nil {
+			_, err = msgp.UnmarshalAsJSON(&out, body[:len(body)-len(rest)])
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "converting response")
+		}
+		body = rest
+	}
+	return out.Bytes(), nil
+}
+
+// requestToMsgp returns r with its body converted to MSGP, if it is JSON, or
+// r itself otherwise.  The body of r is read and closed, but its other fields
+// are left as they are: the converted request is a clone.
+func requestToMsgp(r *http.Request, opts []Option) (*http.Request, error) {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || mediaType != "application/json" || r.Body == nil || r.Body == http.NoBody {
+		return r, nil
+	}
+
+	var out bytes.Buffer
+	err = ConvertStreamWithOptions(r.Body, &out, opts...)
+	r.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	body := out.Bytes()
+	r = r.Clone(r.Context())
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	r.GetBody = func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(body)), nil
+	}
+	r.ContentLength = int64(len(body))
+	r.Header.Set("Content-Type", MsgpackContentType)
+	r.Header.Set("Content-Length", strconv.Itoa(len(body)))
+	return r, nil
+}
@@ -0,0 +1,113 @@
+package json2msgp_test
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ndau/json2msgp"
+	"github.com/stretchr/testify/require"
+	"github.com/tinylib/msgp/msgp"
+)
+
+func TestMiddleware(t *testing.T) {
+	var gotType string
+	var gotBody []byte
+	backend := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var err error
+		gotType = r.Header.Get("Content-Type")
+		gotBody, err = ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		require.Equal(t, int64(len(gotBody)), r.ContentLength)
+	})
+	handler := json2msgp.Middleware(backend, json2msgp.WithTypeHints(map[string][]string{"Fee": []string{"uint64"}}))
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		wantCode    int
+		wantType    string
+		wantBody    string
+	}{
+		{"json", "application/json; charset=utf-8", `{"Fee":200}`, http.StatusOK, "application/msgpack", "81a3466565ccc8"},
+		{"not json", "text/plain", `{"Fee":200}`, http.StatusOK, "text/plain", hex.EncodeToString([]byte(`{"Fee":200}`))},
+		{"bad json", "application/json", `{"Fee":`, http.StatusBadRequest, "", ""},
+		{"unhinted float", "application/json", `{"x":1.5}`, http.StatusBadRequest, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotType, gotBody = "", nil
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			require.Equal(t, tt.wantCode, rec.Code)
+			require.Equal(t, tt.wantType, gotType)
+			require.Equal(t, tt.wantBody, hex.EncodeToString(gotBody))
+		})
+	}
+}
+
+func TestMiddlewareWithResponses(t *testing.T) {
+	// the backend echoes the MSGP it receives, wrapped in an array
+	backend := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		w.Header().Set("Content-Type", json2msgp.MsgpackContentType)
+		w.Header().Set("X-Accept", r.Header.Get("Accept"))
+		w.WriteHeader(http.StatusCreated)
+		w.Write(append(msgp.AppendArrayHeader(nil, 1), body...))
+	})
+	handler := json2msgp.MiddlewareWithResponses(backend, json2msgp.WithTypeHints(map[string][]string{"Fee": []string{"uint64"}}))
+
+	tests := []struct {
+		name       string
+		accept     string
+		wantType   string
+		wantAccept string
+		wantBody   string
+	}{
+		{"json client", "application/json", "application/json", json2msgp.MsgpackContentType, `[{"Fee":200}]`},
+		{"msgp client", "application/msgpack, application/json", json2msgp.MsgpackContentType, "application/msgpack, application/json", "\x91\x81\xa3Fee\xcc\xc8"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"Fee":200}`))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Accept", tt.accept)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			require.Equal(t, http.StatusCreated, rec.Code)
+			require.Equal(t, tt.wantType, rec.Header().Get("Content-Type"))
+			require.Equal(t, tt.wantAccept, rec.Header().Get("X-Accept"))
+			require.Equal(t, tt.wantBody, rec.Body.String())
+		})
+	}
+
+	// a response which is not MSGP passes through
+	text := json2msgp.MiddlewareWithResponses(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte("hi"))
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept", "application/json")
+	rec := httptest.NewRecorder()
+	text.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "hi", rec.Body.String())
+}
@@ -0,0 +1,361 @@
+package json2msgp
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+	"github.com/tinylib/msgp/msgp"
+)
+
+// ConvertToJSON reverses Convert: it decodes the MSGP value b into the
+// values encoding/json decodes JSON to, such that converting them again
+// with the same hints reproduces b wherever the heuristics allow.
+//
+//   - numbers are float64, as for encoding/json, so integers beyond 2^53 may
+//     lose precision.  A float32 is widened via its shortest decimal form,
+//     so 0.1 stays 0.1.  NaN and the infinities are the strings "NaN",
+//     "+Inf" and "-Inf".
+//   - binary data is a base64 string, which the heuristic decodes again.
+//   - an AddressExtension is its address, and a time is an RFC 3339 string.
+//     Any other extension is {"$ext": <type>, "data": "<base64>"}.
+//   - map keys which are integers are written in decimal.
+//
+// Strings which look like base64 or ndau addresses are returned as they
+// are, so they need a "str" hint, or WithoutBase64Detection and
+// WithoutAddressDetection, to convert back to strings.
+func ConvertToJSON(b []byte, opts ...JSONOption) (interface{}, error) {
+	r := &jsonRenderer{size: len(b)}
+	for _, opt := range opts {
+		opt(r)
+	}
+	v, rest, err := r.value(b, 0)
+	if err != nil {
+		return nil, errors.Wrap(err, "ConvertToJSON")
+	}
+	if len(rest) > 0 {
+		return nil, fmt.Errorf("ConvertToJSON: %w: %d bytes at offset %d", ErrTrailingMsgp, len(rest), len(b)-len(rest))
+	}
+	return v, nil
+}
+
+// ConvertStreamToJSON is like ConvertToJSON for each MSGP value in in, and
+// writes them to out as JSON, one per line.  Integers are written exactly.
+//
+// The type hints are looked up as the Converter looks them up, and decide
+// how binary data is written: as UTF-8 text for keys hinted "bin", as a hex
+// string for keys hinted "hex", and otherwise as base64.  Text and base64
+// convert back to the same bytes with the same hints; hex is for reading
+// only.  Integers of keys hinted "ndautimestamp" or "ndauduration" are
+// written as times and durations, as ParseNdauTimestamp and
+// ParseNdauDuration read them.
+func ConvertStreamToJSON(in io.Reader, out io.Writer, hints map[string][]string, opts ...JSONOption) error {
+	data, err := ioutil.ReadAll(in)
+	if err != nil {
+		return errors.Wrap(err, "ConvertStreamToJSON reading input")
+	}
+	enc := json.NewEncoder(out)
+	enc.SetEscapeHTML(false)
+	r := &jsonRenderer{
+		hints:       hints,
+		pathHints:   compilePathHints(hints),
+		nestedHints: compileNestedHints(hints),
+		exact:       true,
+		size:        len(data),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	for rest := data; len(rest) > 0; {
+		var v interface{}
+		if v, rest, err = r.value(rest, 0); err != nil {
+			return errors.Wrap(err, "ConvertStreamToJSON")
+		}
+		if err = enc.Encode(v); err != nil {
+			return errors.Wrap(err, "ConvertStreamToJSON writing to out stream")
+		}
+	}
+	return nil
+}
+
+// A JSONOption configures ConvertToJSON and ConvertStreamToJSON.
+type JSONOption func(*jsonRenderer)
+
+// WithUnits renders the integers of the keys listed in units readably, as
+// Humanize does: each becomes {"Raw": <integer>, "Text": "<in its unit>"}.
+// A type hint which already renders an integer as text takes precedence.
+// The result is for reading only.
+func WithUnits(units map[string]Unit) JSONOption {
+	return func(r *jsonRenderer) {
+		r.units = units
+	}
+}
+
+// WithRootKey names the root value, such as the system variable it is, so
+// that units apply to it, and to the elements of a root array, by that name.
+func WithRootKey(name string) JSONOption {
+	return func(r *jsonRenderer) {
+		r.root = name
+	}
+}
+
+// jsonRenderer decodes MSGP values for ConvertToJSON, tracking the current
+// key and path exactly as the Converter does.
+type jsonRenderer struct {
+	hints       map[string][]string
+	pathHints   []pathHint
+	nestedHints map[string][]nestedHint
+	currentKey  string
+	path        []string
+	units       map[string]Unit
+	root        string
+	// whether integers are int64 and uint64 rather than float64
+	exact bool
+	// the size of the input, for error offsets
+	size int
+}
+
+// hint returns the type hint which applies to the current value, if any.
+func (r *jsonRenderer) hint() string {
+	if r.hints == nil {
+		return ""
+	}
+	hint, _ := lookupHint(r.hints, r.pathHints, r.nestedHints, r.currentKey, r.path)
+	return hint
+}
+
+// value decodes the value at the start of b, and returns what follows it.
+func (r *jsonRenderer) value(b []byte, depth int) (interface{}, []byte, error) {
+	if depth > DefaultMaxDepth {
+		return nil, b, fmt.Errorf("%w of %d at offset %d", ErrMaxDepth, DefaultMaxDepth, r.size-len(b))
+	}
+	var (
+		v    interface{}
+		rest []byte
+		err  error
+	)
+	switch msgp.NextType(b) {
+	case msgp.MapType:
+		var n uint32
+		if n, rest, err = msgp.ReadMapHeaderBytes(b); err != nil {
+			break
+		}
+		// each entry takes at least 2 bytes; check before allocating
+		if uint64(n)*2 > uint64(len(rest)) {
+			err = fmt.Errorf("map of %d entries in %d bytes", n, len(rest))
+			break
+		}
+		m := make(map[string]interface{}, n)
+		for i := uint32(0); i < n; i++ {
+			var key string
+			if key, rest, err = r.key(rest); err != nil {
+				return nil, rest, err
+			}
+			r.currentKey = key
+			r.path = append(r.path, keySegment(key))
+			if m[key], rest, err = r.value(rest, depth+1); err != nil {
+				return nil, rest, err
+			}
+			r.path = r.path[:len(r.path)-1]
+		}
+		return m, rest, nil
+	case msgp.ArrayType:
+		var n uint32
+		if n, rest, err = msgp.ReadArrayHeaderBytes(b); err != nil {
+			break
+		}
+		// each element takes at least a byte; check before allocating
+		if uint64(n) > uint64(len(rest)) {
+			err = fmt.Errorf("array of %d elements in %d bytes", n, len(rest))
+			break
+		}
+		a := make([]interface{}, n)
+		for i := range a {
+			r.path = append(r.path, indexSegment(i))
+			if a[i], rest, err = r.value(rest, depth+1); err != nil {
+				return nil, rest, err
+			}
+			r.path = r.path[:len(r.path)-1]
+		}
+		return a, rest, nil
+	case msgp.IntType:
+		var i int64
+		if i, rest, err = msgp.ReadInt64Bytes(b); err == nil {
+			v = r.integer(i)
+		}
+	case msgp.UintType:
+		var u uint64
+		if u, rest, err = msgp.ReadUint64Bytes(b); err == nil {
+			switch {
+			case u <= math.MaxInt64:
+				v = r.integer(int64(u))
+			case r.exact:
+				v = u
+			default:
+				v = float64(u)
+			}
+		}
+	case msgp.Float32Type:
+		var f float32
+		if f, rest, err = msgp.ReadFloat32Bytes(b); err == nil {
+			v = jsonFloat(float64(f), 32)
+		}
+	case msgp.Float64Type:
+		var f float64
+		if f, rest, err = msgp.ReadFloat64Bytes(b); err == nil {
+			v = jsonFloat(f, 64)
+		}
+	case msgp.StrType:
+		v, rest, err = msgp.ReadStringBytes(b)
+	case msgp.BinType:
+		var data []byte
+		if data, rest, err = msgp.ReadBytesZC(b); err == nil {
+			if v, err = r.binary(data); err != nil {
+				return nil, b, err
+			}
+		}
+	case msgp.BoolType:
+		v, rest, err = msgp.ReadBoolBytes(b)
+	case msgp.NilType:
+		rest, err = msgp.ReadNilBytes(b)
+	case msgp.TimeType:
+		var t time.Time
+		if t, rest, err = msgp.ReadTimeBytes(b); err == nil {
+			v = t.UTC().Format(time.RFC3339Nano)
+		}
+	case msgp.ExtensionType:
+		if rest, err = msgp.Skip(b); err != nil {
+			break
+		}
+		typ, data := splitExtension(b[:len(b)-len(rest)])
+		switch typ {
+		case AddressExtensionType:
+			v = string(data)
+		case TimestampExtensionType:
+			var t TimestampExtension
+			if err = t.UnmarshalBinary(data); err == nil {
+				v = time.Time(t).Format(time.RFC3339Nano)
+			}
+		default:
+			v = map[string]interface{}{"$ext": int64(typ), "data": base64.StdEncoding.EncodeToString(data)}
+		}
+	default:
+		_, err = msgp.Skip(b)
+		if err == nil {
+			err = fmt.Errorf("cannot render %s", msgp.NextType(b))
+		}
+	}
+	if err != nil {
+		return nil, b, fmt.Errorf("%w at offset %d: %s", ErrInvalidMsgp, r.size-len(b), err)
+	}
+	return v, rest, nil
+}
+
+// key decodes the map key at the start of b.
+func (r *jsonRenderer) key(b []byte) (string, []byte, error) {
+	var (
+		key  string
+		rest []byte
+		err  error
+	)
+	switch msgp.NextType(b) {
+	case msgp.StrType:
+		key, rest, err = msgp.ReadStringBytes(b)
+	case msgp.BinType:
+		var data []byte
+		if data, rest, err = msgp.ReadBytesZC(b); err == nil {
+			key = string(data)
+		}
+	case msgp.IntType:
+		var i int64
+		if i, rest, err = msgp.ReadInt64Bytes(b); err == nil {
+			key = strconv.FormatInt(i, 10)
+		}
+	case msgp.UintType:
+		var u uint64
+		if u, rest, err = msgp.ReadUint64Bytes(b); err == nil {
+			key = strconv.FormatUint(u, 10)
+		}
+	default:
+		err = fmt.Errorf("cannot render %s as a map key", msgp.NextType(b))
+	}
+	if err != nil {
+		return "", b, fmt.Errorf("%w at offset %d: %s", ErrInvalidMsgp, r.size-len(b), err)
+	}
+	return key, rest, nil
+}
+
+// integer returns the JSON form of the integer i of the current key.
+func (r *jsonRenderer) integer(i int64) interface{} {
+	switch r.hint() {
+	case "ndautimestamp":
+		return FormatNdauTimestamp(i)
+	case "ndauduration":
+		return FormatNdauDuration(i)
+	}
+	var raw interface{} = i
+	if !r.exact {
+		raw = float64(i)
+	}
+	if unit, ok := r.unit(); ok {
+		return map[string]interface{}{"Raw": raw, "Text": unit.Format(i)}
+	}
+	return raw
+}
+
+// unit returns the unit of the integers of the nearest enclosing key, or of
+// the root, if any.
+func (r *jsonRenderer) unit() (Unit, bool) {
+	if r.units == nil {
+		return 0, false
+	}
+	key := nearestKey(r.path)
+	if key == "" {
+		key = r.root
+	}
+	unit, ok := r.units[key]
+	return unit, ok
+}
+
+// binary returns the JSON form of the binary data of the current key.
+func (r *jsonRenderer) binary(data []byte) (interface{}, error) {
+	switch r.hint() {
+	case "bin":
+		if !utf8.Valid(data) {
+			return nil, fmt.Errorf("%w: %q is hinted bin, but is not valid utf-8", ErrBadValue, r.currentKey)
+		}
+		return string(data), nil
+	case "hex":
+		return hex.EncodeToString(data), nil
+	}
+	return base64.StdEncoding.EncodeToString(data), nil
+}
+
+// jsonFloat returns f, a float of bitSize bits, as encoding/json can write
+// it.
+func jsonFloat(f float64, bitSize int) interface{} {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	}
+	if bitSize == 32 {
+		f, _ = strconv.ParseFloat(strconv.FormatFloat(f, 'g', -1, 32), 64)
+	}
+	return f
+}
@@ -0,0 +1,181 @@
+package json2msgp_test
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/ndau/json2msgp"
+	"github.com/stretchr/testify/require"
+	"github.com/tinylib/msgp/msgp"
+)
+
+func TestConvertToJSONRoundTrip(t *testing.T) {
+	hints := map[string][]string{
+		"Fee":      {"uint64"},
+		"Rate":     {"float32"},
+		"Name":     {"str"},
+		"ChangeOn": {"ndautimestamp"},
+		"":         {"int64", "uint64"},
+	}
+	tests := []string{
+		`{"EAIFeeTable":[{"Fee":4000000,"To":["ndnf9ffbzhyf8mk7z5vvqc4quzz5i2exp5zgsmhyhc9cuwr4"]},{"Fee":200,"To":null}]}`,
+		`[[7776000000000,10000000000],[15552000000000,20000000000]]`,
+		`{"Script":"oACI","Name":"DwA=","Rate":0.1,"OK":true,"ChangeOn":"2020-07-01T00:00:00Z"}`,
+		`{"a":{"b":[-1,-200,1e15]}}`,
+	}
+	for _, in := range tests {
+		var v interface{}
+		require.NoError(t, json.Unmarshal([]byte(in), &v))
+		b, err := json2msgp.Convert(v, hints)
+		require.NoError(t, err, in)
+
+		back, err := json2msgp.ConvertToJSON(b)
+		require.NoError(t, err, in)
+		again, err := json2msgp.Convert(back, hints)
+		require.NoError(t, err, in)
+		require.Equal(t, b, again, in)
+	}
+}
+
+func TestConvertToJSON(t *testing.T) {
+	b := msgp.AppendMapHeader(nil, 4)
+	b = msgp.AppendInt(b, 7)
+	b = msgp.AppendFloat32(b, 0.1)
+	b = msgp.AppendString(b, "addr")
+	addr := json2msgp.AddressExtension("ndnf9ffbzhyf8mk7z5vvqc4quzz5i2exp5zgsmhyhc9cuwr4")
+	b, err := msgp.AppendExtension(b, &addr)
+	require.NoError(t, err)
+	b = msgp.AppendString(b, "ext")
+	b, err = msgp.AppendExtension(b, &msgp.RawExtension{Type: 9, Data: []byte{1, 2}})
+	require.NoError(t, err)
+	b = msgp.AppendString(b, "nan")
+	b = msgp.AppendFloat64(b, math.NaN())
+
+	got, err := json2msgp.ConvertToJSON(b)
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{
+		"7":    0.1,
+		"addr": "ndnf9ffbzhyf8mk7z5vvqc4quzz5i2exp5zgsmhyhc9cuwr4",
+		"ext":  map[string]interface{}{"$ext": int64(9), "data": "AQI="},
+		"nan":  "NaN",
+	}, got)
+
+	_, err = json2msgp.ConvertToJSON(append(msgp.AppendNil(nil), 0xc0))
+	require.True(t, errors.Is(err, json2msgp.ErrTrailingMsgp), "got %v", err)
+	_, err = json2msgp.ConvertToJSON([]byte{0x91, 0xc1})
+	require.True(t, errors.Is(err, json2msgp.ErrInvalidMsgp), "got %v", err)
+
+	// headers claiming more than the input holds fail before allocating
+	for _, b := range [][]byte{{0xdd, 0xff, 0xff, 0xff, 0xff}, {0xdf, 0xff, 0xff, 0xff, 0xff}, {0x82, 0xa1, 0x61}} {
+		_, err = json2msgp.ConvertToJSON(b)
+		require.True(t, errors.Is(err, json2msgp.ErrInvalidMsgp), "% x: got %v", b, err)
+	}
+}
+
+func TestConvertStreamToJSON(t *testing.T) {
+	var docs [][]byte
+	for _, name := range []string{"text", "hex", "blob"} {
+		doc := msgp.AppendMapHeader(nil, 1)
+		doc = msgp.AppendString(doc, name)
+		docs = append(docs, msgp.AppendBytes(doc, []byte("<hi>")))
+	}
+	doc := msgp.AppendMapHeader(nil, 2)
+	doc = msgp.AppendString(doc, "Big")
+	doc = msgp.AppendUint64(doc, 1<<63)
+	doc = msgp.AppendString(doc, "Lock")
+	docs = append(docs, msgp.AppendInt64(doc, 90*24*60*60*1000000))
+	in := bytes.Join(docs, nil)
+
+	hints := map[string][]string{"text": {"bin"}, "hex": {"hex"}, "Lock": {"ndauduration"}}
+	out := &bytes.Buffer{}
+	require.NoError(t, json2msgp.ConvertStreamToJSON(bytes.NewReader(in), out, hints))
+	require.Equal(t, `{"text":"<hi>"}
+{"hex":"3c68693e"}
+{"blob":"PGhpPg=="}
+{"Big":9223372036854775808,"Lock":"3m"}
+`, out.String())
+
+	// the JSON converts back to the same MSGP, bar the hex
+	lines := strings.SplitAfter(out.String(), "\n")
+	back := &bytes.Buffer{}
+	require.NoError(t, json2msgp.ConvertStreamWithOptions(strings.NewReader(lines[0]+lines[2]+lines[3]), back,
+		json2msgp.WithTypeHints(map[string][]string{"text": {"bin"}, "Lock": {"ndauduration"}, "Big": {"uint64"}}),
+		json2msgp.WithMultipleDocuments()))
+	require.Equal(t, bytes.Join([][]byte{docs[0], docs[2], docs[3]}, nil), back.Bytes())
+
+	// nested hints apply as the Converter applies them
+	nested := msgp.AppendArrayHeader(nil, 2)
+	for i := 0; i < 2; i++ {
+		nested = msgp.AppendInt64(msgp.AppendArrayHeader(nested, 1), 90*24*60*60*1000000)
+	}
+	out.Reset()
+	require.NoError(t, json2msgp.ConvertStreamToJSON(bytes.NewReader(nested), out,
+		map[string][]string{"": {`["ndauduration"]`, `["int64"]`}}))
+	require.Equal(t, "[[\"3m\"],[7776000000000]]\n", out.String())
+
+	bad := msgp.AppendMapHeader(nil, 1)
+	bad = msgp.AppendString(bad, "text")
+	bad = msgp.AppendBytes(bad, []byte{0xff})
+	err := json2msgp.ConvertStreamToJSON(bytes.NewReader(bad), &bytes.Buffer{}, hints)
+	require.True(t, errors.Is(err, json2msgp.ErrBadValue), "got %v", err)
+}
+
+func TestConvertStreamToJSONWithUnits(t *testing.T) {
+	fees := msgp.AppendMapHeader(nil, 2)
+	fees = msgp.AppendString(fees, "Fee")
+	fees = msgp.AppendUint64(fees, 150000000)
+	fees = msgp.AppendString(fees, "Other")
+	fees = msgp.AppendInt64(fees, 5)
+
+	nested := msgp.AppendMapHeader(nil, 1)
+	nested = msgp.AppendString(nested, "Fee")
+	nested = msgp.AppendArrayHeader(nested, 2)
+	nested = msgp.AppendMapHeader(nested, 1)
+	nested = msgp.AppendString(nested, "a")
+	nested = msgp.AppendInt64(nested, 1)
+	nested = msgp.AppendInt64(nested, 100000000)
+
+	duration := msgp.AppendInt64(nil, 172800000000)
+
+	tests := []struct {
+		name  string
+		in    []byte
+		hints map[string][]string
+		opts  []json2msgp.JSONOption
+		want  string
+	}{
+		{"keys", fees, nil, []json2msgp.JSONOption{json2msgp.WithUnits(json2msgp.SysvarUnits)},
+			`{"Fee":{"Raw":150000000,"Text":"1.5 ndau"},"Other":5}`},
+		{"nearest key", nested, nil, []json2msgp.JSONOption{json2msgp.WithUnits(json2msgp.SysvarUnits)},
+			`{"Fee":[{"a":1},{"Raw":100000000,"Text":"1 ndau"}]}`},
+		{"named root", duration, nil, []json2msgp.JSONOption{
+			json2msgp.WithUnits(json2msgp.SysvarUnits),
+			json2msgp.WithRootKey("DefaultRecourseDuration"),
+		}, `{"Raw":172800000000,"Text":"2d"}`},
+		{"unnamed root", duration, nil, []json2msgp.JSONOption{json2msgp.WithUnits(json2msgp.SysvarUnits)},
+			`172800000000`},
+		{"hint first", fees, map[string][]string{"Fee": {"ndauduration"}},
+			[]json2msgp.JSONOption{json2msgp.WithUnits(json2msgp.SysvarUnits)},
+			`{"Fee":"t2m30s","Other":5}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			require.NoError(t, json2msgp.ConvertStreamToJSON(bytes.NewReader(tt.in), out, tt.hints, tt.opts...))
+			require.Equal(t, tt.want+"\n", out.String())
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package json2msgp
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+// MsgpInputPolicy determines what the stream functions do with input which
+// is not JSON, but is well-formed MSGP: most likely, the output of an earlier
+// conversion.
+type MsgpInputPolicy int
+
+const (
+	// MsgpInputError fails the conversion with ErrMsgpInput.  This is the
+	// default.
+	MsgpInputError MsgpInputPolicy = iota
+	// MsgpInputPassThrough writes the input unchanged, followed by the
+	// delimiter after each value.
+	MsgpInputPassThrough
+)
+
+// WithMsgpInput sets what the stream functions do with input which is
+// already MSGP.
+//
+// Input is only taken for MSGP if it cannot be parsed as JSON, and consists
+// of exactly one well-formed MSGP value, or with WithMultipleDocuments, of
+// one or more, the first of which is not an ASCII character.  Some JSON, such
+// as 1, is also valid MSGP; it is always converted as JSON.
+func WithMsgpInput(policy MsgpInputPolicy) Option {
+	return func(c *Converter) {
+		c.msgpInput = policy
+	}
+}
+
+// splitMsgp returns the MSGP values of the input b, if it is MSGP as
+// described for WithMsgpInput, or nil.
+func (c *Converter) splitMsgp(b []byte) [][]byte {
+	if !msgpLeader(b) {
+		return nil
+	}
+	var values [][]byte
+	for len(b) > 0 {
+		rest, err := validateValue(b, len(b), 0)
+		if err != nil {
+			return nil
+		}
+		values = append(values, b[:len(b)-len(rest):len(b)-len(rest)])
+		b = rest
+	}
+	if len(values) > 1 && !c.multipleDocuments {
+		return nil
+	}
+	return values
+}
+
+// msgpLeader reports whether b starts with a byte which is plausibly the
+// start of MSGP rather than of text: anything but ASCII, which MSGP reads as
+// positive fixints.
+func msgpLeader(b []byte) bool {
+	return len(b) > 0 && b[0] >= 0x80
+}
@@ -0,0 +1,61 @@
+package json2msgp_test
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/ndau/json2msgp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMsgpInput(t *testing.T) {
+	fee := []byte{0x81, 0xa3, 'F', 'e', 'e', 0xcd, 0x01, 0x2c}
+	tests := []struct {
+		name    string
+		in      []byte
+		opts    []json2msgp.Option
+		want    []byte
+		wantErr error
+	}{
+		{"rejected", fee, nil, nil, json2msgp.ErrMsgpInput},
+		{"passed through", fee, []json2msgp.Option{json2msgp.WithMsgpInput(json2msgp.MsgpInputPassThrough)}, fee, nil},
+		{"delimited", append(fee, fee...), []json2msgp.Option{
+			json2msgp.WithMsgpInput(json2msgp.MsgpInputPassThrough),
+			json2msgp.WithMultipleDocuments(),
+			json2msgp.WithDelimiter([]byte{'\n'}),
+		}, append(append(append(fee, '\n'), fee...), '\n'), nil},
+		{"several values need multiple documents", append(fee, fee...), []json2msgp.Option{
+			json2msgp.WithMsgpInput(json2msgp.MsgpInputPassThrough),
+		}, nil, nil},
+		{"truncated msgp is bad JSON", fee[:6], nil, nil, nil},
+		{"text is bad JSON", []byte(`{"a":}`), []json2msgp.Option{json2msgp.WithMultipleDocuments()}, nil, nil},
+		{"JSON which is also msgp", []byte("1"), []json2msgp.Option{json2msgp.WithMsgpInput(json2msgp.MsgpInputPassThrough)}, []byte{1}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			err := json2msgp.ConvertStreamWithOptions(bytes.NewReader(tt.in), &out, tt.opts...)
+			switch {
+			case tt.wantErr != nil:
+				require.True(t, errors.Is(err, tt.wantErr), "got %v", err)
+				require.EqualError(t, err, "ConvertStream: Input appears to already be MSGP")
+			case tt.want == nil:
+				require.Error(t, err)
+				require.False(t, errors.Is(err, json2msgp.ErrMsgpInput), "got %v", err)
+			default:
+				require.NoError(t, err)
+				require.Equal(t, tt.want, out.Bytes())
+			}
+		})
+	}
+}
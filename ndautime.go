@@ -0,0 +1,163 @@
+package json2msgp
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ndau/ndaumath/pkg/constants"
+	"github.com/ndau/ndaumath/pkg/types"
+)
+
+// NdauEpoch is the zero point of ndau timestamps.
+var NdauEpoch = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// ParseNdauDuration parses a duration as the chain writes it, such as "2d",
+// "1y6m" or "t1h30m", into ndau's representation: an int64 number of
+// microseconds.  The format is that of ndaumath's types.ParseDuration, in
+// which m before the t means months of 30 days, and after it minutes; a year
+// is 365 days.  A leading "-" negates the duration, as FormatNdauDuration
+// writes it.  A duration which does not fit in an int64 fails with
+// ErrOverflow.
+func ParseNdauDuration(s string) (int64, error) {
+	body := strings.TrimPrefix(s, "-")
+	negative := len(body) < len(s)
+	if body == "" {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	// Years are the only part without a bound on their digits, so a number
+	// which does not parse is too many years, and the duration fits if the
+	// years fit alongside the rest.  ParseDuration sums in wrapping
+	// arithmetic, which gives the rest exactly.
+	match := constants.DurationRE.FindStringSubmatch(body)
+	if match == nil {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	d, err := types.ParseDuration(body)
+	if err != nil {
+		return 0, fmt.Errorf("%w: duration %q", ErrOverflow, s)
+	}
+	years, _ := strconv.ParseUint("0"+match[constants.DurationRE.SubexpIndex("years")], 10, 64)
+	rest := uint64(d) - years*types.Year
+	// the magnitude of the most negative duration is one more than the
+	// largest positive one
+	limit := uint64(math.MaxInt64)
+	if negative {
+		limit++
+	}
+	if years > (limit-rest)/types.Year {
+		return 0, fmt.Errorf("%w: duration %q", ErrOverflow, s)
+	}
+	if negative {
+		return -int64(d), nil
+	}
+	return int64(d), nil
+}
+
+// gregorianCycle is the length of 400 years of the Gregorian calendar, after
+// which its days of the week and leap years repeat, in microseconds.
+const gregorianCycle = 146097 * 24 * 60 * 60 * 1000000
+
+// ParseNdauTimestamp parses an RFC 3339 time such as "2020-07-01T00:00:00Z"
+// into ndau's representation: an int64 number of microseconds since NdauEpoch.
+// Years beyond 0000-9999 may be written with more digits or a sign, as
+// FormatNdauTimestamp writes them.  A time which does not fit in an int64
+// fails with ErrOverflow.
+func ParseNdauTimestamp(s string) (int64, error) {
+	// time.Parse only reads four-digit years, so an expanded year is moved
+	// by whole cycles into range, and the cycles added back afterwards
+	var cycles int64
+	in := s
+	if year, rest, ok := expandedYear(s); ok {
+		cycles = (year - 2000) / 400
+		in = fmt.Sprintf("%04d", year-cycles*400) + rest
+	}
+	t, err := time.Parse(time.RFC3339Nano, in)
+	if err != nil {
+		return 0, err
+	}
+	us := (t.Unix()-NdauEpoch.Unix())*1000000 + int64(t.Nanosecond()/1000)
+	shift := cycles * gregorianCycle
+	if cycles != 0 && (shift/gregorianCycle != cycles || (shift > 0) != (us+shift > us)) {
+		return 0, fmt.Errorf("%w: timestamp %q", ErrOverflow, s)
+	}
+	return us + shift, nil
+}
+
+// expandedYear returns the year of the time s and what follows it, if the
+// year is not the four digits time.Parse reads.
+func expandedYear(s string) (int64, string, bool) {
+	if s == "" {
+		return 0, "", false
+	}
+	end := strings.IndexByte(s[1:], '-') + 1
+	if end <= 0 {
+		return 0, "", false
+	}
+	if end == 4 && s[0] != '-' && s[0] != '+' {
+		return 0, "", false
+	}
+	year, err := strconv.ParseInt(s[:end], 10, 64)
+	if err != nil {
+		return 0, "", false
+	}
+	return year, s[end:], true
+}
+
+// convertNdauTime encodes s, hinted as "ndauduration" or "ndautimestamp".
+func (c *Converter) convertNdauTime(s string, hint string, buffer []byte) ([]byte, error) {
+	parse := ParseNdauDuration
+	if hint == "ndautimestamp" {
+		parse = ParseNdauTimestamp
+	}
+	us, err := parse(s)
+	if errors.Is(err, ErrOverflow) {
+		return buffer, c.errorf("%w for %s", err, hint)
+	}
+	if err != nil {
+		return buffer, c.errorf("%w: %s", ErrBadValue, err)
+	}
+	// the parsers have checked the range, and a float64 would lose precision
+	return c.codec.AppendInt(buffer, us), nil
+}
+
+// FormatNdauDuration formats a number of microseconds as the chain does,
+// with ndaumath's Duration.String, e.g. "2d", "1y10dt12h" or "t0s".  A
+// negative duration is written with a leading "-", which ParseNdauDuration
+// reads.
+func FormatNdauDuration(us int64) string {
+	if us >= 0 {
+		return types.Duration(us).String()
+	}
+	// the magnitude of the most negative duration does not fit in an int64,
+	// so the years are split off first
+	years, rest := -(us / types.Year), types.Duration(-(us % types.Year))
+	s := "-"
+	if years > 0 {
+		s += strconv.FormatInt(years, 10) + "y"
+	}
+	if rest > 0 {
+		s += rest.String()
+	}
+	return s
+}
+
+// FormatNdauTimestamp formats a number of microseconds since NdauEpoch as an
+// RFC 3339 time, as ParseNdauTimestamp reads it.  Years beyond 0000-9999,
+// which RFC 3339 cannot express, are written with more digits or a sign.
+func FormatNdauTimestamp(us int64) string {
+	t := time.Unix(NdauEpoch.Unix()+us/1000000, us%1000000*1000)
+	return t.UTC().Format(time.RFC3339Nano)
+}
@@ -0,0 +1,185 @@
+package json2msgp_test
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/ndau/json2msgp"
+	"github.com/stretchr/testify/require"
+	"github.com/tinylib/msgp/msgp"
+)
+
+func TestParseNdauDuration(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    int64
+		wantErr bool
+	}{
+		{"2d", 172800000000, false},
+		{"1d", 86400000000, false},
+		{"3m", 7776000000000, false},
+		{"t1h30m", 5400000000, false},
+		{"t1h", 3600000000, false},
+		{"1mt", 2592000000000, false},
+		{"t30s", 30000000, false},
+		{"1y", 31536000000000, false},
+		{"t500000us", 500000, false},
+		{"P1Y2DT3H", 31536000000000 + 2*86400000000 + 3*3600000000, false},
+		{"-t1h", -3600000000, false},
+		{"292471y2m16dt4h54s775807us", math.MaxInt64, false},
+		{"-292471y2m16dt4h54s775808us", math.MinInt64, false},
+		{"", 0, true},
+		{"-", 0, true},
+		{"2 days", 0, true},
+		{"1.5h", 0, true},
+		{"1h", 0, true},
+		{"1w", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := json2msgp.ParseNdauDuration(tt.in)
+		if tt.wantErr {
+			require.Error(t, err, tt.in)
+			continue
+		}
+		require.NoError(t, err, tt.in)
+		require.Equal(t, tt.want, got, tt.in)
+	}
+
+	for _, in := range []string{"292471y2m16dt4h54s775808us", "300000y", "292471y3m", "-292471y2m16dt4h54s775809us", "99999999999999999999y"} {
+		_, err := json2msgp.ParseNdauDuration(in)
+		require.True(t, errors.Is(err, json2msgp.ErrOverflow), "%s: got %v", in, err)
+	}
+}
+
+func TestParseNdauTimestamp(t *testing.T) {
+	got, err := json2msgp.ParseNdauTimestamp("2000-01-02T00:00:00Z")
+	require.NoError(t, err)
+	require.Equal(t, int64(86400000000), got)
+
+	// the 400 year cycle of the calendar
+	got, err = json2msgp.ParseNdauTimestamp("2400-01-01T00:00:00Z")
+	require.NoError(t, err)
+	require.Equal(t, int64(146097*86400000000), got)
+	got, err = json2msgp.ParseNdauTimestamp("-0400-01-01T00:00:00Z")
+	require.NoError(t, err)
+	require.Equal(t, int64(-6*146097*86400000000), got)
+
+	_, err = json2msgp.ParseNdauTimestamp("yesterday")
+	require.Error(t, err)
+	for _, in := range []string{"294277-01-09T04:00:54.775808Z", "-290278-12-22T19:59:05.224191Z", "9999999-01-01T00:00:00Z"} {
+		_, err = json2msgp.ParseNdauTimestamp(in)
+		require.True(t, errors.Is(err, json2msgp.ErrOverflow), "%s: got %v", in, err)
+	}
+}
+
+func TestNdauTimeHints(t *testing.T) {
+	hints := map[string][]string{
+		"DefaultRecourseDuration": []string{"ndauduration"},
+		"ChangeAt":                []string{"ndautimestamp"},
+	}
+
+	// DefaultRecourseDuration from the sysvar tests is 2 days
+	out := &bytes.Buffer{}
+	err := json2msgp.ConvertStream(bytes.NewBufferString(`{"DefaultRecourseDuration":"2d"}`), out, hints)
+	require.NoError(t, err)
+	require.Equal(t, "\x81\xb7DefaultRecourseDuration\xd3\x00\x00\x00\x28\x3b\xae\xc0\x00", out.String())
+
+	// numbers are taken as microseconds
+	out.Reset()
+	err = json2msgp.ConvertStream(bytes.NewBufferString(`{"DefaultRecourseDuration":172800000000}`), out, hints)
+	require.NoError(t, err)
+	require.Equal(t, "\x81\xb7DefaultRecourseDuration\xd3\x00\x00\x00\x28\x3b\xae\xc0\x00", out.String())
+
+	out.Reset()
+	err = json2msgp.ConvertStream(bytes.NewBufferString(`{"ChangeAt":"2000-01-01T00:00:01Z"}`), out, hints)
+	require.NoError(t, err)
+	_, rest, err := msgp.ReadMapHeaderBytes(out.Bytes())
+	require.NoError(t, err)
+	_, rest, err = msgp.ReadStringBytes(rest)
+	require.NoError(t, err)
+	us, _, err := msgp.ReadInt64Bytes(rest)
+	require.NoError(t, err)
+	require.Equal(t, int64(1000000), us)
+
+	// values beyond float64 precision are encoded exactly
+	for _, tt := range []struct {
+		key string
+		in  string
+		us  int64
+	}{
+		{"DefaultRecourseDuration", json2msgp.FormatNdauDuration(1<<53 + 1), 1<<53 + 1},
+		{"DefaultRecourseDuration", json2msgp.FormatNdauDuration(math.MaxInt64), math.MaxInt64},
+		{"DefaultRecourseDuration", json2msgp.FormatNdauDuration(math.MinInt64), math.MinInt64},
+		{"ChangeAt", json2msgp.FormatNdauTimestamp(1<<53 + 1), 1<<53 + 1},
+		{"ChangeAt", json2msgp.FormatNdauTimestamp(math.MaxInt64), math.MaxInt64},
+	} {
+		out.Reset()
+		err = json2msgp.ConvertStream(bytes.NewBufferString(`{"`+tt.key+`":"`+tt.in+`"}`), out, hints)
+		require.NoError(t, err, tt.in)
+		want := msgp.AppendInt64(msgp.AppendString(msgp.AppendMapHeader(nil, 1), tt.key), tt.us)
+		require.Equal(t, want, out.Bytes(), tt.in)
+	}
+
+	err = json2msgp.ConvertStream(bytes.NewBufferString(`{"ChangeAt":"soon"}`), &bytes.Buffer{}, hints)
+	require.True(t, errors.Is(err, json2msgp.ErrBadValue))
+
+	err = json2msgp.ConvertStream(bytes.NewBufferString(`{"DefaultRecourseDuration":"300000y"}`), &bytes.Buffer{}, hints)
+	require.True(t, errors.Is(err, json2msgp.ErrOverflow), "got %v", err)
+}
+
+func TestFormatNdauDuration(t *testing.T) {
+	tests := []struct {
+		in   int64
+		want string
+	}{
+		{0, "t0s"},
+		{172800000000, "2d"},
+		{5400000000, "t1h30m"},
+		{31536000000000 + 2592000000000 + 86400000000 + 1, "1y1m1dt1us"},
+		{-500000, "-t500000us"},
+		{-31536000000000, "-1y"},
+		{math.MinInt64, "-292471y2m16dt4h54s775808us"},
+	}
+	for _, tt := range tests {
+		got := json2msgp.FormatNdauDuration(tt.in)
+		require.Equal(t, tt.want, got)
+	}
+
+	for _, us := range []int64{1, -1, -500000, math.MaxInt64, math.MinInt64, math.MinInt64 + 1} {
+		back, err := json2msgp.ParseNdauDuration(json2msgp.FormatNdauDuration(us))
+		require.NoError(t, err, us)
+		require.Equal(t, us, back)
+	}
+}
+
+func TestFormatNdauTimestamp(t *testing.T) {
+	tests := []struct {
+		in   int64
+		want string
+	}{
+		{86400000000, "2000-01-02T00:00:00Z"},
+		{-1, "1999-12-31T23:59:59.999999Z"},
+		{math.MaxInt64, "294277-01-09T04:00:54.775807Z"},
+		{math.MinInt64, "-290278-12-22T19:59:05.224192Z"},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.want, json2msgp.FormatNdauTimestamp(tt.in))
+	}
+
+	for _, us := range []int64{0, 1, -1, 253402300799999999 - 946684800000000, math.MaxInt64, math.MinInt64, math.MinInt64 + 1} {
+		back, err := json2msgp.ParseNdauTimestamp(json2msgp.FormatNdauTimestamp(us))
+		require.NoError(t, err, us)
+		require.Equal(t, us, back)
+	}
+}
@@ -0,0 +1,137 @@
+package json2msgp
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// nestedHint is an element of a type hint list which may itself be a list,
+// for the elements of a nested array.
+type nestedHint struct {
+	leaf string
+	list []nestedHint
+}
+
+// isNestedHint is true for an element of a type hint list which is a list
+// of hints written as JSON, such as `["int64","uint64"]`.
+func isNestedHint(hint string) bool {
+	return strings.HasPrefix(hint, "[")
+}
+
+// compileNestedHints returns the hint lists among typeHints which have a
+// nested list, by key.
+func compileNestedHints(typeHints map[string][]string) map[string][]nestedHint {
+	var nested map[string][]nestedHint
+	for key, hints := range typeHints {
+		for _, hint := range hints {
+			if isNestedHint(hint) {
+				if nested == nil {
+					nested = make(map[string][]nestedHint)
+				}
+				nested[key] = parseNestedHints(hints)
+				break
+			}
+		}
+	}
+	return nested
+}
+
+// parseNestedHints parses a list of hints, each of which may be a nested
+// list.  An element which is not a valid list is kept as it is, so that it
+// is reported as a bad hint if it is used.
+func parseNestedHints(hints []string) []nestedHint {
+	list := make([]nestedHint, len(hints))
+	for i, hint := range hints {
+		list[i].leaf = hint
+		if !isNestedHint(hint) {
+			continue
+		}
+		var v []interface{}
+		if json.Unmarshal([]byte(hint), &v) != nil {
+			continue
+		}
+		if sub, ok := nestedHintList(v); ok {
+			list[i] = nestedHint{list: sub}
+		}
+	}
+	return list
+}
+
+// nestedHintList converts the decoded JSON list v to hints.
+func nestedHintList(v []interface{}) ([]nestedHint, bool) {
+	if len(v) == 0 {
+		return nil, false
+	}
+	list := make([]nestedHint, len(v))
+	for i, elem := range v {
+		switch x := elem.(type) {
+		case string:
+			list[i].leaf = x
+		case []interface{}:
+			sub, ok := nestedHintList(x)
+			if !ok {
+				return nil, false
+			}
+			list[i].list = sub
+		default:
+			return nil, false
+		}
+	}
+	return list, true
+}
+
+// resolveNestedHint returns the hint of list for the value at position,
+// the indices of the nested arrays it is in, outermost first.  Each index
+// selects an element of the list at its depth, cyclically; an element
+// which is not a list applies to everything within its array element.
+// Missing indices are taken as 0.
+func resolveNestedHint(list []nestedHint, position []string) string {
+	for depth := 0; ; depth++ {
+		i := 0
+		if depth < len(position) {
+			i = segmentIndex(position[depth])
+		}
+		hint := list[i%len(list)]
+		if hint.list == nil {
+			return hint.leaf
+		}
+		list = hint.list
+	}
+}
+
+// isIndexSegment is true for a path segment selecting an array element.
+func isIndexSegment(segment string) bool {
+	return len(segment) > 1 && segment[0] == '[' && segment[1] >= '0' && segment[1] <= '9'
+}
+
+// segmentIndex returns the index an index segment selects.
+func segmentIndex(segment string) int {
+	i, _ := strconv.Atoi(segment[1 : len(segment)-1])
+	return i
+}
+
+// hintPosition returns the index segments of the innermost run of nested
+// arrays path is in, outermost first; type hint lists are indexed by
+// them.  Maps within the arrays do not reset the position, so the elements
+// of an array of records share the hints of their index.
+func hintPosition(path []string) []string {
+	end := len(path)
+	for end > 0 && !isIndexSegment(path[end-1]) {
+		end--
+	}
+	start := end
+	for start > 0 && isIndexSegment(path[start-1]) {
+		start--
+	}
+	return path[start:end]
+}
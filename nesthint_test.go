@@ -0,0 +1,101 @@
+package json2msgp_test
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/ndau/json2msgp"
+	"github.com/stretchr/testify/require"
+	"github.com/tinylib/msgp/msgp"
+)
+
+func TestNestedArrayHints(t *testing.T) {
+	u := func(b []byte) []byte { return msgp.AppendUint64(b, 200) }
+	i := func(b []byte) []byte { return msgp.AppendInt64(b, 200) }
+	f := func(b []byte) []byte { return msgp.AppendFloat64(b, 200) }
+	arr := func(b []byte, elems ...func([]byte) []byte) []byte {
+		b = msgp.AppendArrayHeader(b, uint32(len(elems)))
+		for _, elem := range elems {
+			b = elem(b)
+		}
+		return b
+	}
+	key := msgp.AppendString
+	nest := func(elems ...func([]byte) []byte) func([]byte) []byte {
+		return func(b []byte) []byte { return arr(b, elems...) }
+	}
+
+	tests := []struct {
+		name  string
+		in    string
+		hints map[string][]string
+		want  []byte
+	}{
+		{
+			"arrays of arrays of arrays",
+			`[[[200,200],[200]],[[200]]]`,
+			map[string][]string{"": {`[["uint64","int64"],["int64"]]`, `["float64"]`}},
+			arr(nil, nest(nest(u, i), nest(i)), nest(nest(f))),
+		},
+		{
+			"flat list indexed by innermost array",
+			`[[[200,200]],[[200,200]]]`,
+			map[string][]string{"": {"uint64", "int64"}},
+			arr(nil, nest(nest(u, i)), nest(nest(u, i))),
+		},
+		{
+			"outer index restored after nested array",
+			`[[200,200],200,200]`,
+			map[string][]string{"": {"uint64", "int64"}},
+			arr(nil, nest(u, i), i, u),
+		},
+		{
+			"hint in place of list applies within",
+			`[[[200]],[[200,200]],200]`,
+			map[string][]string{"": {`["float64"]`, "uint64", "int64"}},
+			arr(nil, nest(nest(f)), nest(nest(u, u)), i),
+		},
+		{
+			"nested hints of a key",
+			`{"Rates":[[200,200],[200,200]],"Fee":200}`,
+			map[string][]string{"Rates": {`["uint64"]`, `["int64","float64"]`}, "Fee": {"uint64", "int64"}},
+			arr(key(u(key(msgp.AppendMapHeader(nil, 2), "Fee")), "Rates"), nest(u, u), nest(i, f)),
+		},
+		{
+			"path hint selecting an array",
+			`{"LockedRateTable":[[[200]],[[200,200]]]}`,
+			map[string][]string{"/LockedRateTable": {`["uint64"]`, `[["int64","uint64"]]`}},
+			arr(key(msgp.AppendMapHeader(nil, 1), "LockedRateTable"), nest(nest(u)), nest(nest(i, u))),
+		},
+		{
+			"longer path hint wins",
+			`{"LockedRateTable":[[200,200]]}`,
+			map[string][]string{"/LockedRateTable": {"int64"}, "/LockedRateTable/0/*": {"uint64"}},
+			arr(key(msgp.AppendMapHeader(nil, 1), "LockedRateTable"), nest(u, u)),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := &bytes.Buffer{}
+			require.NoError(t, json2msgp.ConvertStream(strings.NewReader(tt.in), got, tt.hints))
+			require.Equal(t, tt.want, got.Bytes())
+
+			// the constant-memory streamer agrees, bar header widths
+			constant, err := convertConstant(t, tt.in, json2msgp.WithTypeHints(tt.hints))
+			require.NoError(t, err)
+			changes, err := json2msgp.Diff(tt.want, constant)
+			require.NoError(t, err)
+			require.Empty(t, changes)
+		})
+	}
+}
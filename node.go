@@ -0,0 +1,119 @@
+package json2msgp
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/tinylib/msgp/msgp"
+)
+
+// SysvarsQueryPath is the ABCI query path at which an ndau node serves
+// system variables.  The query data is an MSGP array of sysvar names, and the
+// response value is an MSGP map from name to the sysvar's MSGP encoding.
+const SysvarsQueryPath = "/sysvars"
+
+// FetchSysvars returns the current MSGP encodings of the named system
+// variables from the ndau node whose Tendermint RPC listens at nodeURL, e.g.
+// "http://localhost:26657".  If client is nil, http.DefaultClient is used.
+func FetchSysvars(ctx context.Context, client *http.Client, nodeURL string, names ...string) (map[string][]byte, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	data := msgp.AppendArrayHeader(nil, uint32(len(names)))
+	for _, name := range names {
+		data = msgp.AppendString(data, name)
+	}
+	query := url.Values{}
+	query.Set("path", fmt.Sprintf("%q", SysvarsQueryPath))
+	query.Set("data", "0x"+hex.EncodeToString(data))
+	reqURL := strings.TrimSuffix(nodeURL, "/") + "/abci_query?" + query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "FetchSysvars building request")
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "FetchSysvars querying node")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("FetchSysvars: node returned %s", resp.Status)
+	}
+
+	var rpc struct {
+		Result struct {
+			Response struct {
+				Code  uint32 `json:"code"`
+				Log   string `json:"log"`
+				Value []byte `json:"value"`
+			} `json:"response"`
+		} `json:"result"`
+		Error *struct {
+			Message string `json:"message"`
+			Data    string `json:"data"`
+		} `json:"error"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&rpc); err != nil {
+		return nil, errors.Wrap(err, "FetchSysvars decoding response")
+	}
+	if rpc.Error != nil {
+		return nil, fmt.Errorf("FetchSysvars: %s: %s", rpc.Error.Message, rpc.Error.Data)
+	}
+	if rpc.Result.Response.Code != 0 {
+		return nil, fmt.Errorf("FetchSysvars: query failed with code %d: %s", rpc.Result.Response.Code, rpc.Result.Response.Log)
+	}
+
+	sysvars := make(map[string][]byte)
+	b := rpc.Result.Response.Value
+	sz, b, err := msgp.ReadMapHeaderBytes(b)
+	if err != nil {
+		return nil, errors.Wrap(err, "FetchSysvars reading sysvars")
+	}
+	for i := uint32(0); i < sz; i++ {
+		var name string
+		var value []byte
+		name, b, err = msgp.ReadStringBytes(b)
+		if err == nil {
+			value, b, err = msgp.ReadBytesBytes(b, nil)
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "FetchSysvars reading sysvars")
+		}
+		sysvars[name] = value
+	}
+	return sysvars, nil
+}
+
+// FetchSysvarHints infers type hints for each named system variable from its
+// current value on the ndau node at nodeURL, as FetchSysvars and InferHints.
+func FetchSysvarHints(ctx context.Context, client *http.Client, nodeURL string, names ...string) (map[string]map[string][]string, error) {
+	sysvars, err := FetchSysvars(ctx, client, nodeURL, names...)
+	if err != nil {
+		return nil, err
+	}
+	hints := make(map[string]map[string][]string)
+	for name, value := range sysvars {
+		hints[name], err = InferHints(value)
+		if err != nil {
+			return nil, errors.Wrap(err, name)
+		}
+	}
+	return hints, nil
+}
@@ -0,0 +1,65 @@
+package json2msgp_test
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ndau/json2msgp"
+	"github.com/stretchr/testify/require"
+	"github.com/tinylib/msgp/msgp"
+)
+
+// fakeNode serves the given sysvars over a minimal Tendermint abci_query.
+func fakeNode(t *testing.T, sysvars map[string][]byte) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/abci_query", r.URL.Path)
+		require.Equal(t, `"/sysvars"`, r.URL.Query().Get("path"))
+		data, err := hex.DecodeString(strings.TrimPrefix(r.URL.Query().Get("data"), "0x"))
+		require.NoError(t, err)
+
+		sz, data, err := msgp.ReadArrayHeaderBytes(data)
+		require.NoError(t, err)
+		value := msgp.AppendMapHeader(nil, sz)
+		for i := uint32(0); i < sz; i++ {
+			var name string
+			name, data, err = msgp.ReadStringBytes(data)
+			require.NoError(t, err)
+			value = msgp.AppendString(value, name)
+			value = msgp.AppendBytes(value, sysvars[name])
+		}
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":"","result":{"response":{"code":0,"value":%q}}}`,
+			base64.StdEncoding.EncodeToString(value))
+	}))
+}
+
+func TestFetchSysvarHints(t *testing.T) {
+	table, err := json2msgp.Convert(
+		[]interface{}{[]interface{}{7776000000000.0, 10000000000.0}},
+		map[string][]string{"": []string{"int64", "uint64"}},
+	)
+	require.NoError(t, err)
+
+	node := fakeNode(t, map[string][]byte{"LockedRateTable": table})
+	defer node.Close()
+
+	hints, err := json2msgp.FetchSysvarHints(context.Background(), nil, node.URL, "LockedRateTable")
+	require.NoError(t, err)
+	require.Equal(t, map[string]map[string][]string{
+		"LockedRateTable": {"": []string{"int64", "uint64"}},
+	}, hints)
+}
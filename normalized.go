@@ -0,0 +1,242 @@
+package json2msgp
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/tinylib/msgp/msgp"
+)
+
+// WithNormalizedJSON writes, alongside each value converted, its rendering
+// by NormalizedJSON to w, followed by a newline, so that reviewers can read
+// exactly what will go on chain: after the heuristics, the hints and every
+// other option have had their say.  If writing to w fails, so does the
+// conversion.  It is an error to use it with a Codec other than MsgpCodec.
+func WithNormalizedJSON(w io.Writer) Option {
+	return func(c *Converter) {
+		c.normalized = w
+	}
+}
+
+// writeNormalized writes the rendering of the MSGP value b for
+// WithNormalizedJSON.
+func (c *Converter) writeNormalized(b []byte) error {
+	if !c.isMsgp() {
+		return fmt.Errorf("%w: WithNormalizedJSON needs MsgpCodec", ErrBadOption)
+	}
+	text, err := NormalizedJSON(b)
+	if err == nil {
+		_, err = c.normalized.Write(append(text, '\n'))
+	}
+	return errors.Wrap(err, "writing normalized JSON")
+}
+
+// NormalizedJSON renders the MSGP value b as canonical JSON: compact, with
+// map keys sorted, so that equal values render identically.  Where MSGP says
+// more than JSON can, the rendering keeps the difference visible:
+//
+//   - floats always have a fraction or an exponent, as in 1.0, so that they
+//     are told apart from integers; NaN and the infinities are strings.
+//   - binary data is {"$bin": "<base64>"}, an AddressExtension is
+//     {"$address": "<address>"}, and any other extension is
+//     {"$ext": <type>, "data": "<base64>"}.  A timestamp is
+//     {"$time": "<RFC 3339 time>"}.
+//   - map keys which are not strings are rendered as JSON, and quoted.
+func NormalizedJSON(b []byte) ([]byte, error) {
+	out, rest, err := appendNormalized(nil, b, len(b), 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) > 0 {
+		return nil, fmt.Errorf("%w: %d bytes at offset %d", ErrTrailingMsgp, len(rest), len(b)-len(rest))
+	}
+	return out, nil
+}
+
+// appendNormalized appends the rendering of the value at the start of b,
+// which is the tail of a document of size bytes, and returns what follows
+// it.
+func appendNormalized(out, b []byte, size, depth int) ([]byte, []byte, error) {
+	if depth > DefaultMaxDepth {
+		return out, b, fmt.Errorf("%w of %d at offset %d", ErrMaxDepth, DefaultMaxDepth, size-len(b))
+	}
+	var rest []byte
+	var err error
+	switch msgp.NextType(b) {
+	case msgp.MapType:
+		var n uint32
+		if n, rest, err = msgp.ReadMapHeaderBytes(b); err != nil {
+			break
+		}
+		// each entry takes at least 2 bytes; check before allocating
+		if uint64(n)*2 > uint64(len(rest)) {
+			err = fmt.Errorf("map of %d entries in %d bytes", n, len(rest))
+			break
+		}
+		type entry struct{ key, value []byte }
+		entries := make([]entry, n)
+		for i := range entries {
+			if entries[i].key, rest, err = appendNormalized(nil, rest, size, depth+1); err != nil {
+				return out, rest, err
+			}
+			if entries[i].key[0] != '"' {
+				entries[i].key = appendJSONString(nil, string(entries[i].key))
+			}
+			if entries[i].value, rest, err = appendNormalized(nil, rest, size, depth+1); err != nil {
+				return out, rest, err
+			}
+		}
+		sort.SliceStable(entries, func(i, j int) bool { return bytes.Compare(entries[i].key, entries[j].key) < 0 })
+		out = append(out, '{')
+		for i, e := range entries {
+			if i > 0 {
+				out = append(out, ',')
+			}
+			out = append(append(append(out, e.key...), ':'), e.value...)
+		}
+		return append(out, '}'), rest, nil
+	case msgp.ArrayType:
+		var n uint32
+		if n, rest, err = msgp.ReadArrayHeaderBytes(b); err != nil {
+			break
+		}
+		out = append(out, '[')
+		for i := uint32(0); i < n; i++ {
+			if i > 0 {
+				out = append(out, ',')
+			}
+			if out, rest, err = appendNormalized(out, rest, size, depth+1); err != nil {
+				return out, rest, err
+			}
+		}
+		return append(out, ']'), rest, nil
+	case msgp.IntType:
+		var i int64
+		if i, rest, err = msgp.ReadInt64Bytes(b); err == nil {
+			out = strconv.AppendInt(out, i, 10)
+		}
+	case msgp.UintType:
+		var u uint64
+		if u, rest, err = msgp.ReadUint64Bytes(b); err == nil {
+			out = strconv.AppendUint(out, u, 10)
+		}
+	case msgp.Float32Type:
+		var f float32
+		if f, rest, err = msgp.ReadFloat32Bytes(b); err == nil {
+			out = appendJSONFloat(out, float64(f), 32)
+		}
+	case msgp.Float64Type:
+		var f float64
+		if f, rest, err = msgp.ReadFloat64Bytes(b); err == nil {
+			out = appendJSONFloat(out, f, 64)
+		}
+	case msgp.StrType:
+		var s string
+		if s, rest, err = msgp.ReadStringBytes(b); err == nil {
+			out = appendJSONString(out, s)
+		}
+	case msgp.BinType:
+		var v []byte
+		if v, rest, err = msgp.ReadBytesZC(b); err == nil {
+			out = append(out, `{"$bin":`...)
+			out = append(appendJSONString(out, base64.StdEncoding.EncodeToString(v)), '}')
+		}
+	case msgp.BoolType:
+		var v bool
+		if v, rest, err = msgp.ReadBoolBytes(b); err == nil {
+			out = strconv.AppendBool(out, v)
+		}
+	case msgp.NilType:
+		if rest, err = msgp.ReadNilBytes(b); err == nil {
+			out = append(out, "null"...)
+		}
+	case msgp.TimeType:
+		var t time.Time
+		if t, rest, err = msgp.ReadTimeBytes(b); err == nil {
+			out = append(out, `{"$time":`...)
+			out = append(appendJSONString(out, t.UTC().Format(time.RFC3339Nano)), '}')
+		}
+	case msgp.ExtensionType:
+		if rest, err = msgp.Skip(b); err != nil {
+			break
+		}
+		typ, data := splitExtension(b[:len(b)-len(rest)])
+		if typ == AddressExtensionType {
+			out = append(out, `{"$address":`...)
+			out = append(appendJSONString(out, string(data)), '}')
+		} else {
+			out = append(out, `{"$ext":`...)
+			out = strconv.AppendInt(out, int64(typ), 10)
+			out = append(out, `,"data":`...)
+			out = append(appendJSONString(out, base64.StdEncoding.EncodeToString(data)), '}')
+		}
+	default:
+		_, err = msgp.Skip(b)
+		if err == nil {
+			err = fmt.Errorf("cannot render %s", msgp.NextType(b))
+		}
+	}
+	if err != nil {
+		return out, b, fmt.Errorf("%w at offset %d: %s", ErrInvalidMsgp, size-len(b), err)
+	}
+	return out, rest, nil
+}
+
+// splitExtension returns the type and data of the well-formed MSGP
+// extension b.
+func splitExtension(b []byte) (int8, []byte) {
+	// the type follows the length, if the format has one
+	var at int
+	switch b[0] {
+	case 0xc7:
+		at = 2
+	case 0xc8:
+		at = 3
+	case 0xc9:
+		at = 5
+	default:
+		at = 1
+	}
+	return int8(b[at]), b[at+1:]
+}
+
+// appendJSONString appends s as a JSON string, without escaping HTML.
+func appendJSONString(out []byte, s string) []byte {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	// strings always encode
+	enc.Encode(s)
+	return append(out, bytes.TrimSuffix(buf.Bytes(), []byte("\n"))...)
+}
+
+// appendJSONFloat appends f, a float of bitSize bits, as a JSON number with
+// a fraction or exponent.
+func appendJSONFloat(out []byte, f float64, bitSize int) []byte {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return appendJSONString(out, strconv.FormatFloat(f, 'g', -1, bitSize))
+	}
+	s := strconv.FormatFloat(f, 'g', -1, bitSize)
+	if !strings.ContainsAny(s, ".e") {
+		s += ".0"
+	}
+	return append(out, s...)
+}
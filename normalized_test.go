@@ -0,0 +1,72 @@
+package json2msgp_test
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ndau/json2msgp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizedJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		want    string
+		wantErr error
+	}{
+		{"map sorted", "82 a1 62 02 a1 61 01", `{"a":1,"b":2}`, nil},
+		{"integers", "93 ff cf ff ff ff ff ff ff ff ff 00", `[-1,18446744073709551615,0]`, nil},
+		{"floats", "94 cb 3f f0 00 00 00 00 00 00 ca 3f c0 00 00 cb 44 4b 1a e4 d6 e2 ef 50 cb 7f f8 00 00 00 00 00 00",
+			`[1.0,1.5,1e+21,"NaN"]`, nil},
+		{"string not HTML escaped", "a3 3c 26 22", `"<&\""`, nil},
+		{"bin", "c4 02 44 77", `{"$bin":"RHc="}`, nil},
+		{"address", "d4 6e 61", `{"$address":"a"}`, nil},
+		{"other extension", "d4 05 61", `{"$ext":5,"data":"YQ=="}`, nil},
+		{"integer keys", "82 02 c0 01 c3", `{"1":true,"2":null}`, nil},
+		{"trailing data", "01 02", "", json2msgp.ErrTrailingMsgp},
+		{"truncated", "92 01", "", json2msgp.ErrInvalidMsgp},
+		{"huge map header", "df ff ff ff ff", "", json2msgp.ErrInvalidMsgp},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in, err := hex.DecodeString(strings.Replace(tt.in, " ", "", -1))
+			require.NoError(t, err)
+			got, err := json2msgp.NormalizedJSON(in)
+			if tt.wantErr != nil {
+				require.True(t, errors.Is(err, tt.wantErr), "got %v", err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, string(got))
+		})
+	}
+}
+
+func TestWithNormalizedJSON(t *testing.T) {
+	var out, normalized bytes.Buffer
+	cache := json2msgp.NewCache(4)
+	in := `{"b": "RHc=", "a": 2} {"a": 2, "b": "RHc="} [3]`
+	_, err := json2msgp.ConvertStreamCounted(strings.NewReader(in), &out,
+		json2msgp.WithMultipleDocuments(), json2msgp.WithCache(cache),
+		json2msgp.WithTypeHints(map[string][]string{"a": {"float64"}}),
+		json2msgp.WithNormalizedJSON(&normalized))
+	require.NoError(t, err)
+	// the second document is a cache hit, and is written all the same
+	require.Equal(t, `{"a":2.0,"b":{"$bin":"RHc="}}
+{"a":2.0,"b":{"$bin":"RHc="}}
+[3]
+`, normalized.String())
+}
@@ -0,0 +1,81 @@
+package json2msgp
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"errors"
+	"math"
+	"strconv"
+)
+
+// NumberParser parses the text of a number given as a string; see
+// WithNumberParser.
+type NumberParser func(s string) (float64, error)
+
+// WithNumberParser converts strings whose type hint is numeric, such as
+// "uint64" or "float64", by parsing them with parse and encoding the result
+// as the hint says, so that documents with numbers written as "1_000_000"
+// or "0x3D0900" need no cleaning first.  Without it, such strings are
+// converted by the string heuristic, ignoring the hint.  A string which
+// parse rejects is an ErrBadValue error.
+//
+// ParseGoNumber is a parser for the usual forms.  The parsed value is held
+// as a float64, as JSON numbers are, so integers beyond 2^53 may lose
+// precision.
+func WithNumberParser(parse NumberParser) Option {
+	return func(c *Converter) {
+		c.numberParser = parse
+	}
+}
+
+// ParseGoNumber parses s as a Go integer or floating-point literal, without
+// a sign or with a leading "+" or "-": decimal, such as "1_000_000" or
+// "4e6", or with a base prefix, such as "0x3D0900", "0o17" or "0b101".
+// Infinities and NaN are rejected.
+func ParseGoNumber(s string) (float64, error) {
+	if i, err := strconv.ParseInt(s, 0, 64); err == nil {
+		return float64(i), nil
+	}
+	if u, err := strconv.ParseUint(s, 0, 64); err == nil {
+		return float64(u), nil
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, err
+	}
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return 0, errors.New("not a finite number")
+	}
+	return f, nil
+}
+
+// isNumericHint is true for the hints appendHinted encodes, other than the
+// ndau time types, which have string forms of their own.
+func isNumericHint(hint string) bool {
+	switch hint {
+	case "float32", "float64":
+		return true
+	case "ndauduration", "ndautimestamp":
+		return false
+	}
+	_, ok := hintBounds[hint]
+	return ok
+}
+
+// convertNumberString encodes s, whose hint is numeric, with the number
+// parser.
+func (c *Converter) convertNumberString(s, hint string, buffer []byte) ([]byte, error) {
+	x, err := c.numberParser(s)
+	if err != nil {
+		return buffer, c.errorf("%w: %q is not a number: %s", ErrBadValue, s, err)
+	}
+	c.decide(hint, "hint")
+	return c.appendHinted(buffer, hint, x)
+}
@@ -0,0 +1,100 @@
+package json2msgp_test
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/ndau/json2msgp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseGoNumber(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    float64
+		wantErr bool
+	}{
+		{"4000000", 4e6, false},
+		{"1_000_000", 1e6, false},
+		{"4e6", 4e6, false},
+		{"0x3D0900", 4e6, false},
+		{"-0x10", -16, false},
+		{"0o17", 15, false},
+		{"0b101", 5, false},
+		{"1_000.5", 1000.5, false},
+		{"18446744073709551615", 1 << 64, false},
+		{"1__0", 0, true},
+		{"0x", 0, true},
+		{"Inf", 0, true},
+		{"NaN", 0, true},
+		{"", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := json2msgp.ParseGoNumber(tt.in)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestWithNumberParser(t *testing.T) {
+	hints := json2msgp.WithTypeHints(map[string][]string{
+		"Fee":  {"uint64"},
+		"Rate": {"float32"},
+		"Name": {"str"},
+	})
+	parser := json2msgp.WithNumberParser(json2msgp.ParseGoNumber)
+
+	got, err := json2msgp.ConvertWithOptions(map[string]interface{}{
+		"Fee":  []interface{}{"4e6", "0x3D0900", "4_000_000"},
+		"Rate": "0.5",
+		"Name": "0x10",
+	}, hints, parser)
+	require.NoError(t, err)
+	want, err := json2msgp.ConvertWithOptions(map[string]interface{}{
+		"Fee":  []interface{}{4e6, 4e6, 4e6},
+		"Rate": 0.5,
+		"Name": "0x10",
+	}, hints)
+	require.NoError(t, err)
+	require.Equal(t, want, got)
+
+	// the hint still bounds the value
+	_, err = json2msgp.ConvertWithOptions(map[string]interface{}{"Fee": "-1"}, hints, parser)
+	require.True(t, errors.Is(err, json2msgp.ErrOverflow), "got %v", err)
+
+	_, err = json2msgp.ConvertWithOptions(map[string]interface{}{"Fee": "four"}, hints, parser)
+	require.True(t, errors.Is(err, json2msgp.ErrBadValue), "got %v", err)
+
+	// any parser will do
+	percent := json2msgp.WithNumberParser(func(s string) (float64, error) {
+		return strconv.ParseFloat(s[:len(s)-1], 64)
+	})
+	got, err = json2msgp.ConvertWithOptions(map[string]interface{}{"Fee": "12%"}, hints, percent)
+	require.NoError(t, err)
+	want, err = json2msgp.ConvertWithOptions(map[string]interface{}{"Fee": 12.0}, hints)
+	require.NoError(t, err)
+	require.Equal(t, want, got)
+
+	// without a parser, the heuristic applies
+	got, err = json2msgp.ConvertWithOptions(map[string]interface{}{"Fee": "4e6"}, hints)
+	require.NoError(t, err)
+	want, err = json2msgp.ConvertWithOptions(map[string]interface{}{"Fee": "4e6"})
+	require.NoError(t, err)
+	require.Equal(t, want, got)
+}
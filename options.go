@@ -0,0 +1,372 @@
+package json2msgp
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import "time"
+
+// Option configures a Converter.
+type Option func(*Converter)
+
+// NullPolicy determines how a JSON null is represented in the MSGP output.
+type NullPolicy int
+
+const (
+	// NullAsNil encodes null as msgp nil.  This is the default.
+	NullAsNil NullPolicy = iota
+	// NullAsEmptyArray encodes null as an empty array.
+	NullAsEmptyArray
+	// NullAsEmptyMap encodes null as an empty map.
+	NullAsEmptyMap
+	// NullAsZero encodes null as the zero value of the hinted type.
+	// It is an error to use this policy for a key without a type hint.
+	NullAsZero
+)
+
+// HeaderWidth selects the encoding of map and array headers.
+type HeaderWidth int
+
+const (
+	// HeaderCompact uses the smallest header which fits: fixmap/fixarray for up
+	// to 15 elements, then map16/array16, then map32/array32.  This is the default.
+	HeaderCompact HeaderWidth = iota
+	// Header16 forces map16/array16 headers, or 32-bit headers if the
+	// container has more than 65535 elements.
+	Header16
+	// Header32 forces map32/array32 headers.
+	Header32
+)
+
+// StringHeaderWidth selects the encoding of string headers.
+type StringHeaderWidth int
+
+const (
+	// StringCompact uses the smallest header which fits: fixstr for up to 31
+	// bytes, then str8, str16 and str32.  This is the default.
+	StringCompact StringHeaderWidth = iota
+	// String8 forces str8 headers, or wider headers for strings of more than
+	// 255 bytes.
+	String8
+	// String16 forces str16 headers, or str32 headers for strings of more
+	// than 65535 bytes.
+	String16
+	// String32 forces str32 headers.
+	String32
+)
+
+// KeyOrder selects the order in which map keys are written.
+type KeyOrder int
+
+const (
+	// KeyOrderLexical sorts keys byte by byte, so "10" comes before "2".
+	// This is the default.
+	KeyOrderLexical KeyOrder = iota
+	// KeyOrderNumeric sorts keys which are decimal integers, such as block
+	// heights or indices, by value, so "2" comes before "10".  They come
+	// before all other keys, which are sorted as for KeyOrderLexical.
+	KeyOrderNumeric
+)
+
+// WithTypeHints supplies the numeric type hints described in ConvertStream.
+func WithTypeHints(typeHints map[string][]string) Option {
+	return func(c *Converter) {
+		c.typeHints = typeHints
+	}
+}
+
+// WithNullPolicy sets how JSON null is encoded for keys without their own policy.
+func WithNullPolicy(policy NullPolicy) Option {
+	return func(c *Converter) {
+		c.nullPolicy = policy
+	}
+}
+
+// WithKeyNullPolicy sets how JSON null is encoded for values of the given key.
+// The key may also be a path, matched as type hint keys are, such as
+// "/EAIFeeTable/*/To"; the most specific path which matches applies, and
+// otherwise the policy of the nearest enclosing key.
+//
+// For example, consumers which decode `"To": null` in the EAIFeeTable into a
+// non-nil slice can use:
+//
+//	WithKeyNullPolicy("To", NullAsEmptyArray)
+func WithKeyNullPolicy(key string, policy NullPolicy) Option {
+	return func(c *Converter) {
+		if c.keyNullPolicies == nil {
+			c.keyNullPolicies = make(map[string]NullPolicy)
+		}
+		c.keyNullPolicies[key] = policy
+	}
+}
+
+// WithEmptyAsNil encodes empty maps and arrays as msgp nil.
+//
+// Some Go types marshal empty slices as nil, so this is needed to match their
+// encoding byte for byte.  For the reverse mapping, use NullAsEmptyArray or
+// NullAsEmptyMap.
+func WithEmptyAsNil() Option {
+	return func(c *Converter) {
+		c.emptyAsNil = true
+	}
+}
+
+// WithHeaderWidth sets the header width of maps and arrays for keys without
+// their own width.
+func WithHeaderWidth(width HeaderWidth) Option {
+	return func(c *Converter) {
+		c.defaultHeaderWidth = width
+	}
+}
+
+// WithKeyHeaderWidth sets the header width of maps and arrays which are the
+// value of the given key.
+//
+// This is useful to recreate historical encodings exactly, for example when
+// they were produced by an encoder which always wrote map16 headers.
+func WithKeyHeaderWidth(key string, width HeaderWidth) Option {
+	return func(c *Converter) {
+		if c.keyHeaderWidths == nil {
+			c.keyHeaderWidths = make(map[string]HeaderWidth)
+		}
+		c.keyHeaderWidths[key] = width
+	}
+}
+
+// WithStringHeaderWidth sets the header width of string values for keys
+// without their own width.  Map keys are always written compactly.  Only
+// MsgpCodec supports widths other than StringCompact; with another Codec,
+// they fail with ErrBadOption.
+func WithStringHeaderWidth(width StringHeaderWidth) Option {
+	return func(c *Converter) {
+		c.defaultStringWidth = width
+	}
+}
+
+// WithKeyStringHeaderWidth sets the header width of strings which are the
+// value of the given key, including ndau addresses encoded as strings.
+//
+// Like WithKeyHeaderWidth, this recreates historical encodings exactly; some
+// encoders never wrote str8, for example, so their 32 to 255 byte strings
+// have str16 headers.
+func WithKeyStringHeaderWidth(key string, width StringHeaderWidth) Option {
+	return func(c *Converter) {
+		if c.keyStringWidths == nil {
+			c.keyStringWidths = make(map[string]StringHeaderWidth)
+		}
+		c.keyStringWidths[key] = width
+	}
+}
+
+// WithAddressExtension encodes strings which are valid ndau addresses as an
+// AddressExtension rather than as a plain string.
+func WithAddressExtension() Option {
+	return func(c *Converter) {
+		c.addressExtension = true
+	}
+}
+
+// WithKeyAddressKinds requires string values of the given key to be ndau
+// addresses of one of the given kinds, such as address.KindNdau.  Conversion
+// fails for any other string, for example an exchange address where a node
+// address is required.
+func WithKeyAddressKinds(key string, kinds ...byte) Option {
+	return func(c *Converter) {
+		if c.keyAddressKinds == nil {
+			c.keyAddressKinds = make(map[string][]byte)
+		}
+		c.keyAddressKinds[key] = kinds
+	}
+}
+
+// WithDelimiter makes the stream functions write delim after each MSGP value,
+// so that line-oriented tools can split the output into records.
+//
+// MSGP is binary, so the delimiter may also occur inside a value.  Choose one
+// which cannot occur in the data, or split with an msgp reader instead.
+func WithDelimiter(delim []byte) Option {
+	return func(c *Converter) {
+		c.delimiter = delim
+	}
+}
+
+// WithResume makes the stream functions skip the first n values they would
+// write, to resume a conversion which failed with a WriteError after n whole
+// values: convert the same input again, with n from the error's Written, to
+// the same output, once the Partial bytes of the failed value are cut from
+// its end.  An Appender does this for frames by itself.  The values skipped
+// are still converted, but are neither written nor counted.
+//
+// It cannot be used with WithTrace, whose offsets would be wrong.
+func WithResume(n int) Option {
+	return func(c *Converter) {
+		c.resume = n
+	}
+}
+
+// WithKeyOrder sets the order in which map keys are written.
+func WithKeyOrder(order KeyOrder) Option {
+	return func(c *Converter) {
+		c.keyOrder = order
+	}
+}
+
+// WithIntegerKeys writes maps whose keys are all integers, such as "0" and
+// "-3", with MSGP integer keys rather than strings, as some consumers of
+// index-like maps require.  Such maps are written in order of their keys.
+// Keys must be in their shortest decimal form, so a map with a key such as
+// "01" or "+1" keeps its string keys; so do empty maps.
+func WithIntegerKeys() Option {
+	return func(c *Converter) {
+		c.intKeys = true
+	}
+}
+
+// WithCanonical produces a canonical encoding, so that logically equal
+// documents encode to identical bytes and therefore hash identically.
+//
+// Map keys are always sorted.  In addition, canonical mode fixes a single
+// representation for floating point values:
+//
+//   - every value hinted as "float32" or "float64" is encoded as a msgp float64;
+//     float32 hints do not narrow the value.
+//   - Go float32 inputs are widened via the shortest decimal representation
+//     which round-trips them, so float32(0.1) encodes as float64(0.1).
+//   - negative zero is encoded as positive zero.
+func WithCanonical() Option {
+	return func(c *Converter) {
+		c.canonical = true
+	}
+}
+
+// WithAllErrors continues conversion after an error, so that every problem
+// in the document is reported at once.  The returned error is then an
+// ErrorList.
+func WithAllErrors() Option {
+	return func(c *Converter) {
+		c.allErrors = true
+	}
+}
+
+// DefaultMaxDepth is how deeply values may be nested unless WithMaxDepth
+// says otherwise.
+const DefaultMaxDepth = 1000
+
+// WithMaxDepth limits how deeply values may be nested: a value may be at most
+// depth levels below the root, so that adversarial input cannot exhaust the
+// stack.  Zero or less removes the limit.
+func WithMaxDepth(depth int) Option {
+	return func(c *Converter) {
+		c.maxDepth = depth
+	}
+}
+
+// WithMaxInputSize limits the JSON input of the stream functions to n bytes,
+// so that input from an untrusted source cannot exhaust memory.  Larger input
+// is an ErrInputTooLarge error.
+func WithMaxInputSize(n int64) Option {
+	return func(c *Converter) {
+		c.maxInputSize = n
+	}
+}
+
+// WithMaxOutputSize limits the output to n bytes; more is an ErrOutputTooLarge
+// error.  For the stream functions, the limit applies to everything written,
+// including delimiters.  The limit is checked as each document is converted.
+func WithMaxOutputSize(n int) Option {
+	return func(c *Converter) {
+		c.maxOutputSize = n
+	}
+}
+
+// WithMaxStringLength rejects string values longer than n bytes with
+// ErrStringTooLong, before any of the string heuristics look at them.
+func WithMaxStringLength(n int) Option {
+	return func(c *Converter) {
+		c.maxStringLength = n
+	}
+}
+
+// WithMaxBase64Length stops the string heuristic from trying to decode
+// strings longer than n bytes as base64, which costs an allocation of their
+// size.  They are encoded as strings instead, unless hinted as "base64".
+func WithMaxBase64Length(n int) Option {
+	return func(c *Converter) {
+		c.maxBase64Length = n
+	}
+}
+
+// WithoutBase64Detection stops the string heuristic from decoding strings
+// which happen to be valid base64, such as "DwA=", into byte arrays.  They
+// are encoded as strings, unless hinted as "base64".
+func WithoutBase64Detection() Option {
+	return func(c *Converter) {
+		c.noBase64Detection = true
+	}
+}
+
+// WithoutAddressDetection stops the string heuristic from encoding strings
+// which are valid ndau addresses as addresses, which matters with
+// WithAddressExtension.  Strings hinted as "address", and the values of keys
+// given to WithKeyAddressKinds, are still encoded as addresses.
+func WithoutAddressDetection() Option {
+	return func(c *Converter) {
+		c.noAddressDetection = true
+	}
+}
+
+// WithoutBinaryPassthrough stops the string heuristic from encoding strings
+// which are not valid utf-8 as byte arrays.  They go through the rest of the
+// heuristic, and are encoded as strings, bytes and all, unless they are
+// base64.  Strings hinted as "bin" are still encoded as byte arrays.
+func WithoutBinaryPassthrough() Option {
+	return func(c *Converter) {
+		c.noBinaryPassthrough = true
+	}
+}
+
+// WithoutReflection accepts only the types encoding/json decodes to, plus
+// map[string]string, float32, time.Time and time.Duration, and fails with
+// ErrUnsupportedType for any other input.  Without it, pointers, slices and
+// arrays are converted via reflection, and other types however
+// msgp.AppendIntf encodes them.
+func WithoutReflection() Option {
+	return func(c *Converter) {
+		c.noReflection = true
+	}
+}
+
+// WithTimeout aborts the conversion of a document with ErrTimeout once it
+// has taken longer than d, for batch systems which must bound the time spent
+// on each document.  For the stream functions, the time includes parsing the
+// document, but not reading the input.
+func WithTimeout(d time.Duration) Option {
+	return func(c *Converter) {
+		c.timeout = d
+	}
+}
+
+// newConverter returns a Converter configured with opts.
+func newConverter(opts ...Option) *Converter {
+	c := &Converter{codec: MsgpCodec{}, maxDepth: DefaultMaxDepth}
+	for _, opt := range opts {
+		opt(c)
+	}
+	c.pathHints = compilePathHints(c.typeHints)
+	c.nullPolicyPaths = compilePathHints(c.keyNullPolicies)
+	c.nestedHints = compileNestedHints(c.typeHints)
+	compileCoercions(c.coercions)
+	compileRequired(c.required)
+	return c
+}
+
+// ConvertWithOptions is like Convert, but configures the conversion with opts.
+func ConvertWithOptions(in interface{}, opts ...Option) ([]byte, error) {
+	c := newConverter(opts...)
+	return c.convertRoot(in)
+}
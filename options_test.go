@@ -0,0 +1,438 @@
+package json2msgp_test
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ndau/json2msgp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvertStreamWithOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		opts    []json2msgp.Option
+		wantOut string
+		wantErr bool
+	}{
+		{
+			"null default",
+			`{"Fee":9800000,"To":null}`,
+			nil,
+			"82 a3 46 65 65 d2 00 95 89 40 a2 54 6f c0",
+			false,
+		},
+		{
+			"null as empty array for key",
+			`{"Fee":9800000,"To":null}`,
+			[]json2msgp.Option{json2msgp.WithKeyNullPolicy("To", json2msgp.NullAsEmptyArray)},
+			"82 a3 46 65 65 d2 00 95 89 40 a2 54 6f 90",
+			false,
+		},
+		{
+			"null as empty map",
+			`{"x":null}`,
+			[]json2msgp.Option{json2msgp.WithNullPolicy(json2msgp.NullAsEmptyMap)},
+			"81 a1 78 80",
+			false,
+		},
+		{
+			"key policy overrides default",
+			`{"x":null,"y":null}`,
+			[]json2msgp.Option{
+				json2msgp.WithNullPolicy(json2msgp.NullAsEmptyMap),
+				json2msgp.WithKeyNullPolicy("y", json2msgp.NullAsNil),
+			},
+			"82 a1 78 80 a1 79 c0",
+			false,
+		},
+		{
+			"key policy after a nested map",
+			`{"To":[{"a":1},null]}`,
+			[]json2msgp.Option{
+				json2msgp.WithKeyNullPolicy("To", json2msgp.NullAsEmptyArray),
+				json2msgp.WithKeyNullPolicy("a", json2msgp.NullAsEmptyMap),
+			},
+			"81 a2 54 6f 92 81 a1 61 01 90",
+			false,
+		},
+		{
+			"path policy overrides key policy",
+			`{"EAIFeeTable":[{"To":null}],"To":null}`,
+			[]json2msgp.Option{
+				json2msgp.WithKeyNullPolicy("/EAIFeeTable/*/To", json2msgp.NullAsEmptyArray),
+				json2msgp.WithKeyNullPolicy("To", json2msgp.NullAsEmptyMap),
+			},
+			"82 ab 45 41 49 46 65 65 54 61 62 6c 65 91 81 a2 54 6f 90 a2 54 6f 80",
+			false,
+		},
+		{
+			"null as zero of hinted type",
+			`{"ChangeOn":null}`,
+			[]json2msgp.Option{
+				json2msgp.WithTypeHints(map[string][]string{"ChangeOn": []string{"uint64"}}),
+				json2msgp.WithNullPolicy(json2msgp.NullAsZero),
+			},
+			"81 a8 43 68 61 6e 67 65 4f 6e 00",
+			false,
+		},
+		{
+			"empty as nil",
+			`{"a":[],"m":{},"x":[{}]}`,
+			[]json2msgp.Option{json2msgp.WithEmptyAsNil()},
+			"83 a1 61 c0 a1 6d c0 a1 78 91 c0",
+			false,
+		},
+		{
+			"empty as nil leaves null policy alone",
+			`{"To":null}`,
+			[]json2msgp.Option{
+				json2msgp.WithEmptyAsNil(),
+				json2msgp.WithKeyNullPolicy("To", json2msgp.NullAsEmptyArray),
+			},
+			"81 a2 54 6f 90",
+			false,
+		},
+		{
+			"header16 everywhere",
+			`{"a":[1]}`,
+			[]json2msgp.Option{json2msgp.WithHeaderWidth(json2msgp.Header16)},
+			"de 00 01 a1 61 dc 00 01 01",
+			false,
+		},
+		{
+			"header32 for key",
+			`{"a":[1],"b":{}}`,
+			[]json2msgp.Option{json2msgp.WithKeyHeaderWidth("a", json2msgp.Header32)},
+			"82 a1 61 dd 00 00 00 01 01 a1 62 80",
+			false,
+		},
+		{
+			"str8 everywhere but keys",
+			`{"a":"foo"}`,
+			[]json2msgp.Option{json2msgp.WithStringHeaderWidth(json2msgp.String8)},
+			"81 a1 61 d9 03 66 6f 6f",
+			false,
+		},
+		{
+			"str16 for key",
+			`{"a":"foo","b":"x"}`,
+			[]json2msgp.Option{json2msgp.WithKeyStringHeaderWidth("a", json2msgp.String16)},
+			"82 a1 61 da 00 03 66 6f 6f a1 62 a1 78",
+			false,
+		},
+		{
+			"str hint overrides base64",
+			`{"s":"DwA="}`,
+			[]json2msgp.Option{json2msgp.WithTypeHints(map[string][]string{"s": []string{"str"}})},
+			"81 a1 73 a4 44 77 41 3d",
+			false,
+		},
+		{
+			"bin hint keeps raw bytes",
+			`{"s":"foo"}`,
+			[]json2msgp.Option{json2msgp.WithTypeHints(map[string][]string{"s": []string{"bin"}})},
+			"81 a1 73 c4 03 66 6f 6f",
+			false,
+		},
+		{
+			"base64 hint overrides address",
+			`{"s":"ndnf9ffbzhyf8mk7z5vvqc4quzz5i2exp5zgsmhyhc9cuwr4"}`,
+			[]json2msgp.Option{json2msgp.WithTypeHints(map[string][]string{"s": []string{"base64"}})},
+			"81 a1 73 c4 24 9d d9 df f5 f7 db ce 1c 9f f2 69 3b cf 9b ef a9 ce 2a bb 3c f9 8b 67 b1 a7 9c e0 b2 68 72 85 cf 5c bb 0a f8",
+			false,
+		},
+		{
+			"base64 hint rejects non-base64",
+			`{"s":"foo"}`,
+			[]json2msgp.Option{json2msgp.WithTypeHints(map[string][]string{"s": []string{"base64"}})},
+			"",
+			true,
+		},
+		{
+			"null as zero str",
+			`{"s":null}`,
+			[]json2msgp.Option{
+				json2msgp.WithTypeHints(map[string][]string{"s": []string{"str"}}),
+				json2msgp.WithNullPolicy(json2msgp.NullAsZero),
+			},
+			"81 a1 73 a0",
+			false,
+		},
+		{
+			"delimiter",
+			`"foo"`,
+			[]json2msgp.Option{json2msgp.WithDelimiter([]byte{0x1e})},
+			"a3 66 6f 6f 1e",
+			false,
+		},
+		{
+			"null as zero without hint",
+			`{"ChangeOn":null}`,
+			[]json2msgp.Option{json2msgp.WithNullPolicy(json2msgp.NullAsZero)},
+			"",
+			true,
+		},
+		{
+			"lexical key order",
+			`{"10":1,"2":2,"a":3}`,
+			nil,
+			"83 a2 31 30 01 a1 32 02 a1 61 03",
+			false,
+		},
+		{
+			"integer keys",
+			`{"10":"a","2":{"-1":true},"0":null}`,
+			[]json2msgp.Option{json2msgp.WithIntegerKeys()},
+			"83 00 c0 02 81 ff c3 0a a1 61",
+			false,
+		},
+		{
+			"integer keys need every key",
+			`{"1":1,"01":2,"x":{"2":3}}`,
+			[]json2msgp.Option{json2msgp.WithIntegerKeys()},
+			"83 a2 30 31 02 a1 31 01 a1 78 81 02 03",
+			false,
+		},
+		{
+			"numeric key order",
+			`{"a":3,"10":1,"2":2,"02":4,"b":5}`,
+			[]json2msgp.Option{json2msgp.WithKeyOrder(json2msgp.KeyOrderNumeric)},
+			"85 a2 30 32 04 a1 32 02 a2 31 30 01 a1 61 03 a1 62 05",
+			false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want, err := hex.DecodeString(strings.Replace(tt.wantOut, " ", "", -1))
+			require.NoError(t, err)
+
+			in := bytes.NewBufferString(tt.in)
+			out := &bytes.Buffer{}
+			if err := json2msgp.ConvertStreamWithOptions(in, out, tt.opts...); (err != nil) != tt.wantErr {
+				t.Errorf("ConvertStreamWithOptions() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+
+			require.Equal(t, want, out.Bytes())
+		})
+	}
+}
+
+func TestIntegerKeysStringMap(t *testing.T) {
+	got, err := json2msgp.ConvertWithOptions(map[string]string{"7": "a", "-2": "b"}, json2msgp.WithIntegerKeys())
+	require.NoError(t, err)
+	require.Equal(t, []byte{0x82, 0xfe, 0xa1, 'b', 0x07, 0xa1, 'a'}, got)
+}
+
+func TestCanonicalFloats(t *testing.T) {
+	hints := json2msgp.WithTypeHints(map[string][]string{"f": []string{"float32"}, "g": []string{"float64"}})
+
+	tests := []struct {
+		name string
+		in   interface{}
+		opts []json2msgp.Option
+		want string
+	}{
+		{"float32 hint", map[string]interface{}{"f": 0.5}, []json2msgp.Option{hints}, "81 a1 66 ca 3f 00 00 00"},
+		{"float32 hint canonical", map[string]interface{}{"f": 0.5}, []json2msgp.Option{hints, json2msgp.WithCanonical()}, "81 a1 66 cb 3f e0 00 00 00 00 00 00"},
+		{"negative zero canonical", map[string]interface{}{"g": math.Copysign(0, -1)}, []json2msgp.Option{hints, json2msgp.WithCanonical()}, "81 a1 67 cb 00 00 00 00 00 00 00 00"},
+		{"go float32", float32(0.1), nil, "ca 3d cc cc cd"},
+		{"go float32 canonical", float32(0.1), []json2msgp.Option{json2msgp.WithCanonical()}, "cb 3f b9 99 99 99 99 99 9a"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want, err := hex.DecodeString(strings.Replace(tt.want, " ", "", -1))
+			require.NoError(t, err)
+
+			got, err := json2msgp.ConvertWithOptions(tt.in, tt.opts...)
+			require.NoError(t, err)
+			require.Equal(t, want, got)
+		})
+	}
+}
+
+func TestSizeLimits(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		opts    []json2msgp.Option
+		wantErr error
+	}{
+		{"input within limit", `[1,2]`, []json2msgp.Option{json2msgp.WithMaxInputSize(5)}, nil},
+		{"input too large", `[1,2] `, []json2msgp.Option{json2msgp.WithMaxInputSize(5)}, json2msgp.ErrInputTooLarge},
+		{"output within limit", `[1,2]`, []json2msgp.Option{json2msgp.WithMaxOutputSize(3)}, nil},
+		{"output too large", `[1,2,3]`, []json2msgp.Option{json2msgp.WithMaxOutputSize(3)}, json2msgp.ErrOutputTooLarge},
+		{
+			"output limit spans documents",
+			`[1] [2]`,
+			[]json2msgp.Option{json2msgp.WithMultipleDocuments(), json2msgp.WithMaxOutputSize(3)},
+			json2msgp.ErrOutputTooLarge,
+		},
+		{
+			"delimiter counts",
+			`[1]`,
+			[]json2msgp.Option{json2msgp.WithDelimiter([]byte("\n")), json2msgp.WithMaxOutputSize(2)},
+			json2msgp.ErrOutputTooLarge,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := json2msgp.ConvertStreamWithOptions(bytes.NewBufferString(tt.in), &bytes.Buffer{}, tt.opts...)
+			if tt.wantErr == nil {
+				require.NoError(t, err)
+				return
+			}
+			require.True(t, errors.Is(err, tt.wantErr), "got %v", err)
+		})
+	}
+
+	_, err := json2msgp.ConvertWithOptions([]interface{}{1.0, 2.0, 3.0}, json2msgp.WithMaxOutputSize(3))
+	require.True(t, errors.Is(err, json2msgp.ErrOutputTooLarge))
+}
+
+func TestWithoutReflection(t *testing.T) {
+	opt := json2msgp.WithoutReflection()
+
+	in := map[string]interface{}{"a": []interface{}{"foo", 1.0, true, nil}, "m": map[string]string{"k": "v"}, "f": float32(0.5)}
+	want, err := json2msgp.ConvertWithOptions(in)
+	require.NoError(t, err)
+	got, err := json2msgp.ConvertWithOptions(in, opt)
+	require.NoError(t, err)
+	require.Equal(t, want, got)
+
+	for _, in := range []interface{}{3, []string{"foo"}, &in, struct{}{}} {
+		_, err = json2msgp.ConvertWithOptions(map[string]interface{}{"x": in}, opt)
+		require.True(t, errors.Is(err, json2msgp.ErrUnsupportedType), "%T: got %v", in, err)
+		require.Contains(t, err.Error(), "$.x")
+	}
+}
+
+func TestMaxBase64Length(t *testing.T) {
+	opt := json2msgp.WithMaxBase64Length(4)
+
+	got, err := json2msgp.ConvertWithOptions("DwA=", opt)
+	require.NoError(t, err)
+	require.Equal(t, []byte("\xc4\x02\x0f\x00"), got)
+
+	got, err = json2msgp.ConvertWithOptions("DwAAAA==", opt)
+	require.NoError(t, err)
+	require.Equal(t, []byte("\xa8DwAAAA=="), got)
+
+	// a hint still decodes it
+	got, err = json2msgp.ConvertWithOptions(map[string]interface{}{"s": "DwAAAA=="}, opt,
+		json2msgp.WithTypeHints(map[string][]string{"s": []string{"base64"}}))
+	require.NoError(t, err)
+	require.Equal(t, []byte("\x81\xa1s\xc4\x04\x0f\x00\x00\x00"), got)
+}
+
+func TestWithoutBase64Detection(t *testing.T) {
+	opt := json2msgp.WithoutBase64Detection()
+
+	got, err := json2msgp.ConvertWithOptions("DwA=", opt)
+	require.NoError(t, err)
+	require.Equal(t, []byte("\xa4DwA="), got)
+
+	// a hint still decodes it
+	got, err = json2msgp.ConvertWithOptions(map[string]interface{}{"s": "DwA="}, opt,
+		json2msgp.WithTypeHints(map[string][]string{"s": []string{"base64"}}))
+	require.NoError(t, err)
+	require.Equal(t, []byte("\x81\xa1s\xc4\x02\x0f\x00"), got)
+}
+
+func TestWithoutAddressDetection(t *testing.T) {
+	const addr = "ndnf9ffbzhyf8mk7z5vvqc4quzz5i2exp5zgsmhyhc9cuwr4"
+	ext := json2msgp.WithAddressExtension()
+	want := append([]byte("\xd9\x30"), addr...)
+
+	got, err := json2msgp.ConvertWithOptions(addr, ext)
+	require.NoError(t, err)
+	require.NotEqual(t, want, got)
+
+	// the address is also valid base64
+	got, err = json2msgp.ConvertWithOptions(addr, ext, json2msgp.WithoutAddressDetection())
+	require.NoError(t, err)
+	require.Equal(t, byte(0xc4), got[0])
+
+	got, err = json2msgp.ConvertWithOptions(addr, ext, json2msgp.WithoutAddressDetection(),
+		json2msgp.WithoutBase64Detection())
+	require.NoError(t, err)
+	require.Equal(t, want, got)
+
+	// a hint still encodes it as an address
+	hints := json2msgp.WithTypeHints(map[string][]string{"a": []string{"address"}})
+	want, err = json2msgp.ConvertWithOptions(map[string]interface{}{"a": addr}, ext)
+	require.NoError(t, err)
+	got, err = json2msgp.ConvertWithOptions(map[string]interface{}{"a": addr}, ext, hints,
+		json2msgp.WithoutAddressDetection())
+	require.NoError(t, err)
+	require.Equal(t, want, got)
+}
+
+func TestWithoutBinaryPassthrough(t *testing.T) {
+	opt := json2msgp.WithoutBinaryPassthrough()
+
+	got, err := json2msgp.ConvertWithOptions("\xff", opt)
+	require.NoError(t, err)
+	require.Equal(t, []byte("\xa1\xff"), got)
+
+	got, err = json2msgp.ConvertWithOptions(map[string]interface{}{"s": "\xff"}, opt,
+		json2msgp.WithTypeHints(map[string][]string{"s": []string{"bin"}}))
+	require.NoError(t, err)
+	require.Equal(t, []byte("\x81\xa1s\xc4\x01\xff"), got)
+}
+
+func TestMaxStringLength(t *testing.T) {
+	opt := json2msgp.WithMaxStringLength(4)
+
+	got, err := json2msgp.ConvertWithOptions(map[string]interface{}{"s": "DwA="}, opt)
+	require.NoError(t, err)
+	require.Equal(t, []byte("\x81\xa1s\xc4\x02\x0f\x00"), got)
+
+	_, err = json2msgp.ConvertWithOptions(map[string]interface{}{"s": "DwAA"}, opt)
+	require.NoError(t, err)
+
+	// the limit applies even to hinted strings
+	hints := json2msgp.WithTypeHints(map[string][]string{"s": []string{"str"}})
+	_, err = json2msgp.ConvertWithOptions(map[string]interface{}{"s": "DwAAA"}, opt, hints)
+	require.True(t, errors.Is(err, json2msgp.ErrStringTooLong))
+	require.Contains(t, err.Error(), "$.s")
+}
+
+func TestTimeout(t *testing.T) {
+	in := "[" + strings.Repeat(`"foo",`, 10000) + `"foo"]`
+
+	err := json2msgp.ConvertStreamWithOptions(strings.NewReader(in), &bytes.Buffer{}, json2msgp.WithTimeout(time.Minute))
+	require.NoError(t, err)
+
+	err = json2msgp.ConvertStreamWithOptions(strings.NewReader(in), &bytes.Buffer{}, json2msgp.WithTimeout(time.Nanosecond))
+	require.True(t, errors.Is(err, json2msgp.ErrTimeout), "got %v", err)
+
+	// a timeout ends the conversion even when aggregating errors
+	var v interface{}
+	require.NoError(t, json.Unmarshal([]byte(in), &v))
+	_, err = json2msgp.ConvertWithOptions(v, json2msgp.WithTimeout(time.Nanosecond), json2msgp.WithAllErrors())
+	require.True(t, errors.Is(err, json2msgp.ErrTimeout), "got %v", err)
+	var list json2msgp.ErrorList
+	require.False(t, errors.As(err, &list))
+}
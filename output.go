@@ -0,0 +1,48 @@
+package json2msgp
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+)
+
+// ConvertFile converts in as ConvertStreamCounted does, and writes the
+// result to the file at path.  The file is written atomically: the output
+// goes to a temporary file in the same directory, which is renamed to path
+// only once the conversion has succeeded and been synced.  A failed
+// conversion leaves any existing file at path as it was, so automation
+// watching for it never picks up a truncated artifact.
+func ConvertFile(path string, in io.Reader, opts ...Option) (StreamResult, error) {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return StreamResult{}, errors.Wrap(err, "ConvertFile")
+	}
+	result, err := ConvertStreamCounted(in, f, opts...)
+	if err == nil {
+		err = errors.Wrap(f.Chmod(0644), "ConvertFile")
+	}
+	if err == nil {
+		err = errors.Wrap(f.Sync(), "ConvertFile")
+	}
+	if cerr := f.Close(); err == nil {
+		err = errors.Wrap(cerr, "ConvertFile")
+	}
+	if err == nil {
+		err = errors.Wrap(os.Rename(f.Name(), path), "ConvertFile")
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return result, err
+}
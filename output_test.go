@@ -0,0 +1,49 @@
+package json2msgp_test
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ndau/json2msgp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvertFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "out.msgp")
+
+	result, err := json2msgp.ConvertFile(path, strings.NewReader(`[1]`))
+	require.NoError(t, err)
+	require.Equal(t, json2msgp.StreamResult{Values: 1, Bytes: 2}, result)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, []byte{0x91, 1}, data)
+
+	// a failed conversion leaves the earlier file, and no temporary file
+	_, err = json2msgp.ConvertFile(path, strings.NewReader(`[2] {`), json2msgp.WithMultipleDocuments())
+	require.Error(t, err)
+	data, err = os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, []byte{0x91, 1}, data)
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+
+	// nor does it create a file which did not exist
+	missing := filepath.Join(dir, "missing.msgp")
+	_, err = json2msgp.ConvertFile(missing, strings.NewReader(`{`))
+	require.Error(t, err)
+	_, err = os.Stat(missing)
+	require.True(t, os.IsNotExist(err))
+}
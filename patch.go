@@ -0,0 +1,437 @@
+package json2msgp
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/tinylib/msgp/msgp"
+)
+
+// patchOperation is one operation of an RFC 6902 JSON Patch.
+type patchOperation struct {
+	Op    string          `json:"op"`
+	Path  *string         `json:"path"`
+	From  *string         `json:"from"`
+	Value json.RawMessage `json:"value"`
+}
+
+// ApplyPatch applies the RFC 6902 JSON Patch patch to doc, a value as
+// decoded from JSON, and returns the result without modifying doc.  Either
+// every operation succeeds, or an ErrPatch error says which did not.
+func ApplyPatch(doc interface{}, patch []byte) (interface{}, error) {
+	var ops []patchOperation
+	if err := json.Unmarshal(patch, &ops); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrPatch, err)
+	}
+	doc = deepCopy(doc)
+	for i, op := range ops {
+		var err error
+		doc, err = op.apply(doc)
+		if err != nil {
+			return nil, fmt.Errorf("%w: operation %d (%s): %s", ErrPatch, i, op.Op, err)
+		}
+	}
+	return doc, nil
+}
+
+// ConvertPatched applies the JSON Patch patch to the JSON document doc, and
+// converts the result with opts.  This lets a small change to a system
+// variable be reviewed as a patch rather than as a whole new document.
+func ConvertPatched(doc, patch []byte, opts ...Option) ([]byte, error) {
+	c := newConverter(opts...)
+	v, err := c.newDecoder(doc).single()
+	if err != nil {
+		return nil, errors.Wrap(err, "ConvertPatched reading document")
+	}
+	v, err = ApplyPatch(v, patch)
+	if err != nil {
+		return nil, errors.Wrap(err, "ConvertPatched")
+	}
+	return c.convertRoot(v)
+}
+
+// ConvertPatchedMsgp is like ConvertPatched, but the document is the MSGP
+// current, such as a system variable's value on chain.  Byte arrays in it
+// appear to the patch as base64 strings, as they would in JSON.
+//
+// If typeHints is nil, hints are inferred from current, so that values the
+// patch doesn't touch keep their types.
+func ConvertPatchedMsgp(current, patch []byte, typeHints map[string][]string) ([]byte, error) {
+	var err error
+	if typeHints == nil {
+		typeHints, err = InferHints(current)
+		if err != nil {
+			return nil, errors.Wrap(err, "ConvertPatchedMsgp")
+		}
+	}
+	doc, err := msgpToJSON(current)
+	if err != nil {
+		return nil, errors.Wrap(err, "ConvertPatchedMsgp reading document")
+	}
+	return ConvertPatched(doc, patch, WithTypeHints(typeHints))
+}
+
+// ApplyMergePatch applies the RFC 7396 JSON merge patch patch to doc, a value
+// as decoded from JSON, and returns the result without modifying doc.  Keys
+// of the patch override those of doc, recursively, and null values remove
+// them.
+func ApplyMergePatch(doc interface{}, patch []byte) (interface{}, error) {
+	p, err := newConverter().newDecoder(patch).single()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrPatch, err)
+	}
+	return mergePatch(deepCopy(doc), p), nil
+}
+
+// ConvertMerged applies each of the JSON merge patches patches in turn to
+// the JSON document doc, and converts the result with opts.  This suits
+// environment overlays, which override a few keys of a base document.
+func ConvertMerged(doc []byte, patches [][]byte, opts ...Option) ([]byte, error) {
+	c := newConverter(opts...)
+	v, err := c.newDecoder(doc).single()
+	if err != nil {
+		return nil, errors.Wrap(err, "ConvertMerged reading document")
+	}
+	for i, patch := range patches {
+		v, err = ApplyMergePatch(v, patch)
+		if err != nil {
+			return nil, errors.Wrapf(err, "ConvertMerged patch %d", i)
+		}
+	}
+	return c.convertRoot(v)
+}
+
+// MergeConvert reads a JSON document from each of ins, deep-merges them, and
+// converts the result once with typeHints.  It is the library equivalent of
+// merging a base document and its overlays with jq.
+//
+// Each document overrides the ones before it: where both have a map, their
+// keys are merged, recursively, and otherwise the later value replaces the
+// earlier one.  Unlike ConvertMerged, null is kept as a value rather than
+// removing its key.
+func MergeConvert(ins []io.Reader, typeHints map[string][]string) ([]byte, error) {
+	return MergeConvertWithOptions(ins, WithTypeHints(typeHints))
+}
+
+// MergeConvertWithOptions is like MergeConvert, but configures the conversion
+// with opts.
+func MergeConvertWithOptions(ins []io.Reader, opts ...Option) ([]byte, error) {
+	if len(ins) == 0 {
+		return nil, errors.New("MergeConvert: no inputs")
+	}
+	c := newConverter(opts...)
+	var merged interface{}
+	for i, in := range ins {
+		data, err := io.ReadAll(in)
+		if err != nil {
+			return nil, errors.Wrapf(err, "MergeConvert reading input %d", i)
+		}
+		v, err := c.newDecoder(data).single()
+		if err != nil {
+			return nil, errors.Wrapf(err, "MergeConvert unmarshalling input %d", i)
+		}
+		if i == 0 {
+			merged = v
+		} else {
+			merged = deepMerge(merged, v)
+		}
+	}
+	return c.convertRoot(merged)
+}
+
+// deepMerge merges overlay into base, which it may modify.
+func deepMerge(base, overlay interface{}) interface{} {
+	o, ok := overlay.(map[string]interface{})
+	if !ok {
+		return overlay
+	}
+	b, ok := base.(map[string]interface{})
+	if !ok {
+		return overlay
+	}
+	for key, value := range o {
+		if prev, ok := b[key]; ok {
+			value = deepMerge(prev, value)
+		}
+		b[key] = value
+	}
+	return b
+}
+
+func mergePatch(target, patch interface{}) interface{} {
+	p, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	t, ok := target.(map[string]interface{})
+	if !ok {
+		t = make(map[string]interface{})
+	}
+	for key, value := range p {
+		if value == nil {
+			delete(t, key)
+		} else {
+			t[key] = mergePatch(t[key], value)
+		}
+	}
+	return t
+}
+
+// msgpToJSON returns the single MSGP value in b as JSON.
+func msgpToJSON(b []byte) ([]byte, error) {
+	if err := ValidateMsgp(b); err != nil {
+		return nil, err
+	}
+	var out strings.Builder
+	if _, err := msgp.UnmarshalAsJSON(&out, b); err != nil {
+		return nil, err
+	}
+	return []byte(out.String()), nil
+}
+
+func (op patchOperation) apply(doc interface{}) (interface{}, error) {
+	if op.Path == nil {
+		return nil, fmt.Errorf("missing path")
+	}
+	path, err := parsePointer(*op.Path)
+	if err != nil {
+		return nil, err
+	}
+	value := func() (interface{}, error) {
+		if op.Value == nil {
+			return nil, fmt.Errorf("missing value")
+		}
+		var v interface{}
+		err := json.Unmarshal(op.Value, &v)
+		return v, err
+	}
+	from := func() ([]string, error) {
+		if op.From == nil {
+			return nil, fmt.Errorf("missing from")
+		}
+		return parsePointer(*op.From)
+	}
+
+	switch op.Op {
+	case "add":
+		v, err := value()
+		if err != nil {
+			return nil, err
+		}
+		return pointerAdd(doc, path, v)
+	case "remove":
+		_, doc, err = pointerRemove(doc, path)
+		return doc, err
+	case "replace":
+		v, err := value()
+		if err != nil {
+			return nil, err
+		}
+		if _, doc, err = pointerRemove(doc, path); err != nil {
+			return nil, err
+		}
+		return pointerAdd(doc, path, v)
+	case "move":
+		src, err := from()
+		if err != nil {
+			return nil, err
+		}
+		if len(path) > len(src) && reflect.DeepEqual(path[:len(src)], src) {
+			return nil, fmt.Errorf("cannot move %s into itself", *op.From)
+		}
+		v, doc, err := pointerRemove(doc, src)
+		if err != nil {
+			return nil, err
+		}
+		return pointerAdd(doc, path, v)
+	case "copy":
+		src, err := from()
+		if err != nil {
+			return nil, err
+		}
+		v, err := pointerGet(doc, src)
+		if err != nil {
+			return nil, err
+		}
+		return pointerAdd(doc, path, deepCopy(v))
+	case "test":
+		want, err := value()
+		if err != nil {
+			return nil, err
+		}
+		got, err := pointerGet(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		if !reflect.DeepEqual(want, got) {
+			return nil, fmt.Errorf("%s is not %s", *op.Path, op.Value)
+		}
+		return doc, nil
+	}
+	return nil, fmt.Errorf("unknown operation")
+}
+
+// pointerUnescaper unescapes a JSON Pointer token.
+var pointerUnescaper = strings.NewReplacer("~1", "/", "~0", "~")
+
+// parsePointer splits an RFC 6901 JSON Pointer into its unescaped tokens.
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("pointer %q does not start with /", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = pointerUnescaper.Replace(token)
+	}
+	return tokens, nil
+}
+
+// arrayIndex parses token as an index into an array of length n.  end allows
+// the index n, written as "-" or as a number.
+func arrayIndex(token string, n int, end bool) (int, error) {
+	if token == "-" && end {
+		return n, nil
+	}
+	i, err := strconv.Atoi(token)
+	if err != nil || i < 0 || (len(token) > 1 && token[0] == '0') {
+		return 0, fmt.Errorf("bad array index %q", token)
+	}
+	if i > n || (i == n && !end) {
+		return 0, fmt.Errorf("array index %d out of range", i)
+	}
+	return i, nil
+}
+
+func pointerGet(doc interface{}, path []string) (interface{}, error) {
+	for _, token := range path {
+		switch x := doc.(type) {
+		case map[string]interface{}:
+			v, ok := x[token]
+			if !ok {
+				return nil, fmt.Errorf("no key %q", token)
+			}
+			doc = v
+		case []interface{}:
+			i, err := arrayIndex(token, len(x), false)
+			if err != nil {
+				return nil, err
+			}
+			doc = x[i]
+		default:
+			return nil, fmt.Errorf("cannot index %T with %q", doc, token)
+		}
+	}
+	return doc, nil
+}
+
+// pointerUpdate replaces the container holding the last token of path with
+// what f returns for it.
+func pointerUpdate(doc interface{}, path []string, f func(parent interface{}, token string) (interface{}, error)) (interface{}, error) {
+	if len(path) == 1 {
+		return f(doc, path[0])
+	}
+	child, err := pointerGet(doc, path[:1])
+	if err != nil {
+		return nil, err
+	}
+	child, err = pointerUpdate(child, path[1:], f)
+	if err != nil {
+		return nil, err
+	}
+	switch x := doc.(type) {
+	case map[string]interface{}:
+		x[path[0]] = child
+	case []interface{}:
+		i, _ := arrayIndex(path[0], len(x), false)
+		x[i] = child
+	}
+	return doc, nil
+}
+
+func pointerAdd(doc interface{}, path []string, v interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return v, nil
+	}
+	return pointerUpdate(doc, path, func(parent interface{}, token string) (interface{}, error) {
+		switch x := parent.(type) {
+		case map[string]interface{}:
+			x[token] = v
+			return x, nil
+		case []interface{}:
+			i, err := arrayIndex(token, len(x), true)
+			if err != nil {
+				return nil, err
+			}
+			x = append(x, nil)
+			copy(x[i+1:], x[i:])
+			x[i] = v
+			return x, nil
+		}
+		return nil, fmt.Errorf("cannot add %q to %T", token, parent)
+	})
+}
+
+// pointerRemove removes the value at path, and returns it and the new doc.
+func pointerRemove(doc interface{}, path []string) (interface{}, interface{}, error) {
+	if len(path) == 0 {
+		return doc, nil, nil
+	}
+	var removed interface{}
+	doc, err := pointerUpdate(doc, path, func(parent interface{}, token string) (interface{}, error) {
+		switch x := parent.(type) {
+		case map[string]interface{}:
+			v, ok := x[token]
+			if !ok {
+				return nil, fmt.Errorf("no key %q", token)
+			}
+			removed = v
+			delete(x, token)
+			return x, nil
+		case []interface{}:
+			i, err := arrayIndex(token, len(x), false)
+			if err != nil {
+				return nil, err
+			}
+			removed = x[i]
+			return append(x[:i], x[i+1:]...), nil
+		}
+		return nil, fmt.Errorf("cannot remove %q from %T", token, parent)
+	})
+	return removed, doc, err
+}
+
+// deepCopy copies the maps and arrays of a value decoded from JSON.
+func deepCopy(v interface{}) interface{} {
+	switch x := v.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(x))
+		for key, elem := range x {
+			m[key] = deepCopy(elem)
+		}
+		return m
+	case []interface{}:
+		a := make([]interface{}, len(x))
+		for i, elem := range x {
+			a[i] = deepCopy(elem)
+		}
+		return a
+	}
+	return v
+}
@@ -0,0 +1,172 @@
+package json2msgp_test
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/ndau/json2msgp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyPatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		doc     string
+		patch   string
+		want    string
+		wantErr string
+	}{
+		{"add key", `{"foo":"bar"}`, `[{"op":"add","path":"/baz","value":"qux"}]`, `{"baz":"qux","foo":"bar"}`, ""},
+		{"add element", `{"foo":["bar","baz"]}`, `[{"op":"add","path":"/foo/1","value":"qux"}]`, `{"foo":["bar","qux","baz"]}`, ""},
+		{"append", `[1]`, `[{"op":"add","path":"/-","value":2}]`, `[1,2]`, ""},
+		{"remove", `{"a":{"b":[1,2,3]}}`, `[{"op":"remove","path":"/a/b/1"}]`, `{"a":{"b":[1,3]}}`, ""},
+		{"replace", `{"a":1,"b":2}`, `[{"op":"replace","path":"/a","value":3}]`, `{"a":3,"b":2}`, ""},
+		{"replace root", `{"a":1}`, `[{"op":"replace","path":"","value":[1]}]`, `[1]`, ""},
+		{"move", `{"a":{"x":1},"b":{}}`, `[{"op":"move","from":"/a/x","path":"/b/y"}]`, `{"a":{},"b":{"y":1}}`, ""},
+		{"copy", `{"a":[1]}`, `[{"op":"copy","from":"/a","path":"/b"},{"op":"add","path":"/b/-","value":2}]`, `{"a":[1],"b":[1,2]}`, ""},
+		{"test", `{"a":[1,"x"]}`, `[{"op":"test","path":"/a","value":[1,"x"]}]`, `{"a":[1,"x"]}`, ""},
+		{"escapes", `{"a/b":{"m~n":1}}`, `[{"op":"replace","path":"/a~1b/m~0n","value":2}]`, `{"a/b":{"m~n":2}}`, ""},
+		{"test fails", `{"a":1}`, `[{"op":"test","path":"/a","value":2}]`, "", "operation 0 (test): /a is not 2"},
+		{"missing key", `{"a":1}`, `[{"op":"add","path":"/b","value":1},{"op":"remove","path":"/c"}]`, "", `operation 1 (remove): no key "c"`},
+		{"bad index", `[1]`, `[{"op":"add","path":"/01","value":1}]`, "", `bad array index "01"`},
+		{"out of range", `[1]`, `[{"op":"replace","path":"/1","value":1}]`, "", "out of range"},
+		{"into itself", `{"a":{}}`, `[{"op":"move","from":"/a","path":"/a/b"}]`, "", "into itself"},
+		{"missing value", `{}`, `[{"op":"add","path":"/a"}]`, "", "missing value"},
+		{"unknown op", `{}`, `[{"op":"frob","path":"/a"}]`, "", "unknown operation"},
+		{"not a patch", `{}`, `{"op":"add"}`, "", "cannot unmarshal"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var doc interface{}
+			require.NoError(t, json.Unmarshal([]byte(tt.doc), &doc))
+			got, err := json2msgp.ApplyPatch(doc, []byte(tt.patch))
+			if tt.wantErr != "" {
+				require.True(t, errors.Is(err, json2msgp.ErrPatch), "got %v", err)
+				require.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			out, err := json.Marshal(got)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, string(out))
+
+			// the original is untouched
+			out, err = json.Marshal(doc)
+			require.NoError(t, err)
+			require.JSONEq(t, tt.doc, string(out))
+		})
+	}
+}
+
+func TestConvertPatched(t *testing.T) {
+	hints := map[string][]string{"Fee": []string{"uint64"}}
+	got, err := json2msgp.ConvertPatched([]byte(`{"Fee": 100}`), []byte(`[{"op":"replace","path":"/Fee","value":200}]`),
+		json2msgp.WithTypeHints(hints))
+	require.NoError(t, err)
+	require.Equal(t, []byte("\x81\xa3Fee\xcc\xc8"), got)
+
+	_, err = json2msgp.ConvertPatched([]byte(`{"Fee": 100}`), []byte(`[{"op":"remove","path":"/Nope"}]`))
+	require.True(t, errors.Is(err, json2msgp.ErrPatch), "got %v", err)
+}
+
+func TestConvertPatchedMsgp(t *testing.T) {
+	current, err := json2msgp.Convert(map[string]interface{}{
+		"Table":  []interface{}{[]interface{}{7776000000000.0, 10000000000.0}},
+		"Script": "oACI",
+	}, map[string][]string{"Table": []string{"int64", "uint64"}})
+	require.NoError(t, err)
+
+	got, err := json2msgp.ConvertPatchedMsgp(current, []byte(`[{"op":"replace","path":"/Table/0/1","value":20000000000}]`), nil)
+	require.NoError(t, err)
+	want, err := json2msgp.Convert(map[string]interface{}{
+		"Table":  []interface{}{[]interface{}{7776000000000.0, 20000000000.0}},
+		"Script": "oACI",
+	}, map[string][]string{"Table": []string{"int64", "uint64"}})
+	require.NoError(t, err)
+	require.Equal(t, want, got)
+}
+
+func TestApplyMergePatch(t *testing.T) {
+	// from RFC 7396 appendix A
+	tests := []struct {
+		doc, patch, want string
+	}{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"a":"foo"}`, `null`, `null`},
+		{`{"a":"foo"}`, `"bar"`, `"bar"`},
+		{`{"e":null}`, `{"a":1}`, `{"a":1,"e":null}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+	}
+	for _, tt := range tests {
+		var doc interface{}
+		require.NoError(t, json.Unmarshal([]byte(tt.doc), &doc))
+		got, err := json2msgp.ApplyMergePatch(doc, []byte(tt.patch))
+		require.NoError(t, err)
+		out, err := json.Marshal(got)
+		require.NoError(t, err)
+		require.JSONEq(t, tt.want, string(out), "%s + %s", tt.doc, tt.patch)
+		out, err = json.Marshal(doc)
+		require.NoError(t, err)
+		require.JSONEq(t, tt.doc, string(out))
+	}
+
+	_, err := json2msgp.ApplyMergePatch(nil, []byte(`{"a":1,"a":2}`))
+	require.True(t, errors.Is(err, json2msgp.ErrPatch), "got %v", err)
+}
+
+func TestConvertMerged(t *testing.T) {
+	base := []byte(`{"Fee": 100, "Nodes": {"a": 1, "b": 2}}`)
+	got, err := json2msgp.ConvertMerged(base, [][]byte{
+		[]byte(`{"Nodes": {"b": null, "c": 3}}`),
+		[]byte(`{"Fee": 200}`),
+	}, json2msgp.WithTypeHints(map[string][]string{"Fee": []string{"uint64"}}))
+	require.NoError(t, err)
+	require.Equal(t, []byte("\x82\xa3Fee\xcc\xc8\xa5Nodes\x82\xa1a\x01\xa1c\x03"), got)
+
+	_, err = json2msgp.ConvertMerged(base, [][]byte{[]byte(`{`)})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "ConvertMerged patch 0")
+}
+
+func TestMergeConvert(t *testing.T) {
+	got, err := json2msgp.MergeConvert([]io.Reader{
+		strings.NewReader(`{"Fee": 100, "Nodes": {"a": 1, "b": 2}, "To": ["x"]}`),
+		strings.NewReader(`{"Nodes": {"b": null, "c": 3}, "To": "y"}`),
+		strings.NewReader(`{"Fee": 200}`),
+	}, map[string][]string{"Fee": []string{"uint64"}})
+	require.NoError(t, err)
+	require.Equal(t, []byte("\x83\xa3Fee\xcc\xc8\xa5Nodes\x83\xa1a\x01\xa1b\xc0\xa1c\x03\xa2To\xa1y"), got)
+
+	got, err = json2msgp.MergeConvert([]io.Reader{strings.NewReader(`{"a": 1}`), strings.NewReader(`[2]`)}, nil)
+	require.NoError(t, err)
+	require.Equal(t, []byte{0x91, 2}, got)
+
+	_, err = json2msgp.MergeConvert([]io.Reader{strings.NewReader(`{}`), strings.NewReader(`{`)}, nil)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "MergeConvert unmarshalling input 1")
+
+	_, err = json2msgp.MergeConvert(nil, nil)
+	require.Error(t, err)
+}
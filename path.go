@@ -0,0 +1,316 @@
+package json2msgp
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// WithPath converts only the value at path, rather than the whole document,
+// so that one system variable can be picked out of a file holding several.
+// It replaces WithQuery, and vice versa.
+//
+// path is a JSONPath of keys and indices as the package reports them, such
+// as "$.EAIFeeTable[0]" or `$["odd key"]`; the leading "$." may be left out,
+// as in "EAIFeeTable".  A JSON Pointer such as "/EAIFeeTable/0" also works.
+//
+// The value is converted as it would be in place: type hints see the key it
+// is the value of, and errors report its full path.  If there is no value at
+// path, conversion fails with ErrPathNotFound.
+func WithPath(path string) Option {
+	return func(c *Converter) {
+		c.selection = path
+		c.query = ""
+	}
+}
+
+// pathStep is one step of a path: a key, an index, a JSON Pointer token,
+// which is either, or a wildcard.  A recursive step applies to every value
+// at any depth.
+type pathStep struct {
+	kind      stepKind
+	key       string
+	index     int
+	recursive bool
+}
+
+type stepKind int
+
+const (
+	stepKey stepKind = iota
+	stepIndex
+	stepToken
+	stepWildcard
+)
+
+// parsePath parses a path as described for WithPath.
+func parsePath(path string) ([]pathStep, error) {
+	if strings.HasPrefix(path, "/") {
+		tokens, err := parsePointer(path)
+		if err != nil {
+			return nil, err
+		}
+		steps := make([]pathStep, len(tokens))
+		for i, token := range tokens {
+			steps[i] = pathStep{kind: stepToken, key: token}
+		}
+		return steps, nil
+	}
+
+	s := path
+	switch {
+	case strings.HasPrefix(s, "$"):
+		s = s[1:]
+	case s != "" && s[0] != '.' && s[0] != '[':
+		s = "." + s
+	}
+	var steps []pathStep
+	for s != "" {
+		switch s[0] {
+		case '.':
+			recursive := strings.HasPrefix(s, "..")
+			if recursive {
+				s = s[1:]
+				if strings.HasPrefix(s[1:], "[") {
+					// "..[0]" is short for ".*[0]" at any depth
+					s = s[1:]
+					step, rest, err := parseBracket(s, path)
+					if err != nil {
+						return nil, err
+					}
+					step.recursive = true
+					steps = append(steps, step)
+					s = rest
+					continue
+				}
+			}
+			end := strings.IndexAny(s[1:], ".[")
+			if end < 0 {
+				end = len(s) - 1
+			}
+			if end == 0 {
+				return nil, fmt.Errorf("empty key in path %q", path)
+			}
+			step := pathStep{kind: stepKey, key: s[1 : end+1], recursive: recursive}
+			if step.key == "*" {
+				step.kind = stepWildcard
+			}
+			steps = append(steps, step)
+			s = s[end+1:]
+		case '[':
+			step, rest, err := parseBracket(s, path)
+			if err != nil {
+				return nil, err
+			}
+			steps = append(steps, step)
+			s = rest
+		default:
+			return nil, fmt.Errorf("unexpected %q in path %q", s[0], path)
+		}
+	}
+	return steps, nil
+}
+
+// parseBracket parses the bracketed step at the start of s, and returns it
+// and the rest of s.
+func parseBracket(s, path string) (pathStep, string, error) {
+	if strings.HasPrefix(s[1:], `"`) {
+		quoted, err := strconv.QuotedPrefix(s[1:])
+		if err != nil || !strings.HasPrefix(s[1+len(quoted):], "]") {
+			return pathStep{}, "", fmt.Errorf("bad quoted key in path %q", path)
+		}
+		key, _ := strconv.Unquote(quoted)
+		return pathStep{kind: stepKey, key: key}, s[len(quoted)+2:], nil
+	}
+	end := strings.IndexByte(s, ']')
+	if end < 0 {
+		return pathStep{}, "", fmt.Errorf("unterminated [ in path %q", path)
+	}
+	if s[1:end] == "*" {
+		return pathStep{kind: stepWildcard}, s[end+1:], nil
+	}
+	i, err := strconv.Atoi(s[1:end])
+	if err != nil || !isDecimal(s[1:end]) {
+		return pathStep{}, "", fmt.Errorf("bad index in path %q", path)
+	}
+	return pathStep{kind: stepIndex, index: i}, s[end+1:], nil
+}
+
+// pathMatch is a value found at a path.
+type pathMatch struct {
+	value interface{}
+	// path holds the JSONPath segments of the value, and key the current
+	// key the converter has when it reaches the value in place.
+	path []string
+	key  string
+}
+
+// findAll returns the values at the path of steps in in, in document order
+// with map keys sorted.
+func findAll(in interface{}, steps []pathStep) []pathMatch {
+	matches := []pathMatch{{value: in}}
+	for _, step := range steps {
+		var next []pathMatch
+		for _, m := range matches {
+			if step.recursive {
+				m.descendants(func(d pathMatch) { next = d.apply(step, next) })
+			} else {
+				next = m.apply(step, next)
+			}
+		}
+		matches = next
+	}
+	return matches
+}
+
+// child returns the match for the value of key in m.
+func (m pathMatch) child(key string, value interface{}) pathMatch {
+	path := append(m.path[:len(m.path):len(m.path)], keySegment(key))
+	return pathMatch{value: value, path: path, key: key}
+}
+
+// elem returns the match for element i of m.
+func (m pathMatch) elem(i int, value interface{}) pathMatch {
+	path := append(m.path[:len(m.path):len(m.path)], indexSegment(i))
+	return pathMatch{value: value, path: path, key: m.key}
+}
+
+// apply appends the matches of step in m to out.
+func (m pathMatch) apply(step pathStep, out []pathMatch) []pathMatch {
+	switch x := m.value.(type) {
+	case map[string]interface{}:
+		if step.kind == stepWildcard {
+			for _, key := range sortedKeys(x) {
+				out = append(out, m.child(key, x[key]))
+			}
+		} else if v, ok := x[step.key]; ok && step.kind != stepIndex {
+			out = append(out, m.child(step.key, v))
+		}
+	case map[string]string:
+		if step.kind == stepWildcard {
+			for _, key := range sortedKeys(x) {
+				out = append(out, m.child(key, x[key]))
+			}
+		} else if v, ok := x[step.key]; ok && step.kind != stepIndex {
+			out = append(out, m.child(step.key, v))
+		}
+	case []interface{}:
+		switch step.kind {
+		case stepWildcard:
+			for i, v := range x {
+				out = append(out, m.elem(i, v))
+			}
+		case stepIndex:
+			if step.index < len(x) {
+				out = append(out, m.elem(step.index, x[step.index]))
+			}
+		case stepToken:
+			if i, err := arrayIndex(step.key, len(x), false); err == nil {
+				out = append(out, m.elem(i, x[i]))
+			}
+		}
+	}
+	return out
+}
+
+// descendants calls f with m and each value inside it, parents first.
+func (m pathMatch) descendants(f func(pathMatch)) {
+	f(m)
+	switch x := m.value.(type) {
+	case map[string]interface{}:
+		for _, key := range sortedKeys(x) {
+			m.child(key, x[key]).descendants(f)
+		}
+	case map[string]string:
+		for _, key := range sortedKeys(x) {
+			f(m.child(key, x[key]))
+		}
+	case []interface{}:
+		for i, v := range x {
+			m.elem(i, v).descendants(f)
+		}
+	}
+}
+
+// enter leaves the converter in the state it has when it reaches m in place.
+func (c *Converter) enter(m pathMatch) {
+	c.path = append(c.path[:0], m.path...)
+	c.currentKey = m.key
+}
+
+// selectValue returns the value at c.selection in in, and enters it.
+func (c *Converter) selectValue(in interface{}) (interface{}, error) {
+	steps, err := parsePath(c.selection)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrBadOption, err)
+	}
+	for _, step := range steps {
+		if step.kind == stepWildcard || step.recursive {
+			return nil, fmt.Errorf("%w: path %q may match several values; use WithQuery", ErrBadOption, c.selection)
+		}
+	}
+	matches := findAll(in, steps)
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrPathNotFound, c.selection)
+	}
+	c.enter(matches[0])
+	return matches[0].value, nil
+}
+
+// WithQuery makes the stream functions convert each value matching query in
+// each document, rather than the whole document, and write each as its own
+// MSGP value followed by the delimiter.  A document without matches writes
+// nothing.  It replaces WithPath, and vice versa.
+//
+// query is a path as for WithPath, which may also use the wildcards ".*" and
+// "[*]" for every value of a map or array, and ".." for every value at any
+// depth.  For example, "$..Fee" matches every Fee in a document, and
+// "$.EAIFeeTable[*].To[*]" every address in the EAIFeeTable.  Matches come
+// in document order, with map keys sorted.
+//
+// Each value is converted as it would be in place, as for WithPath.
+func WithQuery(query string) Option {
+	return func(c *Converter) {
+		c.query = query
+		c.selection = ""
+	}
+}
+
+// ConvertQuery converts each value of in which matches query, as for
+// WithQuery, and returns their encodings in order.
+func ConvertQuery(in interface{}, query string, opts ...Option) ([][]byte, error) {
+	c := newConverter(append(opts[:len(opts):len(opts)], WithQuery(query))...)
+	return c.convertQuery(in)
+}
+
+// convertQuery converts each value of in which matches c.query.
+func (c *Converter) convertQuery(in interface{}) ([][]byte, error) {
+	steps, err := parsePath(c.query)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrBadOption, err)
+	}
+	// each value is written after the previous ones, as far as traces and
+	// the output size limit are concerned
+	base := c.traceBase
+	defer func() { c.path, c.traceBase = c.path[:0], base }()
+	var outs [][]byte
+	for _, m := range findAll(in, steps) {
+		c.enter(m)
+		b, err := c.convertRoot(m.value)
+		if err != nil {
+			return nil, err
+		}
+		outs = append(outs, b)
+		c.traceBase += len(b) + c.separatorSize()
+	}
+	return outs, nil
+}
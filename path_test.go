@@ -0,0 +1,123 @@
+package json2msgp_test
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ndau/json2msgp"
+	"github.com/stretchr/testify/require"
+	"github.com/tinylib/msgp/msgp"
+)
+
+func TestWithPath(t *testing.T) {
+	doc := `{"EAIFeeTable":[{"Fee":4000000,"To":null},{"Fee":200,"To":null}],"odd key":{"Fee":1},"n":[[1,2]]}`
+	hints := map[string][]string{"Fee": {"float64"}}
+	tests := []struct {
+		name    string
+		path    string
+		want    string
+		wantErr error
+	}{
+		{"root", "$", doc, nil},
+		{"key", "$.EAIFeeTable", `[{"Fee":4000000,"To":null},{"Fee":200,"To":null}]`, nil},
+		{"bare key", "EAIFeeTable[1]", `{"Fee":200,"To":null}`, nil},
+		{"quoted key", `$["odd key"]`, `{"Fee":1}`, nil},
+		{"hinted by its key", "$.EAIFeeTable[1].Fee", `{"Fee":200}`, nil},
+		{"index", "n[0][1]", `2`, nil},
+		{"pointer", "/EAIFeeTable/0", `{"Fee":4000000,"To":null}`, nil},
+		{"pointer escape", "/odd key", `{"Fee":1}`, nil},
+		{"missing key", "$.Nope", "", json2msgp.ErrPathNotFound},
+		{"index out of range", "$.EAIFeeTable[2]", "", json2msgp.ErrPathNotFound},
+		{"index into map", "$[0]", "", json2msgp.ErrPathNotFound},
+		{"key into array", "$.n.x", "", json2msgp.ErrPathNotFound},
+		{"bad syntax", "$.EAIFeeTable[x]", "", json2msgp.ErrBadOption},
+		{"empty key", "$..Fee", "", json2msgp.ErrBadOption},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got bytes.Buffer
+			err := json2msgp.ConvertStreamWithOptions(strings.NewReader(doc), &got,
+				json2msgp.WithPath(tt.path), json2msgp.WithTypeHints(hints))
+			if tt.wantErr != nil {
+				require.True(t, errors.Is(err, tt.wantErr), "got %v", err)
+				return
+			}
+			require.NoError(t, err)
+			var want bytes.Buffer
+			err = json2msgp.ConvertStream(strings.NewReader(tt.want), &want, hints)
+			require.NoError(t, err)
+			if tt.name == "hinted by its key" {
+				// just the value, without the map around it
+				want.Next(5)
+			}
+			require.Equal(t, want.Bytes(), got.Bytes())
+		})
+	}
+
+	// errors report the full path of the value
+	_, err := json2msgp.ConvertWithOptions(map[string]interface{}{"a": []interface{}{-1.0}},
+		json2msgp.WithPath("a"), json2msgp.WithTypeHints(map[string][]string{"a": {"uint8"}}))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "$.a[0]")
+}
+
+func TestWithQuery(t *testing.T) {
+	doc := map[string]interface{}{
+		"EAIFeeTable": []interface{}{
+			map[string]interface{}{"Fee": 4000000.0, "To": []interface{}{"a", "b"}},
+			map[string]interface{}{"Fee": 200.0, "To": nil},
+		},
+		"Other": map[string]interface{}{"Fee": 1.0, "x": []interface{}{[]interface{}{1.0, 2.0}}},
+	}
+	hints := map[string][]string{"Fee": {"float64"}, "x": {"int8", "uint8"}}
+	f := func(x float64) []byte { return msgp.AppendFloat64(nil, x) }
+	tests := []struct {
+		query   string
+		want    [][]byte
+		wantErr error
+	}{
+		{"$..Fee", [][]byte{f(4000000), f(200), f(1)}, nil},
+		{"$.EAIFeeTable[*].Fee", [][]byte{f(4000000), f(200)}, nil},
+		{"$.EAIFeeTable[*].To[*]", [][]byte{{0xa1, 'a'}, {0xa1, 'b'}}, nil},
+		{"$.*.Fee", [][]byte{f(1)}, nil},
+		{"$..x[0][1]", [][]byte{msgp.AppendUint8(nil, 2)}, nil},
+		{"$..[1]", [][]byte{{0x82, 0xa3, 'F', 'e', 'e', 0xcb, 0x40, 0x69, 0, 0, 0, 0, 0, 0, 0xa2, 'T', 'o', 0xc0}, {0xa1, 'b'}, {2}}, nil},
+		{"/Other/Fee", [][]byte{f(1)}, nil},
+		{"$.Nope[*]", nil, nil},
+		{"$[", nil, json2msgp.ErrBadOption},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			got, err := json2msgp.ConvertQuery(doc, tt.query, json2msgp.WithTypeHints(hints))
+			if tt.wantErr != nil {
+				require.True(t, errors.Is(err, tt.wantErr), "got %v", err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+
+	// the stream functions write each match of each document
+	var out bytes.Buffer
+	result, err := json2msgp.ConvertStreamCounted(strings.NewReader(`{"a":[1,2]} {"a":[]} {"a":[3]}`), &out,
+		json2msgp.WithMultipleDocuments(), json2msgp.WithQuery("a[*]"), json2msgp.WithDelimiter([]byte{0}))
+	require.NoError(t, err)
+	require.Equal(t, []byte{1, 0, 2, 0, 3, 0}, out.Bytes())
+	require.Equal(t, 3, result.Values)
+
+	// WithPath takes a single value
+	_, err = json2msgp.ConvertWithOptions(doc, json2msgp.WithPath("$..Fee"))
+	require.True(t, errors.Is(err, json2msgp.ErrBadOption), "got %v", err)
+}
@@ -0,0 +1,67 @@
+package json2msgp
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"fmt"
+	"strings"
+)
+
+// WithStripKeyPrefix removes prefix from the top-level keys of the document
+// which have it, such as the "sysvar." namespace of documents exported from
+// the config service, so that they encode with the names the chain expects.
+// Keys without the prefix are kept as they are.
+//
+// It applies before WithUnflatten, so that the prefix does not become a
+// level of nesting.  Type hints and other per-key options see the stripped
+// keys.  If two keys are the same once stripped, conversion fails with
+// ErrDuplicateKey.
+func WithStripKeyPrefix(prefix string) Option {
+	return func(c *Converter) {
+		c.stripKeyPrefix = prefix
+	}
+}
+
+// WithAddKeyPrefix prepends prefix to every top-level key of the document.
+// It applies after WithFlatten, and after any prefix is stripped.  Type
+// hints and other per-key options see the prefixed keys.
+func WithAddKeyPrefix(prefix string) Option {
+	return func(c *Converter) {
+		c.addKeyPrefix = prefix
+	}
+}
+
+// renameKeys returns in with the top-level keys rename returns, if it is a
+// map.
+func renameKeys(in interface{}, rename func(key string) string) (interface{}, error) {
+	m, ok := in.(map[string]interface{})
+	if !ok {
+		return in, nil
+	}
+	out := make(map[string]interface{}, len(m))
+	for _, key := range sortedKeys(m) {
+		name := rename(key)
+		if _, ok := out[name]; ok {
+			return nil, fmt.Errorf("%w %q after renaming %q", ErrDuplicateKey, name, key)
+		}
+		out[name] = m[key]
+	}
+	return out, nil
+}
+
+// stripPrefix is the rename of WithStripKeyPrefix.
+func (c *Converter) stripPrefix(key string) string {
+	return strings.TrimPrefix(key, c.stripKeyPrefix)
+}
+
+// addPrefix is the rename of WithAddKeyPrefix.
+func (c *Converter) addPrefix(key string) string {
+	return c.addKeyPrefix + key
+}
@@ -0,0 +1,65 @@
+package json2msgp_test
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ndau/json2msgp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyPrefix(t *testing.T) {
+	hints := map[string][]string{"Fee": {"float64"}}
+	tests := []struct {
+		name    string
+		in      string
+		opts    []json2msgp.Option
+		want    string
+		wantErr error
+	}{
+		{"strip", `{"sysvar.Fee":1,"Other":{"sysvar.x":2}}`,
+			[]json2msgp.Option{json2msgp.WithStripKeyPrefix("sysvar.")}, `{"Fee":1,"Other":{"sysvar.x":2}}`, nil},
+		{"strip then unflatten", `{"sysvar.EAIFeeTable.0.Fee":1}`,
+			[]json2msgp.Option{json2msgp.WithStripKeyPrefix("sysvar."), json2msgp.WithUnflatten(".")},
+			`{"EAIFeeTable":[{"Fee":1}]}`, nil},
+		{"strip collision", `{"sysvar.Fee":1,"Fee":2}`,
+			[]json2msgp.Option{json2msgp.WithStripKeyPrefix("sysvar.")}, "", json2msgp.ErrDuplicateKey},
+		{"add", `{"Fee":1,"To":{"a":2}}`,
+			[]json2msgp.Option{json2msgp.WithAddKeyPrefix("ns:")}, `{"ns:Fee":1,"ns:To":{"a":2}}`, nil},
+		{"add after flatten", `{"a":{"b":1}}`,
+			[]json2msgp.Option{json2msgp.WithAddKeyPrefix("ns."), json2msgp.WithFlatten(".")}, `{"ns.a.b":1}`, nil},
+		{"replace", `{"old.Fee":1}`,
+			[]json2msgp.Option{json2msgp.WithStripKeyPrefix("old."), json2msgp.WithAddKeyPrefix("new.")}, `{"new.Fee":1}`, nil},
+		{"not a map", `[{"sysvar.Fee":1}]`,
+			[]json2msgp.Option{json2msgp.WithStripKeyPrefix("sysvar.")}, `[{"sysvar.Fee":1}]`, nil},
+		{"hints see stripped keys", `{"sysvar.Fee":1}`,
+			[]json2msgp.Option{json2msgp.WithStripKeyPrefix("sysvar.")}, `{"Fee":1}`, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got bytes.Buffer
+			opts := append(tt.opts, json2msgp.WithTypeHints(hints))
+			err := json2msgp.ConvertStreamWithOptions(strings.NewReader(tt.in), &got, opts...)
+			if tt.wantErr != nil {
+				require.True(t, errors.Is(err, tt.wantErr), "got %v", err)
+				return
+			}
+			require.NoError(t, err)
+			var want bytes.Buffer
+			err = json2msgp.ConvertStream(strings.NewReader(tt.want), &want, hints)
+			require.NoError(t, err)
+			require.Equal(t, want.Bytes(), got.Bytes())
+		})
+	}
+}
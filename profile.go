@@ -0,0 +1,285 @@
+package json2msgp
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Profile bundles everything which decides how a document is encoded, so
+// that a whole team can pin one file, and get identical encodings from every
+// program which reads it:
+//
+//	{
+//	  "name": "sysvars",
+//	  "hints": {"Fee": ["uint64"]},
+//	  "key_null_policies": {"To": "empty array"},
+//	  "key_order": "lexical",
+//	  "limits": {"max_depth": 64, "timeout": "5s"}
+//	}
+//
+// The policies are named as in their constants, in lower case with spaces:
+// "nil", "empty array", "empty map" and "zero" for nulls; "compact", "16"
+// and "32" for header widths, and also "8" for string header widths; "pass
+// through", "strip", "escape" and "error" for control characters; "error",
+// "first wins" and "last wins" for duplicate keys; "lexical" and "numeric"
+// for key order; and "msgp extension", "timestamp extension" and
+// "microseconds" for times.  Fields left out keep their defaults.
+type Profile struct {
+	// Name identifies the profile to the people using it.
+	Name string `json:"name,omitempty"`
+
+	// See WithTypeHints.
+	Hints map[string][]string `json:"hints,omitempty"`
+	// See WithInferredHints.
+	InferHints bool `json:"infer_hints,omitempty"`
+	// See WithCoercions.
+	Coercions []Coercion `json:"coercions,omitempty"`
+
+	// See WithNullPolicy and WithKeyNullPolicy.
+	NullPolicy      string            `json:"null_policy,omitempty"`
+	KeyNullPolicies map[string]string `json:"key_null_policies,omitempty"`
+	// See WithEmptyAsNil.
+	EmptyAsNil bool `json:"empty_as_nil,omitempty"`
+	// See WithHeaderWidth and WithKeyHeaderWidth.
+	HeaderWidth     string            `json:"header_width,omitempty"`
+	KeyHeaderWidths map[string]string `json:"key_header_widths,omitempty"`
+	// See WithStringHeaderWidth and WithKeyStringHeaderWidth.
+	StringHeaderWidth     string            `json:"string_header_width,omitempty"`
+	KeyStringHeaderWidths map[string]string `json:"key_string_header_widths,omitempty"`
+	// See WithAddressExtension.
+	AddressExtension bool `json:"address_extension,omitempty"`
+	// See WithKeyAddressKinds.  The kinds of each key are a string of kind
+	// characters, such as "an".
+	KeyAddressKinds map[string]string `json:"key_address_kinds,omitempty"`
+	// See WithControlCharPolicy.
+	ControlChars string `json:"control_chars,omitempty"`
+	// See WithDuplicateKeyPolicy.
+	DuplicateKeys string `json:"duplicate_keys,omitempty"`
+	// See WithKeyOrder and WithIntegerKeys.
+	KeyOrder    string `json:"key_order,omitempty"`
+	IntegerKeys bool   `json:"integer_keys,omitempty"`
+	// See WithCanonical.
+	Canonical bool `json:"canonical,omitempty"`
+	// See WithTimeEncoding.
+	TimeEncoding string `json:"time_encoding,omitempty"`
+
+	// See WithLimits.
+	Limits *ProfileLimits `json:"limits,omitempty"`
+}
+
+// ProfileLimits are the Limits of a Profile.  Timeout is a duration as
+// time.ParseDuration reads it, such as "5s".
+type ProfileLimits struct {
+	MaxDepth        int    `json:"max_depth,omitempty"`
+	MaxInputSize    int64  `json:"max_input_size,omitempty"`
+	MaxOutputSize   int    `json:"max_output_size,omitempty"`
+	MaxStringLength int    `json:"max_string_length,omitempty"`
+	MaxBase64Length int    `json:"max_base64_length,omitempty"`
+	Timeout         string `json:"timeout,omitempty"`
+	Parallelism     int    `json:"parallelism,omitempty"`
+}
+
+var (
+	nullPolicyNames = map[string]int{
+		"nil":         int(NullAsNil),
+		"empty array": int(NullAsEmptyArray),
+		"empty map":   int(NullAsEmptyMap),
+		"zero":        int(NullAsZero),
+	}
+	headerWidthNames = map[string]int{
+		"compact": int(HeaderCompact),
+		"16":      int(Header16),
+		"32":      int(Header32),
+	}
+	stringHeaderWidthNames = map[string]int{
+		"compact": int(StringCompact),
+		"8":       int(String8),
+		"16":      int(String16),
+		"32":      int(String32),
+	}
+	controlCharNames = map[string]int{
+		"pass through": int(ControlPassThrough),
+		"strip":        int(ControlStrip),
+		"escape":       int(ControlEscape),
+		"error":        int(ControlError),
+	}
+	duplicateKeyNames = map[string]int{
+		"error":      int(DuplicateKeyError),
+		"first wins": int(DuplicateKeyFirstWins),
+		"last wins":  int(DuplicateKeyLastWins),
+	}
+	keyOrderNames = map[string]int{
+		"lexical": int(KeyOrderLexical),
+		"numeric": int(KeyOrderNumeric),
+	}
+	timeEncodingNames = map[string]int{
+		"msgp extension":      int(TimeAsMsgpExtension),
+		"timestamp extension": int(TimeAsTimestampExtension),
+		"microseconds":        int(TimeAsMicroseconds),
+	}
+)
+
+// ReadProfile reads a Profile from JSON, and checks it.  Unknown fields are
+// an error, so that a misspelled field is not silently ignored.
+func ReadProfile(r io.Reader) (*Profile, error) {
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	p := &Profile{}
+	if err := dec.Decode(p); err != nil {
+		return nil, errors.Wrap(err, "ReadProfile")
+	}
+	if _, err := p.Options(); err != nil {
+		return nil, errors.Wrap(err, "ReadProfile")
+	}
+	return p, nil
+}
+
+// Write writes the profile as JSON, for ReadProfile.
+func (p *Profile) Write(w io.Writer) error {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err == nil {
+		_, err = w.Write(append(data, '\n'))
+	}
+	return errors.Wrap(err, "Profile.Write")
+}
+
+// Options returns the options which configure a conversion as the profile
+// says.  Options given after them override them.  It is an ErrBadOption
+// error for the profile to name an unknown policy.
+//
+// As for WithLimits, the parallelism limit is shared by the conversions
+// using the options returned by one call, so call it once and share them.
+func (p *Profile) Options() ([]Option, error) {
+	var opts []Option
+	add := func(opt Option) { opts = append(opts, opt) }
+	bad := func(field, name string) error {
+		return fmt.Errorf("%w: unknown %s %q", ErrBadOption, field, name)
+	}
+
+	if p.Hints != nil {
+		add(WithTypeHints(p.Hints))
+	}
+	if p.Coercions != nil {
+		add(WithCoercions(p.Coercions...))
+	}
+	if p.NullPolicy != "" {
+		policy, ok := nullPolicyNames[p.NullPolicy]
+		if !ok {
+			return nil, bad("null_policy", p.NullPolicy)
+		}
+		add(WithNullPolicy(NullPolicy(policy)))
+	}
+	for _, key := range sortedKeys(p.KeyNullPolicies) {
+		policy, ok := nullPolicyNames[p.KeyNullPolicies[key]]
+		if !ok {
+			return nil, bad("null_policy", p.KeyNullPolicies[key])
+		}
+		add(WithKeyNullPolicy(key, NullPolicy(policy)))
+	}
+	if p.EmptyAsNil {
+		add(WithEmptyAsNil())
+	}
+	if p.HeaderWidth != "" {
+		width, ok := headerWidthNames[p.HeaderWidth]
+		if !ok {
+			return nil, bad("header_width", p.HeaderWidth)
+		}
+		add(WithHeaderWidth(HeaderWidth(width)))
+	}
+	for _, key := range sortedKeys(p.KeyHeaderWidths) {
+		width, ok := headerWidthNames[p.KeyHeaderWidths[key]]
+		if !ok {
+			return nil, bad("header_width", p.KeyHeaderWidths[key])
+		}
+		add(WithKeyHeaderWidth(key, HeaderWidth(width)))
+	}
+	if p.StringHeaderWidth != "" {
+		width, ok := stringHeaderWidthNames[p.StringHeaderWidth]
+		if !ok {
+			return nil, bad("string_header_width", p.StringHeaderWidth)
+		}
+		add(WithStringHeaderWidth(StringHeaderWidth(width)))
+	}
+	for _, key := range sortedKeys(p.KeyStringHeaderWidths) {
+		width, ok := stringHeaderWidthNames[p.KeyStringHeaderWidths[key]]
+		if !ok {
+			return nil, bad("string_header_width", p.KeyStringHeaderWidths[key])
+		}
+		add(WithKeyStringHeaderWidth(key, StringHeaderWidth(width)))
+	}
+	if p.AddressExtension {
+		add(WithAddressExtension())
+	}
+	for _, key := range sortedKeys(p.KeyAddressKinds) {
+		add(WithKeyAddressKinds(key, []byte(p.KeyAddressKinds[key])...))
+	}
+	if p.ControlChars != "" {
+		policy, ok := controlCharNames[p.ControlChars]
+		if !ok {
+			return nil, bad("control_chars", p.ControlChars)
+		}
+		add(WithControlCharPolicy(ControlCharPolicy(policy)))
+	}
+	if p.DuplicateKeys != "" {
+		policy, ok := duplicateKeyNames[p.DuplicateKeys]
+		if !ok {
+			return nil, bad("duplicate_keys", p.DuplicateKeys)
+		}
+		add(WithDuplicateKeyPolicy(DuplicateKeyPolicy(policy)))
+	}
+	if p.KeyOrder != "" {
+		order, ok := keyOrderNames[p.KeyOrder]
+		if !ok {
+			return nil, bad("key_order", p.KeyOrder)
+		}
+		add(WithKeyOrder(KeyOrder(order)))
+	}
+	if p.IntegerKeys {
+		add(WithIntegerKeys())
+	}
+	if p.Canonical {
+		add(WithCanonical())
+	}
+	if p.TimeEncoding != "" {
+		enc, ok := timeEncodingNames[p.TimeEncoding]
+		if !ok {
+			return nil, bad("time_encoding", p.TimeEncoding)
+		}
+		add(WithTimeEncoding(TimeEncoding(enc)))
+	}
+	if p.InferHints {
+		add(WithInferredHints())
+	}
+	if l := p.Limits; l != nil {
+		limits := Limits{
+			MaxDepth:        l.MaxDepth,
+			MaxInputSize:    l.MaxInputSize,
+			MaxOutputSize:   l.MaxOutputSize,
+			MaxStringLength: l.MaxStringLength,
+			MaxBase64Length: l.MaxBase64Length,
+			Parallelism:     l.Parallelism,
+		}
+		if l.Timeout != "" {
+			timeout, err := time.ParseDuration(l.Timeout)
+			if err != nil {
+				return nil, fmt.Errorf("%w: timeout: %s", ErrBadOption, err)
+			}
+			limits.Timeout = timeout
+		}
+		add(WithLimits(limits))
+	}
+	return opts, nil
+}
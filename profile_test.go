@@ -0,0 +1,103 @@
+package json2msgp_test
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ndau/json2msgp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProfile(t *testing.T) {
+	p, err := json2msgp.ReadProfile(strings.NewReader(`{
+		"name": "sysvars",
+		"hints": {"Fee": ["float64"]},
+		"coercions": [{"from": "string", "to": "number", "hinted": true}],
+		"key_null_policies": {"To": "empty array"},
+		"key_header_widths": {"Nodes": "16"},
+		"key_order": "numeric",
+		"limits": {"max_depth": 8, "timeout": "5s"}
+	}`))
+	require.NoError(t, err)
+	require.Equal(t, "sysvars", p.Name)
+	require.Equal(t, &json2msgp.ProfileLimits{MaxDepth: 8, Timeout: "5s"}, p.Limits)
+
+	in := map[string]interface{}{
+		"Fee":   "1.5",
+		"To":    nil,
+		"Nodes": map[string]interface{}{"10": 1.0, "9": 2.0},
+	}
+	opts, err := p.Options()
+	require.NoError(t, err)
+	got, err := json2msgp.ConvertWithOptions(in, opts...)
+	require.NoError(t, err)
+	want, err := json2msgp.ConvertWithOptions(in,
+		json2msgp.WithTypeHints(map[string][]string{"Fee": {"float64"}}),
+		json2msgp.WithCoercions(json2msgp.Coercion{From: "string", To: "number", Hinted: true}),
+		json2msgp.WithKeyNullPolicy("To", json2msgp.NullAsEmptyArray),
+		json2msgp.WithKeyHeaderWidth("Nodes", json2msgp.Header16),
+		json2msgp.WithKeyOrder(json2msgp.KeyOrderNumeric),
+		json2msgp.WithLimits(json2msgp.Limits{MaxDepth: 8, Timeout: 5 * time.Second}))
+	require.NoError(t, err)
+	require.Equal(t, want, got)
+
+	// the limits apply
+	_, err = json2msgp.ConvertWithOptions([]interface{}{[]interface{}{[]interface{}{[]interface{}{[]interface{}{
+		[]interface{}{[]interface{}{[]interface{}{[]interface{}{[]interface{}{}}}}}}}}}}, opts...)
+	require.True(t, errors.Is(err, json2msgp.ErrMaxDepth), "got %v", err)
+
+	// a profile survives being written and read back
+	var buf bytes.Buffer
+	require.NoError(t, p.Write(&buf))
+	back, err := json2msgp.ReadProfile(&buf)
+	require.NoError(t, err)
+	require.Equal(t, p, back)
+}
+
+func TestProfileFlags(t *testing.T) {
+	p, err := json2msgp.ReadProfile(strings.NewReader(`{"canonical": true, "infer_hints": true}`))
+	require.NoError(t, err)
+	opts, err := p.Options()
+	require.NoError(t, err)
+
+	in := map[string]interface{}{"Fee": 200.0, "Rate": float32(0.1)}
+	got, err := json2msgp.ConvertWithOptions(in, opts...)
+	require.NoError(t, err)
+	want, err := json2msgp.ConvertWithOptions(in, json2msgp.WithCanonical(), json2msgp.WithInferredHints())
+	require.NoError(t, err)
+	require.Equal(t, want, got)
+	plain, err := json2msgp.ConvertWithOptions(in)
+	require.NoError(t, err)
+	require.NotEqual(t, plain, got)
+}
+
+func TestProfileErrors(t *testing.T) {
+	for _, in := range []string{
+		`{"null_policy": "none"}`,
+		`{"key_null_policies": {"To": "empty list"}}`,
+		`{"header_width": "8"}`,
+		`{"key_string_header_widths": {"Name": "64"}}`,
+		`{"control_chars": "drop"}`,
+		`{"duplicate_keys": "any"}`,
+		`{"key_order": "random"}`,
+		`{"time_encoding": "nanoseconds"}`,
+		`{"limits": {"timeout": "soon"}}`,
+	} {
+		_, err := json2msgp.ReadProfile(strings.NewReader(in))
+		require.True(t, errors.Is(err, json2msgp.ErrBadOption), "%s: got %v", in, err)
+	}
+	_, err := json2msgp.ReadProfile(strings.NewReader(`{"hint": {}}`))
+	require.Error(t, err)
+}
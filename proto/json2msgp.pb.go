@@ -0,0 +1,359 @@
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.30.0
+// 	protoc        (unknown)
+// source: json2msgp.proto
+
+package json2msgppb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// Hint is the list of type hints for one key, as in json2msgp.ConvertStream.
+type Hint struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Types []string `protobuf:"bytes,1,rep,name=types,proto3" json:"types,omitempty"`
+}
+
+func (x *Hint) Reset() {
+	*x = Hint{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_json2msgp_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Hint) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Hint) ProtoMessage() {}
+
+func (x *Hint) ProtoReflect() protoreflect.Message {
+	mi := &file_json2msgp_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Hint.ProtoReflect.Descriptor instead.
+func (*Hint) Descriptor() ([]byte, []int) {
+	return file_json2msgp_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Hint) GetTypes() []string {
+	if x != nil {
+		return x.Types
+	}
+	return nil
+}
+
+type ConvertRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// JSON text, or a chunk of it.
+	Json []byte `protobuf:"bytes,1,opt,name=json,proto3" json:"json,omitempty"`
+	// Type hints by key.
+	TypeHints map[string]*Hint `protobuf:"bytes,2,rep,name=type_hints,json=typeHints,proto3" json:"type_hints,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	// Convert as the named system variable; see json2msgp.WithSysvar.
+	Sysvar string `protobuf:"bytes,3,opt,name=sysvar,proto3" json:"sysvar,omitempty"`
+	// Produce the canonical encoding; see json2msgp.WithCanonical.
+	Canonical bool `protobuf:"varint,4,opt,name=canonical,proto3" json:"canonical,omitempty"`
+	// Report every error rather than the first; see json2msgp.WithAllErrors.
+	AllErrors bool `protobuf:"varint,5,opt,name=all_errors,json=allErrors,proto3" json:"all_errors,omitempty"`
+}
+
+func (x *ConvertRequest) Reset() {
+	*x = ConvertRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_json2msgp_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ConvertRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ConvertRequest) ProtoMessage() {}
+
+func (x *ConvertRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_json2msgp_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ConvertRequest.ProtoReflect.Descriptor instead.
+func (*ConvertRequest) Descriptor() ([]byte, []int) {
+	return file_json2msgp_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *ConvertRequest) GetJson() []byte {
+	if x != nil {
+		return x.Json
+	}
+	return nil
+}
+
+func (x *ConvertRequest) GetTypeHints() map[string]*Hint {
+	if x != nil {
+		return x.TypeHints
+	}
+	return nil
+}
+
+func (x *ConvertRequest) GetSysvar() string {
+	if x != nil {
+		return x.Sysvar
+	}
+	return ""
+}
+
+func (x *ConvertRequest) GetCanonical() bool {
+	if x != nil {
+		return x.Canonical
+	}
+	return false
+}
+
+func (x *ConvertRequest) GetAllErrors() bool {
+	if x != nil {
+		return x.AllErrors
+	}
+	return false
+}
+
+type ConvertResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// The MSGP encoding.
+	Msgp []byte `protobuf:"bytes,1,opt,name=msgp,proto3" json:"msgp,omitempty"`
+	// Warnings about heuristic decisions; see json2msgp.Warning.
+	Warnings []string `protobuf:"bytes,2,rep,name=warnings,proto3" json:"warnings,omitempty"`
+}
+
+func (x *ConvertResponse) Reset() {
+	*x = ConvertResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_json2msgp_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ConvertResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ConvertResponse) ProtoMessage() {}
+
+func (x *ConvertResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_json2msgp_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ConvertResponse.ProtoReflect.Descriptor instead.
+func (*ConvertResponse) Descriptor() ([]byte, []int) {
+	return file_json2msgp_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *ConvertResponse) GetMsgp() []byte {
+	if x != nil {
+		return x.Msgp
+	}
+	return nil
+}
+
+func (x *ConvertResponse) GetWarnings() []string {
+	if x != nil {
+		return x.Warnings
+	}
+	return nil
+}
+
+var File_json2msgp_proto protoreflect.FileDescriptor
+
+var file_json2msgp_proto_rawDesc = []byte{
+	0x0a, 0x0f, 0x6a, 0x73, 0x6f, 0x6e, 0x32, 0x6d, 0x73, 0x67, 0x70, 0x2e, 0x70, 0x72, 0x6f, 0x74,
+	0x6f, 0x12, 0x09, 0x6a, 0x73, 0x6f, 0x6e, 0x32, 0x6d, 0x73, 0x67, 0x70, 0x22, 0x1c, 0x0a, 0x04,
+	0x48, 0x69, 0x6e, 0x74, 0x12, 0x14, 0x0a, 0x05, 0x74, 0x79, 0x70, 0x65, 0x73, 0x18, 0x01, 0x20,
+	0x03, 0x28, 0x09, 0x52, 0x05, 0x74, 0x79, 0x70, 0x65, 0x73, 0x22, 0x91, 0x02, 0x0a, 0x0e, 0x43,
+	0x6f, 0x6e, 0x76, 0x65, 0x72, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x12, 0x0a,
+	0x04, 0x6a, 0x73, 0x6f, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x04, 0x6a, 0x73, 0x6f,
+	0x6e, 0x12, 0x47, 0x0a, 0x0a, 0x74, 0x79, 0x70, 0x65, 0x5f, 0x68, 0x69, 0x6e, 0x74, 0x73, 0x18,
+	0x02, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x28, 0x2e, 0x6a, 0x73, 0x6f, 0x6e, 0x32, 0x6d, 0x73, 0x67,
+	0x70, 0x2e, 0x43, 0x6f, 0x6e, 0x76, 0x65, 0x72, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74,
+	0x2e, 0x54, 0x79, 0x70, 0x65, 0x48, 0x69, 0x6e, 0x74, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52,
+	0x09, 0x74, 0x79, 0x70, 0x65, 0x48, 0x69, 0x6e, 0x74, 0x73, 0x12, 0x16, 0x0a, 0x06, 0x73, 0x79,
+	0x73, 0x76, 0x61, 0x72, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x73, 0x79, 0x73, 0x76,
+	0x61, 0x72, 0x12, 0x1c, 0x0a, 0x09, 0x63, 0x61, 0x6e, 0x6f, 0x6e, 0x69, 0x63, 0x61, 0x6c, 0x18,
+	0x04, 0x20, 0x01, 0x28, 0x08, 0x52, 0x09, 0x63, 0x61, 0x6e, 0x6f, 0x6e, 0x69, 0x63, 0x61, 0x6c,
+	0x12, 0x1d, 0x0a, 0x0a, 0x61, 0x6c, 0x6c, 0x5f, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x73, 0x18, 0x05,
+	0x20, 0x01, 0x28, 0x08, 0x52, 0x09, 0x61, 0x6c, 0x6c, 0x45, 0x72, 0x72, 0x6f, 0x72, 0x73, 0x1a,
+	0x4d, 0x0a, 0x0e, 0x54, 0x79, 0x70, 0x65, 0x48, 0x69, 0x6e, 0x74, 0x73, 0x45, 0x6e, 0x74, 0x72,
+	0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03,
+	0x6b, 0x65, 0x79, 0x12, 0x25, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x0b, 0x32, 0x0f, 0x2e, 0x6a, 0x73, 0x6f, 0x6e, 0x32, 0x6d, 0x73, 0x67, 0x70, 0x2e, 0x48,
+	0x69, 0x6e, 0x74, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x22, 0x41,
+	0x0a, 0x0f, 0x43, 0x6f, 0x6e, 0x76, 0x65, 0x72, 0x74, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73,
+	0x65, 0x12, 0x12, 0x0a, 0x04, 0x6d, 0x73, 0x67, 0x70, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52,
+	0x04, 0x6d, 0x73, 0x67, 0x70, 0x12, 0x1a, 0x0a, 0x08, 0x77, 0x61, 0x72, 0x6e, 0x69, 0x6e, 0x67,
+	0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x09, 0x52, 0x08, 0x77, 0x61, 0x72, 0x6e, 0x69, 0x6e, 0x67,
+	0x73, 0x32, 0x97, 0x01, 0x0a, 0x09, 0x4a, 0x73, 0x6f, 0x6e, 0x32, 0x4d, 0x73, 0x67, 0x70, 0x12,
+	0x40, 0x0a, 0x07, 0x43, 0x6f, 0x6e, 0x76, 0x65, 0x72, 0x74, 0x12, 0x19, 0x2e, 0x6a, 0x73, 0x6f,
+	0x6e, 0x32, 0x6d, 0x73, 0x67, 0x70, 0x2e, 0x43, 0x6f, 0x6e, 0x76, 0x65, 0x72, 0x74, 0x52, 0x65,
+	0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1a, 0x2e, 0x6a, 0x73, 0x6f, 0x6e, 0x32, 0x6d, 0x73, 0x67,
+	0x70, 0x2e, 0x43, 0x6f, 0x6e, 0x76, 0x65, 0x72, 0x74, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73,
+	0x65, 0x12, 0x48, 0x0a, 0x0d, 0x43, 0x6f, 0x6e, 0x76, 0x65, 0x72, 0x74, 0x53, 0x74, 0x72, 0x65,
+	0x61, 0x6d, 0x12, 0x19, 0x2e, 0x6a, 0x73, 0x6f, 0x6e, 0x32, 0x6d, 0x73, 0x67, 0x70, 0x2e, 0x43,
+	0x6f, 0x6e, 0x76, 0x65, 0x72, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1a, 0x2e,
+	0x6a, 0x73, 0x6f, 0x6e, 0x32, 0x6d, 0x73, 0x67, 0x70, 0x2e, 0x43, 0x6f, 0x6e, 0x76, 0x65, 0x72,
+	0x74, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x28, 0x01, 0x42, 0x2d, 0x5a, 0x2b, 0x67,
+	0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x6e, 0x64, 0x61, 0x75, 0x2f, 0x6a,
+	0x73, 0x6f, 0x6e, 0x32, 0x6d, 0x73, 0x67, 0x70, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x3b, 0x6a,
+	0x73, 0x6f, 0x6e, 0x32, 0x6d, 0x73, 0x67, 0x70, 0x70, 0x62, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74,
+	0x6f, 0x33,
+}
+
+var (
+	file_json2msgp_proto_rawDescOnce sync.Once
+	file_json2msgp_proto_rawDescData = file_json2msgp_proto_rawDesc
+)
+
+func file_json2msgp_proto_rawDescGZIP() []byte {
+	file_json2msgp_proto_rawDescOnce.Do(func() {
+		file_json2msgp_proto_rawDescData = protoimpl.X.CompressGZIP(file_json2msgp_proto_rawDescData)
+	})
+	return file_json2msgp_proto_rawDescData
+}
+
+var file_json2msgp_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
+var file_json2msgp_proto_goTypes = []interface{}{
+	(*Hint)(nil),            // 0: json2msgp.Hint
+	(*ConvertRequest)(nil),  // 1: json2msgp.ConvertRequest
+	(*ConvertResponse)(nil), // 2: json2msgp.ConvertResponse
+	nil,                     // 3: json2msgp.ConvertRequest.TypeHintsEntry
+}
+var file_json2msgp_proto_depIdxs = []int32{
+	3, // 0: json2msgp.ConvertRequest.type_hints:type_name -> json2msgp.ConvertRequest.TypeHintsEntry
+	0, // 1: json2msgp.ConvertRequest.TypeHintsEntry.value:type_name -> json2msgp.Hint
+	1, // 2: json2msgp.Json2Msgp.Convert:input_type -> json2msgp.ConvertRequest
+	1, // 3: json2msgp.Json2Msgp.ConvertStream:input_type -> json2msgp.ConvertRequest
+	2, // 4: json2msgp.Json2Msgp.Convert:output_type -> json2msgp.ConvertResponse
+	2, // 5: json2msgp.Json2Msgp.ConvertStream:output_type -> json2msgp.ConvertResponse
+	4, // [4:6] is the sub-list for method output_type
+	2, // [2:4] is the sub-list for method input_type
+	2, // [2:2] is the sub-list for extension type_name
+	2, // [2:2] is the sub-list for extension extendee
+	0, // [0:2] is the sub-list for field type_name
+}
+
+func init() { file_json2msgp_proto_init() }
+func file_json2msgp_proto_init() {
+	if File_json2msgp_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_json2msgp_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Hint); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_json2msgp_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ConvertRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_json2msgp_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ConvertResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_json2msgp_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   4,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_json2msgp_proto_goTypes,
+		DependencyIndexes: file_json2msgp_proto_depIdxs,
+		MessageInfos:      file_json2msgp_proto_msgTypes,
+	}.Build()
+	File_json2msgp_proto = out.File
+	file_json2msgp_proto_rawDesc = nil
+	file_json2msgp_proto_goTypes = nil
+	file_json2msgp_proto_depIdxs = nil
+}
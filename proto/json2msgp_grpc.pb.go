@@ -0,0 +1,198 @@
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: json2msgp.proto
+
+package json2msgppb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	Json2Msgp_Convert_FullMethodName       = "/json2msgp.Json2Msgp/Convert"
+	Json2Msgp_ConvertStream_FullMethodName = "/json2msgp.Json2Msgp/ConvertStream"
+)
+
+// Json2MsgpClient is the client API for Json2Msgp service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type Json2MsgpClient interface {
+	// Convert converts a single JSON document.
+	Convert(ctx context.Context, in *ConvertRequest, opts ...grpc.CallOption) (*ConvertResponse, error)
+	// ConvertStream converts a large JSON document sent in chunks.  The first
+	// message carries the options; the JSON text is the concatenation of the
+	// chunks of all messages.
+	ConvertStream(ctx context.Context, opts ...grpc.CallOption) (Json2Msgp_ConvertStreamClient, error)
+}
+
+type json2MsgpClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewJson2MsgpClient(cc grpc.ClientConnInterface) Json2MsgpClient {
+	return &json2MsgpClient{cc}
+}
+
+func (c *json2MsgpClient) Convert(ctx context.Context, in *ConvertRequest, opts ...grpc.CallOption) (*ConvertResponse, error) {
+	out := new(ConvertResponse)
+	err := c.cc.Invoke(ctx, Json2Msgp_Convert_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *json2MsgpClient) ConvertStream(ctx context.Context, opts ...grpc.CallOption) (Json2Msgp_ConvertStreamClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Json2Msgp_ServiceDesc.Streams[0], Json2Msgp_ConvertStream_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &json2MsgpConvertStreamClient{stream}
+	return x, nil
+}
+
+type Json2Msgp_ConvertStreamClient interface {
+	Send(*ConvertRequest) error
+	CloseAndRecv() (*ConvertResponse, error)
+	grpc.ClientStream
+}
+
+type json2MsgpConvertStreamClient struct {
+	grpc.ClientStream
+}
+
+func (x *json2MsgpConvertStreamClient) Send(m *ConvertRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *json2MsgpConvertStreamClient) CloseAndRecv() (*ConvertResponse, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(ConvertResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Json2MsgpServer is the server API for Json2Msgp service.
+// All implementations must embed UnimplementedJson2MsgpServer
+// for forward compatibility
+type Json2MsgpServer interface {
+	// Convert converts a single JSON document.
+	Convert(context.Context, *ConvertRequest) (*ConvertResponse, error)
+	// ConvertStream converts a large JSON document sent in chunks.  The first
+	// message carries the options; the JSON text is the concatenation of the
+	// chunks of all messages.
+	ConvertStream(Json2Msgp_ConvertStreamServer) error
+	mustEmbedUnimplementedJson2MsgpServer()
+}
+
+// UnimplementedJson2MsgpServer must be embedded to have forward compatible implementations.
+type UnimplementedJson2MsgpServer struct {
+}
+
+func (UnimplementedJson2MsgpServer) Convert(context.Context, *ConvertRequest) (*ConvertResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Convert not implemented")
+}
+func (UnimplementedJson2MsgpServer) ConvertStream(Json2Msgp_ConvertStreamServer) error {
+	return status.Errorf(codes.Unimplemented, "method ConvertStream not implemented")
+}
+func (UnimplementedJson2MsgpServer) mustEmbedUnimplementedJson2MsgpServer() {}
+
+// UnsafeJson2MsgpServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to Json2MsgpServer will
+// result in compilation errors.
+type UnsafeJson2MsgpServer interface {
+	mustEmbedUnimplementedJson2MsgpServer()
+}
+
+func RegisterJson2MsgpServer(s grpc.ServiceRegistrar, srv Json2MsgpServer) {
+	s.RegisterService(&Json2Msgp_ServiceDesc, srv)
+}
+
+func _Json2Msgp_Convert_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ConvertRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(Json2MsgpServer).Convert(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Json2Msgp_Convert_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(Json2MsgpServer).Convert(ctx, req.(*ConvertRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Json2Msgp_ConvertStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(Json2MsgpServer).ConvertStream(&json2MsgpConvertStreamServer{stream})
+}
+
+type Json2Msgp_ConvertStreamServer interface {
+	SendAndClose(*ConvertResponse) error
+	Recv() (*ConvertRequest, error)
+	grpc.ServerStream
+}
+
+type json2MsgpConvertStreamServer struct {
+	grpc.ServerStream
+}
+
+func (x *json2MsgpConvertStreamServer) SendAndClose(m *ConvertResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *json2MsgpConvertStreamServer) Recv() (*ConvertRequest, error) {
+	m := new(ConvertRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Json2Msgp_ServiceDesc is the grpc.ServiceDesc for Json2Msgp service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Json2Msgp_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "json2msgp.Json2Msgp",
+	HandlerType: (*Json2MsgpServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Convert",
+			Handler:    _Json2Msgp_Convert_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "ConvertStream",
+			Handler:       _Json2Msgp_ConvertStream_Handler,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "json2msgp.proto",
+}
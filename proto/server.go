@@ -0,0 +1,101 @@
+package json2msgppb
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative json2msgp.proto
+
+import (
+	"bytes"
+	"context"
+	"io"
+
+	"github.com/ndau/json2msgp"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Server implements the Json2Msgp service with json2msgp.ConvertStreamWithOptions.
+// Register it with RegisterJson2MsgpServer.
+type Server struct {
+	UnimplementedJson2MsgpServer
+
+	opts []json2msgp.Option
+}
+
+// NewServer returns a Server which configures every conversion with opts,
+// followed by the options of the request, which override them.
+func NewServer(opts ...json2msgp.Option) *Server {
+	return &Server{opts: opts}
+}
+
+// Convert converts a single JSON document.
+func (s *Server) Convert(ctx context.Context, req *ConvertRequest) (*ConvertResponse, error) {
+	return s.convert(req, req.GetJson())
+}
+
+// ConvertStream converts the concatenated chunks of the messages of stream,
+// with the options of the first.
+func (s *Server) ConvertStream(stream Json2Msgp_ConvertStreamServer) error {
+	first, err := stream.Recv()
+	if err == io.EOF {
+		return status.Error(codes.InvalidArgument, "ConvertStream: no messages")
+	}
+	if err != nil {
+		return err
+	}
+	in := bytes.NewBuffer(append([]byte(nil), first.GetJson()...))
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		in.Write(req.GetJson())
+	}
+	resp, err := s.convert(first, in.Bytes())
+	if err != nil {
+		return err
+	}
+	return stream.SendAndClose(resp)
+}
+
+// convert converts in with the options of req.
+func (s *Server) convert(req *ConvertRequest, in []byte) (*ConvertResponse, error) {
+	var warnings []json2msgp.Warning
+	opts := append(s.opts[:len(s.opts):len(s.opts)], json2msgp.WithWarnings(&warnings))
+	if len(req.GetTypeHints()) > 0 {
+		hints := make(map[string][]string, len(req.GetTypeHints()))
+		for key, hint := range req.GetTypeHints() {
+			hints[key] = hint.GetTypes()
+		}
+		opts = append(opts, json2msgp.WithTypeHints(hints))
+	}
+	if req.GetSysvar() != "" {
+		opts = append(opts, json2msgp.WithSysvar(req.GetSysvar()))
+	}
+	if req.GetCanonical() {
+		opts = append(opts, json2msgp.WithCanonical())
+	}
+	if req.GetAllErrors() {
+		opts = append(opts, json2msgp.WithAllErrors())
+	}
+
+	var out bytes.Buffer
+	if err := json2msgp.ConvertStreamWithOptions(bytes.NewReader(in), &out, opts...); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	resp := &ConvertResponse{Msgp: out.Bytes()}
+	for _, w := range warnings {
+		resp.Warnings = append(resp.Warnings, w.String())
+	}
+	return resp, nil
+}
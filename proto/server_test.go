@@ -0,0 +1,82 @@
+package json2msgppb_test
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/ndau/json2msgp"
+	json2msgppb "github.com/ndau/json2msgp/proto"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// dial starts a server on an in-memory listener and returns a client for it.
+func dial(t *testing.T) json2msgppb.Json2MsgpClient {
+	l := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer()
+	json2msgppb.RegisterJson2MsgpServer(srv, json2msgppb.NewServer())
+	go srv.Serve(l)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return l.Dial() }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return json2msgppb.NewJson2MsgpClient(conn)
+}
+
+func TestServerConvert(t *testing.T) {
+	client := dial(t)
+	const in = `{"Fee":200,"Name":"DwA="}`
+	hints := map[string][]string{"Fee": {"uint64"}}
+	want := &bytes.Buffer{}
+	require.NoError(t, json2msgp.ConvertStream(strings.NewReader(in), want, hints))
+
+	resp, err := client.Convert(context.Background(), &json2msgppb.ConvertRequest{
+		Json:      []byte(in),
+		TypeHints: map[string]*json2msgppb.Hint{"Fee": {Types: hints["Fee"]}},
+	})
+	require.NoError(t, err)
+	require.Equal(t, want.Bytes(), resp.GetMsgp())
+	require.Len(t, resp.GetWarnings(), 1)
+
+	_, err = client.Convert(context.Background(), &json2msgppb.ConvertRequest{Json: []byte(`{"Fee":0.5}`)})
+	require.Equal(t, codes.InvalidArgument, status.Code(err), "got %v", err)
+}
+
+func TestServerConvertStream(t *testing.T) {
+	client := dial(t)
+	const in = `{"EAIFeeTable":[{"Fee":4000000},{"Fee":200}]}`
+	want := &bytes.Buffer{}
+	require.NoError(t, json2msgp.ConvertStreamWithOptions(strings.NewReader(in), want, json2msgp.WithCanonical()))
+
+	stream, err := client.ConvertStream(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, stream.Send(&json2msgppb.ConvertRequest{Json: []byte(in[:10]), Canonical: true}))
+	require.NoError(t, stream.Send(&json2msgppb.ConvertRequest{Json: []byte(in[10:])}))
+	resp, err := stream.CloseAndRecv()
+	require.NoError(t, err)
+	require.Equal(t, want.Bytes(), resp.GetMsgp())
+
+	stream, err = client.ConvertStream(context.Background())
+	require.NoError(t, err)
+	_, err = stream.CloseAndRecv()
+	require.Equal(t, codes.InvalidArgument, status.Code(err), "got %v", err)
+}
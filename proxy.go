@@ -0,0 +1,100 @@
+package json2msgp
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// wantsJSONKey marks requests whose client accepts JSON but not MSGP.
+type wantsJSONKey struct{}
+
+// NewProxy returns a reverse proxy to the MSGP-speaking API at target, for
+// JSON clients during a migration.
+//
+// Request bodies are converted as by Middleware.  Clients which accept JSON
+// but not MSGP have the upstream asked for MSGP instead, and its MSGP
+// responses converted to JSON.  Byte arrays appear in the JSON as base64;
+// every other request and response passes through unchanged.
+func NewProxy(target *url.URL, opts ...Option) http.Handler {
+	proxy := httputil.NewSingleHostReverseProxy(target)
+	proxy.ModifyResponse = func(resp *http.Response) error {
+		if resp.Request == nil || resp.Request.Context().Value(wantsJSONKey{}) == nil {
+			return nil
+		}
+		mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+		if err != nil || mediaType != MsgpackContentType {
+			return nil
+		}
+		return responseToJSON(resp)
+	}
+
+	return Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if acceptsJSONOnly(r.Header.Get("Accept")) {
+			r = r.Clone(context.WithValue(r.Context(), wantsJSONKey{}, true))
+			r.Header.Set("Accept", MsgpackContentType)
+		}
+		proxy.ServeHTTP(w, r)
+	}), opts...)
+}
+
+// acceptsJSONOnly reports whether an Accept header lists JSON but not MSGP.
+func acceptsJSONOnly(accept string) bool {
+	var json bool
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		switch mediaType {
+		case "application/json":
+			json = true
+		case MsgpackContentType, "application/x-msgpack":
+			return false
+		}
+	}
+	return json
+}
+
+// responseToJSON replaces the MSGP body of resp with its JSON form, as
+// msgpBodyToJSON converts it.  A gzip-compressed body is decompressed, and
+// the JSON is sent uncompressed; any other Content-Encoding is an error.
+func responseToJSON(resp *http.Response) error {
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return errors.Wrap(err, "proxy reading response")
+	}
+	body, err = decodeContent(resp.Header.Get("Content-Encoding"), body)
+	if err != nil {
+		return errors.Wrap(err, "proxy")
+	}
+	out, err := msgpBodyToJSON(body)
+	if err != nil {
+		return errors.Wrap(err, "proxy")
+	}
+
+	resp.Header.Del("Content-Encoding")
+	resp.Body = ioutil.NopCloser(bytes.NewReader(out))
+	resp.ContentLength = int64(len(out))
+	resp.Header.Set("Content-Type", "application/json")
+	resp.Header.Set("Content-Length", strconv.Itoa(len(out)))
+	return nil
+}
@@ -0,0 +1,93 @@
+package json2msgp_test
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/ndau/json2msgp"
+	"github.com/stretchr/testify/require"
+	"github.com/tinylib/msgp/msgp"
+)
+
+func TestProxy(t *testing.T) {
+	// the upstream echoes the MSGP it receives, wrapped in an array and
+	// compressed as X-Encoding asks
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		require.Equal(t, json2msgp.MsgpackContentType, r.Header.Get("Content-Type"))
+		body = append(msgp.AppendArrayHeader(nil, 1), body...)
+		switch encoding := r.Header.Get("X-Encoding"); encoding {
+		case "gzip":
+			buf := &bytes.Buffer{}
+			gz := gzip.NewWriter(buf)
+			gz.Write(body)
+			gz.Close()
+			body = buf.Bytes()
+			fallthrough
+		case "br":
+			w.Header().Set("Content-Encoding", encoding)
+		}
+		w.Header().Set("Content-Type", json2msgp.MsgpackContentType)
+		w.Header().Set("X-Accept", r.Header.Get("Accept"))
+		w.Write(body)
+	}))
+	defer upstream.Close()
+	target, err := url.Parse(upstream.URL)
+	require.NoError(t, err)
+
+	proxy := httptest.NewServer(json2msgp.NewProxy(target, json2msgp.WithTypeHints(map[string][]string{"Fee": []string{"uint64"}})))
+	defer proxy.Close()
+
+	tests := []struct {
+		name       string
+		accept     string
+		encoding   string
+		wantStatus int
+		wantType   string
+		wantAccept string
+		wantBody   string
+	}{
+		{"json client", "application/json", "", http.StatusOK, "application/json", json2msgp.MsgpackContentType, `[{"Fee":200}]`},
+		{"msgp client", "application/msgpack, application/json", "", http.StatusOK, json2msgp.MsgpackContentType, "application/msgpack, application/json", "\x91\x81\xa3Fee\xcc\xc8"},
+		{"gzip response", "application/json", "gzip", http.StatusOK, "application/json", json2msgp.MsgpackContentType, `[{"Fee":200}]`},
+		{"unsupported encoding", "application/json", "br", http.StatusBadGateway, "", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPost, proxy.URL, strings.NewReader(`{"Fee":200}`))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Accept", tt.accept)
+			req.Header.Set("X-Encoding", tt.encoding)
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			body, err := ioutil.ReadAll(resp.Body)
+			require.NoError(t, err)
+
+			require.Equal(t, tt.wantStatus, resp.StatusCode)
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			require.Equal(t, tt.wantType, resp.Header.Get("Content-Type"))
+			require.Equal(t, tt.wantAccept, resp.Header.Get("X-Accept"))
+			require.Equal(t, tt.wantBody, string(body))
+		})
+	}
+}
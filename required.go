@@ -0,0 +1,86 @@
+package json2msgp
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"fmt"
+	"strings"
+)
+
+// requirement is the keys which the maps at path must have.
+type requirement struct {
+	path string
+	keys []string
+
+	// path parsed by newConverter, or the ErrBadOption error of parsing it
+	steps []pathStep
+	err   error
+}
+
+// WithRequiredKeys requires every map matching path to have keys, to catch
+// truncated or badly merged documents before they are encoded.  path is a
+// query as for WithQuery, such as "$.EAIFeeTable[*]" for every entry of the
+// EAIFeeTable, or "$" for the document itself.
+//
+// The keys are checked after WithDefaults fills in missing ones, and before
+// WithFlatten.  If any are missing, or a value matching path is not a map,
+// conversion fails with an ErrorList of ErrMissingKey errors, one per key.
+// The option may be given more than once.
+func WithRequiredKeys(path string, keys ...string) Option {
+	return func(c *Converter) {
+		c.required = append(c.required, requirement{path: path, keys: keys})
+	}
+}
+
+// compileRequired parses the paths of reqs, so that they are parsed once
+// rather than for every document.
+func compileRequired(reqs []requirement) {
+	for i := range reqs {
+		steps, err := parsePath(reqs[i].path)
+		if err != nil {
+			reqs[i].err = fmt.Errorf("%w: required keys: %s", ErrBadOption, err)
+			continue
+		}
+		reqs[i].steps = steps
+	}
+}
+
+// checkRequired checks that in has the keys required of it.
+func (c *Converter) checkRequired(in interface{}) error {
+	prefix := "$" + strings.Join(c.path, "")
+	var errs ErrorList
+	for _, req := range c.required {
+		if req.err != nil {
+			return req.err
+		}
+		for _, m := range findAll(in, req.steps) {
+			path := prefix + strings.Join(m.path, "")
+			for _, key := range req.keys {
+				var ok bool
+				switch x := m.value.(type) {
+				case map[string]interface{}:
+					_, ok = x[key]
+				case map[string]string:
+					_, ok = x[key]
+				default:
+					errs = append(errs, fmt.Errorf("%s: %w %q: not a map", path, ErrMissingKey, key))
+					continue
+				}
+				if !ok {
+					errs = append(errs, fmt.Errorf("%s: %w %q", path, ErrMissingKey, key))
+				}
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package json2msgp_test
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ndau/json2msgp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithRequiredKeys(t *testing.T) {
+	feeTable := json2msgp.WithRequiredKeys("$.EAIFeeTable[*]", "Fee", "To")
+	tests := []struct {
+		name    string
+		in      string
+		opts    []json2msgp.Option
+		wantErr string
+	}{
+		{"present", `{"EAIFeeTable":[{"Fee":1,"To":null},{"Fee":2,"To":["a"]}]}`, []json2msgp.Option{feeTable}, ""},
+		{"no matches", `{"EAIFeeTable":[]}`, []json2msgp.Option{feeTable}, ""},
+		{"missing", `{"EAIFeeTable":[{"Fee":1,"To":null},{"To":null},{}]}`, []json2msgp.Option{feeTable},
+			"$.EAIFeeTable[1]: Missing required key \"Fee\"\n" +
+				"$.EAIFeeTable[2]: Missing required key \"Fee\"\n" +
+				"$.EAIFeeTable[2]: Missing required key \"To\""},
+		{"not a map", `{"EAIFeeTable":[5]}`, []json2msgp.Option{json2msgp.WithRequiredKeys("$.EAIFeeTable[*]", "Fee")},
+			"$.EAIFeeTable[0]: Missing required key \"Fee\": not a map"},
+		{"root", `{"a":1}`, []json2msgp.Option{json2msgp.WithRequiredKeys("$", "a", "b")},
+			"$: Missing required key \"b\""},
+		{"after defaults", `{"a":1}`, []json2msgp.Option{
+			json2msgp.WithRequiredKeys("$", "a", "b"),
+			json2msgp.WithDefaults(map[string]interface{}{"b": 2.0}),
+		}, ""},
+		{"under path", `{"svi":{"x":{"Current":1}}}`, []json2msgp.Option{
+			json2msgp.WithPath("svi"),
+			json2msgp.WithRequiredKeys("$.*", "Current", "Future"),
+		}, "$.svi.x: Missing required key \"Future\""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			err := json2msgp.ConvertStreamWithOptions(strings.NewReader(tt.in), &out, tt.opts...)
+			if tt.wantErr == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.True(t, errors.Is(err, json2msgp.ErrMissingKey), "got %v", err)
+			require.EqualError(t, err, tt.wantErr)
+		})
+	}
+
+	err := json2msgp.ConvertStreamWithOptions(strings.NewReader(`{}`), &bytes.Buffer{},
+		json2msgp.WithRequiredKeys("$.EAIFeeTable[", "Fee"))
+	require.True(t, errors.Is(err, json2msgp.ErrBadOption), "got %v", err)
+}
@@ -0,0 +1,250 @@
+package json2msgp
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"encoding/base64"
+	"fmt"
+	"sort"
+
+	"github.com/ndau/ndaumath/pkg/address"
+)
+
+// SchemaKind is the kind of JSON value a Schema accepts.
+type SchemaKind int
+
+const (
+	// AnyKind accepts any value.
+	AnyKind SchemaKind = iota
+	// ObjectKind accepts a JSON object.
+	ObjectKind
+	// ArrayKind accepts a JSON array.
+	ArrayKind
+	// StringKind accepts any string.
+	StringKind
+	// Base64Kind accepts a string which is valid padded standard base64.
+	Base64Kind
+	// AddressKind accepts a string which is a valid ndau address.
+	AddressKind
+	// NumberKind accepts a number.
+	NumberKind
+	// BoolKind accepts true or false.
+	BoolKind
+)
+
+var schemaKindNames = map[SchemaKind]string{
+	AnyKind:     "any value",
+	ObjectKind:  "object",
+	ArrayKind:   "array",
+	StringKind:  "string",
+	Base64Kind:  "base64 string",
+	AddressKind: "ndau address",
+	NumberKind:  "number",
+	BoolKind:    "bool",
+}
+
+func (k SchemaKind) String() string {
+	return schemaKindNames[k]
+}
+
+// Schema describes the structure of a JSON value.
+type Schema struct {
+	Kind SchemaKind
+	// Nullable also accepts null.
+	Nullable bool
+	// Fields are the keys an object must have, and their schemas.
+	Fields map[string]*Schema
+	// Values is the schema of every value of an object other than Fields.
+	// If nil, only the keys in Fields are allowed.
+	Values *Schema
+	// Elem is the schema of every element of an array.  If nil, any is allowed.
+	Elem *Schema
+	// Len is the exact length of an array, if positive.
+	Len int
+}
+
+// Validate checks that v, as decoded by encoding/json, conforms to s.  It
+// reports every problem found, as an ErrorList of ErrSchema errors.
+func (s *Schema) Validate(v interface{}) error {
+	var errs ErrorList
+	s.validate(v, "$", &errs)
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func (s *Schema) validate(v interface{}, path string, errs *ErrorList) {
+	fail := func(format string, args ...interface{}) {
+		*errs = append(*errs, fmt.Errorf("%s: %w: %s", path, ErrSchema, fmt.Sprintf(format, args...)))
+	}
+	if v == nil {
+		if !s.Nullable && s.Kind != AnyKind {
+			fail("expected %s, got null", s.Kind)
+		}
+		return
+	}
+
+	switch s.Kind {
+	case AnyKind:
+	case ObjectKind:
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			fail("expected %s, got %T", s.Kind, v)
+			return
+		}
+		for _, key := range sortedKeys(s.Fields) {
+			val, ok := m[key]
+			if !ok {
+				fail("missing required key %q", key)
+				continue
+			}
+			s.Fields[key].validate(val, path+keySegment(key), errs)
+		}
+		for _, key := range sortedKeys(m) {
+			if _, ok := s.Fields[key]; ok {
+				continue
+			}
+			if s.Values == nil {
+				fail("unexpected key %q", key)
+				continue
+			}
+			s.Values.validate(m[key], path+keySegment(key), errs)
+		}
+	case ArrayKind:
+		a, ok := v.([]interface{})
+		if !ok {
+			fail("expected %s, got %T", s.Kind, v)
+			return
+		}
+		if s.Len > 0 && len(a) != s.Len {
+			fail("expected %d elements, got %d", s.Len, len(a))
+		}
+		if s.Elem != nil {
+			for i, elem := range a {
+				s.Elem.validate(elem, path+indexSegment(i), errs)
+			}
+		}
+	case StringKind, Base64Kind, AddressKind:
+		str, ok := v.(string)
+		if !ok {
+			fail("expected %s, got %T", s.Kind, v)
+			return
+		}
+		if s.Kind == Base64Kind {
+			if _, err := base64.StdEncoding.DecodeString(str); err != nil {
+				fail("expected %s: %s", s.Kind, err)
+			}
+		}
+		if s.Kind == AddressKind {
+			if _, err := address.Validate(str); err != nil {
+				fail("expected %s: %s", s.Kind, err)
+			}
+		}
+	case NumberKind:
+		if _, ok := v.(float64); !ok {
+			fail("expected %s, got %T", s.Kind, v)
+		}
+	case BoolKind:
+		if _, ok := v.(bool); !ok {
+			fail("expected %s, got %T", s.Kind, v)
+		}
+	}
+}
+
+// sortedKeys returns the keys of a map with string keys, sorted.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// The schemas of known system variables, by name.
+var sysvarSchemas = knownSysvarSchemas()
+
+func knownSysvarSchemas() map[string]*Schema {
+	number := &Schema{Kind: NumberKind}
+	addresses := &Schema{Kind: ArrayKind, Elem: &Schema{Kind: AddressKind}}
+	script := &Schema{Kind: Base64Kind}
+	rateTable := &Schema{Kind: ArrayKind, Elem: &Schema{Kind: ArrayKind, Len: 2, Elem: number}}
+	sviEntry := &Schema{Kind: ArrayKind, Len: 2, Elem: &Schema{Kind: Base64Kind}}
+
+	return map[string]*Schema{
+		"AccountAttributes": {
+			Kind:   ObjectKind,
+			Values: &Schema{Kind: ObjectKind, Values: &Schema{Kind: ObjectKind, Values: &Schema{}}},
+		},
+		"CommandValidatorChangeAddress": addresses,
+		"DefaultRecourseDuration":       number,
+		"EAIFeeTable": {
+			Kind: ArrayKind,
+			Elem: &Schema{
+				Kind: ObjectKind,
+				Fields: map[string]*Schema{
+					"Fee": number,
+					"To":  {Kind: ArrayKind, Nullable: true, Elem: &Schema{Kind: AddressKind}},
+				},
+			},
+		},
+		"LockedRateTable":                         rateTable,
+		"MinDurationBetweenNodeRewardNominations": number,
+		"MinNodeRegistrationStakeAmount":          number,
+		"NodeGoodnessFunction":                    script,
+		"NodeRewardNominationTimeout":             number,
+		"NominateNodeRewardAddress":               addresses,
+		"ReleaseFromEndowmentAddress":             addresses,
+		"TransactionFeeScript":                    script,
+		"UnlockedRateTable":                       rateTable,
+		"svi": {
+			Kind: ObjectKind,
+			Values: &Schema{
+				Kind: ObjectKind,
+				Fields: map[string]*Schema{
+					"ChangeOn": number,
+					"Current":  sviEntry,
+					"Future":   sviEntry,
+				},
+			},
+		},
+	}
+}
+
+// RegisterSysvarSchema adds or replaces the schema of the named system variable.
+func RegisterSysvarSchema(name string, schema *Schema) {
+	sysvarLock.Lock()
+	defer sysvarLock.Unlock()
+	sysvarSchemas[name] = schema
+}
+
+// SysvarSchema returns the schema of the named system variable, if known.
+func SysvarSchema(name string) (*Schema, bool) {
+	sysvarLock.RLock()
+	defer sysvarLock.RUnlock()
+	schema, ok := sysvarSchemas[name]
+	return schema, ok
+}
+
+// ValidateSysvar checks the structure of the JSON document data against the
+// schema of the named system variable, before it is converted.  It reports
+// every problem found, as an ErrorList of ErrSchema errors.
+func ValidateSysvar(name string, data []byte) error {
+	schema, ok := SysvarSchema(name)
+	if !ok {
+		return fmt.Errorf("ValidateSysvar: %w %q", ErrUnknownSysvar, name)
+	}
+	v, err := newConverter().newDecoder(data).single()
+	if err != nil {
+		return err
+	}
+	return schema.Validate(v)
+}
@@ -0,0 +1,79 @@
+package json2msgp_test
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ndau/json2msgp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateSysvar(t *testing.T) {
+	tests := []struct {
+		name    string
+		sysvar  string
+		in      string
+		wantErr string
+	}{
+		{
+			"EAIFeeTable",
+			"EAIFeeTable",
+			`[{"Fee":4000000,"To":["ndaea8w9gz84ncxrytepzxgkg9ymi4k7c9p427i6b57xw3r4"]},{"Fee":9800000,"To":null}]`,
+			"",
+		},
+		{
+			"EAIFeeTable missing To",
+			"EAIFeeTable",
+			`[{"Fee":4000000},{"Fee":"lots","To":[5]}]`,
+			strings.Join([]string{
+				`$[0]: Schema violation: missing required key "To"`,
+				`$[1].Fee: Schema violation: expected number, got string`,
+				`$[1].To[0]: Schema violation: expected ndau address, got float64`,
+			}, "\n"),
+		},
+		{
+			"LockedRateTable",
+			"LockedRateTable",
+			`[[7776000000000,10000000000],[15552000000000]]`,
+			`$[1]: Schema violation: expected 2 elements, got 1`,
+		},
+		{
+			"svi",
+			"svi",
+			`{"EAIFeeTable":{"ChangeOn":0,"Current":["A2etqqaA3qQExilg+ywQ4ElRsyoDJh9lR5A+Thg5PcTR","RUFJRmVlVGFibGU="],"Future":["A2etqqaA3qQExilg+ywQ4ElRsyoDJh9lR5A+Thg5PcTR","RUFJRmVlVGFibGU="],"Past":1}}`,
+			`$.EAIFeeTable: Schema violation: unexpected key "Past"`,
+		},
+		{
+			"TransactionFeeScript",
+			"TransactionFeeScript",
+			`"oAAgiA=="`,
+			"",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := json2msgp.ValidateSysvar(tt.sysvar, []byte(tt.in))
+			if tt.wantErr == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.EqualError(t, err, tt.wantErr)
+			require.True(t, errors.Is(err, json2msgp.ErrSchema))
+		})
+	}
+}
+
+func TestValidateUnknownSysvar(t *testing.T) {
+	err := json2msgp.ValidateSysvar("NoSuchSysvar", []byte(`1`))
+	require.True(t, errors.Is(err, json2msgp.ErrUnknownSysvar))
+}
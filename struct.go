@@ -0,0 +1,140 @@
+package json2msgp
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"reflect"
+	"strings"
+)
+
+// WithStructs converts Go structs as maps of their exported fields, so that
+// partially typed data need not be marshalled to JSON and back first.  The
+// field values are converted as map values are, with the same hints and
+// heuristics, looked up by field name.
+//
+// Fields are named and omitted as their tags say: the msg tag of msgp's
+// generated code, else the json tag.  A name of "-" omits the field, and the
+// omitempty flag omits it when it is false, zero, nil or empty.  The fields
+// of embedded structs without a name in their tag are promoted, as
+// encoding/json promotes them; a field of the outer struct wins over a
+// promoted field of the same name.  A nil pointer, interface, slice or map
+// is converted as null, as encoding/json writes it.
+//
+// Without this option, structs are passed to the Codec's AppendIntf, which
+// for msgp fails unless they implement msgp.Marshaler.  WithoutReflection
+// rejects structs either way.
+func WithStructs() Option {
+	return func(c *Converter) {
+		c.structs = true
+	}
+}
+
+// structMap returns the fields of the struct v as a map, by name.
+func structMap(v reflect.Value) map[string]interface{} {
+	m := make(map[string]interface{}, v.NumField())
+	addFields(m, v, make(map[string]bool))
+	return m
+}
+
+// addFields adds the fields of the struct v to m, unless they are in outer,
+// the names of the fields of the structs v is embedded in.
+func addFields(m map[string]interface{}, v reflect.Value, outer map[string]bool) {
+	t := v.Type()
+	var embedded []reflect.Value
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, omitEmpty, ok := fieldName(field)
+		if !ok {
+			continue
+		}
+		value := v.Field(i)
+		if field.Anonymous && name == "" {
+			if value.Kind() == reflect.Ptr {
+				if value.IsNil() {
+					continue
+				}
+				value = value.Elem()
+			}
+			if value.Kind() == reflect.Struct {
+				embedded = append(embedded, value)
+				continue
+			}
+		}
+		if field.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		if outer[name] {
+			continue
+		}
+		outer[name] = true
+		if omitEmpty && isEmptyValue(value) {
+			continue
+		}
+		if isNil(value) {
+			m[name] = nil
+			continue
+		}
+		m[name] = value.Interface()
+	}
+	for _, value := range embedded {
+		addFields(m, value, outer)
+	}
+}
+
+// fieldName returns the name a field's tag gives it, if any, and whether it
+// is omitted when empty.  It returns false if the field is always omitted.
+func fieldName(field reflect.StructField) (string, bool, bool) {
+	tag, ok := field.Tag.Lookup("msg")
+	if !ok {
+		tag = field.Tag.Get("json")
+	}
+	if tag == "-" {
+		return "", false, false
+	}
+	parts := strings.Split(tag, ",")
+	omitEmpty := false
+	for _, opt := range parts[1:] {
+		if opt == "omitempty" {
+			omitEmpty = true
+		}
+	}
+	return parts[0], omitEmpty, true
+}
+
+// isEmptyValue is true for the values omitempty omits, as for encoding/json.
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+	return false
+}
+
+// isNil is true for a nil pointer, interface, slice or map.
+func isNil(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Slice, reflect.Map:
+		return v.IsNil()
+	}
+	return false
+}
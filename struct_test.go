@@ -0,0 +1,74 @@
+package json2msgp_test
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ndau/json2msgp"
+	"github.com/stretchr/testify/require"
+)
+
+type feeRule struct {
+	Fee    float64
+	To     []string `msg:"To" json:"recipients"`
+	Note   string   `json:"note,omitempty"`
+	Skip   int      `json:"-"`
+	hidden int
+}
+
+type sysvarBase struct {
+	Name    string
+	Version int
+}
+
+type feeSchedule struct {
+	sysvarBase
+	Version string
+	Entries []feeRule `json:"EAIFeeTable"`
+	Next    *feeRule
+	Script  string
+}
+
+func TestWithStructs(t *testing.T) {
+	in := &feeSchedule{
+		sysvarBase: sysvarBase{Name: "EAIFeeTable", Version: 1},
+		Version:    "v2",
+		Entries: []feeRule{
+			{Fee: 4000000, To: []string{"ndnf9ffbzhyf8mk7z5vvqc4quzz5i2exp5zgsmhyhc9cuwr4"}, Skip: 1, hidden: 2},
+			{Fee: 200, Note: "burn"},
+		},
+		Script: "oACI",
+	}
+	hints := map[string][]string{"Fee": {"uint64"}}
+	got, err := json2msgp.ConvertWithOptions(in, json2msgp.WithTypeHints(hints), json2msgp.WithStructs())
+	require.NoError(t, err)
+
+	want, err := json2msgp.Convert(map[string]interface{}{
+		"Name":    "EAIFeeTable",
+		"Version": "v2",
+		"EAIFeeTable": []interface{}{
+			map[string]interface{}{"Fee": 4000000.0, "To": []interface{}{"ndnf9ffbzhyf8mk7z5vvqc4quzz5i2exp5zgsmhyhc9cuwr4"}},
+			map[string]interface{}{"Fee": 200.0, "To": nil, "note": "burn"},
+		},
+		"Next":   nil,
+		"Script": "oACI",
+	}, hints)
+	require.NoError(t, err)
+	require.Equal(t, want, got)
+
+	// structs are otherwise left to the codec, and never converted without
+	// reflection
+	_, err = json2msgp.ConvertWithOptions(in)
+	require.Error(t, err)
+	_, err = json2msgp.ConvertWithOptions(*in, json2msgp.WithStructs(), json2msgp.WithoutReflection())
+	require.True(t, errors.Is(err, json2msgp.ErrUnsupportedType), "got %v", err)
+}
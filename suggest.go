@@ -0,0 +1,171 @@
+package json2msgp
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"encoding/base64"
+	"fmt"
+	"sort"
+	"unicode/utf8"
+
+	"github.com/ndau/ndaumath/pkg/address"
+	"github.com/pkg/errors"
+)
+
+// Confidence grades a suggested hint.
+type Confidence string
+
+// Confidence levels.
+const (
+	// ConfidenceHigh means the values seen require the hint.
+	ConfidenceHigh Confidence = "high"
+	// ConfidenceMedium means the values seen suggest the hint.
+	ConfidenceMedium Confidence = "medium"
+	// ConfidenceLow means the hint is a guess, usually the heuristic's own
+	// choice made explicit.
+	ConfidenceLow Confidence = "low"
+)
+
+// Note explains a suggestion of SuggestHints, or why a key got none.
+type Note struct {
+	Key        string
+	Hint       string
+	Confidence Confidence
+	Reason     string
+}
+
+// String returns the note as a single line.
+func (n Note) String() string {
+	if n.Hint == "" {
+		return fmt.Sprintf("%q: no hint: %s", n.Key, n.Reason)
+	}
+	return fmt.Sprintf("%q: %s (%s confidence): %s", n.Key, n.Hint, n.Confidence, n.Reason)
+}
+
+// SuggestHints analyzes the JSON document json and proposes type hints for
+// it, with a note explaining each, to jump-start a new hint file.  The
+// suggestions are only as good as the values in the document, so review them
+// before use.
+//
+// Each key's values are considered together, as the Converter applies one
+// hint to all of them; array elements count towards the key holding the
+// array.  Negative numbers suggest "int64", numbers too large for an int64
+// "uint64", and fractions "float64".  Strings which are all base64 suggest
+// "base64", all addresses "address", and all RFC 3339 times
+// "ndautimestamp"; strings of which only some look like base64, and would be
+// encoded inconsistently by the heuristic, suggest "str".
+func SuggestHints(json []byte) (map[string][]string, []Note) {
+	v, err := newConverter().newDecoder(json).single()
+	if err != nil {
+		return nil, []Note{{Reason: errors.Wrap(err, "SuggestHints").Error()}}
+	}
+
+	stats := make(map[string]*keyStats)
+	collectStats(stats, "", v)
+
+	keys := make([]string, 0, len(stats))
+	for key := range stats {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	hints := make(map[string][]string)
+	var notes []Note
+	for _, key := range keys {
+		note, ok := stats[key].suggest()
+		if !ok {
+			continue
+		}
+		note.Key = key
+		if note.Hint != "" {
+			hints[key] = []string{note.Hint}
+		}
+		notes = append(notes, note)
+	}
+	return hints, notes
+}
+
+// keyStats summarizes the values of one key.
+type keyStats struct {
+	numbers, negatives, fractions, huge, large int
+	strings, base64s, addresses, timestamps    int
+}
+
+func collectStats(stats map[string]*keyStats, key string, v interface{}) {
+	s := stats[key]
+	if s == nil {
+		s = new(keyStats)
+		stats[key] = s
+	}
+	switch x := v.(type) {
+	case map[string]interface{}:
+		for k, elem := range x {
+			collectStats(stats, k, elem)
+		}
+	case []interface{}:
+		for _, elem := range x {
+			collectStats(stats, key, elem)
+		}
+	case float64:
+		s.numbers++
+		switch {
+		case x != float64(int64(x)) && x < 1<<63:
+			s.fractions++
+		case x < 0:
+			s.negatives++
+		case x >= 1<<63:
+			s.huge++
+		case x >= 1<<32:
+			s.large++
+		}
+	case string:
+		s.strings++
+		if _, err := address.Validate(x); err == nil {
+			s.addresses++
+		} else if _, err := ParseNdauTimestamp(x); err == nil {
+			s.timestamps++
+		} else if _, err := base64.StdEncoding.DecodeString(x); err == nil && utf8.ValidString(x) {
+			s.base64s++
+		}
+	}
+}
+
+// suggest returns the note for a key, or false if there is nothing to say.
+func (s *keyStats) suggest() (Note, bool) {
+	switch {
+	case s.numbers > 0 && s.strings > 0:
+		return Note{Reason: fmt.Sprintf("%d numbers and %d strings; no single hint fits", s.numbers, s.strings)}, true
+	case s.fractions > 0 && (s.negatives > 0 || s.huge > 0):
+		return Note{Hint: "float64", Confidence: ConfidenceMedium, Reason: fmt.Sprintf("%d of %d numbers are fractions", s.fractions, s.numbers)}, true
+	case s.fractions > 0:
+		return Note{Hint: "float64", Confidence: ConfidenceHigh, Reason: fmt.Sprintf("%d of %d numbers are fractions", s.fractions, s.numbers)}, true
+	case s.negatives > 0 && s.huge > 0:
+		return Note{Reason: "both negative numbers and numbers too large for an int64"}, true
+	case s.negatives > 0:
+		return Note{Hint: "int64", Confidence: ConfidenceHigh, Reason: fmt.Sprintf("%d of %d numbers are negative", s.negatives, s.numbers)}, true
+	case s.huge > 0:
+		return Note{Hint: "uint64", Confidence: ConfidenceHigh, Reason: fmt.Sprintf("%d of %d numbers are too large for an int64", s.huge, s.numbers)}, true
+	case s.large > 0:
+		return Note{Hint: "uint64", Confidence: ConfidenceMedium, Reason: fmt.Sprintf("%d of %d numbers are large and none negative", s.large, s.numbers)}, true
+	case s.numbers > 0:
+		return Note{Hint: "int64", Confidence: ConfidenceLow, Reason: fmt.Sprintf("%d small integers; int64 is what the heuristic assumes", s.numbers)}, true
+	case s.strings == 0:
+		return Note{}, false
+	case s.addresses == s.strings:
+		return Note{Hint: "address", Confidence: ConfidenceHigh, Reason: fmt.Sprintf("all %d strings are ndau addresses", s.strings)}, true
+	case s.timestamps == s.strings:
+		return Note{Hint: "ndautimestamp", Confidence: ConfidenceMedium, Reason: fmt.Sprintf("all %d strings are RFC 3339 times", s.strings)}, true
+	case s.base64s == s.strings:
+		return Note{Hint: "base64", Confidence: ConfidenceMedium, Reason: fmt.Sprintf("all %d strings are valid base64", s.strings)}, true
+	case s.base64s > 0:
+		return Note{Hint: "str", Confidence: ConfidenceHigh, Reason: fmt.Sprintf("%d of %d strings look like base64, so the heuristic would encode them inconsistently", s.base64s, s.strings)}, true
+	}
+	return Note{}, false
+}
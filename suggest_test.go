@@ -0,0 +1,68 @@
+package json2msgp_test
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"testing"
+
+	"github.com/ndau/json2msgp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSuggestHints(t *testing.T) {
+	hints, notes := json2msgp.SuggestHints([]byte(`{
+		"Rates": [{"Rate": 0.5, "Delta": -3, "Fee": 20000000000}, {"Rate": 1, "Delta": 4, "Fee": 1}],
+		"Max": 18446744073709551615,
+		"Count": 3,
+		"Scripts": ["oACI", "oACJ"],
+		"Names": ["oACI", "plain!"],
+		"Labels": ["plain!"],
+		"Owner": "ndaaiz7rmgxqm5m4i6vn6v4wdeabw2pa6ufpacxw3h4v3zd6",
+		"Since": "2020-07-01T00:00:00Z",
+		"Mixed": [1, "a!"]
+	}`))
+	require.Equal(t, map[string][]string{
+		"Count":   []string{"int64"},
+		"Delta":   []string{"int64"},
+		"Fee":     []string{"uint64"},
+		"Max":     []string{"uint64"},
+		"Names":   []string{"str"},
+		"Owner":   []string{"address"},
+		"Rate":    []string{"float64"},
+		"Scripts": []string{"base64"},
+		"Since":   []string{"ndautimestamp"},
+	}, hints)
+
+	var lines []string
+	for _, note := range notes {
+		lines = append(lines, note.String())
+	}
+	require.Equal(t, []string{
+		`"Count": int64 (low confidence): 1 small integers; int64 is what the heuristic assumes`,
+		`"Delta": int64 (high confidence): 1 of 2 numbers are negative`,
+		`"Fee": uint64 (medium confidence): 1 of 2 numbers are large and none negative`,
+		`"Max": uint64 (high confidence): 1 of 1 numbers are too large for an int64`,
+		`"Mixed": no hint: 1 numbers and 1 strings; no single hint fits`,
+		`"Names": str (high confidence): 1 of 2 strings look like base64, so the heuristic would encode them inconsistently`,
+		`"Owner": address (high confidence): all 1 strings are ndau addresses`,
+		`"Rate": float64 (high confidence): 1 of 2 numbers are fractions`,
+		`"Scripts": base64 (medium confidence): all 2 strings are valid base64`,
+		`"Since": ndautimestamp (medium confidence): all 1 strings are RFC 3339 times`,
+	}, lines)
+
+	// the suggestions convert the document
+	_, err := json2msgp.Convert(map[string]interface{}{"Rate": 0.5, "Delta": -3.0}, hints)
+	require.NoError(t, err)
+
+	hints, notes = json2msgp.SuggestHints([]byte(`{"a": `))
+	require.Nil(t, hints)
+	require.Len(t, notes, 1)
+	require.Contains(t, notes[0].Reason, "SuggestHints")
+}
@@ -0,0 +1,59 @@
+package json2msgp
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import "encoding/base64"
+
+// SVIKey locates a system variable on chain: the public key of its namespace,
+// and its name within that namespace.
+type SVIKey struct {
+	Namespace []byte
+	Name      string
+}
+
+// value returns k as it appears in an svi document: a pair of base64 strings.
+func (k SVIKey) value() []interface{} {
+	return []interface{}{
+		base64.StdEncoding.EncodeToString(k.Namespace),
+		base64.StdEncoding.EncodeToString([]byte(k.Name)),
+	}
+}
+
+// SVIChange returns the svi entry of a system variable which is read from
+// current until block height changeOn, and from future after it.
+//
+// Like the other svi helpers, it returns the value JSON decoding would, ready
+// for Convert.  Hint "ChangeOn" as "uint64" to match the chain's encoding.
+func SVIChange(current, future SVIKey, changeOn uint64) map[string]interface{} {
+	return map[string]interface{}{
+		"ChangeOn": float64(changeOn),
+		"Current":  current.value(),
+		"Future":   future.value(),
+	}
+}
+
+// SVIEntry returns the svi entry of a system variable which is always read
+// from key.
+func SVIEntry(key SVIKey) map[string]interface{} {
+	return SVIChange(key, key, 0)
+}
+
+// BuildSVI returns an svi document mapping each of names to the system
+// variable of that name in namespace.
+//
+// Entries for variables stored under another name, or which change at some
+// height, can be added to the result with SVIEntry and SVIChange.
+func BuildSVI(namespace []byte, names ...string) map[string]interface{} {
+	svi := make(map[string]interface{}, len(names))
+	for _, name := range names {
+		svi[name] = SVIEntry(SVIKey{Namespace: namespace, Name: name})
+	}
+	return svi
+}
@@ -0,0 +1,52 @@
+package json2msgp_test
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+
+	"github.com/ndau/json2msgp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildSVI(t *testing.T) {
+	namespace, err := base64.StdEncoding.DecodeString("A2etqqaA3qQExilg+ywQ4ElRsyoDJh9lR5A+Thg5PcTR")
+	require.NoError(t, err)
+
+	hints := map[string][]string{"ChangeOn": []string{"uint64"}}
+
+	svi := json2msgp.BuildSVI(namespace, "EAIFeeTable", "LockedRateTable")
+	svi["DefaultRecourseDuration"] = json2msgp.SVIEntry(json2msgp.SVIKey{Namespace: namespace, Name: "DefaultSettlementDuration"})
+	svi["NodeGoodnessFunction"] = json2msgp.SVIChange(
+		json2msgp.SVIKey{Namespace: namespace, Name: "NodeGoodnessFunction"},
+		json2msgp.SVIKey{Namespace: namespace, Name: "NodeGoodnessFunction2"},
+		1000,
+	)
+	got, err := json2msgp.Convert(svi, hints)
+	require.NoError(t, err)
+
+	var want interface{}
+	err = json.Unmarshal([]byte(`{
+		"DefaultRecourseDuration":{"ChangeOn":0,"Current":["A2etqqaA3qQExilg+ywQ4ElRsyoDJh9lR5A+Thg5PcTR","RGVmYXVsdFNldHRsZW1lbnREdXJhdGlvbg=="],"Future":["A2etqqaA3qQExilg+ywQ4ElRsyoDJh9lR5A+Thg5PcTR","RGVmYXVsdFNldHRsZW1lbnREdXJhdGlvbg=="]},
+		"EAIFeeTable":{"ChangeOn":0,"Current":["A2etqqaA3qQExilg+ywQ4ElRsyoDJh9lR5A+Thg5PcTR","RUFJRmVlVGFibGU="],"Future":["A2etqqaA3qQExilg+ywQ4ElRsyoDJh9lR5A+Thg5PcTR","RUFJRmVlVGFibGU="]},
+		"LockedRateTable":{"ChangeOn":0,"Current":["A2etqqaA3qQExilg+ywQ4ElRsyoDJh9lR5A+Thg5PcTR","TG9ja2VkUmF0ZVRhYmxl"],"Future":["A2etqqaA3qQExilg+ywQ4ElRsyoDJh9lR5A+Thg5PcTR","TG9ja2VkUmF0ZVRhYmxl"]},
+		"NodeGoodnessFunction":{"ChangeOn":1000,"Current":["A2etqqaA3qQExilg+ywQ4ElRsyoDJh9lR5A+Thg5PcTR","Tm9kZUdvb2RuZXNzRnVuY3Rpb24="],"Future":["A2etqqaA3qQExilg+ywQ4ElRsyoDJh9lR5A+Thg5PcTR","Tm9kZUdvb2RuZXNzRnVuY3Rpb24y"]}
+	}`), &want)
+	require.NoError(t, err)
+	wantOut, err := json2msgp.Convert(want, hints)
+	require.NoError(t, err)
+
+	require.Equal(t, wantOut, got)
+	schema, ok := json2msgp.SysvarSchema("svi")
+	require.True(t, ok)
+	require.NoError(t, schema.Validate(svi))
+}
@@ -0,0 +1,85 @@
+package json2msgp
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"bytes"
+	"encoding/json"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/tinylib/msgp/msgp"
+)
+
+// The system variable types registered with RegisterSysvar.
+var (
+	sysvarLock  sync.RWMutex
+	sysvarTypes = make(map[string]func() msgp.Marshaler)
+)
+
+// RegisterSysvar registers the Go type of the named system variable.
+//
+// newValue must return a pointer to a new zero value of the type, which must
+// be able to unmarshal itself from JSON.  Its msgp marshaler then determines
+// the encoding exactly, rather than the heuristics.
+//
+// This package does not import the ndau types itself, and registers none:
+// until the program which embeds it registers the ones it knows about,
+// WithSysvar does nothing.  For example:
+//
+//	json2msgp.RegisterSysvar(sv.EAIFeeTableName, func() msgp.Marshaler { return new(eai.FeeTable) })
+func RegisterSysvar(name string, newValue func() msgp.Marshaler) {
+	sysvarLock.Lock()
+	defer sysvarLock.Unlock()
+	sysvarTypes[name] = newValue
+}
+
+// WithSysvar converts the document as the named system variable.  If a type
+// is registered for it, the document is decoded into that type and encoded
+// with its own msgp marshaler, guaranteeing the encoding matches the chain.
+// Otherwise, the heuristics and hints apply as usual, silently; so typed
+// conversion needs RegisterSysvar to have been called for name first.
+func WithSysvar(name string) Option {
+	return func(c *Converter) {
+		c.sysvar = name
+	}
+}
+
+// convertSysvar converts in with the type registered for c.sysvar.  It
+// returns false if there is none.
+func (c *Converter) convertSysvar(in interface{}) ([]byte, bool, error) {
+	if c.sysvar == "" || !c.isMsgp() {
+		return nil, false, nil
+	}
+	sysvarLock.RLock()
+	newValue, ok := sysvarTypes[c.sysvar]
+	sysvarLock.RUnlock()
+	if !ok {
+		return nil, false, nil
+	}
+
+	// Going through JSON text lets the type apply its own JSON conventions,
+	// whether the input arrived as text or as Go values.
+	data, err := json.Marshal(in)
+	if err != nil {
+		return nil, true, errors.Wrapf(err, "sysvar %s: re-encoding JSON", c.sysvar)
+	}
+	value := newValue()
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err = dec.Decode(value); err != nil {
+		return nil, true, errors.Wrapf(err, "sysvar %s: decoding JSON", c.sysvar)
+	}
+	out, err := value.MarshalMsg(make([]byte, 0))
+	if err != nil {
+		return nil, true, errors.Wrapf(err, "sysvar %s: marshalling msgp", c.sysvar)
+	}
+	return out, true, nil
+}
@@ -0,0 +1,62 @@
+package json2msgp_test
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"testing"
+
+	"github.com/ndau/json2msgp"
+	"github.com/stretchr/testify/require"
+	"github.com/tinylib/msgp/msgp"
+)
+
+// feeTable stands in for a msgp-generated sysvar type.  Its Fee is a uint64,
+// which the heuristics would encode as an int64.
+type feeTable []struct {
+	Fee uint64
+	To  []string
+}
+
+func (z feeTable) MarshalMsg(b []byte) ([]byte, error) {
+	b = msgp.AppendArrayHeader(b, uint32(len(z)))
+	for _, entry := range z {
+		b = msgp.AppendMapHeader(b, 2)
+		b = msgp.AppendString(b, "Fee")
+		b = msgp.AppendUint64(b, entry.Fee)
+		b = msgp.AppendString(b, "To")
+		b = msgp.AppendArrayHeader(b, uint32(len(entry.To)))
+		for _, to := range entry.To {
+			b = msgp.AppendString(b, to)
+		}
+	}
+	return b, nil
+}
+
+func TestSysvar(t *testing.T) {
+	json2msgp.RegisterSysvar("TestFeeTable", func() msgp.Marshaler { return new(feeTable) })
+
+	in := []interface{}{map[string]interface{}{"Fee": 200.0, "To": []interface{}{"foo"}}}
+
+	got, err := json2msgp.ConvertWithOptions(in, json2msgp.WithSysvar("TestFeeTable"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("\x91\x82\xa3Fee\xcc\xc8\xa2To\x91\xa3foo"), got)
+
+	// unknown names fall back to the heuristics
+	got, err = json2msgp.ConvertWithOptions(in, json2msgp.WithSysvar("Unknown"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("\x91\x82\xa3Fee\xd1\x00\xc8\xa2To\x91\xa3foo"), got)
+
+	// the type catches fields it doesn't know
+	_, err = json2msgp.ConvertWithOptions(
+		[]interface{}{map[string]interface{}{"Fees": 1.0}},
+		json2msgp.WithSysvar("TestFeeTable"),
+	)
+	require.Error(t, err)
+}
@@ -0,0 +1,73 @@
+package json2msgp
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"fmt"
+	"io"
+)
+
+// Tee is an io.Writer which writes everything to several writers, such as a
+// file to persist MSGP in and a socket to stream it to, without buffering it
+// for each.  Unlike io.MultiWriter, it keeps going when one of its writers
+// fails: it stops writing to that one, and remembers why.  It only fails
+// itself, with ErrAllWritersFailed, once every writer has.
+type Tee struct {
+	writers []io.Writer
+	errs    []error
+	failed  int
+}
+
+// NewTee returns a Tee which writes to writers.
+func NewTee(writers ...io.Writer) *Tee {
+	return &Tee{writers: writers, errs: make([]error, len(writers))}
+}
+
+// Write implements io.Writer.
+func (t *Tee) Write(p []byte) (int, error) {
+	if t.failed == len(t.writers) {
+		return 0, ErrAllWritersFailed
+	}
+	var last error
+	for i, w := range t.writers {
+		if t.errs[i] != nil {
+			continue
+		}
+		n, err := w.Write(p)
+		if err == nil && n < len(p) {
+			err = io.ErrShortWrite
+		}
+		if err != nil {
+			t.errs[i] = err
+			t.failed++
+			last = err
+		}
+	}
+	if t.failed == len(t.writers) {
+		return 0, fmt.Errorf("%w: %s", ErrAllWritersFailed, last)
+	}
+	return len(p), nil
+}
+
+// Errs returns the error which stopped each writer, in the order they were
+// given to NewTee, or nil for those still written to.
+func (t *Tee) Errs() []error {
+	return append([]error(nil), t.errs...)
+}
+
+// ConvertStreamTee is like ConvertStreamCounted, but writes to every writer
+// of outs, through a Tee.  It returns the error which stopped each writer,
+// or nil for those which got everything, as well as the error which stopped
+// the conversion, if any.
+func ConvertStreamTee(in io.Reader, outs []io.Writer, opts ...Option) (StreamResult, []error, error) {
+	t := NewTee(outs...)
+	result, err := ConvertStreamCounted(in, t, opts...)
+	return result, t.Errs(), err
+}
@@ -0,0 +1,63 @@
+package json2msgp_test
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/ndau/json2msgp"
+	"github.com/stretchr/testify/require"
+)
+
+// limitedWriter fails once it has been given more than n bytes.
+type limitedWriter struct {
+	n   int
+	err error
+}
+
+func (w *limitedWriter) Write(p []byte) (int, error) {
+	if len(p) > w.n {
+		return 0, w.err
+	}
+	w.n -= len(p)
+	return len(p), nil
+}
+
+// silentWriter writes nothing and claims no error.
+type silentWriter struct{}
+
+func (silentWriter) Write(p []byte) (int, error) { return 0, nil }
+
+func TestConvertStreamTee(t *testing.T) {
+	var a, b bytes.Buffer
+	broken := errors.New("connection reset")
+	in := `[1] [2] [3]`
+	result, errs, err := json2msgp.ConvertStreamTee(strings.NewReader(in),
+		[]io.Writer{&a, &limitedWriter{n: 3, err: broken}, silentWriter{}, &b},
+		json2msgp.WithMultipleDocuments())
+	require.NoError(t, err)
+	require.Equal(t, json2msgp.StreamResult{Values: 3, Bytes: 6}, result)
+	require.Equal(t, []byte{0x91, 1, 0x91, 2, 0x91, 3}, a.Bytes())
+	require.Equal(t, a.Bytes(), b.Bytes())
+	require.Equal(t, []error{nil, broken, io.ErrShortWrite, nil}, errs)
+
+	// once every writer has failed, so does the conversion
+	result, errs, err = json2msgp.ConvertStreamTee(strings.NewReader(in),
+		[]io.Writer{&limitedWriter{n: 2, err: broken}, &limitedWriter{n: 4, err: broken}},
+		json2msgp.WithMultipleDocuments())
+	require.True(t, errors.Is(err, json2msgp.ErrAllWritersFailed), "got %v", err)
+	require.Contains(t, err.Error(), "connection reset")
+	require.Equal(t, json2msgp.StreamResult{Values: 2, Bytes: 4}, result)
+	require.Equal(t, []error{broken, broken}, errs)
+}
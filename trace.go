@@ -0,0 +1,57 @@
+package json2msgp
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+// Span records that the bytes [Start, End) of the output encode the value at
+// the JSONPath Path of the input.
+type Span struct {
+	Start, End int
+	Path       string
+}
+
+// Trace maps output byte offsets back to the input values which produced them.
+//
+// Spans are recorded as values are completed, so children precede their
+// parents.  Offsets count from the start of everything written by a stream
+// function, including earlier documents and delimiters.
+type Trace []Span
+
+// WithTrace records a Span in *trace for every value converted.
+func WithTrace(trace *Trace) Option {
+	return func(c *Converter) {
+		c.trace = trace
+	}
+}
+
+// Lookup returns the path of the innermost value whose encoding contains the
+// byte at offset, or false if no value does.
+func (t Trace) Lookup(offset int) (string, bool) {
+	var best *Span
+	for i := range t {
+		s := &t[i]
+		if s.Start <= offset && offset < s.End && (best == nil || s.End-s.Start < best.End-best.Start) {
+			best = s
+		}
+	}
+	if best == nil {
+		return "", false
+	}
+	return best.Path, true
+}
+
+// traceSpan records a span for the current value, which occupies
+// buffer[start:end].
+func (c *Converter) traceSpan(start, end int) {
+	*c.trace = append(*c.trace, Span{
+		Start: c.traceBase + start,
+		End:   c.traceBase + end,
+		Path:  c.pathString(),
+	})
+}
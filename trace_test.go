@@ -0,0 +1,53 @@
+package json2msgp_test
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ndau/json2msgp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrace(t *testing.T) {
+	var trace json2msgp.Trace
+	out := &bytes.Buffer{}
+	err := json2msgp.ConvertStreamWithOptions(
+		bytes.NewBufferString(`{"Fee":1,"To":["oACI"]} {"Fee":2}`),
+		out,
+		json2msgp.WithTrace(&trace),
+		json2msgp.WithMultipleDocuments(),
+	)
+	require.NoError(t, err)
+
+	// 82 a3 46 65 65 01 a2 54 6f 91 c4 03 a0 00 88 | 81 a3 46 65 65 02
+	tests := []struct {
+		offset int
+		want   string
+	}{
+		{0, "$"},
+		{1, "$"}, // keys belong to the map
+		{5, "$.Fee"},
+		{9, "$.To"},
+		{10, "$.To[0]"},
+		{14, "$.To[0]"},
+		{15, "$"},
+		{20, "$.Fee"},
+	}
+	for _, tt := range tests {
+		got, ok := trace.Lookup(tt.offset)
+		require.True(t, ok, "offset %d", tt.offset)
+		require.Equal(t, tt.want, got, "offset %d", tt.offset)
+	}
+
+	_, ok := trace.Lookup(out.Len())
+	require.False(t, ok)
+}
@@ -0,0 +1,121 @@
+package json2msgp
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// Consumer supplies JSON messages, e.g. from a Kafka or NATS subscription.
+type Consumer interface {
+	// Next returns the next message.  It returns io.EOF when there are no
+	// more messages.
+	Next(ctx context.Context) ([]byte, error)
+}
+
+// ConsumerFunc adapts a function to the Consumer interface.
+type ConsumerFunc func(ctx context.Context) ([]byte, error)
+
+// Next calls f.
+func (f ConsumerFunc) Next(ctx context.Context) ([]byte, error) {
+	return f(ctx)
+}
+
+// LineConsumer returns a Consumer of newline-delimited JSON messages read
+// from r.  Blank lines are skipped.
+func LineConsumer(r io.Reader) Consumer {
+	br := bufio.NewReader(r)
+	return ConsumerFunc(func(ctx context.Context) ([]byte, error) {
+		for {
+			line, err := br.ReadBytes('\n')
+			line = bytes.TrimSpace(line)
+			if len(line) > 0 {
+				return line, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+	})
+}
+
+// Transformer converts the JSON messages of a Consumer, and hands the MSGP
+// encodings to a producer in batches.  It is the glue for bridges between
+// message queues.
+type Transformer struct {
+	// Produce receives each batch of MSGP messages, in order.  An error stops
+	// the Transformer.
+	Produce func(ctx context.Context, batch [][]byte) error
+
+	// OnError receives each message which fails to convert, e.g. to route it
+	// to a dead letter queue.  If it returns an error, or is nil, the
+	// Transformer stops with that error.
+	OnError func(ctx context.Context, msg []byte, err error) error
+
+	// The number of messages per batch.  Zero or less means 1.  A partial
+	// batch is produced when the consumer runs out of messages.
+	BatchSize int
+
+	// Options configure the conversion of every message.
+	Options []Option
+}
+
+// Run transforms messages from c until it returns io.EOF, or an error occurs.
+func (t *Transformer) Run(ctx context.Context, c Consumer) error {
+	size := t.BatchSize
+	if size < 1 {
+		size = 1
+	}
+	batch := make([][]byte, 0, size)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		err := t.Produce(ctx, batch)
+		batch = make([][]byte, 0, size)
+		return err
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		msg, err := c.Next(ctx)
+		if err == io.EOF {
+			return flush()
+		}
+		if err != nil {
+			return errors.Wrap(err, "Transformer consuming")
+		}
+
+		var out bytes.Buffer
+		if err = ConvertStreamWithOptions(bytes.NewReader(msg), &out, t.Options...); err != nil {
+			if t.OnError == nil {
+				return err
+			}
+			if err = t.OnError(ctx, msg, err); err != nil {
+				return err
+			}
+			continue
+		}
+
+		batch = append(batch, out.Bytes())
+		if len(batch) == size {
+			if err = flush(); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package json2msgp_test
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ndau/json2msgp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransformer(t *testing.T) {
+	var batches [][]string
+	var failed []string
+	tr := json2msgp.Transformer{
+		Produce: func(ctx context.Context, batch [][]byte) error {
+			var b []string
+			for _, msg := range batch {
+				b = append(b, string(msg))
+			}
+			batches = append(batches, b)
+			return nil
+		},
+		OnError: func(ctx context.Context, msg []byte, err error) error {
+			require.True(t, errors.Is(err, json2msgp.ErrUnhintedNumber))
+			failed = append(failed, string(msg))
+			return nil
+		},
+		BatchSize: 2,
+	}
+
+	err := tr.Run(context.Background(), json2msgp.LineConsumer(strings.NewReader("1\n2\n\n1.5\n3\n4\n5")))
+	require.NoError(t, err)
+	require.Equal(t, [][]string{{"\x01", "\x02"}, {"\x03", "\x04"}, {"\x05"}}, batches)
+	require.Equal(t, []string{"1.5"}, failed)
+}
+
+func TestTransformerStops(t *testing.T) {
+	stop := errors.New("stop")
+	produced := 0
+	tr := json2msgp.Transformer{
+		Produce: func(ctx context.Context, batch [][]byte) error {
+			produced++
+			return stop
+		},
+	}
+	err := tr.Run(context.Background(), json2msgp.LineConsumer(strings.NewReader("1\n2\n")))
+	require.Equal(t, stop, err)
+	require.Equal(t, 1, produced)
+
+	// without OnError, a conversion failure stops the transformer
+	err = tr.Run(context.Background(), json2msgp.LineConsumer(strings.NewReader("1.5\n")))
+	require.True(t, errors.Is(err, json2msgp.ErrUnhintedNumber))
+}
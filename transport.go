@@ -0,0 +1,60 @@
+package json2msgp
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"mime"
+	"net/http"
+)
+
+// Transport is an http.RoundTripper which lets a client written against a
+// JSON API talk to an MSGP-only service.  It converts application/json request
+// bodies to MSGP, configured by Options, and MSGP responses to JSON as
+// NewProxy does.  Everything else passes through unchanged.
+//
+//	client := &http.Client{Transport: &json2msgp.Transport{Options: opts}}
+type Transport struct {
+	// Base performs the requests.  If nil, http.DefaultTransport is used.
+	Base http.RoundTripper
+	// Options configure the conversion of request bodies.
+	Options []Option
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	out, err := requestToMsgp(req, t.Options)
+	if err != nil {
+		return nil, err
+	}
+	if acceptsJSONOnly(out.Header.Get("Accept")) {
+		if out == req {
+			out = req.Clone(req.Context())
+		}
+		out.Header.Set("Accept", MsgpackContentType)
+	}
+
+	resp, err := base.RoundTrip(out)
+	if err != nil {
+		return nil, err
+	}
+	mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil || mediaType != MsgpackContentType {
+		return resp, nil
+	}
+	if err = responseToJSON(resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
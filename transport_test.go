@@ -0,0 +1,60 @@
+package json2msgp_test
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ndau/json2msgp"
+	"github.com/stretchr/testify/require"
+	"github.com/tinylib/msgp/msgp"
+)
+
+func TestTransport(t *testing.T) {
+	// the service only speaks MSGP, and echoes what it receives in an array
+	service := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, json2msgp.MsgpackContentType, r.Header.Get("Content-Type"))
+		require.Equal(t, json2msgp.MsgpackContentType, r.Header.Get("Accept"))
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		w.Header().Set("Content-Type", json2msgp.MsgpackContentType)
+		w.Write(append(msgp.AppendArrayHeader(nil, 1), body...))
+	}))
+	defer service.Close()
+
+	client := &http.Client{Transport: &json2msgp.Transport{
+		Options: []json2msgp.Option{json2msgp.WithTypeHints(map[string][]string{"Fee": []string{"uint64"}})},
+	}}
+	req, err := http.NewRequest(http.MethodPost, service.URL, strings.NewReader(`{"Fee":200}`))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+	require.Equal(t, `[{"Fee":200}]`, string(body))
+
+	// the caller's request is left alone
+	require.Equal(t, "application/json", req.Header.Get("Accept"))
+
+	req, err = http.NewRequest(http.MethodPost, service.URL, strings.NewReader(`{"Rate":1.5}`))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	_, err = client.Do(req)
+	require.Error(t, err)
+}
@@ -0,0 +1,75 @@
+package json2msgp
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"fmt"
+
+	"github.com/tinylib/msgp/msgp"
+)
+
+// ValidateMsgp checks that b holds exactly one complete, well-formed MSGP
+// value: every prefix is valid, every map and array has as many elements as
+// its header says, and nothing follows the value.  It is cheap enough to use
+// as a safety check on the output of every conversion.
+//
+// Values nested more than DefaultMaxDepth deep are rejected with ErrMaxDepth,
+// as this package never produces them.
+func ValidateMsgp(b []byte) error {
+	rest, err := validateValue(b, len(b), 0)
+	if err != nil {
+		return err
+	}
+	if len(rest) > 0 {
+		return fmt.Errorf("%w: %d bytes at offset %d", ErrTrailingMsgp, len(rest), len(b)-len(rest))
+	}
+	return nil
+}
+
+// validateValue validates the value at the start of b, which is the tail of
+// a document of size bytes, and returns what follows it.
+func validateValue(b []byte, size, depth int) ([]byte, error) {
+	if depth > DefaultMaxDepth {
+		return b, fmt.Errorf("%w of %d at offset %d", ErrMaxDepth, DefaultMaxDepth, size-len(b))
+	}
+	var elements uint32
+	var rest []byte
+	var err error
+	switch msgp.NextType(b) {
+	case msgp.MapType:
+		elements, rest, err = msgp.ReadMapHeaderBytes(b)
+		// a key and a value per entry, which can overflow a uint32
+		if err == nil {
+			return validateElements(rest, size, depth, 2*uint64(elements))
+		}
+	case msgp.ArrayType:
+		elements, rest, err = msgp.ReadArrayHeaderBytes(b)
+		if err == nil {
+			return validateElements(rest, size, depth, uint64(elements))
+		}
+	default:
+		rest, err = msgp.Skip(b)
+	}
+	if err != nil {
+		return b, fmt.Errorf("%w at offset %d: %s", ErrInvalidMsgp, size-len(b), err)
+	}
+	return rest, nil
+}
+
+func validateElements(b []byte, size, depth int, n uint64) ([]byte, error) {
+	var err error
+	for ; n > 0; n-- {
+		b, err = validateValue(b, size, depth+1)
+		if err != nil {
+			return b, err
+		}
+	}
+	return b, nil
+}
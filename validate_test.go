@@ -0,0 +1,61 @@
+package json2msgp_test
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ndau/json2msgp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateMsgp(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		wantErr error
+		wantMsg string
+	}{
+		{"nil", "c0", nil, ""},
+		{"map", "82 a1 61 01 a1 62 92 c3 c4 01 00", nil, ""},
+		{"empty", "", json2msgp.ErrInvalidMsgp, "offset 0"},
+		{"never used", "91 c1", json2msgp.ErrInvalidMsgp, "offset 1"},
+		{"short map", "82 a1 61 01", json2msgp.ErrInvalidMsgp, "offset 4"},
+		{"short array16", "dc 00 03 01 02", json2msgp.ErrInvalidMsgp, "offset 5"},
+		{"short string", "a3 61 62", json2msgp.ErrInvalidMsgp, "offset 0"},
+		{"huge map32", "df ff ff ff ff", json2msgp.ErrInvalidMsgp, "offset 5"},
+		{"trailing", "c0 c0", json2msgp.ErrTrailingMsgp, "1 bytes at offset 1"},
+		{"too deep", strings.Repeat("91", json2msgp.DefaultMaxDepth+1) + "c0", json2msgp.ErrMaxDepth, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in, err := hex.DecodeString(strings.Replace(tt.in, " ", "", -1))
+			require.NoError(t, err)
+			err = json2msgp.ValidateMsgp(in)
+			if tt.wantErr == nil {
+				require.NoError(t, err)
+				return
+			}
+			require.True(t, errors.Is(err, tt.wantErr), "got %v", err)
+			require.Contains(t, err.Error(), tt.wantMsg)
+		})
+	}
+
+	// everything the converter produces is valid
+	out, err := json2msgp.Convert(map[string]interface{}{
+		"a": []interface{}{"x", 1.0, nil, map[string]interface{}{}},
+		"b": "oACI",
+	}, nil)
+	require.NoError(t, err)
+	require.NoError(t, json2msgp.ValidateMsgp(out))
+}
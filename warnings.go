@@ -0,0 +1,59 @@
+package json2msgp
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import "fmt"
+
+// WarningKind classifies a Warning.
+type WarningKind int
+
+const (
+	// WarnBase64 means a string was decoded as base64 into a byte array.
+	WarnBase64 WarningKind = iota
+	// WarnAddress means a string was recognized as an ndau address.
+	WarnAddress
+	// WarnBinary means a string which was not valid utf-8 became a byte array.
+	WarnBinary
+	// WarnLossyNumber means a number did not survive conversion to its hinted
+	// type exactly: a fractional part was truncated, or float32 lost precision.
+	WarnLossyNumber
+)
+
+// Warning describes a conversion decision which succeeded, but which a
+// reviewer may want to double-check.
+type Warning struct {
+	Kind    WarningKind
+	Path    string
+	Message string
+}
+
+func (w Warning) String() string {
+	return fmt.Sprintf("%s: %s", w.Path, w.Message)
+}
+
+// WithWarnings appends a Warning to *warnings for each ambiguous decision
+// made during conversion.
+func WithWarnings(warnings *[]Warning) Option {
+	return func(c *Converter) {
+		c.warnings = warnings
+	}
+}
+
+// warn records a warning about the current value, if warnings are wanted.
+func (c *Converter) warn(kind WarningKind, format string, args ...interface{}) {
+	if c.warnings == nil {
+		return
+	}
+	*c.warnings = append(*c.warnings, Warning{
+		Kind:    kind,
+		Path:    c.pathString(),
+		Message: fmt.Sprintf(format, args...),
+	})
+}
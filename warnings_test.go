@@ -0,0 +1,46 @@
+package json2msgp_test
+
+// ----- ---- --- -- -
+// Copyright 2020 The Axiom Foundation. All Rights Reserved.
+//
+// Licensed under the Apache License 2.0 (the "License").  You may not use
+// this file except in compliance with the License.  You can obtain a copy
+// in the file LICENSE in the source distribution or at
+// https://www.apache.org/licenses/LICENSE-2.0.txt
+// - -- --- ---- -----
+
+import (
+	"testing"
+
+	"github.com/ndau/json2msgp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWarnings(t *testing.T) {
+	in := map[string]interface{}{
+		"Addr":   "ndnf9ffbzhyf8mk7z5vvqc4quzz5i2exp5zgsmhyhc9cuwr4",
+		"Bin":    string([]byte{0xff}),
+		"Fee":    1.5,
+		"Rate":   0.1,
+		"Script": "oACI",
+		"Text":   "hello",
+	}
+	hints := map[string][]string{"Fee": []string{"int64"}, "Rate": []string{"float32"}}
+
+	var warnings []json2msgp.Warning
+	_, err := json2msgp.ConvertWithOptions(in, json2msgp.WithTypeHints(hints), json2msgp.WithWarnings(&warnings))
+	require.NoError(t, err)
+
+	kinds := make(map[string]json2msgp.WarningKind)
+	for _, w := range warnings {
+		kinds[w.Path] = w.Kind
+	}
+	require.Equal(t, map[string]json2msgp.WarningKind{
+		"$.Addr":   json2msgp.WarnAddress,
+		"$.Bin":    json2msgp.WarnBinary,
+		"$.Fee":    json2msgp.WarnLossyNumber,
+		"$.Rate":   json2msgp.WarnLossyNumber,
+		"$.Script": json2msgp.WarnBase64,
+	}, kinds)
+	require.Equal(t, `$.Script: string "oACI" decoded as base64 into 3 bytes`, warnings[4].String())
+}